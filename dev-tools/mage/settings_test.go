@@ -6,11 +6,14 @@ package mage
 
 import (
 	"context"
+	"runtime"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	v1 "github.com/elastic/elastic-agent/pkg/api/v1"
 )
 
 func TestGetVersion(t *testing.T) {
@@ -911,3 +914,19 @@ func TestEnvMap(t *testing.T) {
 		assert.Equal(t, "CustomValue", envMap["CustomKey"])
 	})
 }
+
+func TestGeneratePackageManifestVersionedHome(t *testing.T) {
+	const hash = "4f2d39ab0c1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a"
+	for _, snapshot := range []bool{false, true} {
+		manifest, err := GeneratePackageManifest("elastic-agent", "9.1.0", snapshot, hash, hash[:6], false, nil)
+		require.NoError(t, err)
+		m, err := v1.ParseManifest(strings.NewReader(manifest))
+		require.NoError(t, err)
+
+		// the runtime expectations match what packaging produces
+		assert.Equal(t, "data/elastic-agent-"+hash[:6], m.Package.VersionedHome)
+		resolved, ok := m.ResolvePath(m.Package.VersionedHome)
+		require.True(t, ok)
+		assert.Equal(t, m.ExpectedVersionedHome("9.1.0"+GenerateSnapshotSuffix(snapshot), runtime.GOOS, runtime.GOARCH), resolved)
+	}
+}
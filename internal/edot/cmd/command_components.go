@@ -19,10 +19,12 @@ func newComponentsCommandWithArgs(_ []string, _ *cli.IOStreams) *cobra.Command {
 		SilenceUsage:  true, // do not display usage on error
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			return otelcol.Components(cmd)
+			output, _ := cmd.Flags().GetString("output")
+			return otelcol.Components(cmd, output)
 		},
 	}
 
+	cmd.Flags().StringP("output", "o", otelcol.ComponentsOutputYAML, "Output format, one of: yaml, json")
 	SetupOtelFlags(cmd.Flags())
 	cmd.SetHelpFunc(func(c *cobra.Command, s []string) {
 		hideInheritedFlags(c)
@@ -50,7 +50,7 @@ func TestComponentsCommand(t *testing.T) {
 
 	b := bytes.NewBufferString("")
 	cmd.SetOut(b)
-	err = otelcol.Components(cmd)
+	err = otelcol.Components(cmd, otelcol.ComponentsOutputYAML)
 	require.NoError(t, err)
 	outputComponents := &componentsOutput{}
 	err = yaml.Unmarshal(b.Bytes(), outputComponents)
@@ -8,8 +8,11 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -50,7 +53,7 @@ func TestComponentsCommand(t *testing.T) {
 
 	b := bytes.NewBufferString("")
 	cmd.SetOut(b)
-	err = otelcol.Components(cmd)
+	err = otelcol.Components(cmd, otelcol.ComponentsOutputYAML)
 	require.NoError(t, err)
 	outputComponents := &componentsOutput{}
 	err = yaml.Unmarshal(b.Bytes(), outputComponents)
@@ -107,3 +110,38 @@ func TestComponentsCommand(t *testing.T) {
 		require.Truef(t, found, "extension not found: %s", extension.Name)
 	}
 }
+
+func TestComponentsCommandJSON(t *testing.T) {
+	cmd := &cobra.Command{}
+
+	yamlOut := bytes.NewBufferString("")
+	cmd.SetOut(yamlOut)
+	require.NoError(t, otelcol.Components(cmd, otelcol.ComponentsOutputYAML))
+	yamlComponents := &componentsOutput{}
+	require.NoError(t, yaml.Unmarshal(yamlOut.Bytes(), yamlComponents))
+
+	jsonOut := bytes.NewBufferString("")
+	cmd.SetOut(jsonOut)
+	require.NoError(t, otelcol.Components(cmd, otelcol.ComponentsOutputJSON))
+	jsonComponents := &componentsOutput{}
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), jsonComponents))
+
+	require.NotEmpty(t, jsonComponents.Receivers)
+	require.Equal(t, yamlComponents, jsonComponents)
+
+	// the JSON keys match the YAML ones
+	var shape struct {
+		BuildInfo map[string]any   `json:"buildinfo"`
+		Receivers []map[string]any `json:"receivers"`
+	}
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &shape))
+	require.ElementsMatch(t, []string{"command", "description", "version"}, slices.Collect(maps.Keys(shape.BuildInfo)))
+	require.ElementsMatch(t, []string{"name", "stability"}, slices.Collect(maps.Keys(shape.Receivers[0])))
+}
+
+func TestComponentsCommandUnsupportedOutput(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.SetOut(bytes.NewBufferString(""))
+	err := otelcol.Components(cmd, "xml")
+	require.ErrorContains(t, err, `unsupported output format "xml"`)
+}
@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/edot/otelcol"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func newSchemaCommandWithArgs(_ []string, _ *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema <component-id>",
+		Short: "Outputs the configuration keys of a component in this collector distribution",
+		Long: `Outputs the configuration keys of a receiver, processor, exporter, connector or extension in this collector distribution,
+with their type and default value. The component is identified by its type, e.g. "filelog", the name of a component ID such as "otlp/elastic" is ignored.
+The output format is not stable and can change between releases.`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true, // do not display usage on error
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind, _ := cmd.Flags().GetString("kind")
+			output, _ := cmd.Flags().GetString("output")
+			return otelcol.Schema(cmd, args[0], kind, output)
+		},
+	}
+
+	cmd.Flags().String("kind", "", "Kind of the component, one of: receiver, processor, exporter, connector, extension. Defaults to all kinds")
+	cmd.Flags().StringP("output", "o", otelcol.ComponentsOutputYAML, "Output format, one of: yaml, json")
+	cmd.SetHelpFunc(func(c *cobra.Command, s []string) {
+		hideInheritedFlags(c)
+		c.Root().HelpFunc()(c, s)
+	})
+
+	return cmd
+}
@@ -0,0 +1,336 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+const (
+	filelogReceiverType      = "filelog"
+	elasticsearchExporterID  = "elasticsearch"
+	debugExporterID          = "debug"
+	beatsFieldsPrefix        = "fields."
+	beatsDefaultESProtocol   = "http"
+	beatsLogInputType        = "log"
+	beatsFilestreamInputType = "filestream"
+)
+
+// componentNameRegexp matches the names the collector accepts in a component ID.
+var componentNameRegexp = regexp.MustCompile(`^[^\pZ\pC\pS/]+$`)
+
+// beatsInputSettings are the input settings translated into the filelog receiver configuration, by input type.
+var beatsInputSettings = map[string]map[string]bool{
+	beatsLogInputType:        {"type": true, "id": true, "enabled": true, "paths": true, "encoding": true, "fields": true, "fields_under_root": true, "tail_files": true},
+	beatsFilestreamInputType: {"type": true, "id": true, "enabled": true, "paths": true, "encoding": true, "fields": true, "fields_under_root": true},
+}
+
+func newFromBeatsCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "from-beats",
+		Short: "Translates the log and filestream inputs of a Filebeat configuration into OpenTelemetry collector configuration",
+		Long: `Translates the log and filestream inputs of a Filebeat configuration into filelog receivers and prints the equivalent OpenTelemetry collector configuration to stdout.
+The Elasticsearch output is translated into the elasticsearch exporter, the debug exporter is used otherwise.
+Inputs, modules and settings that cannot be translated are reported on stderr and skipped.`,
+		SilenceUsage:  true, // do not display usage on error
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			input, _ := cmd.Flags().GetString("input")
+			output, _ := cmd.Flags().GetString("output")
+			if output != translateOutputYAML && output != translateOutputJSON {
+				return fmt.Errorf("unsupported output format %q, must be one of: %s, %s", output, translateOutputYAML, translateOutputJSON)
+			}
+			return translateBeatsConfigFile(input, output, streams)
+		},
+	}
+
+	cmd.Flags().StringP("input", "i", "", "Beats configuration file to translate, e.g. filebeat.yml")
+	cmd.Flags().StringP("output", "o", translateOutputYAML, "Output format of the collector configuration, one of: yaml, json")
+	_ = cmd.MarkFlagRequired("input")
+	origHelpFunc := cmd.HelpFunc()
+	cmd.SetHelpFunc(func(c *cobra.Command, s []string) {
+		hideInheritedFlags(c)
+		origHelpFunc(c, s)
+	})
+
+	return cmd
+}
+
+func translateBeatsConfigFile(input string, output string, streams *cli.IOStreams) error {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("failed to read beats configuration %s: %w", input, err)
+	}
+	var beatsCfg map[string]any
+	if err := yaml.Unmarshal(data, &beatsCfg); err != nil {
+		return fmt.Errorf("failed to parse beats configuration %s: %w", input, err)
+	}
+
+	otelCfg, skipped := translateBeatsConfig(beatsCfg)
+	for _, msg := range skipped {
+		fmt.Fprintf(streams.Err, "skipping %s\n", msg)
+	}
+	if otelCfg == nil {
+		return errors.New("beats configuration contains no inputs that can be translated to OpenTelemetry collector configuration")
+	}
+	return writeTranslatedConfig(streams.Out, otelCfg, output)
+}
+
+// translateBeatsConfig translates the log and filestream inputs of a beats configuration into
+// filelog receivers of a logs pipeline. It returns a description of every input, module or setting
+// that is not translated, and a nil configuration when no input is translated.
+func translateBeatsConfig(beatsCfg map[string]any) (map[string]any, []string) {
+	var skipped []string
+	if modules, ok := lookupBeatsSetting(beatsCfg, "metricbeat.modules").([]any); ok {
+		for i, module := range modules {
+			name, _ := lookupBeatsSetting(asMap(module), "module").(string)
+			skipped = append(skipped, fmt.Sprintf("metricbeat module %d (%s): metricbeat modules are not supported", i, name))
+		}
+	}
+
+	receivers := map[string]any{}
+	inputs, _ := lookupBeatsSetting(beatsCfg, "filebeat.inputs").([]any)
+	for i, input := range inputs {
+		inputCfg := asMap(input)
+		id, receiver, inputSkipped, err := translateBeatsInput(i, inputCfg)
+		skipped = append(skipped, inputSkipped...)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("filebeat input %d: %v", i, err))
+			continue
+		}
+		receivers[id] = receiver
+	}
+	if len(receivers) == 0 {
+		return nil, skipped
+	}
+
+	receiverIDs := make([]string, 0, len(receivers))
+	for id := range receivers {
+		receiverIDs = append(receiverIDs, id)
+	}
+	sort.Strings(receiverIDs)
+
+	exporterID, exporter, outputSkipped := translateBeatsOutput(beatsCfg)
+	skipped = append(skipped, outputSkipped...)
+
+	return map[string]any{
+		"receivers": receivers,
+		"exporters": map[string]any{exporterID: exporter},
+		"service": map[string]any{
+			"pipelines": map[string]any{
+				"logs": map[string]any{
+					"receivers": receiverIDs,
+					"exporters": []string{exporterID},
+				},
+			},
+		},
+	}, skipped
+}
+
+// translateBeatsInput translates the input at index i into a filelog receiver, returning its ID
+// and the settings of the input that are not translated.
+func translateBeatsInput(i int, input map[string]any) (string, map[string]any, []string, error) {
+	inputType, _ := input["type"].(string)
+	if inputType == "" {
+		inputType = beatsLogInputType
+	}
+	settings, ok := beatsInputSettings[inputType]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("input type %q is not supported", inputType)
+	}
+	if enabled, ok := input["enabled"].(bool); ok && !enabled {
+		return "", nil, nil, errors.New("input is disabled")
+	}
+
+	rawPaths, _ := input["paths"].([]any)
+	paths := make([]string, 0, len(rawPaths))
+	for _, p := range rawPaths {
+		if s, ok := p.(string); ok && s != "" {
+			paths = append(paths, s)
+		}
+	}
+	if len(paths) == 0 {
+		return "", nil, nil, errors.New("input has no paths")
+	}
+
+	id := fmt.Sprintf("%s/%d", filelogReceiverType, i)
+	if inputID, ok := input["id"].(string); ok && componentNameRegexp.MatchString(inputID) {
+		id = filelogReceiverType + "/" + inputID
+	}
+	describe := fmt.Sprintf("filebeat input %d (%s)", i, inputType)
+
+	receiver := map[string]any{
+		"include":           paths,
+		"start_at":          "beginning",
+		"include_file_path": true,
+	}
+	if tail, _ := input["tail_files"].(bool); tail {
+		receiver["start_at"] = "end"
+	}
+	if encoding, ok := input["encoding"].(string); ok && encoding != "" {
+		receiver["encoding"] = encoding
+	}
+
+	var skipped []string
+	if fields := asMap(input["fields"]); len(fields) > 0 {
+		prefix := beatsFieldsPrefix
+		if underRoot, _ := input["fields_under_root"].(bool); underRoot {
+			prefix = ""
+		}
+		attributes := map[string]any{}
+		for _, field := range flattenBeatsFields(prefix, fields, attributes) {
+			skipped = append(skipped, fmt.Sprintf("%s field %s: only string fields are supported", describe, field))
+		}
+		if len(attributes) > 0 {
+			receiver["attributes"] = attributes
+		}
+	}
+
+	var unsupported []string
+	for key := range input {
+		if !settings[key] {
+			unsupported = append(unsupported, key)
+		}
+	}
+	sort.Strings(unsupported)
+	for _, key := range unsupported {
+		skipped = append(skipped, fmt.Sprintf("%s setting %s: setting is not supported", describe, key))
+	}
+	return id, receiver, skipped, nil
+}
+
+// flattenBeatsFields adds the string fields of fields to attributes, with their dotted path
+// prefixed with prefix, and returns the path of the fields that are not strings.
+func flattenBeatsFields(prefix string, fields map[string]any, attributes map[string]any) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var unsupported []string
+	for _, k := range keys {
+		switch v := fields[k].(type) {
+		case string:
+			attributes[prefix+k] = v
+		case map[string]any:
+			unsupported = append(unsupported, flattenBeatsFields(prefix+k+".", v, attributes)...)
+		default:
+			unsupported = append(unsupported, prefix+k)
+		}
+	}
+	return unsupported
+}
+
+// translateBeatsOutput translates the Elasticsearch output of the beats configuration into the
+// elasticsearch exporter, and falls back to the debug exporter for any other output.
+func translateBeatsOutput(beatsCfg map[string]any) (string, map[string]any, []string) {
+	debug := map[string]any{"verbosity": "basic"}
+	names := beatsOutputNames(beatsCfg)
+	if len(names) == 0 {
+		return debugExporterID, debug, []string{"output: no output is configured, using the debug exporter"}
+	}
+
+	es := asMap(lookupBeatsSetting(beatsCfg, "output."+elasticsearchExporterID))
+	rawHosts, _ := es["hosts"].([]any)
+	if len(rawHosts) == 0 {
+		return debugExporterID, debug, []string{fmt.Sprintf("output %s: only the elasticsearch output with hosts is supported, using the debug exporter", strings.Join(names, ", "))}
+	}
+
+	protocol, _ := es["protocol"].(string)
+	if protocol == "" {
+		protocol = beatsDefaultESProtocol
+	}
+	endpoints := make([]string, 0, len(rawHosts))
+	for _, h := range rawHosts {
+		host, ok := h.(string)
+		if !ok || host == "" {
+			continue
+		}
+		if !strings.Contains(host, "://") {
+			host = protocol + "://" + host
+		}
+		endpoints = append(endpoints, host)
+	}
+
+	exporter := map[string]any{"endpoints": endpoints}
+	if user, ok := es["username"].(string); ok && user != "" {
+		exporter["user"] = user
+	}
+	if password, ok := es["password"].(string); ok && password != "" {
+		exporter["password"] = password
+	}
+
+	var skipped []string
+	var unsupported []string
+	for key := range es {
+		switch key {
+		case "hosts", "protocol", "username", "password":
+		default:
+			unsupported = append(unsupported, key)
+		}
+	}
+	sort.Strings(unsupported)
+	for _, key := range unsupported {
+		skipped = append(skipped, fmt.Sprintf("output elasticsearch setting %s: setting is not supported", key))
+	}
+	return elasticsearchExporterID, exporter, skipped
+}
+
+// beatsOutputNames returns the sorted names of the outputs configured in the beats configuration.
+func beatsOutputNames(beatsCfg map[string]any) []string {
+	var names []string
+	for name := range asMap(beatsCfg["output"]) {
+		names = append(names, name)
+	}
+	for key := range beatsCfg {
+		if name, ok := strings.CutPrefix(key, "output."); ok {
+			name, _, _ = strings.Cut(name, ".")
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+// lookupBeatsSetting returns the value of the dotted setting key in cfg, which beats accept both
+// as a single dotted key, `filebeat.inputs:`, and as nested maps, `filebeat: {inputs: }`.
+func lookupBeatsSetting(cfg map[string]any, key string) any {
+	if v, ok := cfg[key]; ok {
+		return v
+	}
+	for i := strings.Index(key, "."); i > 0; i = nextDot(key, i) {
+		if nested, ok := cfg[key[:i]].(map[string]any); ok {
+			if v := lookupBeatsSetting(nested, key[i+1:]); v != nil {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+func nextDot(key string, i int) int {
+	next := strings.Index(key[i+1:], ".")
+	if next < 0 {
+		return -1
+	}
+	return i + 1 + next
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
@@ -0,0 +1,159 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func TestTranslateBeatsConfig(t *testing.T) {
+	var beatsCfg map[string]any
+	require.NoError(t, yaml.Unmarshal([]byte(`
+filebeat.inputs:
+  - type: filestream
+    id: system-logs
+    paths: [/var/log/system.log, /var/log/syslog]
+    fields:
+      env: test
+      owner:
+        team: observability
+      retries: 3
+    parsers:
+      - ndjson: {}
+  - type: log
+    paths: [/var/log/app.log]
+    tail_files: true
+    fields_under_root: true
+    fields:
+      app: myapp
+  - type: log
+    enabled: false
+    paths: [/var/log/disabled.log]
+  - type: tcp
+    host: localhost:9000
+metricbeat:
+  modules:
+    - module: system
+output.elasticsearch:
+  hosts: [localhost:9200, https://es.example.com:9200]
+  username: elastic
+  password: changeme
+  index: custom
+`), &beatsCfg))
+
+	otelCfg, skipped := translateBeatsConfig(beatsCfg)
+	require.Equal(t, map[string]any{
+		"receivers": map[string]any{
+			"filelog/system-logs": map[string]any{
+				"include":           []string{"/var/log/system.log", "/var/log/syslog"},
+				"start_at":          "beginning",
+				"include_file_path": true,
+				"attributes":        map[string]any{"fields.env": "test", "fields.owner.team": "observability"},
+			},
+			"filelog/1": map[string]any{
+				"include":           []string{"/var/log/app.log"},
+				"start_at":          "end",
+				"include_file_path": true,
+				"attributes":        map[string]any{"app": "myapp"},
+			},
+		},
+		"exporters": map[string]any{
+			"elasticsearch": map[string]any{
+				"endpoints": []string{"http://localhost:9200", "https://es.example.com:9200"},
+				"user":      "elastic",
+				"password":  "changeme",
+			},
+		},
+		"service": map[string]any{
+			"pipelines": map[string]any{
+				"logs": map[string]any{
+					"receivers": []string{"filelog/1", "filelog/system-logs"},
+					"exporters": []string{"elasticsearch"},
+				},
+			},
+		},
+	}, otelCfg)
+	require.Equal(t, []string{
+		"metricbeat module 0 (system): metricbeat modules are not supported",
+		"filebeat input 0 (filestream) field fields.retries: only string fields are supported",
+		"filebeat input 0 (filestream) setting parsers: setting is not supported",
+		"filebeat input 2: input is disabled",
+		`filebeat input 3: input type "tcp" is not supported`,
+		"output elasticsearch setting index: setting is not supported",
+	}, skipped)
+}
+
+func TestTranslateBeatsConfigOutputs(t *testing.T) {
+	inputs := []any{map[string]any{"type": "log", "paths": []any{"/var/log/app.log"}}}
+
+	otelCfg, skipped := translateBeatsConfig(map[string]any{"filebeat.inputs": inputs})
+	require.Equal(t, map[string]any{"debug": map[string]any{"verbosity": "basic"}}, otelCfg["exporters"])
+	require.Equal(t, []string{"output: no output is configured, using the debug exporter"}, skipped)
+
+	otelCfg, skipped = translateBeatsConfig(map[string]any{
+		"filebeat.inputs": inputs,
+		"output":          map[string]any{"logstash": map[string]any{"hosts": []any{"localhost:5044"}}},
+	})
+	require.Equal(t, map[string]any{"debug": map[string]any{"verbosity": "basic"}}, otelCfg["exporters"])
+	require.Equal(t, []string{"output logstash: only the elasticsearch output with hosts is supported, using the debug exporter"}, skipped)
+
+	otelCfg, skipped = translateBeatsConfig(map[string]any{
+		"filebeat.inputs": []any{map[string]any{"type": "log"}},
+	})
+	require.Nil(t, otelCfg)
+	require.Equal(t, []string{"filebeat input 0: input has no paths"}, skipped)
+}
+
+func TestFromBeatsCommand(t *testing.T) {
+	dir := t.TempDir()
+	beatsCfg := filepath.Join(dir, "filebeat.yml")
+	require.NoError(t, os.WriteFile(beatsCfg, []byte(`
+filebeat.inputs:
+  - type: filestream
+    paths: [/var/log/system.log]
+    prospector.scanner.check_interval: 1s
+`), 0o600))
+	metricbeatCfg := filepath.Join(dir, "metricbeat.yml")
+	require.NoError(t, os.WriteFile(metricbeatCfg, []byte(`
+metricbeat.modules:
+  - module: system
+`), 0o600))
+
+	t.Run("translated", func(t *testing.T) {
+		streams, _, out, errOut := cli.NewTestingIOStreams()
+		cmd := newFromBeatsCommandWithArgs(nil, streams)
+		cmd.SetArgs([]string{"--input", beatsCfg})
+		require.NoError(t, cmd.Execute())
+
+		var printed map[string]any
+		require.NoError(t, yaml.Unmarshal(out.Bytes(), &printed))
+		require.Contains(t, printed["receivers"], "filelog/0")
+		require.Contains(t, errOut.String(), "skipping filebeat input 0 (filestream) setting prospector.scanner.check_interval: setting is not supported\n")
+		require.Contains(t, errOut.String(), "skipping output: no output is configured, using the debug exporter\n")
+	})
+
+	t.Run("nothing to translate", func(t *testing.T) {
+		streams, _, out, errOut := cli.NewTestingIOStreams()
+		cmd := newFromBeatsCommandWithArgs(nil, streams)
+		cmd.SetArgs([]string{"--input", metricbeatCfg})
+		require.ErrorContains(t, cmd.Execute(), "beats configuration contains no inputs")
+		require.Empty(t, out.String())
+		require.Contains(t, errOut.String(), "skipping metricbeat module 0 (system): metricbeat modules are not supported\n")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		streams, _, _, _ := cli.NewTestingIOStreams()
+		cmd := newFromBeatsCommandWithArgs(nil, streams)
+		cmd.SetArgs([]string{"--input", filepath.Join(dir, "missing.yml")})
+		require.ErrorContains(t, cmd.Execute(), "failed to read beats configuration")
+	})
+}
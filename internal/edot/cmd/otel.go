@@ -7,7 +7,9 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -44,6 +46,10 @@ func NewOtelCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Comman
 			if err != nil {
 				return err
 			}
+			cfgFiles, err = readStdinConfig(cfgFiles, streamsIn(streams))
+			if err != nil {
+				return err
+			}
 			supervised, err := cmd.Flags().GetBool(manager.OtelSetSupervisedFlagName)
 			if err != nil {
 				return err
@@ -59,7 +65,24 @@ func NewOtelCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Comman
 			if err := prepareEnv(); err != nil {
 				return err
 			}
-			return RunCollector(cmd.Context(), cfgFiles, supervised, supervisedLoggingLevel, supervisedMonitoringURL)
+			remoteConfig, err := GetRemoteConfigSettings(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			envAllowList, err := GetEnvAllowList(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			healthAddr, err := cmd.Flags().GetString(otelHealthAddrFlagName)
+			if err != nil {
+				return err
+			}
+			opts := []edotOtelCol.SettingOpt{edotOtelCol.WithRemoteConfig(remoteConfig), edotOtelCol.WithEnvAllowList(envAllowList)}
+			if !supervised && remoteConfig.RefreshInterval > 0 {
+				// reloading is opt-in, the config files are watched like the remote config locations are refreshed
+				opts = append(opts, edotOtelCol.WithConfigFilesWatch())
+			}
+			return RunCollector(cmd.Context(), cfgFiles, supervised, supervisedLoggingLevel, supervisedMonitoringURL, healthAddr, opts...)
 		},
 		PreRun: func(c *cobra.Command, args []string) {
 			// hide inherited flags not to bloat help with flags not related to otel
@@ -78,19 +101,33 @@ func NewOtelCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Comman
 	SetupOtelFlags(cmd.Flags())
 	cmd.AddCommand(newValidateCommandWithArgs(args, streams))
 	cmd.AddCommand(newComponentsCommandWithArgs(args, streams))
+	cmd.AddCommand(newSchemaCommandWithArgs(args, streams))
+	cmd.AddCommand(newTranslateCommandWithArgs(args, streams))
+	cmd.AddCommand(newFromBeatsCommandWithArgs(args, streams))
 	cmd.AddCommand(newOtelDiagnosticsCommand(streams))
+	cmd.AddCommand(newPipelineCommand(streams))
 
 	return cmd
 }
 
+// streamsIn returns the stdin of streams, nil when there is none.
+func streamsIn(streams *cli.IOStreams) io.Reader {
+	if streams == nil {
+		return nil
+	}
+	return streams.In
+}
+
 func hideInheritedFlags(c *cobra.Command) {
 	c.InheritedFlags().VisitAll(func(f *pflag.Flag) {
 		f.Hidden = true
 	})
 }
 
-func RunCollector(cmdCtx context.Context, configFiles []string, supervised bool, supervisedLoggingLevel string, supervisedMonitoringURL string) error {
-	settings, err := prepareCollectorSettings(configFiles, supervised, supervisedLoggingLevel)
+// RunCollector runs the collector with configFiles until cmdCtx is cancelled. When healthAddr is set,
+// the liveness and readiness probes of the collector are served on it.
+func RunCollector(cmdCtx context.Context, configFiles []string, supervised bool, supervisedLoggingLevel string, supervisedMonitoringURL string, healthAddr string, opts ...edotOtelCol.SettingOpt) error {
+	settings, err := prepareCollectorSettings(configFiles, supervised, supervisedLoggingLevel, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to prepare collector settings: %w", err)
 	}
@@ -113,6 +150,21 @@ func RunCollector(cmdCtx context.Context, configFiles []string, supervised bool,
 		}()
 	}
 
+	var runOpts []edotOtelCol.RunOpt
+	if healthAddr != "" {
+		health, err := edotOtelCol.NewHealthServer(healthAddr)
+		if err != nil {
+			return err
+		}
+		health.Start()
+		defer func() {
+			stopCtx, stopCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer stopCancel()
+			_ = health.Stop(stopCtx)
+		}()
+		runOpts = append(runOpts, edotOtelCol.WithHealthServer(health))
+	}
+
 	service.BeforeRun()
 	defer service.Cleanup()
 
@@ -128,7 +180,7 @@ func RunCollector(cmdCtx context.Context, configFiles []string, supervised bool,
 		service.HandleSignals(stopCollector, cancel)
 	}
 
-	return edotOtelCol.Run(ctx, stop, settings.otelSettings)
+	return edotOtelCol.Run(ctx, stop, settings.otelSettings, runOpts...)
 }
 
 type edotSettings struct {
@@ -136,15 +188,18 @@ type edotSettings struct {
 	otelSettings *otelcol.CollectorSettings
 }
 
-func prepareCollectorSettings(configFiles []string, supervised bool, supervisedLoggingLevel string) (edotSettings, error) {
+func prepareCollectorSettings(configFiles []string, supervised bool, supervisedLoggingLevel string, opts ...edotOtelCol.SettingOpt) (edotSettings, error) {
 	var settings edotSettings
 	conf := map[string]any{
 		"endpoint": paths.DiagnosticsExtensionSocket(),
 	}
 	if supervised {
-		settings.otelSettings = edotOtelCol.NewSettings(release.Version(), configFiles,
+		settings.otelSettings = edotOtelCol.NewSettings(release.Version(), configFiles, append(append([]edotOtelCol.SettingOpt{
 			edotOtelCol.WithConfigConvertorFactory(manager.NewForceExtensionConverterFactory(elasticdiagnostics.DiagnosticsExtensionID.String(), conf)),
-		)
+			edotOtelCol.WithFileExporterDirs(),
+			// the collector run by the Elastic Agent is long-running, its file exporters rotate their files
+			edotOtelCol.WithFileExporterRotation(),
+		}, configConverterOpts()...), opts...)...)
 
 		// setup logger
 		defaultCfg := logger.DefaultLoggingConfig()
@@ -181,11 +236,23 @@ func prepareCollectorSettings(configFiles []string, supervised bool, supervisedL
 
 		settings.otelSettings.DisableGracefulShutdown = false
 	} else {
-		settings.otelSettings = edotOtelCol.NewSettings(release.Version(), configFiles, edotOtelCol.WithConfigConvertorFactory(manager.NewForceExtensionConverterFactory(elasticdiagnostics.DiagnosticsExtensionID.String(), conf)))
+		settings.otelSettings = edotOtelCol.NewSettings(release.Version(), configFiles, append(append([]edotOtelCol.SettingOpt{
+			edotOtelCol.WithConfigConvertorFactory(manager.NewForceExtensionConverterFactory(elasticdiagnostics.DiagnosticsExtensionID.String(), conf)),
+			edotOtelCol.WithFileExporterDirs(),
+		}, configConverterOpts()...), opts...)...)
 	}
 	return settings, nil
 }
 
+// configConverterOpts returns the converters shaping the user configuration, applied wherever the
+// configuration is resolved so that validating and printing it matches what the collector runs.
+func configConverterOpts() []edotOtelCol.SettingOpt {
+	return []edotOtelCol.SettingOpt{
+		edotOtelCol.WithConfigConvertorFactory(edotOtelCol.NewOTLPFailoverConverterFactory()),
+		edotOtelCol.WithConfigConvertorFactory(edotOtelCol.NewElasticsearchExporterDefaultsConverterFactory()),
+	}
+}
+
 func prepareEnv() error {
 	if _, ok := os.LookupEnv("STATE_PATH"); !ok {
 		// STATE_PATH is not set. Set it to defaultStateDirectory because we do not want to use any of the paths, that are also used by Beats or Agent
@@ -5,29 +5,66 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/spf13/pflag"
 	"go.opentelemetry.io/collector/featuregate"
 
+	edotOtelCol "github.com/elastic/elastic-agent/internal/edot/otelcol"
+	"github.com/elastic/elastic-agent/internal/edot/otelcol/remoteconfigprovider"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/otel/manager"
 )
 
 const (
-	otelConfigFlagName = "config"
-	otelSetFlagName    = "set"
+	otelConfigFlagName    = "config"
+	otelConfigDirFlagName = "config-dir"
+	otelSetFlagName       = "set"
+
+	otelConfigRefreshIntervalFlagName = "config-refresh-interval"
+	otelConfigBearerTokenFileFlagName = "config-bearer-token-file"
+	otelConfigCAFileFlagName          = "config-ca-file"
+
+	otelEnvAllowListFlagName = "env-allow-list"
+
+	otelHealthAddrFlagName = "health-addr"
+
+	// otelConfigStdin is the config location reading the configuration from stdin.
+	otelConfigStdin = "-"
 )
 
 func SetupOtelFlags(flags *pflag.FlagSet) {
 	flags.StringArray(otelConfigFlagName, []string{}, "Locations to the config file(s), note that only a"+
-		" single location can be set per flag entry e.g. `--config=file:/path/to/first --config=file:path/to/second`.")
+		" single location can be set per flag entry e.g. `--config=file:/path/to/first --config=file:path/to/second`."+
+		" Use `--config -` to read the configuration from stdin.")
+
+	flags.String(otelConfigDirFlagName, "", "Directory of config files, its *.yml and *.yaml files are loaded in lexical order"+
+		" before the --config locations, e.g. `--config-dir=/etc/otel/conf.d`. A file overrides the keys set by the files before it."+
+		" Files added to the directory are only loaded on restart.")
 
 	flags.StringArray(otelSetFlagName, []string{}, "Set arbitrary component config property. The component has to be defined in the config file and the flag"+
 		" has a higher precedence. Array config properties are overridden and maps are joined. Example --set \"processors::batch::timeout=2s\"")
 
+	flags.Duration(otelConfigRefreshIntervalFlagName, 0, "Interval at which http and https config locations are fetched again,"+
+		" setting it also watches the config files for changes. A changed configuration is validated then reloaded. Disabled by default.")
+	flags.String(otelConfigBearerTokenFileFlagName, "", "File containing a bearer token sent when fetching http and https config locations.")
+	flags.String(otelConfigCAFileFlagName, "", "PEM file with certificate authorities trusted, in addition to the system ones,"+
+		" to verify the server of https config locations.")
+	flags.StringSlice(otelEnvAllowListFlagName, nil, "Environment variables that can be expanded in the configuration,"+
+		" e.g. `--env-allow-list=HOME,ELASTIC_*`. A trailing * allows every variable with the prefix, expanding any other"+
+		" variable is a configuration error. All environment variables can be expanded by default.")
+	flags.String(otelHealthAddrFlagName, "", "Address serving the liveness ("+edotOtelCol.HealthLivenessPath+") and readiness ("+
+		edotOtelCol.HealthReadinessPath+") probes of the collector over HTTP, e.g. `--health-addr=:13133`. The collector is ready once"+
+		" the configuration is loaded and the pipelines are started. Disabled by default.")
+
 	flags.Bool(manager.OtelSetSupervisedFlagName, false, "Set that this collector is supervised.")
 	// the only error we can get here is that the flag does not exist
 	// but look above, so we explicitly ignore it
@@ -55,9 +92,21 @@ func GetConfigFiles(flags *pflag.FlagSet, useDefault bool) ([]string, error) {
 		return nil, fmt.Errorf("failed to retrieve config flags: %w", err)
 	}
 
+	configDir, err := flags.GetString(otelConfigDirFlagName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve config-dir flag: %w", err)
+	}
+	if configDir != "" {
+		dirFiles, err := configDirFiles(configDir)
+		if err != nil {
+			return nil, err
+		}
+		configFiles = append(dirFiles, configFiles...)
+	}
+
 	if len(configFiles) == 0 {
 		if !useDefault {
-			return nil, fmt.Errorf("at least one config flag must be provided")
+			return nil, fmt.Errorf("at least one config or config-dir flag must be provided")
 		}
 		configFiles = append(configFiles, paths.OtelConfigFile())
 	}
@@ -76,6 +125,92 @@ func GetConfigFiles(flags *pflag.FlagSet, useDefault bool) ([]string, error) {
 	return configFiles, nil
 }
 
+// configDirFiles returns the *.yml and *.yaml files of dir in lexical order. Hidden files and
+// subdirectories are ignored, and a directory without config file is an error.
+func configDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if ext := filepath.Ext(name); ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.yml or *.yaml config file in config directory %s", dir)
+	}
+	return files, nil
+}
+
+// readStdinConfig replaces the "-" config location with the configuration read from in, until EOF.
+// Stdin can only be read once, so "-" can only be set once, and reading an empty configuration is
+// an error.
+func readStdinConfig(configFiles []string, in io.Reader) ([]string, error) {
+	idx := slices.Index(configFiles, otelConfigStdin)
+	if idx == -1 {
+		return configFiles, nil
+	}
+	if slices.Contains(configFiles[idx+1:], otelConfigStdin) {
+		return nil, fmt.Errorf("the configuration can only be read once from stdin with --%s %s", otelConfigFlagName, otelConfigStdin)
+	}
+	if in == nil {
+		return nil, errors.New("no stdin to read the configuration from")
+	}
+	content, err := io.ReadAll(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the configuration from stdin: %w", err)
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, errors.New("no configuration read from stdin, the input is empty")
+	}
+	configFiles = slices.Clone(configFiles)
+	configFiles[idx] = "yaml:" + string(content)
+	return configFiles, nil
+}
+
+// GetRemoteConfigSettings returns the settings used to fetch the http and https config locations.
+func GetRemoteConfigSettings(flags *pflag.FlagSet) (remoteconfigprovider.Settings, error) {
+	var settings remoteconfigprovider.Settings
+	var err error
+	settings.RefreshInterval, err = flags.GetDuration(otelConfigRefreshIntervalFlagName)
+	if err != nil {
+		return settings, fmt.Errorf("failed to retrieve %s flag: %w", otelConfigRefreshIntervalFlagName, err)
+	}
+	settings.CAFile, err = flags.GetString(otelConfigCAFileFlagName)
+	if err != nil {
+		return settings, fmt.Errorf("failed to retrieve %s flag: %w", otelConfigCAFileFlagName, err)
+	}
+	tokenFile, err := flags.GetString(otelConfigBearerTokenFileFlagName)
+	if err != nil {
+		return settings, fmt.Errorf("failed to retrieve %s flag: %w", otelConfigBearerTokenFileFlagName, err)
+	}
+	if tokenFile != "" {
+		token, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return settings, fmt.Errorf("failed to read bearer token file: %w", err)
+		}
+		settings.BearerToken = strings.TrimSpace(string(token))
+	}
+	return settings, nil
+}
+
+// GetEnvAllowList returns the environment variables that can be expanded in the configuration,
+// empty when all of them can be expanded.
+func GetEnvAllowList(flags *pflag.FlagSet) ([]string, error) {
+	allowList, err := flags.GetStringSlice(otelEnvAllowListFlagName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve %s flag: %w", otelEnvAllowListFlagName, err)
+	}
+	return allowList, nil
+}
+
 func getSets(setVals []string) ([]string, error) {
 	var sets []string
 	for _, s := range setVals {
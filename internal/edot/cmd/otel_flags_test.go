@@ -5,7 +5,12 @@
 package cmd
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
@@ -20,7 +25,10 @@ func TestOtelFlagsSetup(t *testing.T) {
 
 	expectedFlags := []string{
 		otelConfigFlagName,
+		otelConfigDirFlagName,
 		otelSetFlagName,
+		otelEnvAllowListFlagName,
+		otelHealthAddrFlagName,
 		"feature-gates",
 	}
 
@@ -59,6 +67,43 @@ func TestGetConfigFilesWithDefault(t *testing.T) {
 	require.Equal(t, expectedConfigFiles, configFiles)
 }
 
+func TestGetConfigFilesFromDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"20-exporters.yaml", "10-receivers.yml", ".hidden.yml", "README.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "30-subdir.yml"), 0o755))
+
+	cmd := NewOtelCommandWithArgs(nil, nil)
+	require.NoError(t, cmd.Flag(otelConfigDirFlagName).Value.Set(dir))
+	require.NoError(t, cmd.Flag(otelConfigFlagName).Value.Set("override.yml"))
+
+	// the directory files come first so that the config locations override them
+	configFiles, err := GetConfigFiles(cmd.Flags(), true)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "10-receivers.yml"),
+		filepath.Join(dir, "20-exporters.yaml"),
+		"override.yml",
+	}, configFiles)
+
+	cmd = NewOtelCommandWithArgs(nil, nil)
+	require.NoError(t, cmd.Flag(otelConfigDirFlagName).Value.Set(dir))
+	configFiles, err = GetConfigFiles(cmd.Flags(), false)
+	require.NoError(t, err)
+	require.Len(t, configFiles, 2)
+
+	cmd = NewOtelCommandWithArgs(nil, nil)
+	require.NoError(t, cmd.Flag(otelConfigDirFlagName).Value.Set(t.TempDir()))
+	_, err = GetConfigFiles(cmd.Flags(), true)
+	require.ErrorContains(t, err, "no *.yml or *.yaml config file in config directory")
+
+	cmd = NewOtelCommandWithArgs(nil, nil)
+	require.NoError(t, cmd.Flag(otelConfigDirFlagName).Value.Set(filepath.Join(dir, "missing")))
+	_, err = GetConfigFiles(cmd.Flags(), true)
+	require.ErrorContains(t, err, "failed to read config directory")
+}
+
 func TestGetConfigErrorWhenNoConfig(t *testing.T) {
 	cmd := NewOtelCommandWithArgs(nil, nil)
 
@@ -66,6 +111,39 @@ func TestGetConfigErrorWhenNoConfig(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestReadStdinConfig(t *testing.T) {
+	const cfg = "receivers:\n  nop:\n"
+
+	t.Run("no stdin location", func(t *testing.T) {
+		configFiles, err := readStdinConfig([]string{"otel.yml"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"otel.yml"}, configFiles)
+	})
+
+	t.Run("replaced with stdin content", func(t *testing.T) {
+		configFiles := []string{"otel.yml", otelConfigStdin, "yaml:service::telemetry::logs::level: debug"}
+		resolved, err := readStdinConfig(configFiles, strings.NewReader(cfg))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"otel.yml", "yaml:" + cfg, "yaml:service::telemetry::logs::level: debug"}, resolved)
+		assert.Equal(t, otelConfigStdin, configFiles[1], "the given locations are not modified")
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		_, err := readStdinConfig([]string{otelConfigStdin}, strings.NewReader(" \n"))
+		require.ErrorContains(t, err, "the input is empty")
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		_, err := readStdinConfig([]string{otelConfigStdin}, iotest.ErrReader(errors.New("broken pipe")))
+		require.ErrorContains(t, err, "broken pipe")
+	})
+
+	t.Run("read twice", func(t *testing.T) {
+		_, err := readStdinConfig([]string{otelConfigStdin, otelConfigStdin}, strings.NewReader(cfg))
+		require.ErrorContains(t, err, "only be read once")
+	})
+}
+
 func TestGetSets(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -150,3 +228,15 @@ func TestSetToYaml(t *testing.T) {
 		assert.Equal(t, tc.expectedSet, actualSet)
 	}
 }
+
+func TestGetEnvAllowList(t *testing.T) {
+	cmd := NewOtelCommandWithArgs(nil, nil)
+	allowList, err := GetEnvAllowList(cmd.Flags())
+	require.NoError(t, err)
+	assert.Empty(t, allowList)
+
+	require.NoError(t, cmd.Flag(otelEnvAllowListFlagName).Value.Set("HOME,ELASTIC_*"))
+	allowList, err = GetEnvAllowList(cmd.Flags())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"HOME", "ELASTIC_*"}, allowList)
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func newPipelineCommand(streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pipeline",
+		Short: "Enable or disable a pipeline of the collector run by the Elastic Agent",
+		Long: `Enable or disable a single pipeline of the collector run by the currently running Elastic Agent daemon,
+without editing its configuration. A disabled pipeline is reported with the StatusDisabled status and stays
+disabled across configuration reloads which don't change it.`,
+	}
+	cmd.AddCommand(newPipelineToggleCommand(streams, "enable", "Start a disabled pipeline, e.g. logs or logs/custom", true))
+	cmd.AddCommand(newPipelineToggleCommand(streams, "disable", "Stop a pipeline until it is enabled again, e.g. logs or logs/custom", false))
+	return cmd
+}
+
+func newPipelineToggleCommand(streams *cli.IOStreams, use string, short string, enabled bool) *cobra.Command {
+	return &cobra.Command{
+		Use:     use + " <pipeline>",
+		Short:   short,
+		Example: fmt.Sprintf("elastic-agent otel pipeline %s logs", use),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := client.New()
+			if err := c.Connect(cmd.Context()); err != nil {
+				return fmt.Errorf("failed communicating to running daemon at %s: %w", control.Address(), err)
+			}
+			defer c.Disconnect()
+
+			if err := c.SetOtelPipelineEnabled(cmd.Context(), args[0], enabled); err != nil {
+				return fmt.Errorf("failed to %s pipeline %q: %w", use, args[0], err)
+			}
+			fmt.Fprintf(streams.Out, "Pipeline %q %sd\n", args[0], use)
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
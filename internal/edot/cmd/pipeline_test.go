@@ -0,0 +1,34 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func TestPipelineCommand(t *testing.T) {
+	streams, _, _, _ := cli.NewTestingIOStreams()
+	cmd := newPipelineCommand(streams)
+	cmd.SetOut(streams.Out)
+	cmd.SetErr(streams.Err)
+
+	var names []string
+	for _, sub := range cmd.Commands() {
+		names = append(names, sub.Name())
+	}
+	assert.ElementsMatch(t, []string{"enable", "disable"}, names)
+
+	for _, args := range [][]string{{"disable"}, {"enable", "logs", "metrics"}} {
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		require.Error(t, err, "a single pipeline name is required: %v", args)
+		assert.Contains(t, err.Error(), "accepts 1 arg(s)")
+	}
+}
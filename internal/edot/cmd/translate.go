@@ -0,0 +1,131 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/install/componentvalidation"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/otel/translate"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	translateOutputYAML = "yaml"
+	translateOutputJSON = "json"
+)
+
+func newTranslateCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "translate",
+		Short: "Translates an Elastic Agent policy into OpenTelemetry collector configuration",
+		Long: `Translates an Elastic Agent policy into the equivalent OpenTelemetry collector configuration and prints it to stdout.
+Only components that can run inside the collector are translated; all other components are reported on stderr and skipped.`,
+		SilenceUsage:  true, // do not display usage on error
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfgPath, _ := cmd.Flags().GetString("config")
+			variablesWait, _ := cmd.Flags().GetDuration("variables-wait")
+			output, _ := cmd.Flags().GetString("output")
+			if output != translateOutputYAML && output != translateOutputJSON {
+				return fmt.Errorf("unsupported output format %q, must be one of: %s, %s", output, translateOutputYAML, translateOutputJSON)
+			}
+			return translatePolicy(cmd.Context(), paths.Components(), cfgPath, variablesWait, output, streams)
+		},
+	}
+
+	cmd.Flags().StringP("config", "c", paths.ConfigFile(), "Elastic Agent policy file to translate")
+	cmd.Flags().Duration("variables-wait", 0, "Maximum time to wait for variables before translating the policy")
+	cmd.Flags().StringP("output", "o", translateOutputYAML, "Output format of the collector configuration, one of: yaml, json")
+	origHelpFunc := cmd.HelpFunc()
+	cmd.SetHelpFunc(func(c *cobra.Command, s []string) {
+		hideInheritedFlags(c)
+		origHelpFunc(c, s)
+	})
+
+	return cmd
+}
+
+func translatePolicy(ctx context.Context, componentsDir string, cfgPath string, variablesWait time.Duration, output string, streams *cli.IOStreams) error {
+	l, err := logger.NewWithLogpLevel("", logp.ErrorLevel, false)
+	if err != nil {
+		return err
+	}
+
+	comps, err := componentvalidation.GetComponentsFromPolicy(ctx, l, componentsDir, cfgPath, variablesWait)
+	if err != nil {
+		return fmt.Errorf("failed to load policy %s: %w", cfgPath, err)
+	}
+
+	supported := supportedOtelComponents(comps, streams.Err)
+	if len(supported) == 0 {
+		return errors.New("policy contains no components that can be translated to OpenTelemetry collector configuration")
+	}
+
+	agentInfo, err := info.NewAgentInfoWithLog(ctx, "error", false)
+	if err != nil {
+		return fmt.Errorf("could not load agent info: %w", err)
+	}
+
+	otelCfg, err := translate.GetOtelConfig(&component.Model{Components: supported}, agentInfo, nil, l)
+	if err != nil {
+		return fmt.Errorf("failed to translate policy: %w", err)
+	}
+
+	return writeTranslatedConfig(streams.Out, otelCfg.ToStringMap(), output)
+}
+
+// writeTranslatedConfig writes the translated collector configuration to w in the output format.
+func writeTranslatedConfig(w io.Writer, cfg map[string]any, output string) error {
+	if output != translateOutputJSON {
+		return writeOtelConfig(w, cfg)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode collector configuration: %w", err)
+	}
+	return nil
+}
+
+// supportedOtelComponents returns the components that can be run by the collector, reporting the
+// reason for every skipped component to w.
+func supportedOtelComponents(comps []component.Component, w io.Writer) []component.Component {
+	var supported []component.Component
+	for _, comp := range comps {
+		if comp.Err != nil {
+			fmt.Fprintf(w, "skipping component %s: %v\n", comp.ID, comp.Err)
+			continue
+		}
+		if err := translate.VerifyComponentIsOtelSupported(&comp); err != nil {
+			fmt.Fprintf(w, "skipping component %s: %v\n", comp.ID, err)
+			continue
+		}
+		supported = append(supported, comp)
+	}
+	return supported
+}
+
+func writeOtelConfig(w io.Writer, cfg map[string]any) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode collector configuration: %w", err)
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,123 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func TestTranslatePolicy(t *testing.T) {
+	const (
+		receiver = "metricbeatreceiver/_agent-component/system/metrics-default"
+		exporter = "elasticsearch/_agent-component/default"
+		pipeline = "logs/_agent-component/system/metrics-default"
+	)
+
+	testCases := []struct {
+		output    string
+		unmarshal func([]byte, any) error
+	}{
+		{output: translateOutputYAML, unmarshal: yaml.Unmarshal},
+		{output: translateOutputJSON, unmarshal: json.Unmarshal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.output, func(t *testing.T) {
+			streams, _, out, _ := cli.NewTestingIOStreams()
+			componentsDir := filepath.Join("testdata", "otel", "components")
+			require.NoError(t, translatePolicy(t.Context(), componentsDir, filepath.Join("testdata", "otel", "elastic-agent.yml"), 0, tc.output, streams))
+
+			var translated struct {
+				Receivers map[string]any `yaml:"receivers" json:"receivers"`
+				Exporters map[string]any `yaml:"exporters" json:"exporters"`
+				Service   struct {
+					Pipelines map[string]struct {
+						Receivers []string `yaml:"receivers" json:"receivers"`
+						Exporters []string `yaml:"exporters" json:"exporters"`
+					} `yaml:"pipelines" json:"pipelines"`
+				} `yaml:"service" json:"service"`
+			}
+			require.NoError(t, tc.unmarshal(out.Bytes(), &translated))
+
+			require.Contains(t, translated.Receivers, receiver)
+			require.Contains(t, translated.Exporters, exporter)
+			require.Contains(t, out.String(), "127.0.0.1:9200")
+			require.Contains(t, translated.Service.Pipelines, pipeline)
+			require.Equal(t, []string{receiver}, translated.Service.Pipelines[pipeline].Receivers)
+			require.Equal(t, []string{exporter}, translated.Service.Pipelines[pipeline].Exporters)
+		})
+	}
+}
+
+func TestTranslateCommandErrors(t *testing.T) {
+	invalidPolicy := filepath.Join(t.TempDir(), "invalid.yml")
+	require.NoError(t, os.WriteFile(invalidPolicy, []byte("inputs: [\n"), 0o600))
+
+	testCases := []struct {
+		name        string
+		args        []string
+		expectedErr string
+	}{
+		{
+			name:        "missing file",
+			args:        []string{"--config", filepath.Join(t.TempDir(), "missing.yml")},
+			expectedErr: "failed to load policy",
+		},
+		{
+			name:        "invalid policy",
+			args:        []string{"--config", invalidPolicy},
+			expectedErr: "failed to load policy",
+		},
+		{
+			name:        "unsupported output format",
+			args:        []string{"--config", filepath.Join("testdata", "otel", "elastic-agent.yml"), "--output", "xml"},
+			expectedErr: `unsupported output format "xml"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			streams, _, out, _ := cli.NewTestingIOStreams()
+			cmd := newTranslateCommandWithArgs(nil, streams)
+			cmd.SetArgs(tc.args)
+			require.ErrorContains(t, cmd.Execute(), tc.expectedErr)
+			require.Empty(t, out.String())
+		})
+	}
+}
+
+func TestWriteTranslatedConfig(t *testing.T) {
+	cfg := map[string]any{
+		"receivers": map[string]any{"filelog": map[string]any{"include": []any{"/var/log/system.log"}}},
+		"service":   map[string]any{"pipelines": map[string]any{"logs": map[string]any{"receivers": []any{"filelog"}}}},
+	}
+
+	testCases := []struct {
+		output    string
+		unmarshal func([]byte, any) error
+	}{
+		{output: translateOutputYAML, unmarshal: yaml.Unmarshal},
+		{output: translateOutputJSON, unmarshal: json.Unmarshal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.output, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, writeTranslatedConfig(&out, cfg, tc.output))
+			var written map[string]any
+			require.NoError(t, tc.unmarshal(out.Bytes(), &written))
+			require.Equal(t, cfg, written)
+		})
+	}
+}
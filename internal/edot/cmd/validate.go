@@ -5,15 +5,35 @@
 package cmd
 
 import (
-	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/collector/confmap"
 
 	"github.com/elastic/elastic-agent/internal/edot/otelcol"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 )
 
-func newValidateCommandWithArgs(_ []string, _ *cli.IOStreams) *cobra.Command {
+const (
+	printConfigFlagName = "print-config"
+	redactFlagName      = "redact"
+	preflightFlagName   = "preflight"
+
+	validateOutputText = "text"
+	validateOutputJSON = "json"
+)
+
+// validationResult is the result of `otel validate --output json`.
+type validationResult struct {
+	Valid     bool                      `json:"valid"`
+	Errors    []otelcol.ValidationError `json:"errors"`
+	Preflight []otelcol.PreflightCheck  `json:"preflight,omitempty"`
+}
+
+func newValidateCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:           "validate",
 		Short:         "Validates the OpenTelemetry collector configuration without running the collector",
@@ -25,11 +45,65 @@ func newValidateCommandWithArgs(_ []string, _ *cli.IOStreams) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			return validateOtelConfig(cmd.Context(), cfgFiles)
+			cfgFiles, err = readStdinConfig(cfgFiles, streamsIn(streams))
+			if err != nil {
+				return err
+			}
+			output, _ := cmd.Flags().GetString("output")
+			if output != validateOutputText && output != validateOutputJSON {
+				return fmt.Errorf("unsupported output format %q, must be one of: %s, %s", output, validateOutputText, validateOutputJSON)
+			}
+			remoteConfig, err := GetRemoteConfigSettings(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			// the configuration is validated once, it is not refreshed
+			remoteConfig.RefreshInterval = 0
+			envAllowList, err := GetEnvAllowList(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			opts := append(configConverterOpts(), otelcol.WithRemoteConfig(remoteConfig), otelcol.WithEnvAllowList(envAllowList))
+			// stdout only holds the JSON result with --output json, the printed configuration goes to stderr
+			printOut := streams.Out
+			if output == validateOutputJSON {
+				printOut = streams.Err
+			}
+			// the configuration is resolved once, so that what is printed, validated and checked is the
+			// same even when a remote configuration changes in the meantime
+			conf, err := otelcol.ResolveConfig(cmd.Context(), cfgFiles, opts...)
+			if printConfig, _ := cmd.Flags().GetBool(printConfigFlagName); printConfig && err == nil {
+				redact, _ := cmd.Flags().GetBool(redactFlagName)
+				err = printEffectiveOtelConfig(printOut, streams.Err, conf, redact)
+			}
+			if err == nil {
+				err = otelcol.ValidateResolved(cmd.Context(), conf, opts...)
+			}
+			var preflight []otelcol.PreflightCheck
+			if runPreflight, _ := cmd.Flags().GetBool(preflightFlagName); runPreflight && err == nil {
+				preflight = otelcol.Preflight(cmd.Context(), conf)
+				if output == validateOutputText {
+					writePreflightChecks(streams.Out, preflight)
+				}
+			}
+			// the JSON result is written whatever failed, so that stdout can always be parsed
+			if output == validateOutputJSON {
+				if writeErr := writeValidationResult(streams.Out, err, preflight); writeErr != nil {
+					return writeErr
+				}
+			}
+			if err != nil {
+				return err
+			}
+			return preflightError(preflight)
 		},
 	}
 
 	SetupOtelFlags(cmd.Flags())
+	cmd.Flags().Bool(printConfigFlagName, false, "Print the merged configuration with all variables expanded before validating it")
+	cmd.Flags().Bool(redactFlagName, false, "Redact sensitive values from the configuration printed with --"+printConfigFlagName)
+	cmd.Flags().Bool(preflightFlagName, false, "Check the exporter destinations of a valid configuration: file exporter paths are writable and endpoint hosts resolve")
+	cmd.Flags().StringP("output", "o", validateOutputText, "Output format of the validation errors, one of: text, json. The json output lists the errors with their configuration path and kind and is written to stdout, the configuration printed with --"+printConfigFlagName+" is then written to stderr")
 	origHelpFunc := cmd.HelpFunc()
 	cmd.SetHelpFunc(func(c *cobra.Command, s []string) {
 		hideInheritedFlags(c)
@@ -39,6 +113,58 @@ func newValidateCommandWithArgs(_ []string, _ *cli.IOStreams) *cobra.Command {
 	return cmd
 }
 
-func validateOtelConfig(ctx context.Context, cfgFiles []string) error {
-	return otelcol.Validate(ctx, cfgFiles)
+// writePreflightChecks writes one line per preflight check with its result. Failed checks tolerated
+// because another target of the same failover connector passed are reported as warnings.
+func writePreflightChecks(w io.Writer, checks []otelcol.PreflightCheck) {
+	failures := make(map[otelcol.PreflightCheck]bool)
+	for _, c := range otelcol.PreflightFailures(checks) {
+		failures[c] = true
+	}
+	for _, c := range checks {
+		switch {
+		case c.Passed():
+			fmt.Fprintf(w, "PASS %s %s %s\n", c.Component, c.Check, c.Target)
+		case failures[c]:
+			fmt.Fprintf(w, "FAIL %s %s %s: %s\n", c.Component, c.Check, c.Target, c.Error)
+		default:
+			fmt.Fprintf(w, "WARN %s %s %s: %s, another target of %s passed\n", c.Component, c.Check, c.Target, c.Error, c.Failover)
+		}
+	}
+}
+
+// preflightError returns an error when the preflight checks failed, see otelcol.PreflightFailures.
+func preflightError(checks []otelcol.PreflightCheck) error {
+	if failed := len(otelcol.PreflightFailures(checks)); failed > 0 {
+		return fmt.Errorf("%d of %d preflight checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// writeValidationResult writes the result of the validation as JSON, validationErr is the error returned by otelcol.Validate
+// and preflight the preflight checks, if they ran.
+func writeValidationResult(w io.Writer, validationErr error, preflight []otelcol.PreflightCheck) error {
+	result := validationResult{
+		Valid:     validationErr == nil,
+		Errors:    otelcol.ValidationErrors(validationErr),
+		Preflight: preflight,
+	}
+	if result.Errors == nil {
+		result.Errors = []otelcol.ValidationError{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("failed to encode validation result: %w", err)
+	}
+	return nil
+}
+
+// printEffectiveOtelConfig writes the resolved configuration conf to out, optionally redacting
+// sensitive values the same way diagnostics do. The redaction warnings are written to errOut.
+func printEffectiveOtelConfig(out, errOut io.Writer, conf *confmap.Conf, redact bool) error {
+	cfg := conf.ToStringMap()
+	if redact {
+		cfg = diagnostics.Redact(cfg, errOut)
+	}
+	return writeOtelConfig(out, cfg)
 }
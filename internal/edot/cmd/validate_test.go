@@ -5,11 +5,19 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"io"
+	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/edot/otelcol"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
 )
 
 func TestValidateCommand(t *testing.T) {
@@ -43,6 +51,36 @@ func TestValidateCommand(t *testing.T) {
 			[]string{filepath.Join("testdata", "otel", "otel.yml"), "yaml:processors::resource::attributes: [{ value: elastic-otel-test4 }]"},
 			true,
 		},
+		{
+			"otel config with otlphttp exporter",
+			[]string{filepath.Join("testdata", "otel", "otel.yml"), "yaml:exporters::otlphttp::endpoint: http://localhost:8200"},
+			false,
+		},
+		{
+			"otel config with otlphttp exporter without http scheme",
+			[]string{filepath.Join("testdata", "otel", "otel.yml"), "yaml:exporters::otlphttp::endpoint: localhost:8200"},
+			true,
+		},
+		{
+			"otel config with yaml anchors",
+			[]string{filepath.Join("testdata", "otel", "otel-anchors.yml")},
+			false,
+		},
+		{
+			"otel config with spanmetrics connector",
+			[]string{filepath.Join("testdata", "otel", "otel-connectors.yml")},
+			false,
+		},
+		{
+			"otel config with connector not used as a receiver",
+			[]string{filepath.Join("testdata", "otel", "otel-connectors.yml"), "yaml:service::pipelines::metrics::receivers: [otlp]"},
+			true,
+		},
+		{
+			"otel config with undeclared connector",
+			[]string{filepath.Join("testdata", "otel", "otel-connectors.yml"), "yaml:service::pipelines::traces::exporters: [routing, debug]"},
+			true,
+		},
 		{
 			"agent config",
 			[]string{filepath.Join("testdata", "otel", "elastic-agent.yml")},
@@ -52,7 +90,7 @@ func TestValidateCommand(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.Name, func(t *testing.T) {
-			err := validateOtelConfig(context.Background(), tc.ConfigPaths)
+			err := otelcol.Validate(context.Background(), tc.ConfigPaths)
 
 			if tc.ExpectingErr {
 				require.Error(t, err)
@@ -62,3 +100,219 @@ func TestValidateCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintEffectiveOtelConfig(t *testing.T) {
+	t.Setenv("TEST_OTEL_API_TOKEN", "supersecret")
+	cfgFiles := []string{
+		filepath.Join("testdata", "otel", "otel.yml"),
+		"yaml:exporters::debug::api_token: ${env:TEST_OTEL_API_TOKEN}",
+	}
+	conf, err := otelcol.ResolveConfig(context.Background(), cfgFiles)
+	require.NoError(t, err)
+
+	t.Run("expanded", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, printEffectiveOtelConfig(&out, io.Discard, conf, false))
+		require.Contains(t, out.String(), "api_token: supersecret")
+		require.Contains(t, out.String(), "value: elastic-otel-test")
+	})
+
+	t.Run("redacted", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, printEffectiveOtelConfig(&out, io.Discard, conf, true))
+		require.NotContains(t, out.String(), "supersecret")
+		require.Contains(t, out.String(), "<REDACTED>")
+	})
+}
+
+func TestValidateCommandPrintConfigConverters(t *testing.T) {
+	streams, _, out, _ := cli.NewTestingIOStreams()
+	cmd := newValidateCommandWithArgs(nil, streams)
+	cmd.SetArgs([]string{
+		"--config", filepath.Join("testdata", "otel", "otel.yml"),
+		"--set", "exporters::elasticsearch::endpoints=[http://localhost:9200]",
+		"--" + printConfigFlagName,
+	})
+	require.NoError(t, cmd.Execute())
+
+	// the printed configuration has the elasticsearch exporter defaults the collector runs with
+	var printed struct {
+		Exporters struct {
+			Elasticsearch map[string]any `yaml:"elasticsearch"`
+		} `yaml:"exporters"`
+	}
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &printed))
+	require.Equal(t, "gzip", printed.Exporters.Elasticsearch["compression"])
+	require.Equal(t, map[string]any{"batch": map[string]any{"flush_timeout": "1s"}}, printed.Exporters.Elasticsearch["sending_queue"])
+}
+
+func TestValidateCommandPrintConfigAnchors(t *testing.T) {
+	streams, _, out, _ := cli.NewTestingIOStreams()
+	cmd := newValidateCommandWithArgs(nil, streams)
+	cmd.SetArgs([]string{
+		"--config", filepath.Join("testdata", "otel", "otel-anchors.yml"),
+		"--" + printConfigFlagName,
+	})
+	require.NoError(t, cmd.Execute())
+
+	// the merge key is expanded, and the keys of the map override the merged ones
+	var printed struct {
+		Exporters map[string]map[string]any `yaml:"exporters"`
+	}
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &printed))
+	require.Equal(t, "detailed", printed.Exporters["debug/sampled"]["verbosity"])
+	require.Equal(t, 10000, printed.Exporters["debug/sampled"]["sampling_initial"])
+	require.Equal(t, 100, printed.Exporters["debug/sampled"]["sampling_thereafter"])
+	require.Equal(t, 10000, printed.Exporters["debug"]["sampling_thereafter"])
+	require.NotContains(t, printed.Exporters["debug/sampled"], "<<")
+}
+
+func TestValidateCommandJSONPrintConfig(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		streams, _, out, errOut := cli.NewTestingIOStreams()
+		cmd := newValidateCommandWithArgs(nil, streams)
+		cmd.SetArgs([]string{
+			"--config", filepath.Join("testdata", "otel", "otel.yml"),
+			"--" + printConfigFlagName,
+			"--output", validateOutputJSON,
+		})
+		require.NoError(t, cmd.Execute())
+
+		// stdout only holds the result, the configuration is printed to stderr
+		require.JSONEq(t, `{"valid": true, "errors": []}`, out.String())
+		require.Contains(t, errOut.String(), "value: elastic-otel-test")
+	})
+
+	t.Run("resolve failure", func(t *testing.T) {
+		streams, _, out, _ := cli.NewTestingIOStreams()
+		cmd := newValidateCommandWithArgs(nil, streams)
+		cmd.SetArgs([]string{
+			"--config", filepath.Join(t.TempDir(), "missing.yml"),
+			"--" + printConfigFlagName,
+			"--output", validateOutputJSON,
+		})
+		require.Error(t, cmd.Execute())
+
+		var result validationResult
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.False(t, result.Valid)
+		require.Len(t, result.Errors, 1)
+		require.Equal(t, otelcol.ValidationErrorKindLoad, result.Errors[0].Kind)
+	})
+}
+
+func TestValidateCommandConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	fragments := map[string]string{
+		"10-pipeline.yml": `
+receivers:
+  filelog:
+    include: [ /var/log/system.log ]
+exporters:
+  debug:
+    verbosity: basic
+service:
+  pipelines:
+    logs:
+      receivers: [filelog]
+      exporters: [debug]
+`,
+		"20-processors.yml": `
+processors:
+  batch:
+service:
+  pipelines:
+    logs:
+      processors: [batch]
+`,
+	}
+	for name, content := range fragments {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	streams, _, out, _ := cli.NewTestingIOStreams()
+	cmd := newValidateCommandWithArgs(nil, streams)
+	cmd.SetArgs([]string{"--config-dir", dir, "--" + printConfigFlagName})
+	require.NoError(t, cmd.Execute())
+	require.Contains(t, out.String(), "batch")
+
+	// the merged configuration is validated, not every file on its own
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "30-invalid.yml"), []byte("service:\n  pipelines:\n    logs:\n      processors: [nonexistingprocessor]\n"), 0o600))
+	streams, _, _, _ = cli.NewTestingIOStreams()
+	cmd = newValidateCommandWithArgs(nil, streams)
+	cmd.SetArgs([]string{"--config-dir", dir})
+	require.ErrorContains(t, cmd.Execute(), "nonexistingprocessor")
+}
+
+func TestValidateCommandStdinConfig(t *testing.T) {
+	cfg, err := os.ReadFile(filepath.Join("testdata", "otel", "otel.yml"))
+	require.NoError(t, err)
+
+	streams, in, _, _ := cli.NewTestingIOStreams()
+	_, err = in.Write(cfg)
+	require.NoError(t, err)
+	cmd := newValidateCommandWithArgs(nil, streams)
+	cmd.SetArgs([]string{"--config", otelConfigStdin})
+	require.NoError(t, cmd.Execute())
+
+	streams, _, _, _ = cli.NewTestingIOStreams()
+	cmd = newValidateCommandWithArgs(nil, streams)
+	cmd.SetArgs([]string{"--config", otelConfigStdin})
+	require.ErrorContains(t, cmd.Execute(), "no configuration read from stdin")
+}
+
+func TestWriteValidationResult(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeValidationResult(&out, otelcol.Validate(context.Background(), []string{filepath.Join("testdata", "otel", "otel.yml")}), nil))
+		require.JSONEq(t, `{"valid": true, "errors": []}`, out.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cfgFiles := []string{
+			filepath.Join("testdata", "otel", "otel.yml"),
+			"yaml:service::pipelines::logs::processors: [nonexistingprocessor]",
+		}
+		var out bytes.Buffer
+		require.NoError(t, writeValidationResult(&out, otelcol.Validate(context.Background(), cfgFiles), nil))
+
+		var result validationResult
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.False(t, result.Valid)
+		require.Len(t, result.Errors, 1)
+		require.Equal(t, "service::pipelines::logs", result.Errors[0].Path)
+		require.Equal(t, otelcol.ValidationErrorKindInvalid, result.Errors[0].Kind)
+		require.Contains(t, result.Errors[0].Message, `"nonexistingprocessor"`)
+	})
+}
+
+func TestPreflightOtelConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfgFiles := []string{
+		filepath.Join("testdata", "otel", "otel.yml"),
+		"yaml:exporters::file::path: " + filepath.Join(dir, "output.json"),
+		"yaml:exporters::otlp::endpoint: doesnotexist.invalid:4317",
+	}
+	conf, err := otelcol.ResolveConfig(context.Background(), cfgFiles)
+	require.NoError(t, err)
+	checks := otelcol.Preflight(context.Background(), conf)
+	require.Len(t, checks, 2)
+	require.Equal(t, "file", checks[0].Component)
+	require.True(t, checks[0].Passed())
+	require.Equal(t, "otlp", checks[1].Component)
+	require.False(t, checks[1].Passed())
+
+	var out bytes.Buffer
+	writePreflightChecks(&out, checks)
+	require.Contains(t, out.String(), "PASS file writable "+filepath.Join(dir, "output.json"))
+	require.Contains(t, out.String(), "FAIL otlp resolvable doesnotexist.invalid:4317: ")
+	require.EqualError(t, preflightError(checks), "1 of 2 preflight checks failed")
+	require.NoError(t, preflightError(checks[:1]))
+
+	out.Reset()
+	require.NoError(t, writeValidationResult(&out, nil, checks))
+	var result validationResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	require.True(t, result.Valid)
+	require.Equal(t, checks, result.Preflight)
+}
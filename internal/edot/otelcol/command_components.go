@@ -5,6 +5,7 @@
 package otelcol
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 
@@ -21,21 +22,59 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 )
 
+const (
+	// ComponentsOutputYAML prints the components as YAML, this is the default.
+	ComponentsOutputYAML = "yaml"
+	// ComponentsOutputJSON prints the components as JSON.
+	ComponentsOutputJSON = "json"
+)
+
 type componentWithStability struct {
-	Name      component.Type
-	Stability map[string]string
+	Name      component.Type    `json:"name"`
+	Stability map[string]string `json:"stability"`
+}
+
+// buildInfo mirrors component.BuildInfo with the keys used by the YAML output, the upstream type
+// has no json tags.
+type buildInfo struct {
+	Command     string `yaml:"command" json:"command"`
+	Description string `yaml:"description" json:"description"`
+	Version     string `yaml:"version" json:"version"`
 }
 
 type componentsOutput struct {
-	BuildInfo  component.BuildInfo
-	Receivers  []componentWithStability
-	Processors []componentWithStability
-	Exporters  []componentWithStability
-	Connectors []componentWithStability
-	Extensions []componentWithStability
+	BuildInfo  buildInfo                `json:"buildinfo"`
+	Receivers  []componentWithStability `json:"receivers"`
+	Processors []componentWithStability `json:"processors"`
+	Exporters  []componentWithStability `json:"exporters"`
+	Connectors []componentWithStability `json:"connectors"`
+	Extensions []componentWithStability `json:"extensions"`
+}
+
+// outputMarshaler returns the function marshaling the output of the commands in the given output
+// format, either ComponentsOutputYAML or ComponentsOutputJSON.
+func outputMarshaler(output string) (func(any) ([]byte, error), error) {
+	switch output {
+	case "", ComponentsOutputYAML:
+		return yaml.Marshal, nil
+	case ComponentsOutputJSON:
+		return func(v any) ([]byte, error) {
+			data, err := json.MarshalIndent(v, "", "  ")
+			return append(data, '\n'), err
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %q, must be one of: %s, %s", output, ComponentsOutputYAML, ComponentsOutputJSON)
+	}
 }
 
-func Components(cmd *cobra.Command) error {
+// Components writes the components available in this collector distribution to the output of cmd
+// in the given output format, either ComponentsOutputYAML or ComponentsOutputJSON.
+func Components(cmd *cobra.Command, output string) error {
+	marshal, err := outputMarshaler(output)
+	if err != nil {
+		return err
+	}
+
 	set := NewSettings(release.Version(), []string{})
 	factories, err := set.Factories()
 	if err != nil {
@@ -99,13 +138,17 @@ func Components(cmd *cobra.Command) error {
 			},
 		})
 	}
-	components.BuildInfo = set.BuildInfo
+	components.BuildInfo = buildInfo{
+		Command:     set.BuildInfo.Command,
+		Description: set.BuildInfo.Description,
+		Version:     set.BuildInfo.Version,
+	}
 
-	yamlData, err := yaml.Marshal(components)
+	data, err := marshal(components)
 	if err != nil {
 		return err
 	}
-	fmt.Fprint(cmd.OutOrStdout(), string(yamlData))
+	fmt.Fprint(cmd.OutOrStdout(), string(data))
 	return nil
 }
 
@@ -0,0 +1,187 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+)
+
+// componentKinds are the kinds of components accepted by Schema, in the order they are reported.
+var componentKinds = []string{"receiver", "processor", "exporter", "connector", "extension"}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// componentSchema describes the configuration of a component.
+type componentSchema struct {
+	Kind string `json:"kind" yaml:"kind"`
+	Type string `json:"type" yaml:"type"`
+	// Fields are the configuration keys of the component and their Go type, nested for sections.
+	Fields any `json:"fields" yaml:"fields"`
+	// Defaults is the default configuration of the component.
+	Defaults map[string]any `json:"defaults" yaml:"defaults"`
+}
+
+// Schema writes the configuration keys of the component id, e.g. "filelog" or "otlp/elastic",
+// with their type and default value to the output of cmd. When kind is empty, the components of
+// all kinds named id are described, e.g. both the otlp receiver and exporter. The output format
+// is either ComponentsOutputYAML or ComponentsOutputJSON.
+func Schema(cmd *cobra.Command, id string, kind string, output string) error {
+	marshal, err := outputMarshaler(output)
+	if err != nil {
+		return err
+	}
+	if kind != "" && !isComponentKind(kind) {
+		return fmt.Errorf("unsupported component kind %q, must be one of: %s", kind, strings.Join(componentKinds, ", "))
+	}
+
+	set := NewSettings(release.Version(), []string{})
+	factories, err := set.Factories()
+	if err != nil {
+		return fmt.Errorf("failed to initialize factories: %w", err)
+	}
+
+	// the name of the component doesn't matter, only its type
+	componentType, _, _ := strings.Cut(id, "/")
+	var schemas []componentSchema
+	for _, k := range componentKinds {
+		if kind != "" && k != kind {
+			continue
+		}
+		var factory component.Factory
+		var found bool
+		switch k {
+		case "receiver":
+			factory, found = findFactory(factories.Receivers, componentType)
+		case "processor":
+			factory, found = findFactory(factories.Processors, componentType)
+		case "exporter":
+			factory, found = findFactory(factories.Exporters, componentType)
+		case "connector":
+			factory, found = findFactory(factories.Connectors, componentType)
+		case "extension":
+			factory, found = findFactory(factories.Extensions, componentType)
+		}
+		if !found {
+			continue
+		}
+		schema, err := newComponentSchema(k, factory)
+		if err != nil {
+			return err
+		}
+		schemas = append(schemas, schema)
+	}
+	if len(schemas) == 0 {
+		return fmt.Errorf("component %q is not available in this collector distribution, use the components command to list them", id)
+	}
+
+	data, err := marshal(schemas)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(cmd.OutOrStdout(), string(data))
+	return nil
+}
+
+func isComponentKind(kind string) bool {
+	for _, k := range componentKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func findFactory[T component.Factory](factories map[component.Type]T, componentType string) (component.Factory, bool) {
+	for t, factory := range factories {
+		if t.String() == componentType {
+			return factory, true
+		}
+	}
+	return nil, false
+}
+
+func newComponentSchema(kind string, factory component.Factory) (componentSchema, error) {
+	cfg := factory.CreateDefaultConfig()
+	conf := confmap.New()
+	if err := conf.Marshal(cfg); err != nil {
+		return componentSchema{}, fmt.Errorf("failed to marshal the default configuration of %s %s: %w", kind, factory.Type(), err)
+	}
+	return componentSchema{
+		Kind:     kind,
+		Type:     factory.Type().String(),
+		Fields:   configFields(reflect.TypeOf(cfg), nil),
+		Defaults: conf.ToStringMap(),
+	}, nil
+}
+
+// configFields returns the configuration keys of t, as read by the collector through their
+// mapstructure tags, with their Go type. Sections are returned as nested maps.
+func configFields(t reflect.Type, parents []reflect.Type) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return typeName(t)
+	}
+	for _, parent := range parents {
+		if parent == t {
+			// recursive configuration, the type is enough
+			return typeName(t)
+		}
+	}
+	parents = append(parents, t)
+
+	fields := make(map[string]any)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "squash") || (field.Anonymous && name == "") {
+			if squashed, ok := configFields(field.Type, parents).(map[string]any); ok {
+				for k, v := range squashed {
+					fields[k] = v
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = configFields(field.Type, parents)
+	}
+	if len(fields) == 0 {
+		// e.g. optional wrappers with unexported fields
+		return typeName(t)
+	}
+	return fields
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "[]" + typeName(t.Elem())
+	case reflect.Map:
+		return "map[" + typeName(t.Key()) + "]" + typeName(t.Elem())
+	case reflect.Pointer:
+		return typeName(t.Elem())
+	default:
+		return t.String()
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClientConfig struct {
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+type testConfig struct {
+	testClientConfig `mapstructure:",squash"`
+	Timeout          time.Duration     `mapstructure:"timeout"`
+	Headers          map[string]string `mapstructure:"headers"`
+	Include          []string          `mapstructure:"include"`
+	Retry            *struct {
+		Enabled bool `mapstructure:"enabled"`
+	} `mapstructure:"retry"`
+	Next     *testConfig `mapstructure:"next"`
+	Ignored  string      `mapstructure:"-"`
+	internal string
+}
+
+func TestConfigFields(t *testing.T) {
+	assert.Equal(t, map[string]any{
+		"endpoint": "string",
+		"timeout":  "time.Duration",
+		"headers":  "map[string]string",
+		"include":  "[]string",
+		"retry": map[string]any{
+			"enabled": "bool",
+		},
+		"next": "otelcol.testConfig",
+	}, configFields(reflect.TypeOf(&testConfig{}), nil))
+}
+
+func TestSchema(t *testing.T) {
+	t.Run("debug exporter", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		require.NoError(t, Schema(cmd, "debug/detailed", "", ComponentsOutputJSON))
+
+		var schemas []componentSchema
+		require.NoError(t, json.Unmarshal(out.Bytes(), &schemas))
+		require.Len(t, schemas, 1)
+		assert.Equal(t, "exporter", schemas[0].Kind)
+		assert.Equal(t, "debug", schemas[0].Type)
+		assert.Contains(t, schemas[0].Fields, "verbosity")
+		assert.Contains(t, schemas[0].Defaults, "verbosity")
+	})
+
+	t.Run("unknown component", func(t *testing.T) {
+		err := Schema(&cobra.Command{}, "doesnotexist", "", ComponentsOutputYAML)
+		require.ErrorContains(t, err, `component "doesnotexist" is not available`)
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		err := Schema(&cobra.Command{}, "debug", "sink", ComponentsOutputYAML)
+		require.ErrorContains(t, err, `unsupported component kind "sink"`)
+	})
+}
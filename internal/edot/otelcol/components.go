@@ -111,6 +111,7 @@ import (
 	"github.com/elastic/opentelemetry-collector-components/extension/apmconfigextension"
 
 	// Connectors
+	failoverconnector "github.com/open-telemetry/opentelemetry-collector-contrib/connector/failoverconnector"
 	otlpjsonconnector "github.com/open-telemetry/opentelemetry-collector-contrib/connector/otlpjsonconnector"
 	routingconnector "github.com/open-telemetry/opentelemetry-collector-contrib/connector/routingconnector"
 	spanmetricsconnector "github.com/open-telemetry/opentelemetry-collector-contrib/connector/spanmetricsconnector"
@@ -236,6 +237,7 @@ func components(extensionFactories ...extension.Factory) func() (otelcol.Factori
 		factories.Connectors, err = otelcol.MakeFactoryMap[connector.Factory](
 			otlpjsonconnector.NewFactory(),
 			routingconnector.NewFactory(),
+			failoverconnector.NewFactory(),
 			spanmetricsconnector.NewFactory(),
 			elasticapmconnector.NewFactory(),
 			profilingmetricsconnector.NewFactory(),
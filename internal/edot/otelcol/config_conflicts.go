@@ -0,0 +1,189 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+// uriSchemeRegexp matches the scheme of a config URI the same way the collector's resolver does.
+var uriSchemeRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]+:`)
+
+// configNode tracks which config URI last set a value while configs are merged.
+type configNode struct {
+	source   string
+	children map[string]*configNode
+}
+
+// ConfigConflictError is the error returned when resolving a configuration where a key is a map in
+// one config URI and a scalar or list in another.
+type ConfigConflictError struct {
+	// Key is the path of the conflicting key, e.g. "exporters::otlp::headers".
+	Key string
+	// First is the config URI that first set the key, the file name for local files.
+	First string
+	// FirstIsMap is true when the key is a map in First, and not a map in Second.
+	FirstIsMap bool
+	// Second is the config URI that conflicts with First.
+	Second string
+}
+
+func (e *ConfigConflictError) Error() string {
+	return fmt.Sprintf("config key %q in %s %s, but in %s %s",
+		e.Key, e.First, describeConfigNode(e.FirstIsMap), e.Second, describeConfigNode(!e.FirstIsMap))
+}
+
+// configConflictsRecorder records the configuration retrieved for each config URI while the configuration
+// is resolved, so that conflicts are checked against the content the collector runs or validates, e.g. a
+// file changed on disk before a reload or a configuration fetched from a configuration server.
+type configConflictsRecorder struct {
+	mu        sync.Mutex
+	retrieved map[string]map[string]any
+}
+
+func newConfigConflictsRecorder() *configConflictsRecorder {
+	return &configConflictsRecorder{retrieved: map[string]map[string]any{}}
+}
+
+// providerFactory wraps factory so that the configurations it retrieves are recorded.
+func (r *configConflictsRecorder) providerFactory(factory confmap.ProviderFactory) confmap.ProviderFactory {
+	return confmap.NewProviderFactory(func(settings confmap.ProviderSettings) confmap.Provider {
+		return &conflictsRecordingProvider{
+			Provider: factory.Create(settings),
+			recorder: r,
+		}
+	})
+}
+
+func (r *configConflictsRecorder) record(uri string, retrieved *confmap.Retrieved) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conf, err := retrieved.AsConf()
+	if err != nil {
+		// not a map, e.g. a scalar expanded into another configuration
+		delete(r.retrieved, uri)
+		return
+	}
+	r.retrieved[uri] = conf.ToStringMap()
+}
+
+// checkConflicts merges the configurations last retrieved for configURIs in order, with the same
+// precedence as the collector's resolver, and returns a *ConfigConflictError naming both originating
+// config URIs when a key is a map in one configuration and a scalar or list in another. Such configs are
+// silently overridden by the collector, which usually hides a mistake in one of the fragments.
+func (r *configConflictsRecorder) checkConflicts(configURIs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	root := &configNode{children: map[string]*configNode{}}
+	for _, uri := range configURIs {
+		source, retrievedURI := configSource(uri)
+		cfg, ok := r.retrieved[retrievedURI]
+		if !ok {
+			continue
+		}
+		if err := mergeConfigNode(root, cfg, nil, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configSource returns the name of uri reported in conflicts, the file name for local files, and the
+// URI passed by the resolver to the providers when retrieving it.
+func configSource(uri string) (string, string) {
+	if filename, ok := strings.CutPrefix(uri, "file:"); ok {
+		return filename, uri
+	}
+	if !uriSchemeRegexp.MatchString(uri) {
+		return uri, "file:" + uri
+	}
+	return uri, uri
+}
+
+type conflictsRecordingProvider struct {
+	confmap.Provider
+	recorder *configConflictsRecorder
+}
+
+func (p *conflictsRecordingProvider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	retrieved, err := p.Provider.Retrieve(ctx, uri, watcher)
+	if err != nil {
+		return nil, err
+	}
+	p.recorder.record(uri, retrieved)
+	return retrieved, nil
+}
+
+// newConfigConflictsConverterFactory returns a converter failing the resolution of configURIs when the
+// configurations retrieved for them conflict, see configConflictsRecorder.checkConflicts.
+func newConfigConflictsConverterFactory(configURIs []string, recorder *configConflictsRecorder) confmap.ConverterFactory {
+	return confmap.NewConverterFactory(func(confmap.ConverterSettings) confmap.Converter {
+		return &configConflictsConverter{configURIs: configURIs, recorder: recorder}
+	})
+}
+
+type configConflictsConverter struct {
+	configURIs []string
+	recorder   *configConflictsRecorder
+}
+
+func (c *configConflictsConverter) Convert(_ context.Context, _ *confmap.Conf) error {
+	return c.recorder.checkConflicts(c.configURIs)
+}
+
+func mergeConfigNode(node *configNode, cfg map[string]any, keyPath []string, source string) error {
+	keys := make([]string, 0, len(cfg))
+	for k := range cfg {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		value := cfg[k]
+		if value == nil {
+			// a null value such as `otlp:` doesn't set the key, it conflicts with neither a map nor a scalar
+			continue
+		}
+		childPath := append(keyPath[:len(keyPath):len(keyPath)], k)
+		existing, found := node.children[k]
+
+		childMap, isMap := value.(map[string]any)
+		if found && (existing.children != nil) != isMap {
+			return &ConfigConflictError{
+				Key:        strings.Join(childPath, "::"),
+				First:      existing.source,
+				FirstIsMap: existing.children != nil,
+				Second:     source,
+			}
+		}
+		if !isMap {
+			node.children[k] = &configNode{source: source}
+			continue
+		}
+		if !found {
+			existing = &configNode{children: map[string]*configNode{}}
+			node.children[k] = existing
+		}
+		existing.source = source
+		if err := mergeConfigNode(existing, childMap, childPath, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func describeConfigNode(isMap bool) string {
+	if isMap {
+		return "is a map"
+	}
+	return "is not a map"
+}
@@ -0,0 +1,118 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+	"go.opentelemetry.io/collector/confmap/provider/fileprovider"
+	"go.opentelemetry.io/collector/confmap/provider/yamlprovider"
+)
+
+// resolveConfigConflicts resolves uris with the conflicts check, fileProvider retrieving the files.
+func resolveConfigConflicts(t *testing.T, fileProvider confmap.ProviderFactory, uris ...string) error {
+	recorder := newConfigConflictsRecorder()
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: uris,
+		ProviderFactories: []confmap.ProviderFactory{
+			recorder.providerFactory(fileProvider),
+			recorder.providerFactory(envprovider.NewFactory()),
+			recorder.providerFactory(yamlprovider.NewFactory()),
+		},
+		DefaultScheme:      "env",
+		ConverterFactories: []confmap.ConverterFactory{newConfigConflictsConverterFactory(uris, recorder)},
+	})
+	require.NoError(t, err)
+	_, err = resolver.Resolve(context.Background())
+	return err
+}
+
+func TestConfigConflicts(t *testing.T) {
+	checkConflicts := func(uris ...string) error {
+		return resolveConfigConflicts(t, fileprovider.NewFactory(), uris...)
+	}
+	dir := t.TempDir()
+	writeConfig := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+		return p
+	}
+
+	receivers := writeConfig("receivers.yml", `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+`)
+	exporters := writeConfig("exporters.yml", `
+exporters:
+  debug:
+    verbosity: basic
+receivers:
+  otlp:
+    protocols:
+      http:
+`)
+	override := writeConfig("override.yml", `
+exporters:
+  debug:
+    verbosity: detailed
+`)
+	conflicting := writeConfig("conflicting.yml", `
+receivers:
+  otlp: enabled
+`)
+
+	t.Run("compatible fragments", func(t *testing.T) {
+		require.NoError(t, checkConflicts(receivers, "file:"+exporters, override, "env:OTEL_CONFIG"))
+	})
+
+	t.Run("non file URIs", func(t *testing.T) {
+		err := checkConflicts(receivers, "yaml:receivers::otlp: enabled")
+		require.ErrorContains(t, err, `config key "receivers::otlp" in `+receivers+` is a map, but in yaml:receivers::otlp: enabled is not a map`)
+	})
+
+	t.Run("retrieved content", func(t *testing.T) {
+		// the content retrieved for a reload is checked, not the file on disk
+		reloaded := retrievedContentProviderFactory(fileprovider.NewFactory(), "file:"+exporters, []byte("receivers:\n  otlp: enabled\n"))
+		err := resolveConfigConflicts(t, reloaded, receivers, exporters)
+		require.ErrorContains(t, err, `config key "receivers::otlp" in `+receivers+` is a map, but in `+exporters+` is not a map`)
+		var conflictErr *ConfigConflictError
+		require.ErrorAs(t, err, &conflictErr)
+	})
+
+	t.Run("map replaced by scalar", func(t *testing.T) {
+		err := checkConflicts(receivers, exporters, conflicting)
+		require.ErrorContains(t, err, `config key "receivers::otlp" in `+exporters+` is a map, but in `+conflicting+` is not a map`)
+	})
+
+	t.Run("scalar replaced by map", func(t *testing.T) {
+		err := checkConflicts(conflicting, receivers)
+		require.ErrorContains(t, err, `config key "receivers::otlp" in `+conflicting+` is not a map, but in `+receivers+` is a map`)
+	})
+
+	t.Run("null values are unset", func(t *testing.T) {
+		null := writeConfig("null.yml", `
+receivers:
+  otlp:
+`)
+		require.NoError(t, checkConflicts(conflicting, null))
+		require.NoError(t, checkConflicts(null, conflicting))
+		require.NoError(t, checkConflicts(receivers, null, exporters))
+		err := checkConflicts(receivers, null, conflicting)
+		require.ErrorContains(t, err, `config key "receivers::otlp" in `+receivers+` is a map, but in `+conflicting+` is not a map`)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		err := checkConflicts(filepath.Join(dir, "missing.yml"))
+		require.Error(t, err)
+	})
+}
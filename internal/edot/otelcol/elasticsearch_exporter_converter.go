@@ -0,0 +1,67 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const elasticsearchExporterType = "elasticsearch"
+
+// elasticsearchExporterDefaults are the settings applied to every elasticsearch exporter unless
+// they are explicitly configured, they favor ingestion throughput over the upstream defaults.
+var elasticsearchExporterDefaults = map[string]any{
+	"compression": "gzip",
+	"sending_queue": map[string]any{
+		"batch": map[string]any{
+			"flush_timeout": "1s",
+		},
+	},
+}
+
+// elasticsearchExporterDefaultsConverter is a Converter that applies elasticsearchExporterDefaults
+// to every elasticsearch exporter.
+type elasticsearchExporterDefaultsConverter struct{}
+
+func (elasticsearchExporterDefaultsConverter) Convert(_ context.Context, conf *confmap.Conf) error {
+	exporters, err := conf.Sub("exporters")
+	if err != nil {
+		//nolint:nilerr // ignore the error, the collector reports invalid exporters configuration on its own
+		return nil
+	}
+	for id, cfg := range exporters.ToStringMap() {
+		if id != elasticsearchExporterType && !strings.HasPrefix(id, elasticsearchExporterType+"/") {
+			continue
+		}
+		cfgMap, ok := cfg.(map[string]any)
+		if !ok && cfg != nil {
+			continue
+		}
+		// explicit settings are merged over the defaults
+		merged := confmap.NewFromStringMap(elasticsearchExporterDefaults)
+		if err := merged.Merge(confmap.NewFromStringMap(cfgMap)); err != nil {
+			return fmt.Errorf("elasticsearch exporter %s: %w", id, err)
+		}
+		err := conf.Merge(confmap.NewFromStringMap(map[string]any{
+			"exporters": map[string]any{id: merged.ToStringMap()},
+		}))
+		if err != nil {
+			return fmt.Errorf("elasticsearch exporter %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// NewElasticsearchExporterDefaultsConverterFactory returns a converter factory that applies the
+// Elastic Agent defaults to every elasticsearch exporter, explicit settings take precedence.
+func NewElasticsearchExporterDefaultsConverterFactory() confmap.ConverterFactory {
+	return confmap.NewConverterFactory(func(_ confmap.ConverterSettings) confmap.Converter {
+		return elasticsearchExporterDefaultsConverter{}
+	})
+}
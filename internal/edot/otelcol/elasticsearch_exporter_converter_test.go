@@ -0,0 +1,53 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestElasticsearchExporterDefaultsConverter(t *testing.T) {
+	conf := confmap.NewFromStringMap(map[string]any{
+		"exporters": map[string]any{
+			"elasticsearch": map[string]any{
+				"endpoints": []any{"http://localhost:9200"},
+			},
+			"elasticsearch/overridden": map[string]any{
+				"compression": "none",
+				"sending_queue": map[string]any{
+					"batch": map[string]any{
+						"flush_timeout": "30s",
+						"max_size":      500,
+					},
+				},
+			},
+			"elasticsearch/empty": nil,
+			"debug":               map[string]any{"verbosity": "detailed"},
+		},
+	})
+	require.NoError(t, elasticsearchExporterDefaultsConverter{}.Convert(context.Background(), conf))
+
+	assert.Equal(t, "gzip", conf.Get("exporters::elasticsearch::compression"))
+	assert.Equal(t, "1s", conf.Get("exporters::elasticsearch::sending_queue::batch::flush_timeout"))
+	assert.Equal(t, []any{"http://localhost:9200"}, conf.Get("exporters::elasticsearch::endpoints"))
+
+	assert.Equal(t, "none", conf.Get("exporters::elasticsearch/overridden::compression"))
+	assert.Equal(t, "30s", conf.Get("exporters::elasticsearch/overridden::sending_queue::batch::flush_timeout"))
+	assert.Equal(t, 500, conf.Get("exporters::elasticsearch/overridden::sending_queue::batch::max_size"))
+
+	assert.Equal(t, "gzip", conf.Get("exporters::elasticsearch/empty::compression"))
+	assert.Equal(t, "1s", conf.Get("exporters::elasticsearch/empty::sending_queue::batch::flush_timeout"))
+
+	assert.Equal(t, map[string]any{"verbosity": "detailed"}, conf.Get("exporters::debug"))
+}
+
+func TestElasticsearchExporterDefaultsConverterNoExporters(t *testing.T) {
+	require.NoError(t, elasticsearchExporterDefaultsConverter{}.Convert(context.Background(), confmap.New()))
+}
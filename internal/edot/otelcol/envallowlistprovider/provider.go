@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package envallowlistprovider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+)
+
+const schemeName = "env"
+
+// build time guard that provider implements confmap.Provider
+var _ confmap.Provider = (*provider)(nil)
+
+// provider is a drop-in replacement of the collector's env provider that only expands the
+// environment variables of an allow-list, so a configuration cannot read arbitrary variables of
+// the host environment.
+type provider struct {
+	allowList []string
+	env       confmap.Provider
+}
+
+// NewFactory returns a confmap.ProviderFactory for the "env" scheme that only expands the
+// environment variables in allowList. An entry ending with "*" allows every variable starting
+// with the entry prefix, e.g. "ELASTIC_*". Expanding any other variable fails, including when
+// the reference has a default value.
+func NewFactory(allowList []string) confmap.ProviderFactory {
+	envFactory := envprovider.NewFactory()
+	return confmap.NewProviderFactory(func(ps confmap.ProviderSettings) confmap.Provider {
+		return &provider{
+			allowList: allowList,
+			env:       envFactory.Create(ps),
+		}
+	})
+}
+
+func (p *provider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	name, ok := strings.CutPrefix(uri, schemeName+":")
+	if !ok {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+	// the env provider supports a default value with ${env:NAME:-default}
+	name, _, _ = strings.Cut(name, ":-")
+	if !p.allowed(name) {
+		return nil, fmt.Errorf("environment variable %q is not in the allow-list of expandable environment variables", name)
+	}
+	return p.env.Retrieve(ctx, uri, watcher)
+}
+
+func (p *provider) allowed(name string) bool {
+	for _, allowed := range p.allowList {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *provider) Scheme() string {
+	return schemeName
+}
+
+func (p *provider) Shutdown(ctx context.Context) error {
+	return p.env.Shutdown(ctx)
+}
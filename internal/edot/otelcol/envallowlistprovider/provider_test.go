@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package envallowlistprovider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+)
+
+func TestProviderRetrieve(t *testing.T) {
+	t.Setenv("ALLOWED_VAR", "allowed")
+	t.Setenv("ELASTIC_PREFIXED", "prefixed")
+	t.Setenv("SECRET_VAR", "secret")
+
+	p := NewFactory([]string{"ALLOWED_VAR", "UNSET_VAR", "ELASTIC_*"}).Create(confmap.ProviderSettings{Logger: zap.NewNop()})
+	t.Cleanup(func() {
+		assert.NoError(t, p.Shutdown(context.Background()))
+	})
+	assert.Equal(t, "env", p.Scheme())
+
+	for uri, expected := range map[string]any{
+		"env:ALLOWED_VAR":          "allowed",
+		"env:ELASTIC_PREFIXED":     "prefixed",
+		"env:UNSET_VAR:-fallback":  "fallback",
+		"env:ALLOWED_VAR:-ignored": "allowed",
+	} {
+		ret, err := p.Retrieve(context.Background(), uri, nil)
+		require.NoError(t, err, uri)
+		raw, err := ret.AsRaw()
+		require.NoError(t, err, uri)
+		assert.Equal(t, expected, raw, uri)
+	}
+
+	for _, uri := range []string{"env:SECRET_VAR", "env:SECRET_VAR:-default", "env:ELASTIC"} {
+		_, err := p.Retrieve(context.Background(), uri, nil)
+		assert.ErrorContains(t, err, "is not in the allow-list", uri)
+	}
+
+	_, err := p.Retrieve(context.Background(), "file:ALLOWED_VAR", nil)
+	assert.ErrorContains(t, err, "not supported")
+}
@@ -0,0 +1,127 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const fileExporterType = "file"
+
+// fileExporterRotationDefaults is the size-based rotation applied to the file exporters of the collector run
+// by the Elastic Agent, so that a file exporter left in a long-running configuration doesn't grow forever.
+var fileExporterRotationDefaults = map[string]any{
+	"max_megabytes": 100,
+	"max_backups":   5,
+}
+
+// fileExporterDirs is a Converter that creates the parent directory of every file exporter path,
+// the file exporter itself fails to start when the directory is missing.
+type fileExporterDirs struct{}
+
+func (fileExporterDirs) Convert(_ context.Context, conf *confmap.Conf) error {
+	exporters, err := conf.Sub("exporters")
+	if err != nil {
+		//nolint:nilerr // ignore the error, the collector reports invalid exporters configuration on its own
+		return nil
+	}
+	for id, cfg := range exporters.ToStringMap() {
+		if id != fileExporterType && !strings.HasPrefix(id, fileExporterType+"/") {
+			continue
+		}
+		cfgMap, ok := cfg.(map[string]any)
+		if !ok {
+			continue
+		}
+		path, ok := cfgMap["path"].(string)
+		if !ok || path == "" {
+			continue
+		}
+		if err := ensureParentDir(path); err != nil {
+			return fmt.Errorf("file exporter %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func ensureParentDir(path string) error {
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	switch {
+	case err == nil:
+		if !info.IsDir() {
+			return fmt.Errorf("parent %s of path %s is not a directory", dir, path)
+		}
+		return nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("failed to stat parent %s of path %s: %w", dir, path, err)
+	}
+	// MkdirAll fails with ENOTDIR when one of the ancestors is a file
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create parent %s of path %s: %w", dir, path, err)
+	}
+	return nil
+}
+
+// newFileExporterDirsConverterFactory returns a converter factory that ensures the parent directory
+// of every file exporter path exists before the collector starts, see WithFileExporterDirs.
+func newFileExporterDirsConverterFactory() confmap.ConverterFactory {
+	return confmap.NewConverterFactory(func(_ confmap.ConverterSettings) confmap.Converter {
+		return fileExporterDirs{}
+	})
+}
+
+// fileExporterRotation is a Converter that applies fileExporterRotationDefaults to every file exporter
+// without rotation settings. File exporters appending to their file are left as is, the file exporter
+// doesn't support rotating a file it appends to.
+type fileExporterRotation struct{}
+
+func (fileExporterRotation) Convert(_ context.Context, conf *confmap.Conf) error {
+	exporters, err := conf.Sub("exporters")
+	if err != nil {
+		//nolint:nilerr // ignore the error, the collector reports invalid exporters configuration on its own
+		return nil
+	}
+	for id, cfg := range exporters.ToStringMap() {
+		if id != fileExporterType && !strings.HasPrefix(id, fileExporterType+"/") {
+			continue
+		}
+		cfgMap, ok := cfg.(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, explicit := cfgMap["rotation"]; explicit {
+			continue
+		}
+		if appendMode, _ := cfgMap["append"].(bool); appendMode {
+			continue
+		}
+		err := conf.Merge(confmap.NewFromStringMap(map[string]any{
+			"exporters": map[string]any{
+				id: map[string]any{"rotation": fileExporterRotationDefaults},
+			},
+		}))
+		if err != nil {
+			return fmt.Errorf("file exporter %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// newFileExporterRotationConverterFactory returns a converter factory that rotates the files written by the
+// file exporters by size unless they configure their own rotation, see WithFileExporterRotation.
+func newFileExporterRotationConverterFactory() confmap.ConverterFactory {
+	return confmap.NewConverterFactory(func(_ confmap.ConverterSettings) confmap.Converter {
+		return fileExporterRotation{}
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestFileExporterDirsConverter(t *testing.T) {
+	dir := t.TempDir()
+	notADir := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(notADir, nil, 0o600))
+
+	newConf := func(exporters map[string]any) *confmap.Conf {
+		return confmap.NewFromStringMap(map[string]any{"exporters": exporters})
+	}
+
+	t.Run("creates missing parents", func(t *testing.T) {
+		conf := newConf(map[string]any{
+			"file":         map[string]any{"path": filepath.Join(dir, "a", "b", "data.json")},
+			"file/second":  map[string]any{"path": filepath.Join(dir, "c", "data.json")},
+			"filelike":     map[string]any{"path": filepath.Join(dir, "ignored", "data.json")},
+			"debug":        map[string]any{"verbosity": "detailed"},
+			"file/no_path": nil,
+		})
+		require.NoError(t, fileExporterDirs{}.Convert(context.Background(), conf))
+		assert.DirExists(t, filepath.Join(dir, "a", "b"))
+		assert.DirExists(t, filepath.Join(dir, "c"))
+		assert.NoDirExists(t, filepath.Join(dir, "ignored"))
+	})
+
+	t.Run("parent is a file", func(t *testing.T) {
+		conf := newConf(map[string]any{
+			"file": map[string]any{"path": filepath.Join(notADir, "data.json")},
+		})
+		err := fileExporterDirs{}.Convert(context.Background(), conf)
+		require.ErrorContains(t, err, "is not a directory")
+	})
+
+	t.Run("ancestor is a file", func(t *testing.T) {
+		conf := newConf(map[string]any{
+			"file": map[string]any{"path": filepath.Join(notADir, "sub", "data.json")},
+		})
+		err := fileExporterDirs{}.Convert(context.Background(), conf)
+		require.ErrorContains(t, err, "file exporter file:")
+		require.ErrorContains(t, err, notADir)
+	})
+
+	t.Run("no exporters", func(t *testing.T) {
+		require.NoError(t, fileExporterDirs{}.Convert(context.Background(), confmap.New()))
+	})
+}
+
+func TestFileExporterRotationConverter(t *testing.T) {
+	conf := confmap.NewFromStringMap(map[string]any{
+		"exporters": map[string]any{
+			"file": map[string]any{"path": "/tmp/output.json"},
+			"file/rotated": map[string]any{
+				"path":     "/tmp/rotated.json",
+				"rotation": map[string]any{"max_megabytes": 10},
+			},
+			"file/append":  map[string]any{"path": "/tmp/append.json", "append": true},
+			"file/no_path": nil,
+			"debug":        map[string]any{"verbosity": "detailed"},
+		},
+	})
+	require.NoError(t, fileExporterRotation{}.Convert(context.Background(), conf))
+
+	assert.Equal(t, fileExporterRotationDefaults, conf.Get("exporters::file::rotation"))
+	assert.Equal(t, "/tmp/output.json", conf.Get("exporters::file::path"))
+	assert.Equal(t, map[string]any{"max_megabytes": 10}, conf.Get("exporters::file/rotated::rotation"), "explicit rotation is kept")
+	assert.False(t, conf.IsSet("exporters::file/append::rotation"), "appending exporters can't rotate")
+	assert.Nil(t, conf.Get("exporters::file/no_path"))
+	assert.False(t, conf.IsSet("exporters::debug::rotation"))
+
+	require.NoError(t, fileExporterRotation{}.Convert(context.Background(), confmap.New()))
+}
@@ -0,0 +1,108 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/otelcol"
+)
+
+const (
+	// HealthLivenessPath is the path of the liveness probe served by HealthServer.
+	HealthLivenessPath = "/livez"
+	// HealthReadinessPath is the path of the readiness probe served by HealthServer.
+	HealthReadinessPath = "/readyz"
+)
+
+// collectorState is the part of otelcol.Collector reporting its state.
+type collectorState interface {
+	GetState() otelcol.State
+}
+
+// HealthServer serves the liveness and readiness probes of the collector run with Run over HTTP,
+// for orchestrators to probe the collector.
+//
+// The liveness probe succeeds until the collector starts shutting down. The readiness probe
+// succeeds once the configuration is loaded and the pipelines are started, until the collector
+// starts shutting down. Both probes answer with the state of the collector.
+type HealthServer struct {
+	listener  net.Listener
+	server    *http.Server
+	collector atomic.Pointer[collectorState]
+}
+
+// NewHealthServer returns a HealthServer listening on addr, e.g. `:13133`. It serves the probes once
+// started with Start.
+func NewHealthServer(addr string) (*HealthServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on health address %s: %w", addr, err)
+	}
+	h := &HealthServer{listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthLivenessPath, h.probe(func(state otelcol.State) bool {
+		return state == otelcol.StateStarting || state == otelcol.StateRunning
+	}))
+	mux.HandleFunc(HealthReadinessPath, h.probe(func(state otelcol.State) bool {
+		return state == otelcol.StateRunning
+	}))
+	h.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return h, nil
+}
+
+// Addr returns the address the HealthServer listens on.
+func (h *HealthServer) Addr() net.Addr {
+	return h.listener.Addr()
+}
+
+// Start serves the probes in the background.
+func (h *HealthServer) Start() {
+	go func() {
+		_ = h.server.Serve(h.listener)
+	}()
+}
+
+// Stop stops serving the probes.
+func (h *HealthServer) Stop(ctx context.Context) error {
+	if err := h.server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// watch reports the state of collector from now on.
+func (h *HealthServer) watch(collector collectorState) {
+	h.collector.Store(&collector)
+}
+
+// state returns the state of the watched collector, StateStarting until a collector is watched.
+func (h *HealthServer) state() otelcol.State {
+	collector := h.collector.Load()
+	if collector == nil {
+		return otelcol.StateStarting
+	}
+	return (*collector).GetState()
+}
+
+func (h *HealthServer) probe(healthy func(otelcol.State) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := h.state()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if !healthy(state) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = fmt.Fprintln(w, state)
+	}
+}
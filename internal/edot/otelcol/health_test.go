@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/otelcol"
+)
+
+// fakeCollector reports the state it is set to.
+type fakeCollector struct {
+	state atomic.Int64
+}
+
+func (f *fakeCollector) GetState() otelcol.State {
+	return otelcol.State(f.state.Load())
+}
+
+func TestHealthServer(t *testing.T) {
+	health, err := NewHealthServer("127.0.0.1:0")
+	require.NoError(t, err)
+	health.Start()
+	t.Cleanup(func() {
+		require.NoError(t, health.Stop(t.Context()))
+	})
+
+	probe := func(path string) (int, string) {
+		resp, err := http.Get("http://" + health.Addr().String() + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
+	// no collector is watched yet, it is starting
+	status, body := probe(HealthLivenessPath)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, otelcol.StateStarting.String(), body)
+	status, _ = probe(HealthReadinessPath)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	collector := &fakeCollector{}
+	health.watch(collector)
+	for _, tc := range []struct {
+		state     otelcol.State
+		liveness  int
+		readiness int
+	}{
+		{otelcol.StateStarting, http.StatusOK, http.StatusServiceUnavailable},
+		{otelcol.StateRunning, http.StatusOK, http.StatusOK},
+		{otelcol.StateClosing, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		{otelcol.StateClosed, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+	} {
+		collector.state.Store(int64(tc.state))
+		status, body := probe(HealthLivenessPath)
+		assert.Equal(t, tc.liveness, status, "liveness in state %s", tc.state)
+		assert.Equal(t, tc.state.String(), body)
+		status, _ = probe(HealthReadinessPath)
+		assert.Equal(t, tc.readiness, status, "readiness in state %s", tc.state)
+	}
+}
+
+func TestNewHealthServerInvalidAddr(t *testing.T) {
+	_, err := NewHealthServer("invalid:address:1")
+	require.ErrorContains(t, err, "failed to listen on health address invalid:address:1")
+}
@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+// otlpHTTPEndpointKeys are the keys of the otlphttp exporter configuration holding an endpoint.
+var otlpHTTPEndpointKeys = []string{"endpoint", "traces_endpoint", "metrics_endpoint", "logs_endpoint", "profiles_endpoint"}
+
+// CheckOTLPEndpoints returns an error for every otlp and otlphttp exporter of conf whose endpoint
+// scheme doesn't match the protocol of the exporter: the otlphttp exporter only accepts http:// and
+// https:// URLs, and the otlp exporter, which uses gRPC, doesn't accept a URL with an OTLP/HTTP
+// path like `/v1/traces`. The collector accepts both, and only fails to export once it runs.
+func CheckOTLPEndpoints(conf *confmap.Conf) error {
+	exporters, ok := conf.Get("exporters").(map[string]any)
+	if !ok {
+		return nil
+	}
+	ids := make([]string, 0, len(exporters))
+	for id := range exporters {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var errs []error
+	for _, id := range ids {
+		cfg, ok := exporters[id].(map[string]any)
+		if !ok {
+			continue
+		}
+		exporterType, _, _ := strings.Cut(id, "/")
+		switch exporterType {
+		case otlpHTTPExporterType:
+			for _, key := range otlpHTTPEndpointKeys {
+				endpoint, ok := cfg[key].(string)
+				if !ok || endpoint == "" {
+					continue
+				}
+				if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+					errs = append(errs, fmt.Errorf("exporters::%s: %s %q must start with http:// or https://, use the otlp exporter to export over gRPC", id, key, endpoint))
+				}
+			}
+		case otlpExporterType:
+			endpoint, ok := cfg["endpoint"].(string)
+			if !ok || endpoint == "" {
+				continue
+			}
+			if u, err := url.Parse(endpoint); err == nil && (u.Scheme == "http" || u.Scheme == "https") && strings.Trim(u.Path, "/") != "" {
+				errs = append(errs, fmt.Errorf("exporters::%s: endpoint %q has the path %s, but the otlp exporter exports over gRPC, use the otlphttp exporter to export over HTTP", id, endpoint, u.Path))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
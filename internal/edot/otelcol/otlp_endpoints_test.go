@@ -0,0 +1,49 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestCheckOTLPEndpoints(t *testing.T) {
+	t.Run("matching schemes", func(t *testing.T) {
+		conf := confmap.NewFromStringMap(map[string]any{
+			"exporters": map[string]any{
+				"otlp":              map[string]any{"endpoint": "apm-server:8200", "tls": map[string]any{"insecure": true}},
+				"otlp/secure":       map[string]any{"endpoint": "https://apm-server:8200"},
+				"otlphttp":          map[string]any{"endpoint": "http://apm-server:8200"},
+				"otlphttp/signals":  map[string]any{"traces_endpoint": "https://apm-server:8200/v1/traces"},
+				"debug":             map[string]any{"verbosity": "detailed"},
+				"otlphttp/defaults": nil,
+			},
+		})
+		require.NoError(t, CheckOTLPEndpoints(conf))
+	})
+
+	t.Run("mismatched schemes", func(t *testing.T) {
+		conf := confmap.NewFromStringMap(map[string]any{
+			"exporters": map[string]any{
+				"otlphttp":         map[string]any{"endpoint": "apm-server:8200"},
+				"otlphttp/signals": map[string]any{"endpoint": "https://apm-server:8200", "logs_endpoint": "grpc://apm-server:8200"},
+				"otlp":             map[string]any{"endpoint": "http://apm-server:8200/v1/traces"},
+			},
+		})
+		err := CheckOTLPEndpoints(conf)
+		require.Error(t, err)
+		errs := ValidationErrors(err)
+		require.Len(t, errs, 3)
+		assert.Equal(t, "exporters::otlp", errs[0].Path)
+		assert.Contains(t, errs[0].Message, "use the otlphttp exporter")
+		assert.Equal(t, "exporters::otlphttp", errs[1].Path)
+		assert.Contains(t, errs[1].Message, `endpoint "apm-server:8200" must start with http:// or https://`)
+		assert.Equal(t, "exporters::otlphttp/signals", errs[2].Path)
+		assert.Contains(t, errs[2].Message, "logs_endpoint")
+	})
+}
@@ -0,0 +1,208 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	otlpExporterType      = "otlp"
+	otlpHTTPExporterType  = "otlphttp"
+	failoverConnectorType = "failover"
+)
+
+// otlpFailoverConverter is a Converter that expands the `endpoints` list of the otlp and otlphttp
+// exporters into a failover configuration: one exporter per endpoint, each in its own pipeline, and
+// a failover connector per signal sending the data to the first endpoint that accepts it.
+//
+// For example the otlp/elastic exporter with `endpoints: [a, b]` in the traces pipeline becomes the
+// otlp/elastic_failover_0 and otlp/elastic_failover_1 exporters with the a and b endpoints, in the
+// traces/otlp_elastic_failover_0 and traces/otlp_elastic_failover_1 pipelines, and the traces pipeline
+// exports to the failover/otlp_elastic_traces connector instead. An exporter no pipeline uses is not
+// expanded, it keeps its first endpoint.
+type otlpFailoverConverter struct{}
+
+func (otlpFailoverConverter) Convert(_ context.Context, conf *confmap.Conf) error {
+	exporters, ok := conf.Get("exporters").(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	ids := make([]string, 0, len(exporters))
+	for id := range exporters {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		exporterType, name, _ := strings.Cut(id, "/")
+		if exporterType != otlpExporterType && exporterType != otlpHTTPExporterType {
+			continue
+		}
+		cfg, ok := exporters[id].(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, ok := cfg["endpoints"]; !ok {
+			continue
+		}
+		endpoints, err := failoverEndpoints(cfg)
+		if err != nil {
+			return fmt.Errorf("exporter %s: %w", id, err)
+		}
+		// read the pipelines again, they are updated by the expansion of the previous exporters
+		pipelines, _ := conf.Get("service::pipelines").(map[string]any)
+		if !exporterInPipelines(pipelines, id) {
+			// the collector doesn't start exporters no pipeline uses, there is nothing to fail
+			// over, keep the exporter with its first endpoint so the configuration stays valid
+			delete(cfg, "endpoints")
+			cfg["endpoint"] = endpoints[0]
+			conf.Delete("exporters::" + id)
+			if err := conf.Merge(confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{id: cfg},
+			})); err != nil {
+				return fmt.Errorf("exporter %s: failed to merge the configuration: %w", id, err)
+			}
+			continue
+		}
+		if err := expandOTLPFailover(conf, exporters, pipelines, id, exporterType, name, cfg, endpoints); err != nil {
+			return fmt.Errorf("exporter %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// failoverEndpoints returns the `endpoints` of an exporter configuration.
+func failoverEndpoints(cfg map[string]any) ([]string, error) {
+	if _, ok := cfg["endpoint"]; ok {
+		return nil, errors.New("endpoint and endpoints cannot both be set")
+	}
+	list, ok := cfg["endpoints"].([]any)
+	if !ok || len(list) == 0 {
+		return nil, errors.New("endpoints must be a non-empty list of endpoints")
+	}
+	endpoints := make([]string, 0, len(list))
+	for i, e := range list {
+		endpoint, ok := e.(string)
+		if !ok || endpoint == "" {
+			return nil, fmt.Errorf("endpoints[%d] must be a non-empty string", i)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+// exporterInPipelines returns true when one of the pipelines exports to the exporter id.
+func exporterInPipelines(pipelines map[string]any, id string) bool {
+	for _, pipeline := range pipelines {
+		pipeline, ok := pipeline.(map[string]any)
+		if !ok {
+			continue
+		}
+		pipelineExporters, ok := pipeline["exporters"].([]any)
+		if ok && slices.Contains(pipelineExporters, any(id)) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandOTLPFailover replaces the exporter id having several endpoints in conf with a failover
+// configuration, see otlpFailoverConverter.
+func expandOTLPFailover(conf *confmap.Conf, exporters, pipelines map[string]any, id, exporterType, name string, cfg map[string]any, endpoints []string) error {
+	baseName := failoverConnectorType
+	if name != "" {
+		baseName = name + "_" + failoverConnectorType
+	}
+	// the generated pipeline and connector names are derived from the whole exporter ID, so the
+	// otlp and otlphttp exporters with the same name don't collide
+	flatID := strings.ReplaceAll(id, "/", "_")
+
+	newExporters := make(map[string]any, len(endpoints))
+	endpointIDs := make([]string, 0, len(endpoints))
+	for i, endpoint := range endpoints {
+		endpointID := fmt.Sprintf("%s/%s_%d", exporterType, baseName, i)
+		if _, exists := exporters[endpointID]; exists {
+			return fmt.Errorf("generated exporter %s is already defined", endpointID)
+		}
+		endpointCfg := make(map[string]any, len(cfg))
+		for k, v := range cfg {
+			if k != "endpoints" {
+				endpointCfg[k] = v
+			}
+		}
+		endpointCfg["endpoint"] = endpoint
+		newExporters[endpointID] = endpointCfg
+		endpointIDs = append(endpointIDs, endpointID)
+	}
+
+	connectors := make(map[string]any)
+	newPipelines := make(map[string]any)
+	pipelineIDs := make([]string, 0, len(pipelines))
+	for pipelineID := range pipelines {
+		pipelineIDs = append(pipelineIDs, pipelineID)
+	}
+	sort.Strings(pipelineIDs)
+	for _, pipelineID := range pipelineIDs {
+		pipeline, ok := pipelines[pipelineID].(map[string]any)
+		if !ok {
+			continue
+		}
+		pipelineExporters, ok := pipeline["exporters"].([]any)
+		if !ok || !slices.Contains(pipelineExporters, any(id)) {
+			continue
+		}
+		signal, _, _ := strings.Cut(pipelineID, "/")
+		connectorID := fmt.Sprintf("%s/%s_%s", failoverConnectorType, flatID, signal)
+		if _, exists := connectors[connectorID]; !exists {
+			priorityLevels := make([]any, 0, len(endpointIDs))
+			for i, endpointID := range endpointIDs {
+				endpointPipelineID := fmt.Sprintf("%s/%s_%s_%d", signal, flatID, failoverConnectorType, i)
+				if _, exists := pipelines[endpointPipelineID]; exists {
+					return fmt.Errorf("generated pipeline %s is already defined", endpointPipelineID)
+				}
+				newPipelines[endpointPipelineID] = map[string]any{
+					"receivers": []any{connectorID},
+					"exporters": []any{endpointID},
+				}
+				priorityLevels = append(priorityLevels, []any{endpointPipelineID})
+			}
+			connectors[connectorID] = map[string]any{"priority_levels": priorityLevels}
+		}
+
+		replaced := slices.Clone(pipelineExporters)
+		replaced[slices.Index(replaced, any(id))] = connectorID
+		newPipelines[pipelineID] = map[string]any{"exporters": replaced}
+	}
+
+	conf.Delete("exporters::" + id)
+	err := conf.Merge(confmap.NewFromStringMap(map[string]any{
+		"exporters":  newExporters,
+		"connectors": connectors,
+		"service": map[string]any{
+			"pipelines": newPipelines,
+		},
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to merge the failover configuration: %w", err)
+	}
+	return nil
+}
+
+// NewOTLPFailoverConverterFactory returns a converter factory that expands the `endpoints` list of
+// the otlp and otlphttp exporters into a failover configuration, see the failover connector.
+func NewOTLPFailoverConverterFactory() confmap.ConverterFactory {
+	return confmap.NewConverterFactory(func(_ confmap.ConverterSettings) confmap.Converter {
+		return otlpFailoverConverter{}
+	})
+}
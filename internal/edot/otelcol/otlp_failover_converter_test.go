@@ -0,0 +1,136 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestOTLPFailoverConverter(t *testing.T) {
+	conf := confmap.NewFromStringMap(map[string]any{
+		"receivers": map[string]any{"otlp": map[string]any{}},
+		"exporters": map[string]any{
+			"otlp/elastic": map[string]any{
+				"endpoints": []any{"primary:4317", "backup:4317"},
+				"headers":   map[string]any{"Authorization": "ApiKey secret"},
+			},
+			"otlphttp": map[string]any{"endpoint": "http://localhost:4318"},
+			"debug":    map[string]any{},
+		},
+		"service": map[string]any{
+			"pipelines": map[string]any{
+				"traces": map[string]any{
+					"receivers": []any{"otlp"},
+					"exporters": []any{"otlp/elastic", "debug"},
+				},
+				"logs": map[string]any{
+					"receivers": []any{"otlp"},
+					"exporters": []any{"otlp/elastic"},
+				},
+				"metrics": map[string]any{
+					"receivers": []any{"otlp"},
+					"exporters": []any{"otlphttp"},
+				},
+			},
+		},
+	})
+	require.NoError(t, otlpFailoverConverter{}.Convert(context.Background(), conf))
+
+	assert.False(t, conf.IsSet("exporters::otlp/elastic"))
+	assert.Equal(t, map[string]any{
+		"endpoint": "primary:4317",
+		"headers":  map[string]any{"Authorization": "ApiKey secret"},
+	}, conf.Get("exporters::otlp/elastic_failover_0"))
+	assert.Equal(t, "backup:4317", conf.Get("exporters::otlp/elastic_failover_1::endpoint"))
+	assert.Equal(t, "http://localhost:4318", conf.Get("exporters::otlphttp::endpoint"), "exporters with a single endpoint are unchanged")
+
+	assert.Equal(t, []any{"failover/otlp_elastic_traces", "debug"}, conf.Get("service::pipelines::traces::exporters"))
+	assert.Equal(t, []any{"otlp"}, conf.Get("service::pipelines::traces::receivers"))
+	assert.Equal(t, []any{"failover/otlp_elastic_logs"}, conf.Get("service::pipelines::logs::exporters"))
+	assert.Equal(t, []any{"otlphttp"}, conf.Get("service::pipelines::metrics::exporters"))
+
+	assert.Equal(t, map[string]any{
+		"priority_levels": []any{
+			[]any{"traces/otlp_elastic_failover_0"},
+			[]any{"traces/otlp_elastic_failover_1"},
+		},
+	}, conf.Get("connectors::failover/otlp_elastic_traces"))
+	assert.Equal(t, map[string]any{
+		"receivers": []any{"failover/otlp_elastic_traces"},
+		"exporters": []any{"otlp/elastic_failover_0"},
+	}, conf.Get("service::pipelines::traces/otlp_elastic_failover_0"))
+	assert.Equal(t, map[string]any{
+		"receivers": []any{"failover/otlp_elastic_logs"},
+		"exporters": []any{"otlp/elastic_failover_1"},
+	}, conf.Get("service::pipelines::logs/otlp_elastic_failover_1"))
+}
+
+func TestOTLPFailoverConverterUnusedExporter(t *testing.T) {
+	conf := confmap.NewFromStringMap(map[string]any{
+		"receivers": map[string]any{"otlp": map[string]any{}},
+		"exporters": map[string]any{
+			"otlp/unused": map[string]any{
+				"endpoints": []any{"primary:4317", "backup:4317"},
+			},
+			"debug": map[string]any{},
+		},
+		"service": map[string]any{
+			"pipelines": map[string]any{
+				"traces": map[string]any{
+					"receivers": []any{"otlp"},
+					"exporters": []any{"debug"},
+				},
+			},
+		},
+	})
+	require.NoError(t, otlpFailoverConverter{}.Convert(context.Background(), conf))
+
+	exporters, ok := conf.Get("exporters").(map[string]any)
+	require.True(t, ok)
+	assert.ElementsMatch(t, []string{"otlp/unused", "debug"}, slices.Collect(maps.Keys(exporters)), "no failover exporters are generated")
+	assert.Equal(t, map[string]any{"endpoint": "primary:4317"}, conf.Get("exporters::otlp/unused"))
+	assert.False(t, conf.IsSet("connectors"))
+	assert.Equal(t, []string{"traces"}, slices.Collect(maps.Keys(conf.Get("service::pipelines").(map[string]any))))
+}
+
+func TestOTLPFailoverConverterErrors(t *testing.T) {
+	testcases := map[string]struct {
+		exporter map[string]any
+		err      string
+	}{
+		"endpoint and endpoints": {
+			exporter: map[string]any{"endpoint": "a:4317", "endpoints": []any{"b:4317"}},
+			err:      "endpoint and endpoints cannot both be set",
+		},
+		"empty endpoints": {
+			exporter: map[string]any{"endpoints": []any{}},
+			err:      "endpoints must be a non-empty list of endpoints",
+		},
+		"invalid endpoint": {
+			exporter: map[string]any{"endpoints": []any{"a:4317", 4317}},
+			err:      "endpoints[1] must be a non-empty string",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			conf := confmap.NewFromStringMap(map[string]any{
+				"exporters": map[string]any{"otlp": tc.exporter},
+			})
+			err := otlpFailoverConverter{}.Convert(context.Background(), conf)
+			require.ErrorContains(t, err, "exporter otlp: "+tc.err)
+		})
+	}
+}
+
+func TestOTLPFailoverConverterNoExporters(t *testing.T) {
+	require.NoError(t, otlpFailoverConverter{}.Convert(context.Background(), confmap.New()))
+}
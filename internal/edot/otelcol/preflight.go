@@ -0,0 +1,238 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	// PreflightCheckWritable checks that a file exporter can write to its path.
+	PreflightCheckWritable = "writable"
+	// PreflightCheckResolvable checks that the host of an exporter endpoint resolves.
+	PreflightCheckResolvable = "resolvable"
+
+	preflightResolveTimeout = 5 * time.Second
+)
+
+// PreflightCheck is the result of checking that an exporter destination is usable in the
+// environment the collector runs in.
+type PreflightCheck struct {
+	// Component is the ID of the checked exporter, e.g. "file/logs".
+	Component string `json:"component"`
+	// Check is the kind of check, one of PreflightCheckWritable or PreflightCheckResolvable.
+	Check string `json:"check"`
+	// Target is the checked file path or endpoint.
+	Target string `json:"target"`
+	// Error is the reason the check failed, empty when it passed.
+	Error string `json:"error,omitempty"`
+	// Failover is the ID of the failover connector the exporter is a target of, if any. A failed
+	// check only fails the preflight when it failed for every target of the connector.
+	Failover string `json:"failover,omitempty"`
+}
+
+// Passed returns true when the check passed.
+func (c PreflightCheck) Passed() bool {
+	return c.Error == ""
+}
+
+// Preflight runs lightweight checks on the destinations of the exporters of the resolved
+// configuration conf: file exporter paths must be writable and the hosts of exporter endpoints
+// must resolve. Existing files are only opened for appending, nothing is written to them, but a
+// temporary file is created and removed in the closest existing directory of a missing path to
+// check that it is writable. The checks only catch environmental problems before the collector
+// runs. Checks are ordered by exporter ID.
+func Preflight(ctx context.Context, conf *confmap.Conf) []PreflightCheck {
+	exporters, err := conf.Sub("exporters")
+	if err != nil {
+		return nil
+	}
+	exportersMap := exporters.ToStringMap()
+	ids := make([]string, 0, len(exportersMap))
+	for id := range exportersMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	targets := failoverTargets(conf)
+	var checks []PreflightCheck
+	for _, id := range ids {
+		cfg, ok := exportersMap[id].(map[string]any)
+		if !ok {
+			continue
+		}
+		if id == fileExporterType || strings.HasPrefix(id, fileExporterType+"/") {
+			if path, ok := cfg["path"].(string); ok && path != "" {
+				checks = append(checks, newPreflightCheck(id, PreflightCheckWritable, path, checkWritable(path)))
+			}
+		}
+		for _, endpoint := range exporterEndpoints(cfg) {
+			check := newPreflightCheck(id, PreflightCheckResolvable, endpoint, checkResolvable(ctx, endpoint))
+			check.Failover = targets[id]
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// PreflightFailures returns the failed checks that fail the preflight. The failed checks of the
+// targets of a failover connector are tolerated as long as the same check passed for one of its
+// targets, e.g. at least one of the endpoints of an otlp exporter with `endpoints` resolves.
+func PreflightFailures(checks []PreflightCheck) []PreflightCheck {
+	type failoverCheck struct{ failover, check string }
+	passed := make(map[failoverCheck]bool)
+	for _, c := range checks {
+		if c.Failover != "" && c.Passed() {
+			passed[failoverCheck{c.Failover, c.Check}] = true
+		}
+	}
+	var failures []PreflightCheck
+	for _, c := range checks {
+		if c.Passed() || (c.Failover != "" && passed[failoverCheck{c.Failover, c.Check}]) {
+			continue
+		}
+		failures = append(failures, c)
+	}
+	return failures
+}
+
+// failoverTargets returns the failover connector each exporter is a target of, e.g. the exporters
+// generated for the endpoints of an otlp exporter, see otlpFailoverConverter.
+func failoverTargets(conf *confmap.Conf) map[string]string {
+	connectors, _ := conf.Get("connectors").(map[string]any)
+	pipelines, _ := conf.Get("service::pipelines").(map[string]any)
+	targets := make(map[string]string)
+	for id, cfg := range connectors {
+		if connectorType, _, _ := strings.Cut(id, "/"); connectorType != failoverConnectorType {
+			continue
+		}
+		cfgMap, _ := cfg.(map[string]any)
+		levels, _ := cfgMap["priority_levels"].([]any)
+		for _, level := range levels {
+			levelPipelines, _ := level.([]any)
+			for _, p := range levelPipelines {
+				pipelineID, _ := p.(string)
+				pipeline, _ := pipelines[pipelineID].(map[string]any)
+				exporters, _ := pipeline["exporters"].([]any)
+				for _, e := range exporters {
+					if exporterID, ok := e.(string); ok {
+						targets[exporterID] = id
+					}
+				}
+			}
+		}
+	}
+	return targets
+}
+
+func newPreflightCheck(component, check, target string, err error) PreflightCheck {
+	c := PreflightCheck{Component: component, Check: check, Target: target}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	return c
+}
+
+// exporterEndpoints returns the `endpoint` and `endpoints` settings of an exporter.
+func exporterEndpoints(cfg map[string]any) []string {
+	var endpoints []string
+	if endpoint, ok := cfg["endpoint"].(string); ok && endpoint != "" {
+		endpoints = append(endpoints, endpoint)
+	}
+	if list, ok := cfg["endpoints"].([]any); ok {
+		for _, e := range list {
+			if endpoint, ok := e.(string); ok && endpoint != "" {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+	return endpoints
+}
+
+// checkWritable checks that path can be written, an existing file is opened for appending without
+// writing to it. A missing path is writable when its closest existing parent directory is, the file
+// exporter parent directories are created before the collector starts. The directory is checked by
+// creating and removing a temporary file in it.
+func checkWritable(path string) error {
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return fmt.Errorf("%s is not writable: %w", path, err)
+		}
+		return f.Close()
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("parent %s of path %s is not a directory", dir, path)
+			}
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to stat parent %s of path %s: %w", dir, path, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("no parent directory of path %s exists", path)
+		}
+		dir = parent
+	}
+	// creating a file is the only portable way to check the permissions of the directory
+	f, err := os.CreateTemp(dir, ".otel-preflight-*")
+	if err != nil {
+		return fmt.Errorf("directory %s of path %s is not writable: %w", dir, path, err)
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkResolvable checks that the host of endpoint resolves, endpoint is either an URL or a
+// host:port address.
+func checkResolvable(ctx context.Context, endpoint string) error {
+	host := endpoint
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid endpoint: %w", err)
+		}
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(endpoint); err == nil {
+		host = h
+	}
+	if host == "" {
+		return errors.New("endpoint has no host")
+	}
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, preflightResolveTimeout)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestPreflight(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.json")
+	require.NoError(t, os.WriteFile(existing, nil, 0o600))
+	notADir := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(notADir, nil, 0o600))
+
+	conf := confmap.NewFromStringMap(map[string]any{
+		"exporters": map[string]any{
+			"file":          map[string]any{"path": existing},
+			"file/missing":  map[string]any{"path": filepath.Join(dir, "a", "b", "data.json")},
+			"file/notadir":  map[string]any{"path": filepath.Join(notADir, "data.json")},
+			"file/isadir":   map[string]any{"path": dir},
+			"otlp":          map[string]any{"endpoint": "127.0.0.1:4317"},
+			"otlphttp":      map[string]any{"endpoint": "http://localhost:4318"},
+			"elasticsearch": map[string]any{"endpoints": []any{"https://[::1]:9200", "http://doesnotexist.invalid:9200"}},
+			"debug":         map[string]any{"verbosity": "detailed"},
+		},
+	})
+	checks := Preflight(context.Background(), conf)
+
+	type result struct {
+		component, check, target string
+		passed                   bool
+	}
+	var results []result
+	for _, c := range checks {
+		results = append(results, result{c.Component, c.Check, c.Target, c.Passed()})
+	}
+	assert.Equal(t, []result{
+		{"elasticsearch", PreflightCheckResolvable, "https://[::1]:9200", true},
+		{"elasticsearch", PreflightCheckResolvable, "http://doesnotexist.invalid:9200", false},
+		{"file", PreflightCheckWritable, existing, true},
+		{"file/isadir", PreflightCheckWritable, dir, false},
+		{"file/missing", PreflightCheckWritable, filepath.Join(dir, "a", "b", "data.json"), true},
+		{"file/notadir", PreflightCheckWritable, filepath.Join(notADir, "data.json"), false},
+		{"otlp", PreflightCheckResolvable, "127.0.0.1:4317", true},
+		{"otlphttp", PreflightCheckResolvable, "http://localhost:4318", true},
+	}, results)
+
+	// checks don't leave files behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.NoDirExists(t, filepath.Join(dir, "a"))
+
+	assert.Empty(t, Preflight(context.Background(), confmap.New()))
+}
+
+func TestPreflightFailover(t *testing.T) {
+	conf := confmap.NewFromStringMap(map[string]any{
+		"exporters": map[string]any{
+			"otlp/elastic": map[string]any{"endpoints": []any{"doesnotexist.invalid:4317", "127.0.0.1:4317"}},
+			"otlp/down":    map[string]any{"endpoints": []any{"doesnotexist.invalid:4317", "alsodoesnotexist.invalid:4317"}},
+		},
+		"service": map[string]any{
+			"pipelines": map[string]any{
+				"traces": map[string]any{"exporters": []any{"otlp/elastic", "otlp/down"}},
+			},
+		},
+	})
+	require.NoError(t, otlpFailoverConverter{}.Convert(context.Background(), conf))
+	checks := Preflight(context.Background(), conf)
+	require.Len(t, checks, 4)
+	for _, c := range checks {
+		assert.NotEmpty(t, c.Failover, "%s is a failover target", c.Component)
+	}
+
+	// one endpoint of otlp/elastic resolves, the failure of the other one is tolerated
+	failures := PreflightFailures(checks)
+	require.Len(t, failures, 2)
+	for _, c := range failures {
+		assert.Equal(t, "failover/otlp_down_traces", c.Failover)
+	}
+}
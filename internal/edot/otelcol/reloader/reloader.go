@@ -0,0 +1,144 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package reloader implements the configuration reload shared by the config providers: the source of
+// a retrieved configuration is polled and, when its content changes, the new content is validated before
+// the collector is notified, so an invalid change never stops the running pipelines.
+package reloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+)
+
+// ValidateFunc validates the configuration where the content retrieved from uri is replaced with content.
+type ValidateFunc func(ctx context.Context, uri string, content []byte) error
+
+// FetchFunc returns the current content of a configuration source. Errors are expected to be logged by
+// the function, the source is polled again on the next interval.
+type FetchFunc func(ctx context.Context) ([]byte, error)
+
+// Messages are the messages logged by a Reloader.
+type Messages struct {
+	// Reload is logged when a changed configuration passed validation and the collector is reloading it.
+	Reload string
+	// Rejected is logged when a changed configuration failed validation, the collector keeps running
+	// with the previous configuration.
+	Rejected string
+}
+
+// Reloader watches the configuration sources of a provider.
+//
+// The content validated before notifying the collector is kept until the provider retrieves the URI again,
+// see Validated, so that the collector reloads the exact content that was validated instead of fetching it
+// again.
+type Reloader struct {
+	logger   *zap.Logger
+	validate ValidateFunc
+	messages Messages
+
+	validatedMx sync.Mutex
+	validated   map[string][]byte
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a Reloader validating the changes with validate, a nil validate accepts every change.
+func New(logger *zap.Logger, validate ValidateFunc, messages Messages) *Reloader {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Reloader{
+		logger:    logger,
+		validate:  validate,
+		messages:  messages,
+		validated: make(map[string][]byte),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Watch polls fetch every interval until the content differs from the content retrieved from uri and the
+// new content is valid, then notifies watcher. The collector retrieves the configuration again on reload,
+// which starts a new watch, so a watch notifies at most once. The returned option stops the watch when the
+// Retrieved is closed. field identifies the source in the logs.
+func (r *Reloader) Watch(uri string, content []byte, interval time.Duration, fetch FetchFunc, watcher confmap.WatcherFunc, field zap.Field) confmap.RetrievedOption {
+	watchCtx, cancel := context.WithCancel(r.ctx)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.watch(watchCtx, uri, sha256.Sum256(content), interval, fetch, watcher, field)
+	}()
+	return confmap.WithRetrievedClose(func(context.Context) error {
+		cancel()
+		return nil
+	})
+}
+
+func (r *Reloader) watch(ctx context.Context, uri string, digest [sha256.Size]byte, interval time.Duration, fetch FetchFunc, watcher confmap.WatcherFunc, field zap.Field) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		content, err := fetch(ctx)
+		if err != nil {
+			continue
+		}
+		newDigest := sha256.Sum256(content)
+		if newDigest == digest {
+			continue
+		}
+		digest = newDigest
+
+		if r.validate != nil {
+			if err := r.validate(ctx, uri, content); err != nil {
+				r.logger.Error(r.messages.Rejected, field, zap.Error(err))
+				continue
+			}
+		}
+
+		r.validatedMx.Lock()
+		r.validated[uri] = content
+		r.validatedMx.Unlock()
+
+		r.logger.Info(r.messages.Reload, field)
+		watcher(&confmap.ChangeEvent{})
+		return
+	}
+}
+
+// Validated returns the content of uri that was validated before notifying the collector, if any. The
+// content is only returned once, the next retrieval of uri reads the source again.
+func (r *Reloader) Validated(uri string) ([]byte, bool) {
+	r.validatedMx.Lock()
+	defer r.validatedMx.Unlock()
+	content, found := r.validated[uri]
+	delete(r.validated, uri)
+	return content, found
+}
+
+// Shutdown stops all the watches and waits for them to finish, or for ctx to be done.
+func (r *Reloader) Shutdown(ctx context.Context) {
+	r.cancel()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
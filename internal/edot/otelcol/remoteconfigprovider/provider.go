@@ -0,0 +1,259 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package remoteconfigprovider
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+
+	"github.com/elastic/elastic-agent/internal/edot/otelcol/reloader"
+)
+
+const (
+	// FallbackLogMessage is logged when the configuration cannot be fetched and the last
+	// configuration fetched successfully is used instead.
+	FallbackLogMessage = "Failed to fetch remote configuration, using the last good configuration"
+
+	// ReloadLogMessage is the reloader.Messages Reload message of changed remote configurations.
+	ReloadLogMessage = "Remote configuration changed, reloading collector configuration"
+
+	// RejectedLogMessage is the reloader.Messages Rejected message of changed remote configurations.
+	RejectedLogMessage = "Remote configuration changed but the new configuration is invalid, keeping the running configuration"
+
+	defaultTimeout = 30 * time.Second
+)
+
+// build time guard that provider implements confmap.Provider
+var _ confmap.Provider = (*provider)(nil)
+
+// ValidateFunc validates the configuration with the changed content of a remote configuration.
+type ValidateFunc = reloader.ValidateFunc
+
+// Settings configures how the remote configuration is fetched.
+type Settings struct {
+	// RefreshInterval is how often the configuration is fetched again to detect changes, zero
+	// disables the refresh.
+	RefreshInterval time.Duration
+	// BearerToken is sent in the Authorization header when set.
+	BearerToken string
+	// CAFile is a PEM file with the certificate authorities trusted in addition to the system
+	// ones to verify https servers. The server certificate is always verified.
+	CAFile string
+}
+
+// provider is a drop-in replacement of the collector's http and https providers that supports
+// bearer token authentication, custom certificate authorities and refreshing the configuration
+// with a reloader.Reloader.
+//
+// The last configuration fetched successfully from each URI is kept, so a configuration server
+// being unavailable while the collector reloads doesn't stop the running pipelines.
+//
+// Per the confmap.Provider contract, Retrieve and Shutdown are never called
+// concurrently with themselves or each other.
+type provider struct {
+	scheme   string
+	settings Settings
+	logger   *zap.Logger
+	reloader *reloader.Reloader
+
+	// clientMx guards the client, created by the first fetch, which may run in a refresh
+	clientMx  sync.Mutex
+	client    *http.Client
+	clientErr error
+
+	lastGoodMx sync.Mutex
+	lastGood   map[string][]byte
+}
+
+// NewFactory returns a confmap.ProviderFactory for scheme, either "http" or "https". When the
+// configuration is refreshed, changes are validated with validate before notifying the collector.
+func NewFactory(scheme string, settings Settings, validate ValidateFunc) confmap.ProviderFactory {
+	return confmap.NewProviderFactory(func(ps confmap.ProviderSettings) confmap.Provider {
+		return newProvider(ps, scheme, settings, validate)
+	})
+}
+
+func newProvider(ps confmap.ProviderSettings, scheme string, settings Settings, validate ValidateFunc) *provider {
+	logger := ps.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &provider{
+		scheme:   scheme,
+		settings: settings,
+		logger:   logger,
+		reloader: reloader.New(logger, validate, reloader.Messages{
+			Reload:   ReloadLogMessage,
+			Rejected: RejectedLogMessage,
+		}),
+		lastGood: make(map[string][]byte),
+	}
+}
+
+// Retrieve fetches the configuration at uri, falling back to the last configuration fetched from
+// uri when it fails. After a reload the content that was validated is retrieved instead of fetching
+// uri again. When watcher is set and refresh is enabled, uri is fetched again every RefreshInterval
+// until the returned Retrieved is closed.
+func (p *provider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, p.scheme+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, p.scheme)
+	}
+
+	content, found := p.reloader.Validated(uri)
+	if !found {
+		var err error
+		content, err = p.fetch(ctx, uri)
+		if err != nil {
+			lastGood, found := p.lastGoodContent(uri)
+			if !found {
+				return nil, err
+			}
+			p.logger.Warn(FallbackLogMessage, zap.String("uri", redactURI(uri)), zap.Error(err))
+			content = lastGood
+		}
+	}
+	p.lastGoodMx.Lock()
+	p.lastGood[uri] = content
+	p.lastGoodMx.Unlock()
+
+	var opts []confmap.RetrievedOption
+	if watcher != nil && p.settings.RefreshInterval > 0 {
+		opts = append(opts, p.reloader.Watch(uri, content, p.settings.RefreshInterval, func(ctx context.Context) ([]byte, error) {
+			content, err := p.fetch(ctx, uri)
+			if err != nil {
+				p.logger.Warn(FallbackLogMessage, zap.String("uri", redactURI(uri)), zap.Error(err))
+			}
+			return content, err
+		}, watcher, zap.String("uri", redactURI(uri))))
+	}
+
+	return confmap.NewRetrievedFromYAML(content, opts...)
+}
+
+func (p *provider) lastGoodContent(uri string) ([]byte, bool) {
+	p.lastGoodMx.Lock()
+	defer p.lastGoodMx.Unlock()
+	content, found := p.lastGood[uri]
+	return content, found
+}
+
+func (p *provider) fetch(ctx context.Context, uri string) ([]byte, error) {
+	client, err := p.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request for %s: %w", redactURI(uri), err)
+	}
+	if p.settings.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.settings.BearerToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// the client errors hold the full URL, query included
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("unable to fetch configuration from %s: %w", redactURI(uri), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch configuration from %s: unexpected status code %d", redactURI(uri), resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read configuration from %s: %w", redactURI(uri), err)
+	}
+	return content, nil
+}
+
+// httpClient returns the client used to fetch the configuration, created on first use.
+func (p *provider) httpClient() (*http.Client, error) {
+	p.clientMx.Lock()
+	defer p.clientMx.Unlock()
+	if p.client == nil && p.clientErr == nil {
+		p.client, p.clientErr = newHTTPClient(p.scheme, p.settings.CAFile)
+	}
+	return p.client, p.clientErr
+}
+
+func newHTTPClient(scheme string, caFile string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck // the default transport is always an *http.Transport
+	if scheme == "https" && caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read certificate authorities %s: %w", caFile, err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate authority found in %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// redactURI masks the user info and the query values of uri, e.g. an access token, so credentials
+// are not logged.
+func redactURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		// nothing of an unparsable uri is known to be safe to log
+		scheme, _, _ := strings.Cut(uri, ":")
+		return scheme + "://xxxxx"
+	}
+	if u.User != nil {
+		u.User = url.User("xxxxx")
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query.Set(key, "xxxxx")
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+func (p *provider) Scheme() string {
+	return p.scheme
+}
+
+// Shutdown stops all the refreshes and waits for them to finish.
+func (p *provider) Shutdown(ctx context.Context) error {
+	p.reloader.Shutdown(ctx)
+	p.clientMx.Lock()
+	defer p.clientMx.Unlock()
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ package otelcol
 import (
 	"context"
 	"os"
+	"slices"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/confmap"
@@ -20,15 +21,40 @@ import (
 	"go.opentelemetry.io/collector/otelcol"
 
 	"github.com/elastic/elastic-agent/internal/edot/otelcol/agentprovider"
+	"github.com/elastic/elastic-agent/internal/edot/otelcol/envallowlistprovider"
+	"github.com/elastic/elastic-agent/internal/edot/otelcol/remoteconfigprovider"
+	"github.com/elastic/elastic-agent/internal/edot/otelcol/watchfileprovider"
 )
 
 const buildDescription = "Elastic opentelemetry-collector distribution"
 
-func Run(ctx context.Context, stop chan bool, settings *otelcol.CollectorSettings) error {
+// RunOpt is an option of Run.
+type RunOpt func(o *runOptions)
+
+type runOptions struct {
+	health *HealthServer
+}
+
+// WithHealthServer reports the state of the collector to the liveness and readiness probes of health.
+func WithHealthServer(health *HealthServer) RunOpt {
+	return func(o *runOptions) {
+		o.health = health
+	}
+}
+
+func Run(ctx context.Context, stop chan bool, settings *otelcol.CollectorSettings, opts ...RunOpt) error {
+	var o runOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	svc, err := otelcol.NewCollector(*settings)
 	if err != nil {
 		return err
 	}
+	if o.health != nil {
+		o.health.watch(svc)
+	}
 
 	// cancel context on stop from event manager
 	cancelCtx, cancel := context.WithCancel(ctx)
@@ -45,6 +71,13 @@ type options struct {
 	resolverConfigProviders    []confmap.ProviderFactory
 	resolverConverterFactories []confmap.ConverterFactory
 	extensionFactories         []extension.Factory
+	watchConfigFiles           bool
+	createFileExporterDirs     bool
+	rotateFileExporters        bool
+	remoteConfig               *remoteconfigprovider.Settings
+	envAllowList               []string
+	retrievedURI               string
+	retrievedContent           []byte
 }
 
 type SettingOpt func(o *options)
@@ -67,6 +100,61 @@ func WithExtensionFactory(factory extension.Factory) SettingOpt {
 	}
 }
 
+// WithConfigFilesWatch reloads the collector configuration when one of the config files changes.
+// A changed configuration is validated first and rejected when invalid, keeping the running pipelines.
+func WithConfigFilesWatch() SettingOpt {
+	return func(o *options) {
+		o.watchConfigFiles = true
+	}
+}
+
+// WithFileExporterDirs creates the parent directory of every file exporter path when the configuration
+// is resolved to run the collector, the file exporter fails to start when it is missing. Directories are
+// never created when the configuration is only validated, including before a reload.
+func WithFileExporterDirs() SettingOpt {
+	return func(o *options) {
+		o.createFileExporterDirs = true
+	}
+}
+
+// WithFileExporterRotation rotates the files written by the file exporters once they reach 100MB, keeping
+// the last 5 rotated files, unless a file exporter configures its own `rotation` or appends to its file.
+// The rotated files are written next to the file exporter path, named after it with a timestamp, e.g.
+// `output-2025-01-02T15-04-05.000.json` for `output.json`.
+func WithFileExporterRotation() SettingOpt {
+	return func(o *options) {
+		o.rotateFileExporters = true
+	}
+}
+
+// WithRemoteConfig fetches the http and https config URIs with settings, instead of the collector's
+// http and https providers. The last configuration fetched successfully is used when the configuration
+// server is unavailable, and with a refresh interval a changed configuration is validated then reloaded.
+func WithRemoteConfig(settings remoteconfigprovider.Settings) SettingOpt {
+	return func(o *options) {
+		o.remoteConfig = &settings
+	}
+}
+
+// WithEnvAllowList restricts the environment variables expanded in the configuration, e.g. with
+// `${env:NAME}`, to the ones in allowList. An entry ending with "*" allows every variable starting
+// with the entry prefix. Expanding any other variable fails when validating the configuration.
+// A nil or empty allowList doesn't restrict the environment variables.
+func WithEnvAllowList(allowList []string) SettingOpt {
+	return func(o *options) {
+		o.envAllowList = allowList
+	}
+}
+
+// withRetrievedContent retrieves content for uri instead of using the provider of the uri scheme, so that
+// a changed configuration is validated with the content the provider already retrieved.
+func withRetrievedContent(uri string, content []byte) SettingOpt {
+	return func(o *options) {
+		o.retrievedURI = uri
+		o.retrievedContent = content
+	}
+}
+
 func NewSettings(version string, configPaths []string, opts ...SettingOpt) *otelcol.CollectorSettings {
 	buildInfo := component.BuildInfo{
 		Command:     os.Args[0],
@@ -79,17 +167,71 @@ func NewSettings(version string, configPaths []string, opts ...SettingOpt) *otel
 		opt(&o)
 	}
 
+	// changed configurations are validated with the same providers, converters, remote config settings,
+	// environment allow-list and file exporter rotation, without watching
+	var validateOpts []SettingOpt
+	for _, provider := range o.resolverConfigProviders {
+		validateOpts = append(validateOpts, WithConfigProviderFactory(provider))
+	}
+	for _, converter := range o.resolverConverterFactories {
+		validateOpts = append(validateOpts, WithConfigConvertorFactory(converter))
+	}
+	if o.remoteConfig != nil {
+		validateSettings := *o.remoteConfig
+		validateSettings.RefreshInterval = 0
+		validateOpts = append(validateOpts, WithRemoteConfig(validateSettings))
+	}
+	if len(o.envAllowList) > 0 {
+		validateOpts = append(validateOpts, WithEnvAllowList(o.envAllowList))
+	}
+	if o.rotateFileExporters {
+		validateOpts = append(validateOpts, WithFileExporterRotation())
+	}
+	validate := func(ctx context.Context, uri string, content []byte) error {
+		return Validate(ctx, configPaths, append(slices.Clone(validateOpts), withRetrievedContent(uri, content))...)
+	}
+
+	fileProviderFactory := fileprovider.NewFactory()
+	if o.watchConfigFiles {
+		fileProviderFactory = watchfileprovider.NewFactory(validate)
+	}
+	envProviderFactory := envprovider.NewFactory()
+	if len(o.envAllowList) > 0 {
+		envProviderFactory = envallowlistprovider.NewFactory(o.envAllowList)
+	}
+	httpProviderFactory := httpprovider.NewFactory()
+	httpsProviderFactory := httpsprovider.NewFactory()
+	if o.remoteConfig != nil {
+		httpProviderFactory = remoteconfigprovider.NewFactory("http", *o.remoteConfig, validate)
+		httpsProviderFactory = remoteconfigprovider.NewFactory("https", *o.remoteConfig, validate)
+	}
 	providerFactories := []confmap.ProviderFactory{
-		fileprovider.NewFactory(),
-		envprovider.NewFactory(),
+		fileProviderFactory,
+		envProviderFactory,
 		yamlprovider.NewFactory(),
-		httpprovider.NewFactory(),
-		httpsprovider.NewFactory(),
+		httpProviderFactory,
+		httpsProviderFactory,
 		agentprovider.NewFactory(),
 	}
 	providerFactories = append(providerFactories, o.resolverConfigProviders...)
-	var converterFactories []confmap.ConverterFactory
+	if o.retrievedURI != "" {
+		for i, factory := range providerFactories {
+			providerFactories[i] = retrievedContentProviderFactory(factory, o.retrievedURI, o.retrievedContent)
+		}
+	}
+	// conflicting configs are reported for the content retrieved by the providers
+	conflictsRecorder := newConfigConflictsRecorder()
+	for i, factory := range providerFactories {
+		providerFactories[i] = conflictsRecorder.providerFactory(factory)
+	}
+	converterFactories := []confmap.ConverterFactory{newConfigConflictsConverterFactory(configPaths, conflictsRecorder)}
 	converterFactories = append(converterFactories, o.resolverConverterFactories...)
+	if o.createFileExporterDirs {
+		converterFactories = append(converterFactories, newFileExporterDirsConverterFactory())
+	}
+	if o.rotateFileExporters {
+		converterFactories = append(converterFactories, newFileExporterRotationConverterFactory())
+	}
 	configProviderSettings := otelcol.ConfigProviderSettings{
 		ResolverSettings: confmap.ResolverSettings{
 			URIs:               configPaths,
@@ -108,3 +250,28 @@ func NewSettings(version string, configPaths []string, opts ...SettingOpt) *otel
 		DisableGracefulShutdown: true,
 	}
 }
+
+// retrievedContentProvider returns content when retrieving uri, and delegates the other URIs to the
+// wrapped provider.
+type retrievedContentProvider struct {
+	confmap.Provider
+	uri     string
+	content []byte
+}
+
+func retrievedContentProviderFactory(factory confmap.ProviderFactory, uri string, content []byte) confmap.ProviderFactory {
+	return confmap.NewProviderFactory(func(settings confmap.ProviderSettings) confmap.Provider {
+		return &retrievedContentProvider{
+			Provider: factory.Create(settings),
+			uri:      uri,
+			content:  content,
+		}
+	})
+}
+
+func (p *retrievedContentProvider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if uri == p.uri {
+		return confmap.NewRetrievedFromYAML(p.content)
+	}
+	return p.Provider.Retrieve(ctx, uri, watcher)
+}
@@ -11,10 +11,13 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
 	"go.opentelemetry.io/collector/otelcol"
 )
 
@@ -54,3 +57,114 @@ func startCollector(ctx context.Context, t *testing.T, col *otelcol.Collector, e
 	}()
 	return wg
 }
+
+func TestWithRetrievedContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otel.yml")
+	require.NoError(t, os.WriteFile(path, []byte("receivers:\n  otlp: {}\n"), 0o600))
+	other := filepath.Join(t.TempDir(), "other.yml")
+	require.NoError(t, os.WriteFile(other, []byte("exporters:\n  debug: {}\n"), 0o600))
+
+	// the retrieved content replaces the file content, the other files are still read
+	conf, err := ResolveConfig(t.Context(), []string{"file:" + path, "file:" + other},
+		withRetrievedContent("file:"+path, []byte("receivers:\n  filelog: {}\n")))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"receivers": map[string]any{"filelog": map[string]any{}},
+		"exporters": map[string]any{"debug": map[string]any{}},
+	}, conf.ToStringMap())
+}
+
+// countingConverter counts the configurations it converted.
+type countingConverter struct {
+	conversions *atomic.Int32
+}
+
+func (c countingConverter) Convert(context.Context, *confmap.Conf) error {
+	c.conversions.Add(1)
+	return nil
+}
+
+func TestNewSettingsReloadValidationUsesConverters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otel.yml")
+	require.NoError(t, os.WriteFile(path, []byte("receivers:\n  otlp: {}\n"), 0o600))
+
+	var conversions atomic.Int32
+	converter := confmap.NewConverterFactory(func(confmap.ConverterSettings) confmap.Converter {
+		return countingConverter{conversions: &conversions}
+	})
+	settings := NewSettings("test", []string{"file:" + path}, WithConfigFilesWatch(), WithConfigConvertorFactory(converter))
+
+	// the first provider is the file provider watching the config files
+	provider := settings.ConfigProviderSettings.ResolverSettings.ProviderFactories[0].Create(confmap.ProviderSettings{})
+	defer func() {
+		assert.NoError(t, provider.Shutdown(context.Background()))
+	}()
+	ret, err := provider.Retrieve(t.Context(), "file:"+path, func(*confmap.ChangeEvent) {})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, ret.Close(context.Background()))
+	}()
+
+	require.NoError(t, os.WriteFile(path, []byte("receivers:\n  filelog: {}\n"), 0o600))
+	assert.Eventually(t, func() bool { return conversions.Load() > 0 }, 10*time.Second, 100*time.Millisecond,
+		"the changed configuration must be validated with the converters")
+}
+
+func TestWithFileExporterDirs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	configPaths := []string{"yaml:exporters::file::path: " + filepath.Join(dir, "data.json")}
+
+	// resolving the configuration to validate or print it doesn't create directories
+	_, err := ResolveConfig(t.Context(), configPaths)
+	require.NoError(t, err)
+	assert.NoDirExists(t, dir)
+
+	// resolving the configuration to run the collector does
+	settings := NewSettings("test", configPaths, WithFileExporterDirs())
+	resolver, err := confmap.NewResolver(settings.ConfigProviderSettings.ResolverSettings)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, resolver.Shutdown(context.Background()))
+	}()
+	_, err = resolver.Resolve(t.Context())
+	require.NoError(t, err)
+	assert.DirExists(t, dir)
+}
+
+func TestRunWithHealthServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otel.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`receivers:
+  nop:
+exporters:
+  nop:
+service:
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    logs:
+      receivers: [nop]
+      exporters: [nop]
+`), 0o600))
+
+	health, err := NewHealthServer("127.0.0.1:0")
+	require.NoError(t, err)
+	health.Start()
+	defer func() {
+		assert.NoError(t, health.Stop(context.Background()))
+	}()
+
+	stop := make(chan bool)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Run(t.Context(), stop, NewSettings("test", []string{"file:" + path}), WithHealthServer(health))
+	}()
+
+	assert.Eventually(t, func() bool {
+		return health.state() == otelcol.StateRunning
+	}, 30*time.Second, 100*time.Millisecond, "the collector should be ready once its pipelines are started")
+
+	close(stop)
+	require.NoError(t, <-errCh)
+	assert.Equal(t, otelcol.StateClosed, health.state())
+}
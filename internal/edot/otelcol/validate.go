@@ -6,17 +6,151 @@ package otelcol
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
+	"go.opentelemetry.io/collector/confmap"
 	"go.opentelemetry.io/collector/otelcol"
 
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 )
 
-func Validate(ctx context.Context, configPaths []string) error {
-	settings := NewSettings(release.Version(), configPaths)
+// Validate validates the configuration at configPaths without running the collector. opts
+// configure how the configuration is retrieved, e.g. WithRemoteConfig. The configuration is
+// resolved once, see ResolveConfig, and then validated with ValidateResolved.
+func Validate(ctx context.Context, configPaths []string, opts ...SettingOpt) error {
+	conf, err := ResolveConfig(ctx, configPaths, opts...)
+	if err != nil {
+		return err
+	}
+	return ValidateResolved(ctx, conf, opts...)
+}
+
+// ValidateResolved validates the configuration conf returned by ResolveConfig without running the
+// collector and without retrieving the configuration again, so a remote configuration that changed
+// since it was fetched doesn't make the validated and the resolved configurations differ. opts are
+// the ones conf was resolved with.
+//
+// Besides the validation of the collector, the endpoints of the otlp and otlphttp exporters are
+// checked to match their protocol, see CheckOTLPEndpoints. A *LoadError is returned when the
+// configuration cannot be loaded, e.g. when it has a component of an unknown type, before it is
+// validated.
+func ValidateResolved(ctx context.Context, conf *confmap.Conf, opts ...SettingOpt) error {
+	settings := NewSettings(release.Version(), nil, opts...)
+	settings.ConfigProviderSettings = otelcol.ConfigProviderSettings{
+		ResolverSettings: confmap.ResolverSettings{
+			URIs:              []string{resolvedConfigURI},
+			ProviderFactories: []confmap.ProviderFactory{newResolvedConfigProviderFactory(conf)},
+		},
+	}
+	if err := loadConfig(ctx, settings); err != nil {
+		return &LoadError{Err: err}
+	}
 	col, err := otelcol.NewCollector(*settings)
 	if err != nil {
 		return err
 	}
-	return col.DryRun(ctx)
+	if err := col.DryRun(ctx); err != nil {
+		return err
+	}
+	return CheckOTLPEndpoints(conf)
+}
+
+// loadConfig resolves the configuration of settings and unmarshals it into the configurations of the
+// components, the steps of the collector that come before the validation.
+func loadConfig(ctx context.Context, settings *otelcol.CollectorSettings) (err error) {
+	factories, err := settings.Factories()
+	if err != nil {
+		return fmt.Errorf("failed to initialize factories: %w", err)
+	}
+	provider, err := otelcol.NewConfigProvider(settings.ConfigProviderSettings)
+	if err != nil {
+		return fmt.Errorf("failed to create config provider: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, provider.Shutdown(ctx))
+	}()
+
+	if _, err := provider.Get(ctx, factories); err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+	return nil
+}
+
+// ResolveConfig merges the configuration at configPaths and expands all the variables in it, returning
+// the effective configuration the collector would run with. A *LoadError is returned when the
+// configuration cannot be resolved.
+func ResolveConfig(ctx context.Context, configPaths []string, opts ...SettingOpt) (_ *confmap.Conf, err error) {
+	settings := NewSettings(release.Version(), configPaths, opts...)
+	resolver, err := confmap.NewResolver(settings.ConfigProviderSettings.ResolverSettings)
+	if err != nil {
+		return nil, &LoadError{Err: fmt.Errorf("failed to create config resolver: %w", err)}
+	}
+	defer func() {
+		err = errors.Join(err, resolver.Shutdown(ctx))
+	}()
+
+	conf, err := resolver.Resolve(ctx)
+	if err != nil {
+		return nil, &LoadError{Err: fmt.Errorf("failed to resolve config: %w", err)}
+	}
+	return conf, nil
+}
+
+const (
+	resolvedConfigScheme = "resolved"
+	resolvedConfigURI    = resolvedConfigScheme + ":config"
+)
+
+// resolvedConfigProvider retrieves a configuration that is already resolved, for the collector to
+// load it without retrieving it from its config URIs again.
+type resolvedConfigProvider struct {
+	conf map[string]any
+}
+
+func newResolvedConfigProviderFactory(conf *confmap.Conf) confmap.ProviderFactory {
+	// the resolver expands the variables of the retrieved configuration again, the `$` left by the
+	// first resolution, e.g. from an escaped `$${...}`, are escaped to be kept as is
+	escaped, _ := escapeDollars(conf.ToStringMap()).(map[string]any)
+	return confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+		return &resolvedConfigProvider{conf: escaped}
+	})
+}
+
+func (p *resolvedConfigProvider) Retrieve(_ context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if uri != resolvedConfigURI {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, resolvedConfigScheme)
+	}
+	return confmap.NewRetrieved(p.conf)
+}
+
+func (*resolvedConfigProvider) Scheme() string {
+	return resolvedConfigScheme
+}
+
+func (*resolvedConfigProvider) Shutdown(context.Context) error {
+	return nil
+}
+
+// escapeDollars returns a copy of the configuration value v with the `$` of its strings escaped as `$$`.
+func escapeDollars(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		escaped := make(map[string]any, len(v))
+		for key, value := range v {
+			escaped[key] = escapeDollars(value)
+		}
+		return escaped
+	case []any:
+		escaped := make([]any, len(v))
+		for i, value := range v {
+			escaped[i] = escapeDollars(value)
+		}
+		return escaped
+	case string:
+		return strings.ReplaceAll(v, "$", "$$")
+	default:
+		return v
+	}
 }
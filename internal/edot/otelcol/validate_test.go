@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/elastic/elastic-agent/internal/edot/otelcol/remoteconfigprovider"
+)
+
+func TestValidateFetchesRemoteConfigOnce(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write([]byte(`receivers:
+  nop:
+exporters:
+  nop:
+service:
+  pipelines:
+    logs:
+      receivers: [nop]
+      exporters: [nop]
+`))
+	}))
+	t.Cleanup(srv.Close)
+
+	require.NoError(t, Validate(t.Context(), []string{srv.URL}, WithRemoteConfig(remoteconfigprovider.Settings{})))
+	assert.EqualValues(t, 1, fetches.Load())
+}
+
+func TestResolvedConfigProvider(t *testing.T) {
+	// the values left with a `$` by the first resolution are not expanded again
+	raw := map[string]any{
+		"exporters": map[string]any{
+			"debug": map[string]any{
+				"headers":  map[string]any{"x-token": "${env:NOT_EXPANDED}"},
+				"prefixes": []any{"$1", "a$$b"},
+				"timeout":  5,
+			},
+		},
+	}
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs:              []string{resolvedConfigURI},
+		ProviderFactories: []confmap.ProviderFactory{newResolvedConfigProviderFactory(confmap.NewFromStringMap(raw))},
+	})
+	require.NoError(t, err)
+	conf, err := resolver.Resolve(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, raw, conf.ToStringMap())
+	require.NoError(t, resolver.Shutdown(t.Context()))
+}
@@ -0,0 +1,99 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ValidationErrorKindConflict is a key set with different types by several config URIs, see ConfigConflictError.
+	ValidationErrorKindConflict = "conflict"
+	// ValidationErrorKindLoad is a configuration that cannot be resolved or unmarshalled, e.g. an
+	// unknown component type or a missing config file.
+	ValidationErrorKindLoad = "load"
+	// ValidationErrorKindInvalid is a configuration that is loaded but rejected by the validation
+	// of a component or of the service, e.g. a pipeline referencing a component that is not configured.
+	ValidationErrorKindInvalid = "invalid"
+)
+
+// LoadError is returned by Validate and ResolveConfig when the configuration cannot be loaded: the
+// component factories cannot be initialized, or the configuration cannot be resolved or unmarshalled
+// into the configurations of the components.
+type LoadError struct {
+	Err error
+}
+
+func (e *LoadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// configPathRegexp matches the config path prefixed to the validation errors by the collector,
+// e.g. "service::pipelines::logs: ".
+var configPathRegexp = regexp.MustCompile(`^([A-Za-z0-9_./-]+(?:::[A-Za-z0-9_./-]+)*): `)
+
+// ValidationError is a single error found while validating a configuration with Validate.
+type ValidationError struct {
+	// Path is the path of the invalid configuration key, e.g. "service::pipelines::logs", empty
+	// when the error cannot be attributed to a key.
+	Path string `json:"path,omitempty"`
+	// Kind is one of the ValidationErrorKind constants.
+	Kind string `json:"kind"`
+	// Message is the error message, without the path.
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors splits an error returned by Validate into its individual errors, so they can
+// be reported without relying on the collector error messages.
+func ValidationErrors(err error) []ValidationError {
+	if err == nil {
+		return nil
+	}
+
+	var conflictErr *ConfigConflictError
+	if errors.As(err, &conflictErr) {
+		return []ValidationError{{
+			Path:    conflictErr.Key,
+			Kind:    ValidationErrorKindConflict,
+			Message: conflictErr.Error(),
+		}}
+	}
+
+	var loadErr *LoadError
+	if errors.As(err, &loadErr) {
+		return []ValidationError{{Kind: ValidationErrorKindLoad, Message: loadErr.Error()}}
+	}
+
+	// the collector joins the errors of every invalid component
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok { //nolint:errorlint // only the joined errors are split
+		errs = joined.Unwrap()
+	} else {
+		errs = []error{err}
+	}
+	validationErrs := make([]ValidationError, 0, len(errs))
+	for _, e := range errs {
+		validationErr := ValidationError{Kind: ValidationErrorKindInvalid, Message: e.Error()}
+		if match := configPathRegexp.FindStringSubmatch(validationErr.Message); match != nil {
+			validationErr.Path = match[1]
+			validationErr.Message = strings.TrimPrefix(validationErr.Message, match[0])
+		}
+		validationErrs = append(validationErrs, validationErr)
+	}
+	return validationErrs
+}
@@ -0,0 +1,130 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package otelcol
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected []ValidationError
+	}{
+		{
+			name: "no error",
+		},
+		{
+			name: "conflict",
+			err:  fmt.Errorf("wrapped: %w", &ConfigConflictError{Key: "receivers::otlp", First: "a.yml", FirstIsMap: true, Second: "b.yml"}),
+			expected: []ValidationError{{
+				Path:    "receivers::otlp",
+				Kind:    ValidationErrorKindConflict,
+				Message: `config key "receivers::otlp" in a.yml is a map, but in b.yml is not a map`,
+			}},
+		},
+		{
+			name: "load",
+			err:  fmt.Errorf("wrapped: %w", &LoadError{Err: errors.New(`failed to get config: cannot unmarshal the configuration: 'receivers' unknown type: "foo"`)}),
+			expected: []ValidationError{{
+				Kind:    ValidationErrorKindLoad,
+				Message: `failed to get config: cannot unmarshal the configuration: 'receivers' unknown type: "foo"`,
+			}},
+		},
+		{
+			name: "invalid",
+			err: errors.Join(
+				fmt.Errorf("service::pipelines::logs: %w", errors.New(`references processor "nonexistingprocessor" which is not configured`)),
+				fmt.Errorf("exporters::otlp/elastic: %w", errors.New("must specify an endpoint")),
+				errors.New("service must have at least one pipeline"),
+			),
+			expected: []ValidationError{
+				{
+					Path:    "service::pipelines::logs",
+					Kind:    ValidationErrorKindInvalid,
+					Message: `references processor "nonexistingprocessor" which is not configured`,
+				},
+				{
+					Path:    "exporters::otlp/elastic",
+					Kind:    ValidationErrorKindInvalid,
+					Message: "must specify an endpoint",
+				},
+				{
+					Kind:    ValidationErrorKindInvalid,
+					Message: "service must have at least one pipeline",
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ValidationErrors(tc.err))
+		})
+	}
+}
+
+func TestValidationErrorsCollector(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "otel.yml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`receivers:
+  nop:
+exporters:
+  nop:
+service:
+  pipelines:
+    logs:
+      receivers: [nop]
+      exporters: [nop]
+`), 0o600))
+	require.NoError(t, Validate(t.Context(), []string{cfgPath}))
+
+	tests := []struct {
+		name     string
+		uris     []string
+		kind     string
+		path     string
+		contains string
+	}{
+		{
+			name:     "missing file",
+			uris:     []string{filepath.Join(dir, "missing.yml")},
+			kind:     ValidationErrorKindLoad,
+			contains: "missing.yml",
+		},
+		{
+			name:     "unknown component type",
+			uris:     []string{cfgPath, "yaml:receivers::nonexistingreceiver: {}"},
+			kind:     ValidationErrorKindLoad,
+			contains: `"nonexistingreceiver"`,
+		},
+		{
+			name:     "pipeline referencing an unconfigured component",
+			uris:     []string{cfgPath, "yaml:service::pipelines::logs::processors: [nonexistingprocessor]"},
+			kind:     ValidationErrorKindInvalid,
+			path:     "service::pipelines::logs",
+			contains: `"nonexistingprocessor"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(t.Context(), tc.uris)
+			require.Error(t, err)
+			errs := ValidationErrors(err)
+			require.Len(t, errs, 1, "errors: %v", errs)
+			assert.Equal(t, tc.kind, errs[0].Kind)
+			assert.Equal(t, tc.path, errs[0].Path)
+			assert.Contains(t, errs[0].Message, tc.contains)
+		})
+	}
+}
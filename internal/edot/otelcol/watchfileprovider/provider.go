@@ -0,0 +1,125 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package watchfileprovider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+
+	"github.com/elastic/elastic-agent/internal/edot/otelcol/reloader"
+)
+
+const (
+	schemeName = "file"
+
+	// ReloadLogMessage is the reloader.Messages Reload message of changed config files, logged when
+	// a changed config file passed validation and the collector is reloading its configuration.
+	ReloadLogMessage = "Config file changed, reloading collector configuration"
+
+	// RejectedLogMessage is the reloader.Messages Rejected message of changed config files, logged
+	// when a changed config file failed validation and the collector keeps running with the previous
+	// configuration.
+	//
+	// Both messages are matched by Fixture.UpdateOtelConfig of pkg/testing, which cannot import this
+	// module, keep them in sync.
+	RejectedLogMessage = "Config file changed but the new configuration is invalid, keeping the running configuration"
+
+	defaultPollInterval = 2 * time.Second
+)
+
+// build time guard that provider implements confmap.Provider
+var _ confmap.Provider = (*provider)(nil)
+
+// ValidateFunc validates the configuration with the changed content of a config file.
+type ValidateFunc = reloader.ValidateFunc
+
+// provider is a drop-in replacement of the collector's file provider that watches the retrieved
+// files for changes and reloads them with a reloader.Reloader.
+//
+// Files are polled instead of relying on file system events so that editors replacing the file
+// and atomic renames are picked up the same way as in-place writes. Every poll reads the file
+// through its symlinks and compares the content, so the `..data` symlink swap of a Kubernetes
+// ConfigMap volume is picked up too, whatever the inode or modification time of the new file.
+//
+// Per the confmap.Provider contract, Retrieve and Shutdown are never called
+// concurrently with themselves or each other.
+type provider struct {
+	logger       *zap.Logger
+	reloader     *reloader.Reloader
+	pollInterval time.Duration
+}
+
+// NewFactory returns a confmap.ProviderFactory for the "file" scheme that watches the
+// retrieved files and validates changes with validate before notifying the collector.
+func NewFactory(validate ValidateFunc) confmap.ProviderFactory {
+	return confmap.NewProviderFactory(func(settings confmap.ProviderSettings) confmap.Provider {
+		return newProvider(settings, validate, defaultPollInterval)
+	})
+}
+
+func newProvider(settings confmap.ProviderSettings, validate ValidateFunc, pollInterval time.Duration) *provider {
+	logger := settings.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &provider{
+		logger: logger,
+		reloader: reloader.New(logger, validate, reloader.Messages{
+			Reload:   ReloadLogMessage,
+			Rejected: RejectedLogMessage,
+		}),
+		pollInterval: pollInterval,
+	}
+}
+
+// Retrieve reads the file at uri and, when watcher is set, watches it for changes until
+// the returned Retrieved is closed. After a reload the content that was validated is
+// retrieved instead of reading the file again.
+func (p *provider) Retrieve(_ context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, schemeName+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+	filename := filepath.Clean(uri[len(schemeName)+1:])
+
+	content, found := p.reloader.Validated(uri)
+	if !found {
+		var err error
+		content, err = os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the file %v: %w", filename, err)
+		}
+	}
+
+	var opts []confmap.RetrievedOption
+	if watcher != nil {
+		opts = append(opts, p.reloader.Watch(uri, content, p.pollInterval, func(context.Context) ([]byte, error) {
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				// the file can be missing for a moment while it is being replaced
+				p.logger.Debug("Failed to read watched config file", zap.String("file", filename), zap.Error(err))
+			}
+			return content, err
+		}, watcher, zap.String("file", filename)))
+	}
+
+	return confmap.NewRetrievedFromYAML(content, opts...)
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+// Shutdown stops all the watches and waits for them to finish.
+func (p *provider) Shutdown(ctx context.Context) error {
+	p.reloader.Shutdown(ctx)
+	return nil
+}
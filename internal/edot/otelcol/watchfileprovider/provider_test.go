@@ -0,0 +1,197 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package watchfileprovider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const testPollInterval = 10 * time.Millisecond
+
+func newTestProvider(t *testing.T, validate ValidateFunc) *provider {
+	t.Helper()
+	p := newProvider(confmap.ProviderSettings{}, validate, testPollInterval)
+	t.Cleanup(func() {
+		assert.NoError(t, p.Shutdown(context.Background()))
+	})
+	return p
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestProviderRetrieve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otel.yml")
+	writeConfig(t, path, "receivers:\n  otlp: {}\n")
+
+	p := newTestProvider(t, nil)
+	assert.Equal(t, "file", p.Scheme())
+
+	ret, err := p.Retrieve(t.Context(), "file:"+path, nil)
+	require.NoError(t, err)
+	raw, err := ret.AsRaw()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"receivers": map[string]any{"otlp": map[string]any{}}}, raw)
+
+	_, err = p.Retrieve(t.Context(), "file:"+filepath.Join(t.TempDir(), "missing.yml"), nil)
+	assert.Error(t, err)
+
+	_, err = p.Retrieve(t.Context(), "env:OTEL_CONFIG", nil)
+	assert.Error(t, err)
+}
+
+func TestProviderRetrieveAnchors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otel.yml")
+	writeConfig(t, path, `exporters:
+  debug: &defaults
+    verbosity: detailed
+    sampling_initial: 10
+  debug/other:
+    <<: *defaults
+    sampling_initial: 20
+`)
+
+	p := newTestProvider(t, nil)
+	ret, err := p.Retrieve(t.Context(), "file:"+path, nil)
+	require.NoError(t, err)
+	raw, err := ret.AsRaw()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"exporters": map[string]any{
+		"debug":       map[string]any{"verbosity": "detailed", "sampling_initial": 10},
+		"debug/other": map[string]any{"verbosity": "detailed", "sampling_initial": 20},
+	}}, raw)
+}
+
+func TestProviderWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otel.yml")
+	writeConfig(t, path, "receivers:\n  otlp: {}\n")
+
+	var valid atomic.Bool
+	var validations atomic.Int32
+	var validated atomic.Value
+	p := newTestProvider(t, func(_ context.Context, uri string, content []byte) error {
+		validations.Add(1)
+		assert.Equal(t, "file:"+path, uri)
+		validated.Store(string(content))
+		if !valid.Load() {
+			return errors.New("invalid config")
+		}
+		return nil
+	})
+
+	events := make(chan *confmap.ChangeEvent, 1)
+	ret, err := p.Retrieve(t.Context(), "file:"+path, func(event *confmap.ChangeEvent) {
+		events <- event
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, ret.Close(context.Background()))
+	}()
+
+	// unchanged content is not validated
+	time.Sleep(5 * testPollInterval)
+	assert.Zero(t, validations.Load())
+
+	// an invalid change is rejected and the watcher is not notified
+	writeConfig(t, path, "receivers:\n  otlp: invalid\n")
+	require.Eventually(t, func() bool { return validations.Load() == 1 }, time.Second, testPollInterval)
+	select {
+	case <-events:
+		t.Fatal("watcher notified about an invalid config")
+	case <-time.After(5 * testPollInterval):
+	}
+
+	assert.Equal(t, "receivers:\n  otlp: invalid\n", validated.Load())
+
+	// a valid change notifies the watcher
+	valid.Store(true)
+	writeConfig(t, path, "receivers:\n  otlp: {}\n  filelog: {}\n")
+	select {
+	case event := <-events:
+		assert.NoError(t, event.Error)
+	case <-time.After(time.Second):
+		t.Fatal("watcher not notified about a valid config change")
+	}
+
+	// the reload retrieves the validated content even when the file changed again
+	writeConfig(t, path, "receivers:\n  otlp: invalid\n")
+	reloaded, err := p.Retrieve(t.Context(), "file:"+path, nil)
+	require.NoError(t, err)
+	raw, err := reloaded.AsRaw()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"receivers": map[string]any{"otlp": map[string]any{}, "filelog": map[string]any{}}}, raw)
+}
+
+func TestProviderWatchConfigMapSwap(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ConfigMap mounts are only simulated on unix")
+	}
+	dir := t.TempDir()
+	// a ConfigMap volume exposes its files through the `..data` symlink, which the kubelet swaps
+	// atomically to a new directory on every update
+	swapConfigMap := func(version string, content string) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0o755))
+		writeConfig(t, filepath.Join(dir, version, "otel.yml"), content)
+		require.NoError(t, os.Symlink(version, filepath.Join(dir, "..data_tmp")))
+		require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	}
+	swapConfigMap("..2024_01_01", "receivers:\n  otlp: {}\n")
+	path := filepath.Join(dir, "otel.yml")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "otel.yml"), path))
+
+	p := newTestProvider(t, nil)
+	events := make(chan *confmap.ChangeEvent, 1)
+	ret, err := p.Retrieve(t.Context(), "file:"+path, func(event *confmap.ChangeEvent) {
+		events <- event
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, ret.Close(context.Background()))
+	}()
+
+	swapConfigMap("..2024_01_02", "receivers:\n  filelog: {}\n")
+	select {
+	case event := <-events:
+		assert.NoError(t, event.Error)
+	case <-time.After(time.Second):
+		t.Fatal("watcher not notified about the ConfigMap update")
+	}
+
+	reloaded, err := p.Retrieve(t.Context(), "file:"+path, nil)
+	require.NoError(t, err)
+	raw, err := reloaded.AsRaw()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"receivers": map[string]any{"filelog": map[string]any{}}}, raw)
+}
+
+func TestProviderWatchStopsOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otel.yml")
+	writeConfig(t, path, "receivers:\n  otlp: {}\n")
+
+	p := newTestProvider(t, nil)
+	var notified atomic.Bool
+	ret, err := p.Retrieve(t.Context(), "file:"+path, func(*confmap.ChangeEvent) {
+		notified.Store(true)
+	})
+	require.NoError(t, err)
+	require.NoError(t, ret.Close(context.Background()))
+
+	writeConfig(t, path, "receivers:\n  filelog: {}\n")
+	time.Sleep(5 * testPollInterval)
+	assert.False(t, notified.Load())
+}
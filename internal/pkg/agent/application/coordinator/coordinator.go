@@ -176,6 +176,12 @@ type OTelManager interface {
 	// component config.
 	MergedOtelConfig() *confmap.Conf
 
+	// SetPipelineEnabled starts or stops a pipeline of the plain configuration without changing the configuration.
+	SetPipelineEnabled(ctx context.Context, name string, enabled bool) error
+
+	// DisabledPipelines returns the names of the pipelines disabled with SetPipelineEnabled.
+	DisabledPipelines() []string
+
 	// PerformDiagnostics executes the diagnostic action for the provided units. If no units are provided then
 	// it performs diagnostics for all current units.
 	PerformDiagnostics(context.Context, ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic
@@ -968,6 +974,24 @@ func (c *Coordinator) PerformComponentDiagnostics(ctx context.Context, additiona
 	return diags, err
 }
 
+// SetOtelPipelineEnabled starts or stops a pipeline of the otel collector configuration.
+// Called from external goroutines.
+func (c *Coordinator) SetOtelPipelineEnabled(ctx context.Context, name string, enabled bool) error {
+	if c.otelMgr == nil {
+		return errors.New("the Elastic Agent doesn't run an otel collector")
+	}
+	return c.otelMgr.SetPipelineEnabled(ctx, name, enabled)
+}
+
+// DisabledOtelPipelines returns the names of the otel collector pipelines disabled with SetOtelPipelineEnabled.
+// Called from external goroutines.
+func (c *Coordinator) DisabledOtelPipelines() []string {
+	if c.otelMgr == nil {
+		return nil
+	}
+	return c.otelMgr.DisabledPipelines()
+}
+
 // SetLogLevel changes the entire log level for the running Elastic Agent.
 // Called from external goroutines.
 func (c *Coordinator) SetLogLevel(ctx context.Context, lvl *logp.Level) error {
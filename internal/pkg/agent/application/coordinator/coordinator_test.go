@@ -1751,6 +1751,10 @@ func (f *fakeOTelManager) WatchComponents() <-chan []runtime.ComponentComponentS
 
 func (f *fakeOTelManager) MergedOtelConfig() *confmap.Conf { return nil }
 
+func (f *fakeOTelManager) SetPipelineEnabled(context.Context, string, bool) error { return nil }
+
+func (f *fakeOTelManager) DisabledPipelines() []string { return nil }
+
 func (f *fakeOTelManager) PerformDiagnostics(ctx context.Context, reqs ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic {
 	if f.performDiagnosticsCallback != nil {
 		return f.performDiagnosticsCallback(ctx, reqs...)
@@ -47,7 +47,7 @@ func (e *encryptedOnce) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("unable to read encrypted config: %w", err)
 	}
-	e.ch <- &localConfigChange{rawConfig}
+	e.ch <- &localConfigChange{cfg: rawConfig}
 	<-ctx.Done()
 	return ctx.Err()
 }
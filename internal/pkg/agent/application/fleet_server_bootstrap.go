@@ -222,7 +222,7 @@ func (m *fleetServerBootstrapManager) Run(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case m.ch <- &localConfigChange{injectFleetServerInput}:
+	case m.ch <- &localConfigChange{cfg: injectFleetServerInput}:
 	}
 
 	<-ctx.Done()
@@ -269,7 +269,7 @@ func (m *managedConfigManager) initFleetServer(ctx context.Context, cfg *configu
 	select {
 	case <-ctx.Done():
 		return fmt.Errorf("timeout while waiting for fleet server start: %w", ctx.Err())
-	case m.ch <- &localConfigChange{injectFleetServerInput}:
+	case m.ch <- &localConfigChange{cfg: injectFleetServerInput}:
 	}
 
 	return m.waitForFleetServer(ctx)
@@ -21,13 +21,40 @@ type once struct {
 	loader   *config.Loader
 	ch       chan coordinator.ConfigChange
 	errCh    chan error
+	reloadCh chan chan error
 }
 
 func newOnce(log *logger.Logger, discover config.DiscoverFunc, loader *config.Loader) *once {
-	return &once{log: log, discover: discover, loader: loader, ch: make(chan coordinator.ConfigChange), errCh: make(chan error)}
+	return &once{log: log, discover: discover, loader: loader, ch: make(chan coordinator.ConfigChange), errCh: make(chan error), reloadCh: make(chan chan error)}
 }
 
 func (o *once) Run(ctx context.Context) error {
+	if err := o.load(ctx, nil); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case result := <-o.reloadCh:
+			// report the failures to the caller instead of stopping, the previous configuration keeps running
+			if err := o.load(ctx, result); err != nil {
+				result <- err
+			}
+		}
+	}
+}
+
+// ReloadConfig reloads the configuration files, the configuration being otherwise only loaded once
+// when reloading is disabled. It returns once the coordinator applied the reloaded configuration,
+// or with the error that prevented it, in which case the previous configuration keeps running.
+func (o *once) ReloadConfig(ctx context.Context) error {
+	return requestReload(ctx, o.reloadCh)
+}
+
+// load sends the configuration to the coordinator. When result is not nil, the outcome of applying
+// the configuration is sent to it.
+func (o *once) load(ctx context.Context, result chan<- error) error {
 	files, err := o.discover()
 	if err != nil {
 		return errors.New(err, "could not discover configuration files", errors.TypeConfig)
@@ -41,9 +68,12 @@ func (o *once) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	o.ch <- &localConfigChange{cfg}
-	<-ctx.Done()
-	return ctx.Err()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case o.ch <- &localConfigChange{cfg: cfg, result: result}:
+	}
+	return nil
 }
 
 func (o *once) Errors() <-chan error {
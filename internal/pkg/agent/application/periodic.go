@@ -26,10 +26,11 @@ type periodic struct {
 	discover config.DiscoverFunc
 	ch       chan coordinator.ConfigChange
 	errCh    chan error
+	reloadCh chan chan error
 }
 
 func (p *periodic) Run(ctx context.Context) error {
-	if err := p.work(ctx); err != nil {
+	if err := p.work(ctx, nil); err != nil {
 		return err
 	}
 
@@ -40,14 +41,29 @@ func (p *periodic) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-t.C:
+		case result := <-p.reloadCh:
+			// reload every file, even when unchanged, and report the failures to the caller instead of
+			// stopping, the previous configuration keeps running
+			p.watcher.Invalidate()
+			if err := p.work(ctx, result); err != nil {
+				result <- err
+			}
+			continue
 		}
 
-		if err := p.work(ctx); err != nil {
+		if err := p.work(ctx, nil); err != nil {
 			return err
 		}
 	}
 }
 
+// ReloadConfig reloads the configuration files right away, instead of waiting for the next check
+// for changes. It returns once the coordinator applied the reloaded configuration, or with the
+// error that prevented it, in which case the previous configuration keeps running.
+func (p *periodic) ReloadConfig(ctx context.Context) error {
+	return requestReload(ctx, p.reloadCh)
+}
+
 func (p *periodic) Errors() <-chan error {
 	return p.errCh
 }
@@ -62,7 +78,9 @@ func (p *periodic) Watch() <-chan coordinator.ConfigChange {
 	return p.ch
 }
 
-func (p *periodic) work(ctx context.Context) error {
+// work sends the configuration to the coordinator when the files changed. When result is not nil,
+// the outcome of applying the configuration is sent to it.
+func (p *periodic) work(ctx context.Context, result chan<- error) error {
 	files, err := p.discover()
 	if err != nil {
 		return errors.New(err, "could not discover configuration files", errors.TypeConfig)
@@ -114,13 +132,16 @@ func (p *periodic) work(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case p.ch <- &localConfigChange{cfg}:
+		case p.ch <- &localConfigChange{cfg: cfg, result: result}:
 		}
 
 		return nil
 	}
 
 	p.log.Debug("No configuration change")
+	if result != nil {
+		result <- nil
+	}
 	return nil
 }
 
@@ -145,12 +166,32 @@ func newPeriodic(
 		loader:   loader,
 		ch:       make(chan coordinator.ConfigChange),
 		errCh:    make(chan error),
+		reloadCh: make(chan chan error),
+	}
+}
+
+// requestReload asks the config manager behind reloadCh to reload the configuration and waits for
+// the outcome.
+func requestReload(ctx context.Context, reloadCh chan<- chan error) error {
+	result := make(chan error, 1)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case reloadCh <- result:
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-result:
+		return err
 	}
 }
 
 // localConfigChange implements coordinator.ConfigChange for local file changes.
 type localConfigChange struct {
 	cfg *config.Config
+	// result, when set, receives the outcome of applying cfg for the caller of ReloadConfig
+	result chan<- error
 }
 
 func (l *localConfigChange) Config() *config.Config {
@@ -158,10 +199,14 @@ func (l *localConfigChange) Config() *config.Config {
 }
 
 func (l *localConfigChange) Ack() error {
-	// do nothing
+	if l.result != nil {
+		l.result <- nil
+	}
 	return nil
 }
 
-func (l *localConfigChange) Fail(_ error) {
-	// do nothing
+func (l *localConfigChange) Fail(err error) {
+	if l.result != nil {
+		l.result <- err
+	}
 }
@@ -0,0 +1,57 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package application
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestPeriodicReloadConfig(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "elastic-agent.yml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("agent.logging.level: info\n"), 0o600))
+	log, _ := loggertest.New(t.Name())
+	// the period is long enough for the configuration to only be reloaded by ReloadConfig
+	p := newPeriodic(log, time.Hour, config.Discoverer(cfgPath), config.NewLoader(log, ""))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- p.Run(ctx)
+	}()
+	require.NoError(t, (<-p.Watch()).Ack())
+
+	// the configuration is reloaded even though the file didn't change, and the outcome of applying
+	// it is returned
+	reloadErr := make(chan error, 1)
+	go func() {
+		reloadErr <- p.ReloadConfig(ctx)
+	}()
+	require.NoError(t, (<-p.Watch()).Ack())
+	require.NoError(t, <-reloadErr)
+
+	go func() {
+		reloadErr <- p.ReloadConfig(ctx)
+	}()
+	(<-p.Watch()).Fail(errors.New("invalid configuration"))
+	require.ErrorContains(t, <-reloadErr, "invalid configuration")
+
+	// a configuration file failing to load is reported without stopping the config manager
+	require.NoError(t, os.WriteFile(cfgPath, []byte("agent: [\n"), 0o600))
+	require.ErrorContains(t, p.ReloadConfig(ctx), "failed to load or merge configuration")
+
+	cancel()
+	require.ErrorIs(t, <-runErr, context.Canceled)
+}
@@ -290,7 +290,7 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 		return err
 	}
 
-	comps, err := componentvalidation.GetComponentsFromPolicy(ctx, l, cfgPath, opts.variablesWait)
+	comps, err := componentvalidation.GetComponentsFromPolicy(ctx, l, paths.Components(), cfgPath, opts.variablesWait)
 	if err != nil {
 		// error already includes the context
 		return err
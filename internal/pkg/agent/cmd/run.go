@@ -427,6 +427,10 @@ func runElasticAgent(
 	if ok {
 		control.SetTestModeConfigSetter(testingSetter)
 	}
+	// a standalone Elastic Agent reading its configuration from files can reload it on demand
+	if reloader, ok := configMgr.(server.ConfigReloader); ok {
+		control.SetConfigReloader(reloader)
+	}
 
 	// start the control listener
 	if err := control.Start(); err != nil {
@@ -50,7 +50,7 @@ func newStatusCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().String("output", "human", "Output the status information in either 'human', 'full', 'json', or 'yaml'.  'human' only shows non-healthy details, others show full details. (default: human)")
+	cmd.Flags().String("output", "human", "Output the status information in either 'human', 'full', 'json', or 'yaml'.  'human' only shows non-healthy details, others show full details, 'json' and 'yaml' include the health of the OpenTelemetry collector pipelines in the 'otel' section. (default: human)")
 
 	return cmd
 }
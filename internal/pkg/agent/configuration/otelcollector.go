@@ -8,11 +8,13 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"time"
 )
 
 type CollectorConfig struct {
 	HealthCheckConfig CollectorHealthCheckConfig `yaml:"healthcheck" config:"healthcheck" json:"healthcheck"`
 	TelemetryConfig   CollectorTelemetryConfig   `yaml:"telemetry" config:"telemetry" json:"telemetry"`
+	SelfMetricsConfig CollectorSelfMetricsConfig `yaml:"self_metrics" config:"self_metrics" json:"self_metrics"`
 }
 
 type CollectorHealthCheckConfig struct {
@@ -39,10 +41,25 @@ func (c *CollectorTelemetryConfig) Port() (int, error) {
 	return getPort(c.Endpoint)
 }
 
+// CollectorSelfMetricsConfig controls the receiver scraping the collector's own internal metrics into
+// the metrics pipelines of the collector configuration.
+type CollectorSelfMetricsConfig struct {
+	Enabled  bool          `yaml:"enabled" config:"enabled" json:"enabled"`
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+}
+
+func (c *CollectorSelfMetricsConfig) Validate() error {
+	if c.Interval < 0 {
+		return fmt.Errorf("invalid self metrics interval '%s': must not be negative", c.Interval)
+	}
+	return nil
+}
+
 func DefaultCollectorConfig() *CollectorConfig {
 	return &CollectorConfig{
 		HealthCheckConfig: CollectorHealthCheckConfig{},
 		TelemetryConfig:   CollectorTelemetryConfig{},
+		SelfMetricsConfig: CollectorSelfMetricsConfig{},
 	}
 }
 
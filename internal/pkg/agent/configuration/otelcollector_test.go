@@ -6,6 +6,7 @@ package configuration
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -82,4 +83,11 @@ func TestDefaultCollectorConfig(t *testing.T) {
 	assert.NotNil(t, defaultConfig)
 	assert.Equal(t, CollectorHealthCheckConfig{}, defaultConfig.HealthCheckConfig)
 	assert.Equal(t, CollectorTelemetryConfig{}, defaultConfig.TelemetryConfig)
+	assert.Equal(t, CollectorSelfMetricsConfig{}, defaultConfig.SelfMetricsConfig)
+}
+
+func TestCollectorSelfMetricsConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&CollectorSelfMetricsConfig{Enabled: true}).Validate())
+	assert.NoError(t, (&CollectorSelfMetricsConfig{Enabled: true, Interval: 10 * time.Second}).Validate())
+	assert.Error(t, (&CollectorSelfMetricsConfig{Enabled: true, Interval: -time.Second}).Validate())
 }
@@ -34,7 +34,7 @@ func EnsureNoServiceComponentIssues() error {
 	}
 	// this forces the component calculation to always compute with no root
 	// this allows any runtime preventions to error for a component when it has a no root support
-	comps, err := GetComponentsFromPolicy(ctx, l, paths.ConfigFile(), 0, forceNonRoot)
+	comps, err := GetComponentsFromPolicy(ctx, l, paths.Components(), paths.ConfigFile(), 0, forceNonRoot)
 	if err != nil {
 		return fmt.Errorf("failed to create component model from policy: %w", err)
 	}
@@ -68,14 +68,16 @@ func forceNonRoot(detail component.PlatformDetail) component.PlatformDetail {
 	return detail
 }
 
-func GetComponentsFromPolicy(ctx context.Context, l *logger.Logger, cfgPath string, variablesWait time.Duration, platformModifiers ...component.PlatformModifier) ([]component.Component, error) {
+// GetComponentsFromPolicy computes the components of the policy at cfgPath with the specs of the
+// components in componentsDir.
+func GetComponentsFromPolicy(ctx context.Context, l *logger.Logger, componentsDir string, cfgPath string, variablesWait time.Duration, platformModifiers ...component.PlatformModifier) ([]component.Component, error) {
 	// Load the requirements before trying to load the configuration. These should always load
 	// even if the configuration is wrong.
 	platform, err := component.LoadPlatformDetail(platformModifiers...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to gather system information: %w", err)
 	}
-	specs, err := component.LoadRuntimeSpecs(paths.Components(), platform)
+	specs, err := component.LoadRuntimeSpecs(componentsDir, platform)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect inputs and outputs: %w", err)
 	}
@@ -7,6 +7,7 @@ package basecmd
 import (
 	"github.com/spf13/cobra"
 
+	"github.com/elastic/elastic-agent/internal/pkg/basecmd/reload"
 	"github.com/elastic/elastic-agent/internal/pkg/basecmd/restart"
 	"github.com/elastic/elastic-agent/internal/pkg/basecmd/version"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
@@ -15,6 +16,7 @@ import (
 // NewDefaultCommandsWithArgs returns a list of default commands to executes.
 func NewDefaultCommandsWithArgs(args []string, streams *cli.IOStreams) []*cobra.Command {
 	return []*cobra.Command{
+		reload.NewCommandWithArgs(streams),
 		restart.NewCommandWithArgs(streams),
 		version.NewCommandWithArgs(streams),
 	}
@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package reload
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+// NewCommandWithArgs returns a new reload command.
+func NewCommandWithArgs(streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload",
+		Short: "Reload the configuration files of the currently running standalone Elastic Agent daemon",
+		Long: `Reload the configuration files of the currently running standalone Elastic Agent daemon right away,
+instead of waiting for the next periodic check for changes. The command fails when the reloaded
+configuration cannot be applied, in which case the previous configuration keeps running.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c := client.New()
+			err := c.Connect(context.Background())
+			if err != nil {
+				return errors.New(err, "Failed communicating to running daemon", errors.TypeNetwork, errors.M("socket", control.Address()))
+			}
+			defer c.Disconnect()
+			err = c.ReloadConfig(context.Background())
+			if err != nil {
+				return errors.New(err, "Failed to reload the configuration of the daemon")
+			}
+			return nil
+		},
+	}
+}
@@ -171,10 +171,10 @@ func (w *Watch) scan() (modifiedFiles []string, unchanged []string, err error) {
 			return []string{}, []string{}, err
 		}
 
+		// the record of an unchanged file can be updated too, e.g. after a symlink swap
+		info.record = newRecord
+		w.logbook[file] = info
 		if change {
-			info := w.logbook[file]
-			info.record = newRecord
-			w.logbook[file] = info
 			modifiedFiles = append(modifiedFiles, file)
 		} else {
 			unchanged = append(unchanged, file)
@@ -219,8 +219,10 @@ func ContentComparer(file string, r interface{}) (bool, interface{}, error) {
 	// We already saw the file.
 	fileRecord := r.(record)
 
-	// If the modification time is the same, we assume nothing was changed on disk.
-	if stat.ModTime().Sub(fileRecord.info.ModTime()) == 0 {
+	// If the modification time is the same and the path still resolves to the same file, we assume
+	// nothing was changed on disk. A Kubernetes ConfigMap mount replaces its files by swapping the
+	// `..data` symlink to a new directory, the new file can have the same modification time.
+	if stat.ModTime().Sub(fileRecord.info.ModTime()) == 0 && os.SameFile(stat, fileRecord.info) {
 		return false, fileRecord, nil
 	}
 
@@ -231,7 +233,7 @@ func ContentComparer(file string, r interface{}) (bool, interface{}, error) {
 
 	// content is the same, no change.
 	if bytes.Equal(checksum, fileRecord.checksum) {
-		return false, fileRecord, nil
+		return false, record{info: stat, checksum: fileRecord.checksum}, nil
 	}
 
 	return true, record{info: stat, checksum: checksum}, nil
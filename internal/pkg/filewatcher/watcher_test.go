@@ -7,6 +7,7 @@ package filewatcher
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
@@ -174,6 +175,37 @@ func TestWatch(t *testing.T) {
 		assert.Equal(t, path2, s.Unchanged[0])
 	}))
 
+	t.Run("detects a Kubernetes ConfigMap symlink swap", withWatch(func(t *testing.T, w *Watch) {
+		if runtime.GOOS == "windows" {
+			t.Skip("ConfigMap mounts are only simulated on unix")
+		}
+		tmp := t.TempDir()
+		mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+		writeConfigMap(t, tmp, "..2024_01_01", "hello.txt", "hello", mtime)
+
+		path := filepath.Join(tmp, "hello.txt")
+		require.NoError(t, os.Symlink(filepath.Join("..data", "hello.txt"), path))
+		w.Watch(path)
+
+		r, _, err := w.scan()
+		require.NoError(t, err)
+		assert.Equal(t, []string{path}, r)
+
+		// the new version of the file has the same modification time and size
+		writeConfigMap(t, tmp, "..2024_01_02", "hello.txt", "hallo", mtime)
+		r, u, err := w.scan()
+		require.NoError(t, err)
+		assert.Equal(t, []string{path}, r)
+		assert.Empty(t, u)
+
+		// same content behind a new symlink swap
+		writeConfigMap(t, tmp, "..2024_01_03", "hello.txt", "hallo", mtime)
+		r, u, err = w.scan()
+		require.NoError(t, err)
+		assert.Empty(t, r)
+		assert.Equal(t, []string{path}, u)
+	}))
+
 	t.Run("should cleanup files that disapear", withWatch(func(t *testing.T, w *Watch) {
 		tmp := t.TempDir()
 
@@ -204,6 +236,18 @@ func TestWatch(t *testing.T) {
 	}))
 }
 
+// writeConfigMap writes the file name in dir/version and atomically points dir/..data to it, the way
+// the kubelet updates a ConfigMap volume.
+func writeConfigMap(t *testing.T, dir string, version string, name string, content string, mtime time.Time) {
+	t.Helper()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0o755))
+	file := filepath.Join(dir, version, name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	require.NoError(t, os.Chtimes(file, mtime, mtime))
+	require.NoError(t, os.Symlink(version, filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+}
+
 func withWatch(fn func(t *testing.T, w *Watch)) func(*testing.T) {
 	return func(t *testing.T) {
 		w, err := New(nil, DefaultComparer)
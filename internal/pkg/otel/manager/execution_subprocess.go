@@ -13,6 +13,8 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -72,6 +74,7 @@ type subprocessExecution struct {
 	collectorArgs            []string
 	healthCheckExtensionID   string
 	collectorHealthCheckPort int                                                    // user-configured port; 0 means pick a random port per-start
+	collectorMetricsPort     int                                                    // user-configured port; 0 means pick a random port per-start
 	reportErrFn              func(ctx context.Context, errCh chan error, err error) // required for testing
 }
 
@@ -102,11 +105,22 @@ func (r *subprocessExecution) startCollector(
 		return nil, fmt.Errorf("cannot access collector path: %w", err)
 	}
 
-	httpHealthCheckPort, err := r.getCollectorHealthCheckPort()
+	httpHealthCheckPort, metricsPort, err := r.getCollectorPorts()
 	if err != nil {
 		return nil, fmt.Errorf("could not find port for collector: %w", err)
 	}
 
+	// Override the metrics reader port placeholder (port 0) with the actual resolved port, the exporter
+	// status is derived from the metrics.
+	cfg, err = setCollectorMetricsReaderPort(cfg, metricsPort)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err = setSelfMetricsReceiverPort(cfg, metricsPort)
+	if err != nil {
+		return nil, err
+	}
+
 	// prepare and serialize config first so we can exit early if there's a problem
 	cfgYamlBytes, err := prepareAndSerializeConfig(cfg)
 	if err != nil {
@@ -147,7 +161,7 @@ func (r *subprocessExecution) startCollector(
 		return nil, fmt.Errorf("failed to start supervised collector: %w", err)
 	}
 
-	logger.Infof("supervised collector started with pid: %d and healthcheck port: %d", processInfo.Process.Pid, httpHealthCheckPort)
+	logger.Infof("supervised collector started with pid: %d, healthcheck port: %d and metrics port: %d", processInfo.Process.Pid, httpHealthCheckPort, metricsPort)
 
 	processDoneCh := make(chan struct{})
 	reportPipeErrFn := func(err error) {
@@ -175,6 +189,7 @@ func (r *subprocessExecution) startCollector(
 		healthCheckPollTimer := time.NewTimer(healthCheckPollDuration)
 		defer healthCheckPollTimer.Stop()
 		client := http.Client{}
+		failures := newExportFailures(exportFailureThreshold)
 		for {
 			statuses, err := AllComponentsStatuses(procCtx, client, httpHealthCheckPort)
 			if err != nil {
@@ -190,6 +205,9 @@ func (r *subprocessExecution) startCollector(
 			} else {
 				maxFailuresTimer.Reset(maxFailuresDuration)
 				removeManagedHealthCheckExtensionStatus(statuses, r.healthCheckExtensionID)
+				// the health check extension doesn't report saturated sending queues nor failing exports,
+				// scrape them from the metrics
+				markUnhealthyExporters(procCtx, logger, client, metricsPort, failures, statuses)
 				if !status.CompareStatuses(currentStatus, statuses) {
 					currentStatus = statuses
 					r.reportSubprocessCollectorStatus(procCtx, statusCh, statuses)
@@ -292,17 +310,9 @@ func (r *subprocessExecution) reportSubprocessCollectorStatus(ctx context.Contex
 	reportCollectorStatus(ctx, statusCh, clonedStatus)
 }
 
-func addCollectorMetricsReader(conf *confmap.Conf, port int) error {
-	metricReadersUntyped := conf.Get("service::telemetry::metrics::readers")
-	if metricReadersUntyped == nil {
-		metricReadersUntyped = []any{}
-	}
-	metricsReadersList, ok := metricReadersUntyped.([]any)
-	if !ok {
-		return fmt.Errorf("couldn't convert value of service::telemetry::metrics::readers to a list: %v", metricReadersUntyped)
-	}
-
-	metricsReader := map[string]any{
+// collectorMetricsReader returns the Prometheus metrics reader the exporter status is scraped from.
+func collectorMetricsReader(port int) map[string]any {
+	return map[string]any{
 		"pull": map[string]any{
 			"exporter": map[string]any{
 				"prometheus": map[string]any{
@@ -314,7 +324,21 @@ func addCollectorMetricsReader(conf *confmap.Conf, port int) error {
 			},
 		},
 	}
-	metricsReadersList = append(metricsReadersList, metricsReader)
+}
+
+// addCollectorMetricsReader adds the metrics reader listening on port to the collector configuration, port 0
+// is a placeholder replaced with setCollectorMetricsReaderPort when the collector starts.
+func addCollectorMetricsReader(conf *confmap.Conf, port int) error {
+	metricReadersUntyped := conf.Get("service::telemetry::metrics::readers")
+	if metricReadersUntyped == nil {
+		metricReadersUntyped = []any{}
+	}
+	metricsReadersList, ok := metricReadersUntyped.([]any)
+	if !ok {
+		return fmt.Errorf("couldn't convert value of service::telemetry::metrics::readers to a list: %v", metricReadersUntyped)
+	}
+
+	metricsReadersList = append(metricsReadersList, collectorMetricsReader(port))
 	confMap := map[string]any{
 		"service::telemetry::metrics::readers": metricsReadersList,
 	}
@@ -324,17 +348,57 @@ func addCollectorMetricsReader(conf *confmap.Conf, port int) error {
 	return nil
 }
 
-// getCollectorHealthCheckPort returns the health check port used by the OTel collector.
-// If the port set in the execution struct is 0, a random port is returned instead.
-func (r *subprocessExecution) getCollectorHealthCheckPort() (int, error) {
-	if r.collectorHealthCheckPort != 0 {
-		return r.collectorHealthCheckPort, nil
+// setCollectorMetricsReaderPort returns a copy of conf where the port of the metrics reader placeholder added
+// by addCollectorMetricsReader is port. conf is returned as is when it has no placeholder.
+func setCollectorMetricsReaderPort(conf *confmap.Conf, port int) (*confmap.Conf, error) {
+	readers, ok := conf.Get("service::telemetry::metrics::readers").([]any)
+	if !ok {
+		return conf, nil
+	}
+	placeholder := collectorMetricsReader(0)
+	for i, reader := range readers {
+		if !reflect.DeepEqual(reader, placeholder) {
+			continue
+		}
+		updated := slices.Clone(readers)
+		updated[i] = collectorMetricsReader(port)
+		confCopy := confmap.NewFromStringMap(conf.ToStringMap())
+		if err := confCopy.Merge(confmap.NewFromStringMap(map[string]any{
+			"service::telemetry::metrics::readers": updated,
+		})); err != nil {
+			return nil, fmt.Errorf("failed to set the collector metrics port: %w", err)
+		}
+		return confCopy, nil
+	}
+	return conf, nil
+}
+
+// getCollectorPorts returns the health check and metrics ports used by the OTel collector.
+// Random ports are returned instead of the ports set to 0 in the execution struct.
+func (r *subprocessExecution) getCollectorPorts() (healthCheckPort int, metricsPort int, err error) {
+	healthCheckPort, metricsPort = r.collectorHealthCheckPort, r.collectorMetricsPort
+	var randomCount int
+	if healthCheckPort == 0 {
+		randomCount++
+	}
+	if metricsPort == 0 {
+		randomCount++
 	}
-	ports, err := findRandomTCPPorts(1)
+	if randomCount == 0 {
+		return healthCheckPort, metricsPort, nil
+	}
+	// find the random ports at once so that they are different
+	ports, err := findRandomTCPPorts(randomCount)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
+	}
+	if healthCheckPort == 0 {
+		healthCheckPort, ports = ports[0], ports[1:]
+	}
+	if metricsPort == 0 {
+		metricsPort = ports[0]
 	}
-	return ports[0], nil
+	return healthCheckPort, metricsPort, nil
 }
 
 func removeManagedHealthCheckExtensionStatus(status *otelstatus.AggregateStatus, healthCheckExtensionID string) {
@@ -533,3 +597,15 @@ func (z *zapLast) LastMessage() string {
 	defer z.mx.Unlock()
 	return strings.Join(z.msgs, "; ")
 }
+
+// markUnhealthyExporters scrapes the collector metrics on metricsPort and degrades the status of the exporters
+// with a full sending queue or that keep failing to send data.
+func markUnhealthyExporters(ctx context.Context, logger *logger.Logger, client http.Client, metricsPort int, failures *exportFailures, statuses *otelstatus.AggregateStatus) {
+	families, err := scrapeCollectorMetrics(ctx, client, metricsPort)
+	if err != nil {
+		logger.Debugf("Received an unexpected error while fetching exporter metrics: %v", err)
+		return
+	}
+	markSaturatedExporters(statuses, saturatedExporters(families))
+	markFailingExporters(statuses, failures.update(exporterSendsFrom(families)))
+}
@@ -230,3 +230,43 @@ func TestPrepareAndSerializeConfig(t *testing.T) {
 		assert.Contains(t, string(yamlBytes), "key: value")
 	})
 }
+
+func TestSetCollectorMetricsReaderPort(t *testing.T) {
+	userReader := map[string]any{"periodic": map[string]any{"interval": 10000}}
+	conf := confmap.New()
+	require.NoError(t, conf.Merge(confmap.NewFromStringMap(map[string]any{
+		"service::telemetry::metrics::readers": []any{userReader},
+	})))
+	require.NoError(t, addCollectorMetricsReader(conf, 0))
+
+	updated, err := setCollectorMetricsReaderPort(conf, 8888)
+	require.NoError(t, err)
+	assert.Equal(t, []any{userReader, collectorMetricsReader(8888)}, updated.Get("service::telemetry::metrics::readers"))
+	// the placeholder is kept in the original configuration
+	assert.Equal(t, []any{userReader, collectorMetricsReader(0)}, conf.Get("service::telemetry::metrics::readers"))
+
+	// the configured port is kept
+	configured := confmap.New()
+	require.NoError(t, addCollectorMetricsReader(configured, 9999))
+	updated, err = setCollectorMetricsReaderPort(configured, 8888)
+	require.NoError(t, err)
+	assert.Equal(t, []any{collectorMetricsReader(9999)}, updated.Get("service::telemetry::metrics::readers"))
+}
+
+func TestGetCollectorPorts(t *testing.T) {
+	healthCheckPort, metricsPort, err := (&subprocessExecution{collectorHealthCheckPort: 1234, collectorMetricsPort: 5678}).getCollectorPorts()
+	require.NoError(t, err)
+	assert.Equal(t, 1234, healthCheckPort)
+	assert.Equal(t, 5678, metricsPort)
+
+	healthCheckPort, metricsPort, err = (&subprocessExecution{collectorMetricsPort: 5678}).getCollectorPorts()
+	require.NoError(t, err)
+	assert.NotZero(t, healthCheckPort)
+	assert.Equal(t, 5678, metricsPort)
+
+	healthCheckPort, metricsPort, err = (&subprocessExecution{}).getCollectorPorts()
+	require.NoError(t, err)
+	assert.NotZero(t, healthCheckPort)
+	assert.NotZero(t, metricsPort)
+	assert.NotEqual(t, healthCheckPort, metricsPort)
+}
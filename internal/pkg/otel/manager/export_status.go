@@ -0,0 +1,111 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package manager
+
+import (
+	"errors"
+	"strings"
+
+	otelstatus "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/status"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	// exporterhelper counters of the items sent and failed to be sent, one per signal
+	exporterSentMetricPrefix       = "otelcol_exporter_sent_"
+	exporterSendFailedMetricPrefix = "otelcol_exporter_send_failed_"
+
+	// exportFailureThreshold is the number of consecutive scrapes in which an exporter failed to
+	// send items without sending any before it is reported as failing.
+	exportFailureThreshold = 3
+)
+
+// errExporterSendFailed is the status error of an exporter that keeps failing to send data.
+var errExporterSendFailed = errors.New("exporter is failing to send data")
+
+// exporterSends are the totals of the items an exporter sent and failed to send, across signals.
+type exporterSends struct {
+	sent   float64
+	failed float64
+}
+
+// exporterSendsFrom returns the totals of the exporter sent and send failed counters out of the collector metrics.
+func exporterSendsFrom(families map[string]*dto.MetricFamily) map[string]exporterSends {
+	sends := make(map[string]exporterSends)
+	for name, family := range families {
+		failed := strings.HasPrefix(name, exporterSendFailedMetricPrefix)
+		if !failed && !strings.HasPrefix(name, exporterSentMetricPrefix) {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			exporter := labelValue(m, exporterLabel)
+			if exporter == "" {
+				continue
+			}
+			exporterSends := sends[exporter]
+			if failed {
+				exporterSends.failed += metricValue(m)
+			} else {
+				exporterSends.sent += metricValue(m)
+			}
+			sends[exporter] = exporterSends
+		}
+	}
+	return sends
+}
+
+// exportFailures tracks the exporters that keep failing to send data across scrapes of the
+// collector metrics. It is not safe for concurrent use.
+type exportFailures struct {
+	threshold int
+	last      map[string]exporterSends
+	failures  map[string]int
+}
+
+func newExportFailures(threshold int) *exportFailures {
+	return &exportFailures{
+		threshold: threshold,
+		last:      make(map[string]exporterSends),
+		failures:  make(map[string]int),
+	}
+}
+
+// update records the latest sends and returns the exporters that failed to send items, without
+// sending any, in at least threshold consecutive scrapes. Scrapes where an exporter neither sent
+// nor failed, e.g. while retrying, don't break the sequence; a successful send resets it.
+func (e *exportFailures) update(sends map[string]exporterSends) map[string]struct{} {
+	failing := make(map[string]struct{})
+	for exporter, current := range sends {
+		last, seen := e.last[exporter]
+		e.last[exporter] = current
+		if !seen || current.sent < last.sent || current.failed < last.failed {
+			// first scrape or the collector restarted, start over
+			e.failures[exporter] = 0
+			continue
+		}
+		switch {
+		case current.sent > last.sent:
+			e.failures[exporter] = 0
+		case current.failed > last.failed:
+			e.failures[exporter]++
+		}
+		if e.failures[exporter] >= e.threshold {
+			failing[exporter] = struct{}{}
+		}
+	}
+	for exporter := range e.last {
+		if _, found := sends[exporter]; !found {
+			delete(e.last, exporter)
+			delete(e.failures, exporter)
+		}
+	}
+	return failing
+}
+
+// markFailingExporters reports a recoverable error for every healthy exporter that keeps failing to send data,
+// degrading the pipelines it belongs to and the collector as a whole.
+func markFailingExporters(aggStatus *otelstatus.AggregateStatus, failing map[string]struct{}) {
+	markExporters(aggStatus, failing, errExporterSendFailed)
+}
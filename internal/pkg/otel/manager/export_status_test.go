@@ -0,0 +1,72 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	otelstatus "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componentstatus"
+)
+
+const testSendMetrics = `# HELP otelcol_exporter_send_failed_log_records Number of log records in failed attempts to send to destination.
+# TYPE otelcol_exporter_send_failed_log_records counter
+otelcol_exporter_send_failed_log_records{exporter="otlp/elastic",otel_scope_name="go.opentelemetry.io/collector/exporter/exporterhelper"} 10
+otelcol_exporter_send_failed_spans{exporter="otlp/elastic",otel_scope_name="go.opentelemetry.io/collector/exporter/exporterhelper"} 5
+otelcol_exporter_sent_log_records{exporter="otlp/elastic",otel_scope_name="go.opentelemetry.io/collector/exporter/exporterhelper"} 2
+otelcol_exporter_sent_log_records{exporter="elasticsearch",otel_scope_name="go.opentelemetry.io/collector/exporter/exporterhelper"} 12
+otelcol_exporter_queue_size{data_type="logs",exporter="elasticsearch"} 0
+`
+
+func TestExporterSendsFrom(t *testing.T) {
+	families, err := parseCollectorMetrics(strings.NewReader(testSendMetrics))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]exporterSends{
+		"otlp/elastic":  {sent: 2, failed: 15},
+		"elasticsearch": {sent: 12},
+	}, exporterSendsFrom(families))
+}
+
+func TestExportFailures(t *testing.T) {
+	failures := newExportFailures(2)
+
+	assert.Empty(t, failures.update(map[string]exporterSends{"otlp": {sent: 1, failed: 1}}))
+	// failed once
+	assert.Empty(t, failures.update(map[string]exporterSends{"otlp": {sent: 1, failed: 2}}))
+	// retrying, no change
+	assert.Empty(t, failures.update(map[string]exporterSends{"otlp": {sent: 1, failed: 2}}))
+	// failed twice
+	assert.Equal(t, map[string]struct{}{"otlp": {}}, failures.update(map[string]exporterSends{"otlp": {sent: 1, failed: 3}}))
+	// still failing while nothing is sent
+	assert.Equal(t, map[string]struct{}{"otlp": {}}, failures.update(map[string]exporterSends{"otlp": {sent: 1, failed: 3}}))
+	// recovered
+	assert.Empty(t, failures.update(map[string]exporterSends{"otlp": {sent: 2, failed: 4}}))
+	// collector restarted, counters reset
+	assert.Empty(t, failures.update(map[string]exporterSends{"otlp": {sent: 0, failed: 0}}))
+}
+
+func TestMarkFailingExporters(t *testing.T) {
+	aggStatus := &otelstatus.AggregateStatus{
+		Event: componentstatus.NewEvent(componentstatus.StatusOK),
+		ComponentStatusMap: map[string]*otelstatus.AggregateStatus{
+			"pipeline:traces": {
+				Event: componentstatus.NewEvent(componentstatus.StatusOK),
+				ComponentStatusMap: map[string]*otelstatus.AggregateStatus{
+					"exporter:otlp/elastic": {Event: componentstatus.NewEvent(componentstatus.StatusOK)},
+				},
+			},
+		},
+	}
+
+	markFailingExporters(aggStatus, map[string]struct{}{"otlp/elastic": {}})
+
+	traces := aggStatus.ComponentStatusMap["pipeline:traces"]
+	assert.Equal(t, componentstatus.StatusRecoverableError, aggStatus.Status())
+	assert.Equal(t, componentstatus.StatusRecoverableError, traces.Status())
+	assert.ErrorIs(t, traces.ComponentStatusMap["exporter:otlp/elastic"].Err(), errExporterSendFailed)
+}
@@ -87,9 +87,18 @@ type OTelManager struct {
 
 	healthCheckExtComponentID string
 	collectorMetricsPort      int
+	selfMetrics               configuration.CollectorSelfMetricsConfig
 	collectorCfg              *confmap.Conf
 	components                []component.Component
 
+	// lastCfgUpdate is the last configuration update received by the run loop, applied again when a
+	// pipeline is enabled or disabled.
+	lastCfgUpdate configUpdate
+
+	// disabledPipelines are the pipelines disabled with SetPipelineEnabled, mapped to the hash of their
+	// definition, see removeDisabledPipelines.
+	disabledPipelines map[string][]byte
+
 	// The current configuration that the OTel collector is using. In the case that
 	// the mergedCollectorCfg is nil then the collector is not running.
 	mergedCollectorCfg     *confmap.Conf
@@ -99,7 +108,8 @@ type OTelManager struct {
 	currentComponentStates map[string]runtime.ComponentComponentState
 
 	// Update channels for forwarding updates to the run loop
-	updateCh chan configUpdate
+	updateCh   chan configUpdate
+	pipelineCh chan pipelineToggle
 
 	// Status channels for reading status from the run loop
 	collectorStatusCh chan *status.AggregateStatus
@@ -160,7 +170,9 @@ func NewOTelManager(
 	// determine the otel collector metrics port
 	collectorMetricsPort := 0
 	collectorHealthCheckPort := 0
+	var selfMetrics configuration.CollectorSelfMetricsConfig
 	if agentCollectorConfig != nil {
+		selfMetrics = agentCollectorConfig.SelfMetricsConfig
 		if agentCollectorConfig.HealthCheckConfig.Endpoint != "" {
 			collectorHealthCheckPort, err = agentCollectorConfig.HealthCheckConfig.Port()
 			if err != nil {
@@ -185,7 +197,12 @@ func NewOTelManager(
 	recoveryTimer = newRecoveryBackoff(100*time.Nanosecond, 10*time.Second, time.Minute)
 	if execFactory == nil {
 		execFactory = func(collectorPath string, healthCheckExtensionID string, healthCheckPort int) (collectorExecution, error) {
-			return newSubprocessExecution(collectorPath, healthCheckExtensionID, healthCheckPort)
+			subprocessExec, err := newSubprocessExecution(collectorPath, healthCheckExtensionID, healthCheckPort)
+			if err != nil {
+				return nil, err
+			}
+			subprocessExec.collectorMetricsPort = collectorMetricsPort
+			return subprocessExec, nil
 		}
 	}
 	exec, err = execFactory(executable, healthCheckExtComponentID, collectorHealthCheckPort)
@@ -200,12 +217,15 @@ func NewOTelManager(
 		beatMonitoringConfigGetter: beatMonitoringConfigGetter,
 		healthCheckExtComponentID:  healthCheckExtComponentID,
 		collectorMetricsPort:       collectorMetricsPort,
+		selfMetrics:                selfMetrics,
 		errCh:                      make(chan error, 1), // holds at most one error
 		collectorStatusCh:          make(chan *status.AggregateStatus, 1),
 		// componentStateCh uses a buffer channel to ensure that no state transitions are missed and to prevent
 		// any possible case of deadlock, 5 is used just to give a small buffer.
 		componentStateCh:  make(chan []runtime.ComponentComponentState, 5),
 		updateCh:          make(chan configUpdate, 1),
+		pipelineCh:        make(chan pipelineToggle),
+		disabledPipelines: make(map[string][]byte),
 		doneChan:          make(chan struct{}),
 		execution:         exec,
 		recoveryTimer:     recoveryTimer,
@@ -303,55 +323,15 @@ func (m *OTelManager) Run(ctx context.Context) error {
 			// and reset the retry count
 			m.recoveryTimer.Stop()
 			m.recoveryRetries.Store(0)
-			mergedCfg, err := m.buildMergedConfig(cfgUpdate, m.agentInfo, m.beatMonitoringConfigGetter, m.managerLogger)
-			if err != nil {
-				// critical error, merging the configuration should always work
-				reportErr(ctx, m.errCh, err)
-				continue
-			}
-
-			// this is the only place where we mutate the internal config attributes, take a write lock for the duration
-			m.mx.Lock()
-			previousConfigHash := m.mergedCollectorCfgHash
-			configChanged, configUpdateErr := m.maybeUpdateMergedConfig(mergedCfg)
-			m.collectorCfg = cfgUpdate.collectorCfg
-			m.components = cfgUpdate.components
-			lvl, err := newLogLevelAfterConfigUpdate(cfgUpdate, mergedCfg)
-			if err != nil {
-				m.managerLogger.Warnf("failed to determine new log level: %s", err)
-			} else {
-				m.collectorLogLevel = lvl
-			}
-			m.mx.Unlock()
+			m.applyConfigUpdate(ctx, cfgUpdate, collectorStatusCh, forceFetchStatusCh)
 
-			if configUpdateErr != nil {
-				m.managerLogger.Warn("failed to calculate hash of merged config, proceeding with update", zap.Error(configUpdateErr))
-			}
-
-			if configChanged {
-				m.managerLogger.Debugf(
-					"new config hash (%d) is different than the old config hash (%d), applying update",
-					m.mergedCollectorCfgHash, previousConfigHash)
-				applyErr := m.applyMergedConfig(ctx, collectorStatusCh, m.collectorRunErr, forceFetchStatusCh)
-				// only report the error if we actually apply the update
-				// otherwise, we could override an actual error with a nil in the channel when the collector
-				// state doesn't actually change
-				reportErr(ctx, m.errCh, applyErr)
-			} else {
-				m.managerLogger.Debugf(
-					"new config hash (%d) is identical to the old config hash (%d), skipping update",
-					m.mergedCollectorCfgHash, previousConfigHash)
-
-				// there was a config update, but the hash hasn't changed.
-				// Force fetch the latest collector status in case the user modified the output.status_reporting flag.
-				//
-				// drain the channel first
-				select {
-				case <-forceFetchStatusCh:
-				default:
-				}
-				forceFetchStatusCh <- struct{}{}
+		case toggle := <-m.pipelineCh:
+			changed, err := m.togglePipeline(toggle)
+			if err == nil && changed {
+				// the configuration is the same, so the recovery timer and the retry count are kept
+				m.applyConfigUpdate(ctx, m.lastCfgUpdate, collectorStatusCh, forceFetchStatusCh)
 			}
+			toggle.result <- err
 
 		case otelStatus := <-collectorStatusCh:
 			err = m.reportOtelStatusUpdate(ctx, otelStatus)
@@ -367,6 +347,67 @@ func (m *OTelManager) Run(ctx context.Context) error {
 	}
 }
 
+// applyConfigUpdate merges the configuration update and applies it to the collector when the merged
+// configuration changed.
+func (m *OTelManager) applyConfigUpdate(
+	ctx context.Context,
+	cfgUpdate configUpdate,
+	collectorStatusCh chan *status.AggregateStatus,
+	forceFetchStatusCh chan struct{},
+) {
+	m.lastCfgUpdate = cfgUpdate
+
+	mergedCfg, err := m.buildMergedConfig(cfgUpdate, m.agentInfo, m.beatMonitoringConfigGetter, m.managerLogger)
+	if err != nil {
+		// critical error, merging the configuration should always work
+		reportErr(ctx, m.errCh, err)
+		return
+	}
+
+	// this is the only place where we mutate the internal config attributes, take a write lock for the duration
+	m.mx.Lock()
+	previousConfigHash := m.mergedCollectorCfgHash
+	configChanged, configUpdateErr := m.maybeUpdateMergedConfig(mergedCfg)
+	m.collectorCfg = cfgUpdate.collectorCfg
+	m.components = cfgUpdate.components
+	lvl, err := newLogLevelAfterConfigUpdate(cfgUpdate, mergedCfg)
+	if err != nil {
+		m.managerLogger.Warnf("failed to determine new log level: %s", err)
+	} else {
+		m.collectorLogLevel = lvl
+	}
+	m.mx.Unlock()
+
+	if configUpdateErr != nil {
+		m.managerLogger.Warn("failed to calculate hash of merged config, proceeding with update", zap.Error(configUpdateErr))
+	}
+
+	if configChanged {
+		m.managerLogger.Debugf(
+			"new config hash (%d) is different than the old config hash (%d), applying update",
+			m.mergedCollectorCfgHash, previousConfigHash)
+		applyErr := m.applyMergedConfig(ctx, collectorStatusCh, m.collectorRunErr, forceFetchStatusCh)
+		// only report the error if we actually apply the update
+		// otherwise, we could override an actual error with a nil in the channel when the collector
+		// state doesn't actually change
+		reportErr(ctx, m.errCh, applyErr)
+	} else {
+		m.managerLogger.Debugf(
+			"new config hash (%d) is identical to the old config hash (%d), skipping update",
+			m.mergedCollectorCfgHash, previousConfigHash)
+
+		// there was a config update, but the hash hasn't changed.
+		// Force fetch the latest collector status in case the user modified the output.status_reporting flag.
+		//
+		// drain the channel first
+		select {
+		case <-forceFetchStatusCh:
+		default:
+		}
+		forceFetchStatusCh <- struct{}{}
+	}
+}
+
 // Errors returns channel that can send an error that affects the state of the running agent.
 func (m *OTelManager) Errors() <-chan error {
 	return m.errCh
@@ -484,6 +525,21 @@ func (m *OTelManager) buildMergedConfig(
 		if err != nil {
 			return nil, fmt.Errorf("failed to merge collector otel config: %w", err)
 		}
+
+		if m.selfMetrics.Enabled {
+			// Scrape the collector's own metrics, exposed by the metrics reader added below, into the
+			// user metrics pipelines.
+			err := injectSelfMetricsReceiver(mergedOtelCfg, m.collectorMetricsPort, m.selfMetrics.Interval)
+			if err != nil {
+				return nil, fmt.Errorf("failed to inject self metrics receiver: %w", err)
+			}
+		}
+	}
+
+	// Drop the pipelines disabled with SetPipelineEnabled
+	mergedOtelCfg, err := m.removeDisabledPipelines(mergedOtelCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove disabled pipelines: %w", err)
 	}
 
 	if err := injectDiagnosticsExtension(mergedOtelCfg); err != nil {
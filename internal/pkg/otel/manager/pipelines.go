@@ -0,0 +1,163 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+var errManagerStopped = errors.New("otel manager is stopped")
+
+// pipelineToggle is a request to enable or disable a pipeline, handled by the run loop.
+type pipelineToggle struct {
+	name    string
+	enabled bool
+	result  chan error
+}
+
+// SetPipelineEnabled starts or stops the pipeline of the collector configuration with the given name,
+// e.g. "logs/custom", without changing the configuration. A disabled pipeline is removed from the
+// configuration the collector runs and stays disabled until it is enabled again, or until a configuration
+// update modifies or removes it.
+func (m *OTelManager) SetPipelineEnabled(ctx context.Context, name string, enabled bool) error {
+	toggle := pipelineToggle{name: name, enabled: enabled, result: make(chan error, 1)}
+	select {
+	case m.pipelineCh <- toggle:
+	case <-m.doneChan:
+		return errManagerStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-toggle.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DisabledPipelines returns the names of the pipelines disabled with SetPipelineEnabled, sorted.
+func (m *OTelManager) DisabledPipelines() []string {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+	return slices.Sorted(maps.Keys(m.disabledPipelines))
+}
+
+// togglePipeline updates the set of disabled pipelines for the toggle. It returns false when the pipeline is
+// already in the requested state and the configuration doesn't need to be applied again.
+func (m *OTelManager) togglePipeline(toggle pipelineToggle) (bool, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	_, disabled := m.disabledPipelines[toggle.name]
+	if toggle.enabled {
+		if !disabled {
+			return false, nil
+		}
+		delete(m.disabledPipelines, toggle.name)
+		return true, nil
+	}
+	if disabled {
+		return false, nil
+	}
+
+	if m.collectorCfg == nil || m.mergedCollectorCfg == nil || !m.collectorCfg.IsSet("service::pipelines::"+toggle.name) {
+		return false, fmt.Errorf("pipeline %q is not defined in the collector configuration", toggle.name)
+	}
+	pipelines, _ := m.mergedCollectorCfg.Get("service::pipelines").(map[string]any)
+	if len(pipelines) <= 1 {
+		return false, fmt.Errorf("pipeline %q can't be disabled, the collector needs at least one pipeline", toggle.name)
+	}
+	if connector := pipelineConnector(m.mergedCollectorCfg, toggle.name); connector != "" {
+		return false, fmt.Errorf("pipeline %q can't be disabled, it uses connector %q which must be used by two pipelines", toggle.name, connector)
+	}
+
+	// the hash of the pipeline is recorded by removeDisabledPipelines when the configuration is merged
+	if m.disabledPipelines == nil {
+		m.disabledPipelines = make(map[string][]byte)
+	}
+	m.disabledPipelines[toggle.name] = nil
+	return true, nil
+}
+
+// pipelineConnector returns the first connector used as a receiver or an exporter by the pipeline, or an
+// empty string when the pipeline doesn't use connectors.
+func pipelineConnector(config *confmap.Conf, name string) string {
+	connectors, _ := config.Get("connectors").(map[string]any)
+	if len(connectors) == 0 {
+		return ""
+	}
+	for _, kind := range []string{"receivers", "exporters"} {
+		var ids []string
+		switch v := config.Get("service::pipelines::" + name + "::" + kind).(type) {
+		case []any:
+			for _, id := range v {
+				if id, ok := id.(string); ok {
+					ids = append(ids, id)
+				}
+			}
+		case []string:
+			ids = v
+		}
+		for _, id := range ids {
+			if _, isConnector := connectors[id]; isConnector {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// removeDisabledPipelines returns the configuration without the disabled pipelines. A pipeline which
+// definition changed since it was disabled, or which was removed from the configuration, is enabled again.
+func (m *OTelManager) removeDisabledPipelines(config *confmap.Conf) (*confmap.Conf, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if len(m.disabledPipelines) == 0 {
+		return config, nil
+	}
+
+	pipelines, _ := config.Get("service::pipelines").(map[string]any)
+	removed := false
+	for name, disabledHash := range m.disabledPipelines {
+		pipeline, ok := pipelines[name]
+		if !ok {
+			m.managerLogger.Infof("pipeline %q was removed from the configuration, it is no longer disabled", name)
+			delete(m.disabledPipelines, name)
+			continue
+		}
+		hash, err := calculateConfmapHash(confmap.NewFromStringMap(map[string]any{name: pipeline}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate hash of pipeline %q: %w", name, err)
+		}
+		switch {
+		case disabledHash == nil:
+			m.disabledPipelines[name] = hash
+		case !bytes.Equal(hash, disabledHash):
+			m.managerLogger.Infof("pipeline %q changed in the configuration, it is no longer disabled", name)
+			delete(m.disabledPipelines, name)
+			continue
+		}
+		delete(pipelines, name)
+		removed = true
+	}
+	if !removed {
+		return config, nil
+	}
+
+	raw := config.ToStringMap()
+	service, _ := raw["service"].(map[string]any)
+	service["pipelines"] = pipelines
+	return confmap.NewFromStringMap(raw), nil
+}
@@ -0,0 +1,160 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestOTelManager_togglePipeline(t *testing.T) {
+	newManager := func(cfg map[string]any) *OTelManager {
+		return &OTelManager{
+			collectorCfg:       confmap.NewFromStringMap(cfg),
+			mergedCollectorCfg: confmap.NewFromStringMap(cfg),
+		}
+	}
+
+	t.Run("disable and enable", func(t *testing.T) {
+		m := newManager(testConfig)
+
+		changed, err := m.togglePipeline(pipelineToggle{name: "logs", enabled: false})
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, []string{"logs"}, m.DisabledPipelines())
+
+		changed, err = m.togglePipeline(pipelineToggle{name: "logs", enabled: false})
+		require.NoError(t, err)
+		assert.False(t, changed, "the pipeline is already disabled")
+
+		changed, err = m.togglePipeline(pipelineToggle{name: "logs", enabled: true})
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Empty(t, m.DisabledPipelines())
+
+		changed, err = m.togglePipeline(pipelineToggle{name: "logs", enabled: true})
+		require.NoError(t, err)
+		assert.False(t, changed, "the pipeline is already enabled")
+	})
+
+	t.Run("unknown pipeline", func(t *testing.T) {
+		m := newManager(testConfig)
+		_, err := m.togglePipeline(pipelineToggle{name: "logs/unknown", enabled: false})
+		assert.ErrorContains(t, err, `pipeline "logs/unknown" is not defined in the collector configuration`)
+
+		_, err = (&OTelManager{}).togglePipeline(pipelineToggle{name: "logs", enabled: false})
+		assert.Error(t, err, "no collector configuration")
+	})
+
+	t.Run("last pipeline", func(t *testing.T) {
+		m := newManager(map[string]any{
+			"receivers": map[string]any{"nop": map[string]any{}},
+			"exporters": map[string]any{"nop": map[string]any{}},
+			"service": map[string]any{
+				"pipelines": map[string]any{
+					"logs": map[string]any{
+						"receivers": []string{"nop"},
+						"exporters": []string{"nop"},
+					},
+				},
+			},
+		})
+		_, err := m.togglePipeline(pipelineToggle{name: "logs", enabled: false})
+		assert.ErrorContains(t, err, "the collector needs at least one pipeline")
+		assert.Empty(t, m.DisabledPipelines())
+	})
+
+	t.Run("pipeline using a connector", func(t *testing.T) {
+		m := newManager(map[string]any{
+			"receivers":  map[string]any{"otlp": map[string]any{}},
+			"connectors": map[string]any{"spanmetrics": map[string]any{}},
+			"exporters":  map[string]any{"debug": map[string]any{}},
+			"service": map[string]any{
+				"pipelines": map[string]any{
+					"traces": map[string]any{
+						"receivers": []string{"otlp"},
+						"exporters": []string{"spanmetrics"},
+					},
+					"metrics": map[string]any{
+						"receivers": []string{"spanmetrics"},
+						"exporters": []string{"debug"},
+					},
+					"logs": map[string]any{
+						"receivers": []string{"otlp"},
+						"exporters": []string{"debug"},
+					},
+				},
+			},
+		})
+		_, err := m.togglePipeline(pipelineToggle{name: "traces", enabled: false})
+		assert.ErrorContains(t, err, `it uses connector "spanmetrics"`)
+		_, err = m.togglePipeline(pipelineToggle{name: "metrics", enabled: false})
+		assert.ErrorContains(t, err, `it uses connector "spanmetrics"`)
+
+		changed, err := m.togglePipeline(pipelineToggle{name: "logs", enabled: false})
+		require.NoError(t, err)
+		assert.True(t, changed)
+	})
+}
+
+func TestOTelManager_removeDisabledPipelines(t *testing.T) {
+	withLogsExporter := func(exporter string) *confmap.Conf {
+		conf := confmap.NewFromStringMap(testConfig)
+		require.NoError(t, conf.Merge(confmap.NewFromStringMap(map[string]any{
+			"service::pipelines::logs::exporters": []string{exporter},
+		})))
+		return conf
+	}
+
+	l, _ := loggertest.New("otel")
+	m := &OTelManager{
+		managerLogger:     l,
+		disabledPipelines: map[string][]byte{"logs": nil},
+	}
+
+	// the pipeline is removed and its definition recorded
+	conf, err := m.removeDisabledPipelines(withLogsExporter("nop"))
+	require.NoError(t, err)
+	assert.False(t, conf.IsSet("service::pipelines::logs"))
+	assert.True(t, conf.IsSet("service::pipelines::metrics"))
+	assert.True(t, conf.IsSet("service::pipelines::traces"))
+	assert.True(t, conf.IsSet("service::telemetry::logs::level"), "the rest of the configuration is kept")
+	require.Contains(t, m.disabledPipelines, "logs")
+	assert.NotNil(t, m.disabledPipelines["logs"])
+
+	// a configuration update which doesn't change the pipeline keeps it disabled
+	conf, err = m.removeDisabledPipelines(withLogsExporter("nop"))
+	require.NoError(t, err)
+	assert.False(t, conf.IsSet("service::pipelines::logs"))
+	assert.Equal(t, []string{"logs"}, m.DisabledPipelines())
+
+	// a configuration update which changes the pipeline enables it again
+	conf, err = m.removeDisabledPipelines(withLogsExporter("debug"))
+	require.NoError(t, err)
+	assert.True(t, conf.IsSet("service::pipelines::logs"))
+	assert.Empty(t, m.DisabledPipelines())
+
+	// a pipeline removed from the configuration is no longer disabled
+	m.disabledPipelines["logs/removed"] = nil
+	conf, err = m.removeDisabledPipelines(withLogsExporter("nop"))
+	require.NoError(t, err)
+	assert.True(t, conf.IsSet("service::pipelines::logs"))
+	assert.Empty(t, m.DisabledPipelines())
+}
+
+func TestOTelManager_SetPipelineEnabledStopped(t *testing.T) {
+	m := &OTelManager{
+		pipelineCh: make(chan pipelineToggle),
+		doneChan:   make(chan struct{}),
+	}
+	close(m.doneChan)
+	assert.ErrorIs(t, m.SetPipelineEnabled(context.Background(), "logs", false), errManagerStopped)
+}
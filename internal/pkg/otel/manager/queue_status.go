@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package manager
+
+import (
+	"errors"
+	"strings"
+
+	otelstatus "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/status"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/collector/component/componentstatus"
+)
+
+const (
+	// exporterhelper sending queue metrics, exposed without type suffix by the metrics reader added
+	// with addCollectorMetricsReader
+	exporterQueueSizeMetric     = "otelcol_exporter_queue_size"
+	exporterQueueCapacityMetric = "otelcol_exporter_queue_capacity"
+)
+
+// errExporterQueueFull is the status error of an exporter whose sending queue is full.
+var errExporterQueueFull = errors.New("sending queue is full, the exporter is not keeping up or is backing off")
+
+type exporterQueue struct {
+	exporter string
+	size     float64
+	capacity float64
+}
+
+// saturatedExporters returns the exporters with a full sending queue out of the collector metrics.
+// A queue is identified by the labels of its metrics, an exporter has one queue per signal.
+func saturatedExporters(families map[string]*dto.MetricFamily) map[string]struct{} {
+	queues := make(map[string]*exporterQueue)
+	for _, name := range []string{exporterQueueSizeMetric, exporterQueueCapacityMetric} {
+		for _, m := range families[name].GetMetric() {
+			labels := labelSet(m)
+			queue, ok := queues[labels]
+			if !ok {
+				queue = &exporterQueue{exporter: labelValue(m, exporterLabel)}
+				queues[labels] = queue
+			}
+			if name == exporterQueueSizeMetric {
+				queue.size = metricValue(m)
+			} else {
+				queue.capacity = metricValue(m)
+			}
+		}
+	}
+
+	saturated := make(map[string]struct{})
+	for _, queue := range queues {
+		if queue.exporter != "" && queue.capacity > 0 && queue.size >= queue.capacity {
+			saturated[queue.exporter] = struct{}{}
+		}
+	}
+	return saturated
+}
+
+// markSaturatedExporters reports a recoverable error for every healthy exporter with a full sending queue,
+// degrading the pipelines it belongs to and the collector as a whole.
+func markSaturatedExporters(aggStatus *otelstatus.AggregateStatus, saturated map[string]struct{}) {
+	markExporters(aggStatus, saturated, errExporterQueueFull)
+}
+
+// markExporters reports err as a recoverable error for every healthy exporter in exporters,
+// degrading the pipelines it belongs to and the collector as a whole.
+func markExporters(aggStatus *otelstatus.AggregateStatus, exporters map[string]struct{}, err error) {
+	if aggStatus == nil || len(exporters) == 0 {
+		return
+	}
+	for pipelineStatusID, pipelineStatus := range aggStatus.ComponentStatusMap {
+		if pipelineStatusID == "extensions" {
+			continue
+		}
+		for compID, compStatus := range pipelineStatus.ComponentStatusMap {
+			exporterID, isExporter := strings.CutPrefix(compID, "exporter:")
+			if !isExporter || compStatus.Status() != componentstatus.StatusOK {
+				continue
+			}
+			if _, found := exporters[exporterID]; !found {
+				continue
+			}
+			compStatus.Event = componentstatus.NewRecoverableErrorEvent(err)
+			if pipelineStatus.Status() == componentstatus.StatusOK {
+				pipelineStatus.Event = componentstatus.NewRecoverableErrorEvent(err)
+			}
+			if aggStatus.Status() == componentstatus.StatusOK {
+				aggStatus.Event = componentstatus.NewRecoverableErrorEvent(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	otelstatus "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componentstatus"
+)
+
+const testQueueMetrics = `# HELP otelcol_exporter_queue_capacity Fixed capacity of the retry queue (in batches).
+# TYPE otelcol_exporter_queue_capacity gauge
+otelcol_exporter_queue_capacity{data_type="logs",exporter="elasticsearch/full",otel_scope_name="go.opentelemetry.io/collector/exporter/exporterhelper"} 1000
+otelcol_exporter_queue_capacity{data_type="metrics",exporter="elasticsearch/full",otel_scope_name="go.opentelemetry.io/collector/exporter/exporterhelper"} 1000
+otelcol_exporter_queue_capacity{data_type="logs",exporter="elasticsearch/ok",otel_scope_name="go.opentelemetry.io/collector/exporter/exporterhelper"} 1000
+# HELP otelcol_exporter_queue_size Current size of the retry queue (in batches).
+# TYPE otelcol_exporter_queue_size gauge
+otelcol_exporter_queue_size{data_type="logs",exporter="elasticsearch/full",otel_scope_name="go.opentelemetry.io/collector/exporter/exporterhelper"} 1000
+otelcol_exporter_queue_size{data_type="metrics",exporter="elasticsearch/full",otel_scope_name="go.opentelemetry.io/collector/exporter/exporterhelper"} 3
+otelcol_exporter_queue_size{data_type="logs",exporter="elasticsearch/ok",otel_scope_name="go.opentelemetry.io/collector/exporter/exporterhelper"} 999
+otelcol_exporter_sent_log_records{exporter="elasticsearch/ok"} 12
+`
+
+func TestSaturatedExporters(t *testing.T) {
+	families, err := parseCollectorMetrics(strings.NewReader(testQueueMetrics))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]struct{}{"elasticsearch/full": {}}, saturatedExporters(families))
+
+	families, err = parseCollectorMetrics(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Empty(t, saturatedExporters(families))
+}
+
+func TestParseCollectorMetricsInvalid(t *testing.T) {
+	_, err := parseCollectorMetrics(strings.NewReader("otelcol_exporter_queue_size{exporter=\"debug\"} not-a-number\n"))
+	require.ErrorContains(t, err, "failed to parse metrics")
+}
+
+func TestMarkSaturatedExporters(t *testing.T) {
+	okStatus := func(components map[string]*otelstatus.AggregateStatus) *otelstatus.AggregateStatus {
+		return &otelstatus.AggregateStatus{
+			Event:              componentstatus.NewEvent(componentstatus.StatusOK),
+			ComponentStatusMap: components,
+		}
+	}
+	aggStatus := okStatus(map[string]*otelstatus.AggregateStatus{
+		"pipeline:logs": okStatus(map[string]*otelstatus.AggregateStatus{
+			"receiver:filelog":            okStatus(nil),
+			"exporter:elasticsearch/full": okStatus(nil),
+		}),
+		"pipeline:metrics": okStatus(map[string]*otelstatus.AggregateStatus{
+			"exporter:elasticsearch/ok": okStatus(nil),
+		}),
+		"extensions": okStatus(map[string]*otelstatus.AggregateStatus{
+			"extension:elasticsearch/full": okStatus(nil),
+		}),
+	})
+
+	markSaturatedExporters(aggStatus, map[string]struct{}{"elasticsearch/full": {}})
+
+	logs := aggStatus.ComponentStatusMap["pipeline:logs"]
+	assert.Equal(t, componentstatus.StatusRecoverableError, aggStatus.Status())
+	assert.Equal(t, componentstatus.StatusRecoverableError, logs.Status())
+	assert.ErrorIs(t, logs.ComponentStatusMap["exporter:elasticsearch/full"].Err(), errExporterQueueFull)
+	assert.Equal(t, componentstatus.StatusOK, logs.ComponentStatusMap["receiver:filelog"].Status())
+	assert.Equal(t, componentstatus.StatusOK, aggStatus.ComponentStatusMap["pipeline:metrics"].Status())
+	assert.Equal(t, componentstatus.StatusOK, aggStatus.ComponentStatusMap["extensions"].Status())
+}
@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	otelcomponent "go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/elastic/elastic-agent/internal/pkg/otel/translate"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+)
+
+const (
+	selfMetricsReceiverName = "self-metrics"
+	selfMetricsJobName      = "otelcol-self-metrics"
+
+	// selfMetricsKeepRegex selects the throughput and queue depth metrics of the collector.
+	selfMetricsKeepRegex = "otelcol_(receiver_(accepted|refused)|exporter_(sent|send_failed|queue_size|queue_capacity))_?.*"
+)
+
+var errSelfMetricsFIPS = errors.New("collector self metrics are not supported in FIPS distributions")
+
+// selfMetricsReceiverID returns the ID of the prometheus receiver scraping the collector's own metrics.
+func selfMetricsReceiverID() string {
+	return translate.GetReceiverID(otelcomponent.MustNewType("prometheus"), selfMetricsReceiverName).String()
+}
+
+// selfMetricsScrapeConfig returns the scrape configuration of the collector metrics reader listening on port.
+func selfMetricsScrapeConfig(port int, interval time.Duration) map[string]any {
+	scrapeConfig := map[string]any{
+		"job_name": selfMetricsJobName,
+		"static_configs": []any{
+			map[string]any{"targets": []any{fmt.Sprintf("localhost:%d", port)}},
+		},
+		"metric_relabel_configs": []any{
+			map[string]any{
+				"source_labels": []any{"__name__"},
+				"regex":         selfMetricsKeepRegex,
+				"action":        "keep",
+			},
+		},
+	}
+	if interval > 0 {
+		scrapeConfig["scrape_interval"] = interval.String()
+	}
+	return scrapeConfig
+}
+
+// injectSelfMetricsReceiver adds a prometheus receiver scraping the collector metrics reader listening on port
+// to every metrics pipeline of the user configuration. Port 0 is a placeholder replaced with
+// setSelfMetricsReceiverPort when the collector starts. Nothing is added when there is no metrics pipeline.
+func injectSelfMetricsReceiver(config *confmap.Conf, port int, interval time.Duration) error {
+	pipelines, _ := config.Get("service::pipelines").(map[string]any)
+	var metricsPipelines []string
+	for pipelineID := range pipelines {
+		signal, name, _ := strings.Cut(pipelineID, "/")
+		if signal != "metrics" || strings.HasPrefix(name, translate.OtelNamePrefix) {
+			continue
+		}
+		metricsPipelines = append(metricsPipelines, pipelineID)
+	}
+	if len(metricsPipelines) == 0 {
+		return nil
+	}
+	if release.FIPSDistribution() {
+		return errSelfMetricsFIPS
+	}
+
+	receiverID := selfMetricsReceiverID()
+	pipelinesCfg := map[string]any{}
+	for _, pipelineID := range metricsPipelines {
+		var receivers []any
+		switch v := config.Get("service::pipelines::" + pipelineID + "::receivers").(type) {
+		case []any:
+			receivers = slices.Clone(v)
+		case []string:
+			for _, r := range v {
+				receivers = append(receivers, r)
+			}
+		default:
+			return fmt.Errorf("couldn't convert value of service::pipelines::%s::receivers to a list: %v", pipelineID, v)
+		}
+		pipelinesCfg[pipelineID] = map[string]any{
+			"receivers": append(receivers, receiverID),
+		}
+	}
+	return config.Merge(confmap.NewFromStringMap(map[string]any{
+		"receivers": map[string]any{
+			receiverID: map[string]any{
+				"config": map[string]any{
+					"scrape_configs": []any{selfMetricsScrapeConfig(port, interval)},
+				},
+			},
+		},
+		"service": map[string]any{
+			"pipelines": pipelinesCfg,
+		},
+	}))
+}
+
+// setSelfMetricsReceiverPort returns a copy of conf where the self metrics receiver added by
+// injectSelfMetricsReceiver scrapes the collector metrics reader listening on port. conf is returned
+// as is when it has no self metrics receiver.
+func setSelfMetricsReceiverPort(conf *confmap.Conf, port int) (*confmap.Conf, error) {
+	key := "receivers::" + selfMetricsReceiverID() + "::config::scrape_configs"
+	scrapeConfigs, ok := conf.Get(key).([]any)
+	if !ok || len(scrapeConfigs) != 1 {
+		return conf, nil
+	}
+	scrapeConfig, ok := scrapeConfigs[0].(map[string]any)
+	if !ok || scrapeConfig["job_name"] != selfMetricsJobName {
+		return conf, nil
+	}
+	updated := maps.Clone(scrapeConfig)
+	updated["static_configs"] = []any{
+		map[string]any{"targets": []any{fmt.Sprintf("localhost:%d", port)}},
+	}
+	confCopy := confmap.NewFromStringMap(conf.ToStringMap())
+	if err := confCopy.Merge(confmap.NewFromStringMap(map[string]any{
+		key: []any{updated},
+	})); err != nil {
+		return nil, fmt.Errorf("failed to set the self metrics receiver port: %w", err)
+	}
+	return confCopy, nil
+}
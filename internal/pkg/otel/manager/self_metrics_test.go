@@ -0,0 +1,111 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/logp/logptest"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+)
+
+func TestInjectSelfMetricsReceiver(t *testing.T) {
+	if release.FIPSDistribution() {
+		t.Skip("the prometheus receiver is not available in FIPS distributions")
+	}
+	receiverID := selfMetricsReceiverID()
+	assert.Equal(t, "prometheus/_agent-component/self-metrics", receiverID)
+
+	conf := confmap.NewFromStringMap(map[string]any{
+		"service": map[string]any{
+			"pipelines": map[string]any{
+				"logs":                        map[string]any{"receivers": []any{"filelog"}},
+				"metrics":                     map[string]any{"receivers": []string{"nop"}},
+				"metrics/custom":              map[string]any{"receivers": []any{"hostmetrics"}},
+				"metrics/_agent-component/cm": map[string]any{"receivers": []any{"component"}},
+			},
+		},
+	})
+	require.NoError(t, injectSelfMetricsReceiver(conf, 0, 10*time.Second))
+
+	assert.Equal(t, []any{selfMetricsScrapeConfig(0, 10*time.Second)}, conf.Get("receivers::"+receiverID+"::config::scrape_configs"))
+	assert.Equal(t, []any{"nop", receiverID}, conf.Get("service::pipelines::metrics::receivers"))
+	assert.Equal(t, []any{"hostmetrics", receiverID}, conf.Get("service::pipelines::metrics/custom::receivers"))
+	assert.Equal(t, []any{"filelog"}, conf.Get("service::pipelines::logs::receivers"))
+	assert.Equal(t, []any{"component"}, conf.Get("service::pipelines::metrics/_agent-component/cm::receivers"))
+
+	t.Run("no metrics pipeline", func(t *testing.T) {
+		conf := confmap.NewFromStringMap(map[string]any{
+			"service": map[string]any{
+				"pipelines": map[string]any{
+					"logs": map[string]any{"receivers": []any{"filelog"}},
+				},
+			},
+		})
+		require.NoError(t, injectSelfMetricsReceiver(conf, 0, 0))
+		assert.False(t, conf.IsSet("receivers"))
+	})
+}
+
+func TestSelfMetricsScrapeConfig(t *testing.T) {
+	scrapeConfig := selfMetricsScrapeConfig(8888, 0)
+	assert.NotContains(t, scrapeConfig, "scrape_interval")
+	assert.Equal(t, []any{map[string]any{"targets": []any{"localhost:8888"}}}, scrapeConfig["static_configs"])
+
+	scrapeConfig = selfMetricsScrapeConfig(8888, 30*time.Second)
+	assert.Equal(t, "30s", scrapeConfig["scrape_interval"])
+}
+
+func TestSetSelfMetricsReceiverPort(t *testing.T) {
+	key := "receivers::" + selfMetricsReceiverID() + "::config::scrape_configs"
+	conf := confmap.NewFromStringMap(map[string]any{
+		key: []any{selfMetricsScrapeConfig(0, time.Second)},
+	})
+
+	updated, err := setSelfMetricsReceiverPort(conf, 8888)
+	require.NoError(t, err)
+	assert.Equal(t, []any{selfMetricsScrapeConfig(8888, time.Second)}, updated.Get(key))
+	// the placeholder is kept in the original configuration
+	assert.Equal(t, []any{selfMetricsScrapeConfig(0, time.Second)}, conf.Get(key))
+
+	// configurations without the self metrics receiver are returned as is
+	empty := confmap.New()
+	updated, err = setSelfMetricsReceiverPort(empty, 8888)
+	require.NoError(t, err)
+	assert.Same(t, empty, updated)
+}
+
+func TestOTelManager_buildMergedConfigSelfMetrics(t *testing.T) {
+	if release.FIPSDistribution() {
+		t.Skip("the prometheus receiver is not available in FIPS distributions")
+	}
+	cfgUpdate := configUpdate{
+		collectorCfg:  confmap.NewFromStringMap(testConfig),
+		agentLogLevel: logp.InfoLevel,
+	}
+	receiverKey := "receivers::" + selfMetricsReceiverID()
+
+	mgr := &OTelManager{
+		healthCheckExtComponentID: "healthcheckv2/test-uuid",
+		collectorMetricsPort:      8888,
+	}
+	result, err := mgr.buildMergedConfig(cfgUpdate, &info.AgentInfo{}, mockBeatMonitoringConfigGetter, logptest.NewTestingLogger(t, ""))
+	require.NoError(t, err)
+	assert.False(t, result.IsSet(receiverKey), "self metrics receiver must not be added when disabled")
+
+	mgr.selfMetrics = configuration.CollectorSelfMetricsConfig{Enabled: true, Interval: 5 * time.Second}
+	result, err = mgr.buildMergedConfig(cfgUpdate, &info.AgentInfo{}, mockBeatMonitoringConfigGetter, logptest.NewTestingLogger(t, ""))
+	require.NoError(t, err)
+	assert.Equal(t, []any{selfMetricsScrapeConfig(8888, 5*time.Second)}, result.Get(receiverKey+"::config::scrape_configs"))
+	assert.Equal(t, []any{"nop", selfMetricsReceiverID()}, result.Get("service::pipelines::metrics::receivers"))
+}
@@ -5,15 +5,56 @@
 package v1
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"maps"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/pkg/version"
 )
 
 const (
 	ManifestKind     = "PackageManifest"
 	ManifestFileName = "manifest.yaml"
+
+	// PathMappingEnvPrefix is the prefix of the environment variables overriding the path
+	// mappings of the manifests, see PackageManifest.WithPathMappingOverrides.
+	PathMappingEnvPrefix = "AGENT_PATH_MAPPING_"
+
+	snapshotSuffix = "-SNAPSHOT"
+
+	// versionedHomePrefix prefixes the name of the versioned home directories, in the data
+	// directory of the package.
+	versionedHomePrefix = "elastic-agent-"
+	// versionedHomeHashLen is the length of the commit hash in the name of the versioned homes.
+	versionedHomeHashLen = 6
+)
+
+// gzipMagic are the first bytes of a gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+var (
+	// ErrEmptyManifest is returned when parsing a package manifest without any content.
+	ErrEmptyManifest = errors.New("package manifest is empty")
+	// ErrInvalidKind is returned when the kind of a package manifest is not ManifestKind.
+	ErrInvalidKind = errors.New("invalid package manifest kind")
+	// ErrUnsupportedVersion is returned when the version of a package manifest is not VERSION.
+	ErrUnsupportedVersion = errors.New("unsupported package manifest version")
 )
 
 type PackageDesc struct {
@@ -24,11 +65,132 @@ type PackageDesc struct {
 	VersionedHome string              `yaml:"versioned-home,omitempty" json:"versionedHome,omitempty"`
 	PathMappings  []map[string]string `yaml:"path-mappings,omitempty" json:"pathMappings,omitempty"`
 	Flavors       map[string][]string `yaml:"flavors,omitempty" json:"flavors,omitempty"`
+	// ComponentHomes maps a component name, e.g. "apm-server", to the
+	// slash-separated directory holding its files, relative to the top of the
+	// package, for components that don't live in the components directory of
+	// the versioned home. Use PackageManifest.ComponentHome to look them up.
+	ComponentHomes map[string]string `yaml:"component-homes,omitempty" json:"componentHomes,omitempty"`
+	// Checksums maps a hash algorithm (see SupportedChecksumAlgorithms) to
+	// the hex encoded digest of the package artifact.
+	Checksums map[string]string `yaml:"checksums,omitempty" json:"checksums,omitempty"`
+}
+
+// checksumHashers holds the hash constructors for the algorithms that can be
+// used in PackageDesc.Checksums.
+var checksumHashers = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// SupportedChecksumAlgorithms returns the sorted list of algorithms accepted
+// in PackageDesc.Checksums.
+func SupportedChecksumAlgorithms() []string {
+	algorithms := make([]string, 0, len(checksumHashers))
+	for algorithm := range checksumHashers {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+	return algorithms
+}
+
+// VerifyFile hashes the file at filename with every algorithm declared in
+// Checksums and compares the results with the declared digests. It fails if no
+// checksum is declared, if an algorithm is not supported or if any digest does
+// not match.
+func (d PackageDesc) VerifyFile(filename string) error {
+	if len(d.Checksums) == 0 {
+		return errors.New("package declares no checksums")
+	}
+
+	algorithms := make([]string, 0, len(d.Checksums))
+	for algorithm := range d.Checksums {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+
+	hashers := make([]hash.Hash, 0, len(algorithms))
+	writers := make([]io.Writer, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		newHasher, ok := checksumHashers[algorithm]
+		if !ok {
+			return fmt.Errorf("unsupported checksum algorithm %q, supported algorithms are: %s",
+				algorithm, strings.Join(SupportedChecksumAlgorithms(), ", "))
+		}
+		h := newHasher()
+		hashers = append(hashers, h)
+		writers = append(writers, h)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("opening %q for verification: %w", filename, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return fmt.Errorf("reading %q for verification: %w", filename, err)
+	}
+
+	for i, algorithm := range algorithms {
+		expected := d.Checksums[algorithm]
+		computed := hex.EncodeToString(hashers[i].Sum(nil))
+		if !strings.EqualFold(computed, expected) {
+			return fmt.Errorf("%s checksum mismatch for %q: expected %s, computed %s", algorithm, filename, expected, computed)
+		}
+	}
+	return nil
+}
+
+// SemVer parses the package version. Compare the result with the methods of
+// version.ParsedSemVer instead of comparing raw version strings.
+func (d PackageDesc) SemVer() (*version.ParsedSemVer, error) {
+	v, err := version.ParseVersion(d.Version)
+	if err != nil {
+		return nil, fmt.Errorf("parsing package version %q: %w", d.Version, err)
+	}
+	return v, nil
+}
+
+// IsSnapshotVersion reports whether the package is a snapshot, either because
+// the snapshot flag is set or because the version carries a SNAPSHOT
+// prerelease token (for example "8.12.0-SNAPSHOT").
+func (d PackageDesc) IsSnapshotVersion() bool {
+	if d.Snapshot {
+		return true
+	}
+	v, err := version.ParseVersion(d.Version)
+	if err != nil {
+		return false
+	}
+	_, snapshot := splitSnapshot(v)
+	return snapshot
+}
+
+// splitSnapshot removes the SNAPSHOT marker from v, reporting whether it was
+// present. Besides a standalone SNAPSHOT prerelease token, a "-SNAPSHOT" suffix
+// appended to another prerelease (for example "9.0.0-rc1-SNAPSHOT") is handled,
+// as that is how packaging qualifies snapshot builds.
+func splitSnapshot(v *version.ParsedSemVer) (string, bool) {
+	if v.BuildMetadata() == "" && strings.HasSuffix(v.Original(), snapshotSuffix) {
+		return strings.TrimSuffix(v.Original(), snapshotSuffix), true
+	}
+	return v.ExtractSnapshotFromVersionString()
+}
+
+func (d PackageDesc) isZero() bool {
+	return reflect.ValueOf(d).IsZero()
 }
 
 type PackageManifest struct {
 	apiObject `yaml:",inline"`
-	Package   PackageDesc `yaml:"package" json:"package"`
+	Package   PackageDesc `yaml:"package,omitempty" json:"package,omitzero"`
+	// Packages lists the packages of a bundle shipping more than one artifact.
+	// Use AllPackages to get both Package and Packages.
+	Packages []PackageDesc `yaml:"packages,omitempty" json:"packages,omitempty"`
+
+	// pathMappingOverrides are the path mappings read from the environment by
+	// WithPathMappingOverrides, they are never written.
+	pathMappingOverrides map[string]string
 }
 
 func NewManifest() *PackageManifest {
@@ -40,12 +202,579 @@ func NewManifest() *PackageManifest {
 	}
 }
 
+// NewManifestFromVersion returns a manifest for a package of the given version
+// and versioned home. A SNAPSHOT prerelease token in packageVersion sets the
+// snapshot flag and is removed from the stored version, matching how packaged
+// manifests record snapshots.
+func NewManifestFromVersion(packageVersion string, versionedHome string) (*PackageManifest, error) {
+	v, err := version.ParseVersion(packageVersion)
+	if err != nil {
+		return nil, fmt.Errorf("parsing package version %q: %w", packageVersion, err)
+	}
+	if err := validateRelativePath(versionedHome); err != nil {
+		return nil, fmt.Errorf("invalid versioned home: %w", err)
+	}
+
+	m := NewManifest()
+	m.Package.Version, m.Package.Snapshot = splitSnapshot(v)
+	m.Package.VersionedHome = versionedHome
+	return m, nil
+}
+
+func shortHash(hash string) string {
+	if len(hash) > versionedHomeHashLen {
+		return hash[:versionedHomeHashLen]
+	}
+	return hash
+}
+
+// ParseManifest parses a YAML-encoded package manifest. Unknown top-level keys
+// are rejected so that typos do not silently produce an empty manifest;
+// unknown keys nested in the package description are ignored to stay
+// compatible with manifests written by newer versions. The path mapping
+// overrides of the environment are not applied, see WithPathMappingOverrides.
+//
+// The returned error wraps ErrEmptyManifest, ErrInvalidKind or
+// ErrUnsupportedVersion when the manifest is empty or is not a version
+// VERSION manifest of kind ManifestKind.
 func ParseManifest(r io.Reader) (*PackageManifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading package manifest: %w", err)
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("decoding package manifest: %w", ErrEmptyManifest)
+	}
+
+	var topLevel map[string]interface{}
+	err = yaml.NewDecoder(bytes.NewReader(data)).Decode(&topLevel)
+	if errors.Is(err, io.EOF) || (err == nil && topLevel == nil) {
+		return nil, fmt.Errorf("decoding package manifest: %w", ErrEmptyManifest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding package manifest: %w", err)
+	}
+	if err := checkTopLevelKeys(topLevel, "yaml"); err != nil {
+		return nil, err
+	}
+
 	m := new(PackageManifest)
-	err := yaml.NewDecoder(r).Decode(m)
+	err = yaml.NewDecoder(bytes.NewReader(data)).Decode(m)
+	if err != nil {
+		return nil, fmt.Errorf("decoding package manifest: %w", err)
+	}
+	if err := m.checkKindAndVersion(); err != nil {
+		return nil, fmt.Errorf("decoding package manifest: %w", err)
+	}
+	return m, nil
+}
+
+// ParseManifestJSON parses a JSON-encoded package manifest. Like
+// ParseManifest, it rejects unknown top-level keys and returns the same errors.
+func ParseManifestJSON(r io.Reader) (*PackageManifest, error) {
+	var topLevel map[string]json.RawMessage
+	err := json.NewDecoder(r).Decode(&topLevel)
+	if errors.Is(err, io.EOF) || (err == nil && topLevel == nil) {
+		return nil, fmt.Errorf("decoding package manifest: %w", ErrEmptyManifest)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("decoding package manifest: %w", err)
 	}
+	if err := checkTopLevelKeys(topLevel, "json"); err != nil {
+		return nil, err
+	}
 
+	// re-encoding the already validated JSON document is cheap for a manifest
+	data, err := json.Marshal(topLevel)
+	if err != nil {
+		return nil, fmt.Errorf("decoding package manifest: %w", err)
+	}
+	m := new(PackageManifest)
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("decoding package manifest: %w", err)
+	}
+	if err := m.checkKindAndVersion(); err != nil {
+		return nil, fmt.Errorf("decoding package manifest: %w", err)
+	}
 	return m, nil
 }
+
+// checkTopLevelKeys returns an error naming the first key of topLevel, in
+// sorted order, that is not a PackageManifest field in the given encoding.
+func checkTopLevelKeys[V any](topLevel map[string]V, encoding string) error {
+	known := manifestKeys(encoding)
+	unknown := make([]string, 0)
+	for key := range topLevel {
+		if _, ok := known[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+
+	expected := make([]string, 0, len(known))
+	for key := range known {
+		expected = append(expected, key)
+	}
+	sort.Strings(expected)
+	return fmt.Errorf("decoding package manifest: unknown key %q, expected one of: %s", unknown[0], strings.Join(expected, ", "))
+}
+
+// manifestKeys returns the top-level keys of a PackageManifest, as named by the
+// struct tags of the given encoding, including the inlined apiObject fields.
+func manifestKeys(encoding string) map[string]struct{} {
+	keys := make(map[string]struct{})
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous {
+				collect(field.Type)
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get(encoding), ",")
+			if name != "" && name != "-" {
+				keys[name] = struct{}{}
+			}
+		}
+	}
+	collect(reflect.TypeOf(PackageManifest{}))
+	return keys
+}
+
+// ParseManifestAuto parses a package manifest that is either JSON or YAML
+// encoded, and optionally gzip compressed, e.g. a manifest.yaml.gz file. The
+// compression is detected by the gzip magic bytes and the format by peeking at
+// the first non-whitespace byte of the decompressed input: a '{' selects JSON,
+// anything else YAML. No input is consumed by the detection. A truncated or
+// corrupted gzip stream is reported as a decompression error.
+func ParseManifestAuto(r io.Reader) (*PackageManifest, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing gzip package manifest: %w", err)
+		}
+		defer gz.Close()
+		data, err := io.ReadAll(gz)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing gzip package manifest: %w", err)
+		}
+		br = bufio.NewReader(bytes.NewReader(data))
+	}
+	for n := 1; ; n++ {
+		peeked, err := br.Peek(n)
+		if err != nil {
+			// EOF or only whitespace fitting in the buffer: let the YAML
+			// decoder report on the input
+			return ParseManifest(br)
+		}
+		switch peeked[n-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{':
+			return ParseManifestJSON(br)
+		default:
+			return ParseManifest(br)
+		}
+	}
+}
+
+// ParseManifestStrict parses the manifest like ParseManifest and then validates
+// its contents, see PackageManifest.Validate.
+func ParseManifestStrict(r io.Reader) (*PackageManifest, error) {
+	m, err := ParseManifest(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("validating package manifest: %w", err)
+	}
+	return m, nil
+}
+
+// Validate checks that the manifest has the expected version and kind and that
+// every package description carries a semver version and a clean, relative
+// versioned home.
+func (m *PackageManifest) Validate() error {
+	if err := m.checkKindAndVersion(); err != nil {
+		return err
+	}
+	if len(m.Packages) == 0 || !m.Package.isZero() {
+		if err := validatePackageDesc("package", m.Package); err != nil {
+			return err
+		}
+	}
+	for i, d := range m.Packages {
+		if err := validatePackageDesc(fmt.Sprintf("packages[%d]", i), d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkKindAndVersion returns an error wrapping ErrInvalidKind or
+// ErrUnsupportedVersion when the manifest is not a version VERSION manifest of
+// kind ManifestKind.
+func (m *PackageManifest) checkKindAndVersion() error {
+	if m.Kind != ManifestKind {
+		return fmt.Errorf("%w: expected %q, got %q", ErrInvalidKind, ManifestKind, m.Kind)
+	}
+	if m.Version != VERSION {
+		return fmt.Errorf("%w: expected %q, got %q", ErrUnsupportedVersion, VERSION, m.Version)
+	}
+	return nil
+}
+
+// AllPackages returns the packages described by the manifest, whether they are
+// declared with the singular package key, the plural packages key or both. The
+// singular package, if set, comes first.
+func (m *PackageManifest) AllPackages() []PackageDesc {
+	all := make([]PackageDesc, 0, len(m.Packages)+1)
+	if !m.Package.isZero() {
+		all = append(all, m.Package)
+	}
+	return append(all, m.Packages...)
+}
+
+// CanUpgradeFrom reports whether the Elastic Agent packaged with other can be
+// upgraded to the package of m, comparing the first package returned by
+// AllPackages of each manifest. When the upgrade is not allowed, the second
+// return value explains why.
+//
+// The upgrade is rejected when:
+//   - a package version is missing or is not a semantic version,
+//   - both packages are the same build: same version, snapshot flag and hash,
+//   - the FIPS capability of the packages differ,
+//   - it is a downgrade to an older major version.
+//
+// A snapshot is older than the release of the same version, so upgrading from
+// 9.1.0-SNAPSHOT to 9.1.0 is allowed, as is upgrading between two snapshots of
+// the same version with different hashes. Other downgrades within a major
+// version are allowed.
+func (m *PackageManifest) CanUpgradeFrom(other *PackageManifest) (bool, string) {
+	if other == nil {
+		return false, "the current package manifest is missing"
+	}
+	current, ok := other.firstPackage()
+	if !ok {
+		return false, "the current package manifest describes no package"
+	}
+	target, ok := m.firstPackage()
+	if !ok {
+		return false, "the target package manifest describes no package"
+	}
+	currentVersion, currentSnapshot, err := releaseVersion(current)
+	if err != nil {
+		return false, fmt.Sprintf("the current package version is invalid: %v", err)
+	}
+	targetVersion, targetSnapshot, err := releaseVersion(target)
+	if err != nil {
+		return false, fmt.Sprintf("the target package version is invalid: %v", err)
+	}
+	currentDesc := describeVersion(currentVersion, currentSnapshot)
+	targetDesc := describeVersion(targetVersion, targetSnapshot)
+
+	if currentVersion.Equal(*targetVersion) && currentSnapshot == targetSnapshot && current.Hash == target.Hash {
+		return false, fmt.Sprintf("the Elastic Agent is already at version %s", currentDesc)
+	}
+	if current.Fips && !target.Fips {
+		return false, fmt.Sprintf("the FIPS-capable version %s cannot be upgraded to the non-FIPS-capable version %s", currentDesc, targetDesc)
+	}
+	if !current.Fips && target.Fips {
+		return false, fmt.Sprintf("the non-FIPS-capable version %s cannot be upgraded to the FIPS-capable version %s", currentDesc, targetDesc)
+	}
+	if targetVersion.Major() < currentVersion.Major() {
+		return false, fmt.Sprintf("downgrading from version %s to the older major version %s is not supported", currentDesc, targetDesc)
+	}
+	return true, ""
+}
+
+// firstPackage returns the first package returned by AllPackages.
+func (m *PackageManifest) firstPackage() (PackageDesc, bool) {
+	packages := m.AllPackages()
+	if len(packages) == 0 {
+		return PackageDesc{}, false
+	}
+	return packages[0], true
+}
+
+// releaseVersion returns the version of d without its SNAPSHOT marker, and
+// whether d is a snapshot, see PackageDesc.IsSnapshotVersion.
+func releaseVersion(d PackageDesc) (*version.ParsedSemVer, bool, error) {
+	v, err := d.SemVer()
+	if err != nil {
+		return nil, false, err
+	}
+	release, snapshot := splitSnapshot(v)
+	if !snapshot {
+		return v, d.Snapshot, nil
+	}
+	releaseV, err := version.ParseVersion(release)
+	if err != nil {
+		return nil, false, fmt.Errorf("parsing package version %q: %w", release, err)
+	}
+	return releaseV, true, nil
+}
+
+func describeVersion(v *version.ParsedSemVer, snapshot bool) string {
+	if snapshot {
+		return v.Original() + snapshotSuffix
+	}
+	return v.Original()
+}
+
+func validatePackageDesc(field string, d PackageDesc) error {
+	if d.Version == "" {
+		return fmt.Errorf("%s.version: must not be empty", field)
+	}
+	if _, err := version.ParseVersion(d.Version); err != nil {
+		return fmt.Errorf("%s.version: %q is not a valid semantic version: %w", field, d.Version, err)
+	}
+	if err := validateRelativePath(d.VersionedHome); err != nil {
+		return fmt.Errorf("%s.versioned-home: %w", field, err)
+	}
+	names := make([]string, 0, len(d.ComponentHomes))
+	for name := range d.ComponentHomes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("%s.component-homes: component name must not be empty", field)
+		}
+		if err := validateRelativePath(d.ComponentHomes[name]); err != nil {
+			return fmt.Errorf("%s.component-homes.%s: %w", field, name, err)
+		}
+	}
+	return nil
+}
+
+// validateRelativePath checks that p is a non-empty, slash-separated relative
+// path that does not escape its root. A single trailing slash is accepted.
+func validateRelativePath(p string) error {
+	if p == "" {
+		return errors.New("must not be empty")
+	}
+	if path.IsAbs(p) || strings.Contains(p, "\\") {
+		return fmt.Errorf("%q is not a relative slash-separated path", p)
+	}
+	cleaned := path.Clean(p)
+	if cleaned != strings.TrimSuffix(p, "/") {
+		return fmt.Errorf("%q is not a clean path, expected %q", p, cleaned)
+	}
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return fmt.Errorf("%q must point inside the package", p)
+	}
+	return nil
+}
+
+// ResolvePath maps a logical, slash-separated path relative to the top of the
+// package to the path it is extracted to, by applying the package path
+// mappings as prefix substitutions. Prefixes match whole path elements, so
+// "data/elastic-agent-abc" does not match "data/elastic-agent-abcdef/...".
+//
+// The path mapping overrides, see WithPathMappingOverrides, are considered
+// first, then the mappings of every package returned by AllPackages, in that
+// order. Mappings are evaluated in order and the first mapping with a matching
+// prefix wins; within a single mapping the longest matching prefix is used. If
+// no prefix matches the path as given, the lookup is retried with the versioned
+// home of each package prepended, so paths relative to the versioned home (for
+// example "components/apm-server") resolve as well. Paths without a directory,
+// like "LICENSE.txt" or "manifest.yaml.sig", are files at the top of the
+// package and are never considered relative to a versioned home.
+//
+// The second return value reports whether a mapping was applied; when it is
+// false the logical path is returned unchanged.
+func (m *PackageManifest) ResolvePath(logical string) (string, bool) {
+	packages := m.AllPackages()
+	if mapped, ok := resolvePathMappings(m.overrideMappings(), logical); ok {
+		return mapped, true
+	}
+	for _, d := range packages {
+		if mapped, ok := resolvePathMappings(d.PathMappings, logical); ok {
+			return mapped, true
+		}
+	}
+	if !strings.Contains(logical, "/") {
+		return logical, false
+	}
+	for _, d := range packages {
+		if d.VersionedHome == "" || hasPathPrefix(logical, d.VersionedHome) {
+			continue
+		}
+		if mapped, ok := resolvePathMappings(append(m.overrideMappings(), d.PathMappings...), path.Join(d.VersionedHome, logical)); ok {
+			return mapped, true
+		}
+	}
+	return logical, false
+}
+
+// ComponentHome returns the directory holding the files of the named component,
+// as declared in the component homes of the first package returned by
+// AllPackages that declares it. The path mapping overrides and the path
+// mappings of the packages are applied like ResolvePath does, except that the
+// home is never considered relative to a versioned home. The path is
+// slash-separated and relative to the top of the package. The second return
+// value is false when no package declares a home for the component, its files
+// are then in the components directory of the versioned home.
+func (m *PackageManifest) ComponentHome(name string) (string, bool) {
+	packages := m.AllPackages()
+	for _, d := range packages {
+		home, ok := d.ComponentHomes[name]
+		if !ok {
+			continue
+		}
+		home = strings.TrimSuffix(home, "/")
+		if mapped, ok := resolvePathMappings(m.overrideMappings(), home); ok {
+			return mapped, true
+		}
+		for _, p := range packages {
+			if mapped, ok := resolvePathMappings(p.PathMappings, home); ok {
+				return mapped, true
+			}
+		}
+		return home, true
+	}
+	return "", false
+}
+
+// ExpectedVersionedHome returns the versioned home the package of version built
+// from the commit in Package.Hash is extracted to on the goos/goarch platform:
+// the versioned home declared by packaging, data/elastic-agent-<short hash>,
+// mapped by the path mappings packaging writes to
+// data/elastic-agent-<version>-<short hash>. The version includes the SNAPSHOT
+// marker of snapshot builds, e.g. "9.1.0-SNAPSHOT". Packaging uses the same
+// layout on every platform today, the platform is part of the signature so that
+// callers don't depend on it. The path is slash-separated and relative to the
+// top of the installation, it is empty when the manifest has no hash.
+func (m *PackageManifest) ExpectedVersionedHome(version, goos, goarch string) string {
+	if m.Package.Hash == "" {
+		return ""
+	}
+	return path.Join("data", versionedHomePrefix+version+"-"+shortHash(m.Package.Hash))
+}
+
+// WithPathMappingOverrides sets the path mapping overrides of m from environ,
+// in the format of os.Environ, replacing the ones previously set. They let a
+// deployment, e.g. a container, change where the files of the package are
+// without repackaging it. Each environment variable named PathMappingEnvPrefix
+// followed by any name, e.g. AGENT_PATH_MAPPING_COMPONENTS, holds one mapping
+// as <logical prefix>=<mapped path>, both slash-separated and relative to the
+// top of the package, for example:
+//
+//	data/elastic-agent-4f2d39/components=data/components
+//
+// An error is returned, and the overrides are left unchanged, when one of the
+// variables is malformed or two variables map the same prefix differently.
+//
+// The overrides take precedence over the path mappings of the manifest: they
+// replace a manifest mapping of the same prefix, and are evaluated before the
+// manifest mappings when resolving a path, so an override also wins over a
+// longer manifest prefix. The overrides are not written with the manifest.
+func (m *PackageManifest) WithPathMappingOverrides(environ []string) error {
+	overrides, err := pathMappingOverrides(environ)
+	if err != nil {
+		return err
+	}
+	m.pathMappingOverrides = overrides
+	return nil
+}
+
+// PathMappingOverrides returns the path mapping overrides set with
+// WithPathMappingOverrides.
+func (m *PackageManifest) PathMappingOverrides() map[string]string {
+	return maps.Clone(m.pathMappingOverrides)
+}
+
+// overrideMappings returns the path mapping overrides as a list of mappings,
+// empty when there are none, to be evaluated before the manifest mappings.
+func (m *PackageManifest) overrideMappings() []map[string]string {
+	if len(m.pathMappingOverrides) == 0 {
+		return nil
+	}
+	return []map[string]string{m.pathMappingOverrides}
+}
+
+// pathMappingOverrides parses the path mapping overrides set in environ, in the
+// format of os.Environ, see PackageManifest.WithPathMappingOverrides. It
+// returns nil when there are none.
+func pathMappingOverrides(environ []string) (map[string]string, error) {
+	var overrides map[string]string
+	sources := make(map[string]string)
+	for _, env := range environ {
+		name, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, PathMappingEnvPrefix) {
+			continue
+		}
+		logical, mapped, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid path mapping override %s=%q: expected <logical prefix>=<mapped path>", name, value)
+		}
+		if err := validateRelativePath(logical); err != nil {
+			return nil, fmt.Errorf("invalid path mapping override %s: logical prefix: %w", name, err)
+		}
+		if err := validateRelativePath(mapped); err != nil {
+			return nil, fmt.Errorf("invalid path mapping override %s: mapped path: %w", name, err)
+		}
+		logical = strings.TrimSuffix(logical, "/")
+		if other, ok := sources[logical]; ok && overrides[logical] != mapped {
+			return nil, fmt.Errorf("conflicting path mapping overrides %s and %s for %q", other, name, logical)
+		}
+		if overrides == nil {
+			overrides = make(map[string]string)
+		}
+		overrides[logical] = mapped
+		sources[logical] = name
+	}
+	return overrides, nil
+}
+
+func resolvePathMappings(mappings []map[string]string, logical string) (string, bool) {
+	for _, mapping := range mappings {
+		prefixes := make([]string, 0, len(mapping))
+		for prefix := range mapping {
+			if hasPathPrefix(logical, prefix) {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+		if len(prefixes) == 0 {
+			continue
+		}
+		// longest prefix first, ties broken lexically to stay deterministic
+		sort.Slice(prefixes, func(i, j int) bool {
+			if len(prefixes[i]) != len(prefixes[j]) {
+				return len(prefixes[i]) > len(prefixes[j])
+			}
+			return prefixes[i] < prefixes[j]
+		})
+		prefix := prefixes[0]
+		rel := strings.TrimPrefix(logical, strings.TrimSuffix(prefix, "/"))
+		return path.Join(mapping[prefix], rel), true
+	}
+	return logical, false
+}
+
+// hasPathPrefix reports whether the slash-separated path p is prefix or is
+// inside prefix. A trailing slash on prefix is ignored.
+func hasPathPrefix(p, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// Write encodes the manifest as YAML to w, including the version and kind
+// header. The output can be read back with ParseManifest.
+func (m *PackageManifest) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("encoding package manifest: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encoding package manifest: %w", err)
+	}
+	return nil
+}
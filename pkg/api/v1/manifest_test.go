@@ -5,10 +5,20 @@
 package v1
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEmptyManifest(t *testing.T) {
@@ -47,3 +57,754 @@ package:
 	assert.Equal(t, m.Package.VersionedHome, "data/elastic-agent-4f2d39/")
 	assert.Equal(t, m.Package.PathMappings, []map[string]string{{"data/elastic-agent-4f2d39/": "data/elastic-agent-8.12.0/", "foo": "bar"}, {"manifest.yaml": "data/elastic-agent-8.12.0/manifest.yaml"}})
 }
+
+func TestWriteManifest(t *testing.T) {
+	m := NewManifest()
+	m.Package.Version = "8.12.0"
+	m.Package.Hash = "4f2d39a1b2c3"
+	m.Package.VersionedHome = "data/elastic-agent-4f2d39"
+	m.Package.PathMappings = []map[string]string{{"data/elastic-agent-4f2d39": "data/elastic-agent-8.12.0-4f2d39"}}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, m.Write(buf))
+
+	out := buf.String()
+	assert.Contains(t, out, "version: "+VERSION)
+	assert.Contains(t, out, "kind: "+ManifestKind)
+	assert.NotContains(t, out, "snapshot:")
+	assert.NotContains(t, out, "fips:")
+	assert.NotContains(t, out, "flavors:")
+
+	parsed, err := ParseManifest(buf)
+	require.NoError(t, err)
+	assert.Equal(t, m, parsed)
+}
+
+func TestWriteManifestOmitsEmptyPathMappings(t *testing.T) {
+	m := NewManifest()
+	m.Package.Version = "8.12.0"
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, m.Write(buf))
+	assert.NotContains(t, buf.String(), "path-mappings")
+
+	parsed, err := ParseManifest(buf)
+	require.NoError(t, err)
+	assert.Equal(t, m, parsed)
+}
+
+func TestValidateManifest(t *testing.T) {
+	validManifest := func() *PackageManifest {
+		m := NewManifest()
+		m.Package.Version = "8.12.0-SNAPSHOT"
+		m.Package.VersionedHome = "data/elastic-agent-4f2d39/"
+		return m
+	}
+
+	testcases := []struct {
+		name     string
+		mutate   func(m *PackageManifest)
+		errorMsg string
+	}{
+		{
+			name:   "valid manifest",
+			mutate: func(m *PackageManifest) {},
+		},
+		{
+			name:     "wrong kind",
+			mutate:   func(m *PackageManifest) { m.Kind = "SomethingElse" },
+			errorMsg: "invalid package manifest kind:",
+		},
+		{
+			name:     "wrong api version",
+			mutate:   func(m *PackageManifest) { m.Version = "co.elastic.agent/v2" },
+			errorMsg: "unsupported package manifest version:",
+		},
+		{
+			name:     "empty package version",
+			mutate:   func(m *PackageManifest) { m.Package.Version = "" },
+			errorMsg: "package.version: must not be empty",
+		},
+		{
+			name:     "package version is not semver",
+			mutate:   func(m *PackageManifest) { m.Package.Version = "8.12" },
+			errorMsg: "package.version:",
+		},
+		{
+			name:     "empty versioned home",
+			mutate:   func(m *PackageManifest) { m.Package.VersionedHome = "" },
+			errorMsg: "package.versioned-home: must not be empty",
+		},
+		{
+			name:     "absolute versioned home",
+			mutate:   func(m *PackageManifest) { m.Package.VersionedHome = "/data/elastic-agent-4f2d39" },
+			errorMsg: "package.versioned-home:",
+		},
+		{
+			name:     "unclean versioned home",
+			mutate:   func(m *PackageManifest) { m.Package.VersionedHome = "data/../data/elastic-agent-4f2d39" },
+			errorMsg: "package.versioned-home:",
+		},
+		{
+			name:     "versioned home escaping the package",
+			mutate:   func(m *PackageManifest) { m.Package.VersionedHome = "../elastic-agent-4f2d39" },
+			errorMsg: "package.versioned-home:",
+		},
+		{
+			name:     "component home escaping the package",
+			mutate:   func(m *PackageManifest) { m.Package.ComponentHomes = map[string]string{"apm-server": "../apm-server"} },
+			errorMsg: "package.component-homes.apm-server:",
+		},
+		{
+			name:     "component home without name",
+			mutate:   func(m *PackageManifest) { m.Package.ComponentHomes = map[string]string{"": "data/apm-server"} },
+			errorMsg: "package.component-homes: component name must not be empty",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := validManifest()
+			tc.mutate(m)
+			err := m.Validate()
+			if tc.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.errorMsg)
+		})
+	}
+}
+
+func TestParseManifestStrict(t *testing.T) {
+	manifest := `
+version: co.elastic.agent/v1
+kind: PackageManifest
+package:
+  versioned-home: data/elastic-agent-4f2d39/
+`
+	_, err := ParseManifestStrict(strings.NewReader(manifest))
+	assert.ErrorContains(t, err, "package.version")
+
+	_, err = ParseManifest(strings.NewReader(manifest))
+	assert.NoError(t, err, "non-strict parsing must not validate the manifest")
+}
+
+const jsonManifest = `
+  {
+    "version": "co.elastic.agent/v1",
+    "kind": "PackageManifest",
+    "package": {
+      "version": "8.12.0",
+      "snapshot": true,
+      "versionedHome": "data/elastic-agent-4f2d39/",
+      "pathMappings": [
+        {"data/elastic-agent-4f2d39/": "data/elastic-agent-8.12.0-SNAPSHOT/"}
+      ]
+    }
+  }`
+
+func TestParseManifestJSON(t *testing.T) {
+	m, err := ParseManifestJSON(strings.NewReader(jsonManifest))
+	require.NoError(t, err)
+	assert.Equal(t, VERSION, m.Version)
+	assert.Equal(t, ManifestKind, m.Kind)
+	assert.Equal(t, "8.12.0", m.Package.Version)
+	assert.True(t, m.Package.Snapshot)
+	assert.Equal(t, "data/elastic-agent-4f2d39/", m.Package.VersionedHome)
+	assert.Equal(t, []map[string]string{{"data/elastic-agent-4f2d39/": "data/elastic-agent-8.12.0-SNAPSHOT/"}}, m.Package.PathMappings)
+}
+
+func TestParseManifestAuto(t *testing.T) {
+	yamlManifest := `
+version: co.elastic.agent/v1
+kind: PackageManifest
+package:
+  version: 8.12.0
+  snapshot: true
+  versioned-home: data/elastic-agent-4f2d39/
+  path-mappings:
+    - data/elastic-agent-4f2d39/: data/elastic-agent-8.12.0-SNAPSHOT/
+`
+	fromJSON, err := ParseManifestAuto(strings.NewReader(jsonManifest))
+	require.NoError(t, err)
+	fromYAML, err := ParseManifestAuto(strings.NewReader(yamlManifest))
+	require.NoError(t, err)
+	assert.Equal(t, fromJSON, fromYAML)
+
+	_, err = ParseManifestAuto(strings.NewReader(" \n\t"))
+	assert.Error(t, err, "whitespace only input is not a valid manifest")
+}
+
+func TestParseManifestAutoGzip(t *testing.T) {
+	gzipped := func(t *testing.T, data string) []byte {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+
+	expected, err := ParseManifestAuto(strings.NewReader(jsonManifest))
+	require.NoError(t, err)
+
+	fromJSON, err := ParseManifestAuto(bytes.NewReader(gzipped(t, jsonManifest)))
+	require.NoError(t, err)
+	assert.Equal(t, expected, fromJSON)
+
+	fromYAML, err := ParseManifestAuto(bytes.NewReader(gzipped(t, "version: co.elastic.agent/v1\nkind: PackageManifest\n")))
+	require.NoError(t, err)
+	assert.Equal(t, "PackageManifest", fromYAML.Kind)
+
+	t.Run("truncated", func(t *testing.T) {
+		data := gzipped(t, jsonManifest)
+		_, err := ParseManifestAuto(bytes.NewReader(data[:len(data)/2]))
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.ErrorContains(t, err, "decompressing gzip package manifest")
+	})
+
+	t.Run("corrupted header", func(t *testing.T) {
+		_, err := ParseManifestAuto(bytes.NewReader([]byte{0x1f, 0x8b, 0x00}))
+		assert.ErrorContains(t, err, "decompressing gzip package manifest")
+	})
+}
+
+func TestExpectedVersionedHome(t *testing.T) {
+	m := NewManifest()
+	assert.Empty(t, m.ExpectedVersionedHome("9.1.0", "linux", "amd64"), "no hash")
+
+	m.Package.Hash = "4f2d39ab0c1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a"
+	for _, platform := range [][2]string{{"linux", "amd64"}, {"linux", "arm64"}, {"darwin", "arm64"}, {"windows", "amd64"}} {
+		assert.Equal(t, "data/elastic-agent-9.1.0-4f2d39", m.ExpectedVersionedHome("9.1.0", platform[0], platform[1]))
+		assert.Equal(t, "data/elastic-agent-9.1.0-SNAPSHOT-4f2d39", m.ExpectedVersionedHome("9.1.0-SNAPSHOT", platform[0], platform[1]))
+	}
+	// hashes already short are used as is
+	m.Package.Hash = "abc"
+	assert.Equal(t, "data/elastic-agent-9.1.0-abc", m.ExpectedVersionedHome("9.1.0", "linux", "amd64"))
+}
+
+func TestResolvePath(t *testing.T) {
+	m := NewManifest()
+	m.Package.VersionedHome = "data/elastic-agent-4f2d39"
+	m.Package.PathMappings = []map[string]string{
+		{
+			"data/elastic-agent-4f2d39":            "data/elastic-agent-8.12.0-4f2d39",
+			"data/elastic-agent-4f2d39/components": "data/components",
+		},
+		{
+			"manifest.yaml": "data/elastic-agent-8.12.0-4f2d39/manifest.yaml",
+			"data":          "shadowed",
+		},
+	}
+
+	testcases := []struct {
+		logical  string
+		expected string
+		mapped   bool
+	}{
+		{logical: "manifest.yaml", expected: "data/elastic-agent-8.12.0-4f2d39/manifest.yaml", mapped: true},
+		{logical: "data/elastic-agent-4f2d39/elastic-agent", expected: "data/elastic-agent-8.12.0-4f2d39/elastic-agent", mapped: true},
+		{logical: "data/elastic-agent-4f2d39/components/apm-server", expected: "data/components/apm-server", mapped: true},
+		{logical: "components/apm-server", expected: "data/components/apm-server", mapped: true},
+		{logical: "data/other", expected: "shadowed/other", mapped: true},
+		{logical: "data/elastic-agent-4f2d39abc/elastic-agent", expected: "shadowed/elastic-agent-4f2d39abc/elastic-agent", mapped: true},
+		// files at the top of the package are not relative to the versioned home
+		{logical: "manifest.yaml.sig", expected: "manifest.yaml.sig", mapped: false},
+		{logical: "LICENSE.txt", expected: "LICENSE.txt", mapped: false},
+		{logical: "elastic-agent", expected: "elastic-agent", mapped: false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.logical, func(t *testing.T) {
+			resolved, ok := m.ResolvePath(tc.logical)
+			assert.Equal(t, tc.mapped, ok)
+			assert.Equal(t, tc.expected, resolved)
+		})
+	}
+
+	unmapped := NewManifest()
+	resolved, ok := unmapped.ResolvePath("LICENSE.txt")
+	assert.False(t, ok)
+	assert.Equal(t, "LICENSE.txt", resolved)
+}
+
+func TestResolvePathOverrides(t *testing.T) {
+	yamlManifest := `
+version: co.elastic.agent/v1
+kind: PackageManifest
+package:
+  version: 8.12.0
+  versioned-home: data/elastic-agent-4f2d39
+  path-mappings:
+    - data/elastic-agent-4f2d39: data/elastic-agent-8.12.0-4f2d39
+      data/elastic-agent-4f2d39/components/apm-server: data/apm-server
+      manifest.yaml: data/elastic-agent-8.12.0-4f2d39/manifest.yaml
+`
+	// the overrides of the process environment are only applied on demand
+	t.Setenv(PathMappingEnvPrefix+"COMPONENTS", "data/elastic-agent-4f2d39/components/=/run/components")
+	m, err := ParseManifest(strings.NewReader(yamlManifest))
+	require.NoError(t, err)
+	assert.Empty(t, m.PathMappingOverrides())
+
+	assert.ErrorContains(t, m.WithPathMappingOverrides(os.Environ()), "invalid path mapping override AGENT_PATH_MAPPING_COMPONENTS: mapped path")
+	assert.Empty(t, m.PathMappingOverrides(), "the overrides are unchanged on error")
+
+	require.NoError(t, m.WithPathMappingOverrides([]string{
+		"PATH=/usr/bin",
+		PathMappingEnvPrefix + "COMPONENTS=data/elastic-agent-4f2d39/components/=data/components",
+		PathMappingEnvPrefix + "MANIFEST=manifest.yaml=data/manifest.yaml",
+	}))
+	assert.Equal(t, map[string]string{
+		"data/elastic-agent-4f2d39/components": "data/components",
+		"manifest.yaml":                        "data/manifest.yaml",
+	}, m.PathMappingOverrides())
+
+	testcases := []struct {
+		logical  string
+		expected string
+	}{
+		// added by an override
+		{logical: "data/elastic-agent-4f2d39/components/filebeat", expected: "data/components/filebeat"},
+		{logical: "components/filebeat", expected: "data/components/filebeat"},
+		// overrides win over a manifest mapping of the same or a longer prefix
+		{logical: "manifest.yaml", expected: "data/manifest.yaml"},
+		{logical: "components/apm-server/apm-server", expected: "data/components/apm-server/apm-server"},
+		// not overridden
+		{logical: "data/elastic-agent-4f2d39/elastic-agent", expected: "data/elastic-agent-8.12.0-4f2d39/elastic-agent"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.logical, func(t *testing.T) {
+			resolved, ok := m.ResolvePath(tc.logical)
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, resolved)
+		})
+	}
+
+	// the overrides are not written
+	var buf bytes.Buffer
+	require.NoError(t, m.Write(&buf))
+	assert.NotContains(t, buf.String(), "data/components")
+}
+
+func TestPathMappingOverridesInvalid(t *testing.T) {
+	overrides, err := pathMappingOverrides([]string{"PATH=/usr/bin", "AGENT_PATH_MAPPINGS=a=b"})
+	require.NoError(t, err)
+	assert.Nil(t, overrides)
+
+	testcases := map[string][]string{
+		"expected <logical prefix>=<mapped path>": {"AGENT_PATH_MAPPING_A=data"},
+		"logical prefix: must not be empty":       {"AGENT_PATH_MAPPING_A==data"},
+		"must point inside the package":           {"AGENT_PATH_MAPPING_A=data=../data"},
+		"conflicting path mapping overrides AGENT_PATH_MAPPING_A and AGENT_PATH_MAPPING_B": {
+			"AGENT_PATH_MAPPING_A=data=other", "AGENT_PATH_MAPPING_B=data/=another",
+		},
+	}
+	for expected, environ := range testcases {
+		_, err := pathMappingOverrides(environ)
+		assert.ErrorContains(t, err, expected)
+	}
+}
+
+func TestResolvePathMultiplePackages(t *testing.T) {
+	m := NewManifest()
+	m.Packages = []PackageDesc{
+		{
+			Version:       "8.12.0",
+			VersionedHome: "data/elastic-agent-4f2d39",
+			PathMappings:  []map[string]string{{"data/elastic-agent-4f2d39": "data/elastic-agent-8.12.0-4f2d39"}},
+		},
+		{
+			Version:       "8.12.0",
+			VersionedHome: "data/apm-server-4f2d39",
+			PathMappings:  []map[string]string{{"data/apm-server-4f2d39/": "data/apm-server-8.12.0-4f2d39"}},
+		},
+	}
+
+	resolved, ok := m.ResolvePath("data/apm-server-4f2d39/apm-server")
+	assert.True(t, ok)
+	assert.Equal(t, "data/apm-server-8.12.0-4f2d39/apm-server", resolved)
+
+	// relative to the versioned home of the first package with a matching mapping
+	resolved, ok = m.ResolvePath("components/filebeat")
+	assert.True(t, ok)
+	assert.Equal(t, "data/elastic-agent-8.12.0-4f2d39/components/filebeat", resolved)
+}
+
+func TestComponentHome(t *testing.T) {
+	m := NewManifest()
+	m.Package.VersionedHome = "data/elastic-agent-4f2d39"
+	m.Package.PathMappings = []map[string]string{{"data/elastic-agent-4f2d39": "data/elastic-agent-8.12.0-4f2d39"}}
+	m.Packages = []PackageDesc{
+		{
+			Version:        "8.12.0",
+			VersionedHome:  "data/apm-server-4f2d39",
+			PathMappings:   []map[string]string{{"data/apm-server-4f2d39": "data/apm-server-8.12.0-4f2d39"}},
+			ComponentHomes: map[string]string{"apm-server": "data/apm-server-4f2d39/", "pf-host-agent": "opt/profiling"},
+		},
+		{
+			Version:        "8.12.0",
+			VersionedHome:  "data/other-4f2d39",
+			ComponentHomes: map[string]string{"apm-server": "data/other-4f2d39"},
+		},
+	}
+
+	home, ok := m.ComponentHome("apm-server")
+	assert.True(t, ok)
+	assert.Equal(t, "data/apm-server-8.12.0-4f2d39", home)
+
+	home, ok = m.ComponentHome("pf-host-agent")
+	assert.True(t, ok)
+	assert.Equal(t, "opt/profiling", home, "not relative to a versioned home")
+
+	_, ok = m.ComponentHome("filebeat")
+	assert.False(t, ok)
+}
+
+func TestPackageDescSemVer(t *testing.T) {
+	older, err := PackageDesc{Version: "8.9.0"}.SemVer()
+	require.NoError(t, err)
+	newer, err := PackageDesc{Version: "8.10.0"}.SemVer()
+	require.NoError(t, err)
+	assert.True(t, older.Less(*newer), "8.9.0 must sort before 8.10.0")
+
+	_, err = PackageDesc{Version: "not-a-version"}.SemVer()
+	assert.ErrorContains(t, err, "not-a-version")
+}
+
+func TestPackageDescIsSnapshotVersion(t *testing.T) {
+	testcases := []struct {
+		name     string
+		desc     PackageDesc
+		snapshot bool
+	}{
+		{name: "release", desc: PackageDesc{Version: "8.12.0"}, snapshot: false},
+		{name: "snapshot flag", desc: PackageDesc{Version: "8.12.0", Snapshot: true}, snapshot: true},
+		{name: "snapshot suffix", desc: PackageDesc{Version: "8.12.0-SNAPSHOT"}, snapshot: true},
+		{name: "snapshot flag and suffix", desc: PackageDesc{Version: "8.12.0-SNAPSHOT", Snapshot: true}, snapshot: true},
+		{name: "other prerelease", desc: PackageDesc{Version: "8.12.0-rc1"}, snapshot: false},
+		{name: "qualified snapshot suffix", desc: PackageDesc{Version: "9.0.0-rc1-SNAPSHOT"}, snapshot: true},
+		{name: "unparseable version", desc: PackageDesc{Version: "8.12"}, snapshot: false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.snapshot, tc.desc.IsSnapshotVersion())
+		})
+	}
+}
+
+func TestParseManifestMultiplePackages(t *testing.T) {
+	manifest := `
+version: co.elastic.agent/v1
+kind: PackageManifest
+packages:
+  - version: 8.12.0
+    versioned-home: data/elastic-agent-4f2d39
+  - version: 8.12.0
+    versioned-home: data/apm-server-4f2d39
+`
+	m, err := ParseManifestStrict(strings.NewReader(manifest))
+	require.NoError(t, err)
+	assert.Equal(t, []PackageDesc{
+		{Version: "8.12.0", VersionedHome: "data/elastic-agent-4f2d39"},
+		{Version: "8.12.0", VersionedHome: "data/apm-server-4f2d39"},
+	}, m.AllPackages())
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, m.Write(buf))
+	assert.NotContains(t, buf.String(), "package:", "an empty singular package must not be written")
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"package":`, "an empty singular package must not be encoded")
+	fromJSON, err := ParseManifestJSON(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, m.AllPackages(), fromJSON.AllPackages())
+}
+
+func TestAllPackages(t *testing.T) {
+	m := NewManifest()
+	assert.Empty(t, m.AllPackages())
+
+	m.Package = PackageDesc{Version: "8.12.0"}
+	assert.Equal(t, []PackageDesc{{Version: "8.12.0"}}, m.AllPackages())
+
+	m.Packages = []PackageDesc{{Version: "8.13.0"}}
+	assert.Equal(t, []PackageDesc{{Version: "8.12.0"}, {Version: "8.13.0"}}, m.AllPackages())
+
+	err := m.Validate()
+	assert.ErrorContains(t, err, "package.versioned-home")
+	m.Package.VersionedHome = "data/elastic-agent-4f2d39"
+	err = m.Validate()
+	assert.ErrorContains(t, err, "packages[0].versioned-home")
+}
+
+func TestPackageDescVerifyFile(t *testing.T) {
+	content := []byte("elastic-agent artifact")
+	artifact := filepath.Join(t.TempDir(), "elastic-agent.tar.gz")
+	require.NoError(t, os.WriteFile(artifact, content, 0o600))
+
+	sha256Sum := sha256.Sum256(content)
+	sha512Sum := sha512.Sum512(content)
+
+	testcases := []struct {
+		name      string
+		checksums map[string]string
+		errorMsg  string
+	}{
+		{
+			name: "matching checksums",
+			checksums: map[string]string{
+				"sha256": hex.EncodeToString(sha256Sum[:]),
+				"sha512": strings.ToUpper(hex.EncodeToString(sha512Sum[:])),
+			},
+		},
+		{
+			name:      "no checksums",
+			checksums: nil,
+			errorMsg:  "no checksums",
+		},
+		{
+			name: "mismatching checksum",
+			checksums: map[string]string{
+				"sha256": hex.EncodeToString(sha256Sum[:]),
+				"sha512": hex.EncodeToString(sha256Sum[:]),
+			},
+			errorMsg: "sha512 checksum mismatch",
+		},
+		{
+			name: "unsupported algorithm",
+			checksums: map[string]string{
+				"md5":    "d41d8cd98f00b204e9800998ecf8427e",
+				"sha256": hex.EncodeToString(sha256Sum[:]),
+			},
+			errorMsg: `unsupported checksum algorithm "md5"`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := PackageDesc{Checksums: tc.checksums}.VerifyFile(artifact)
+			if tc.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.errorMsg)
+		})
+	}
+
+	err := PackageDesc{Checksums: map[string]string{"sha256": "00"}}.VerifyFile(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestNewManifestFromVersion(t *testing.T) {
+	testcases := []struct {
+		name            string
+		version         string
+		expectedVersion string
+		snapshot        bool
+	}{
+		{name: "release", version: "8.12.0", expectedVersion: "8.12.0"},
+		{name: "snapshot", version: "8.12.0-SNAPSHOT", expectedVersion: "8.12.0", snapshot: true},
+		{name: "qualified snapshot", version: "9.0.0-rc1-SNAPSHOT", expectedVersion: "9.0.0-rc1", snapshot: true},
+		{name: "build metadata", version: "8.12.0+build202401010000", expectedVersion: "8.12.0+build202401010000"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewManifestFromVersion(tc.version, "data/elastic-agent-4f2d39")
+			require.NoError(t, err)
+			assert.Equal(t, VERSION, m.Version)
+			assert.Equal(t, ManifestKind, m.Kind)
+			assert.Equal(t, tc.expectedVersion, m.Package.Version)
+			assert.Equal(t, tc.snapshot, m.Package.Snapshot)
+			assert.Equal(t, "data/elastic-agent-4f2d39", m.Package.VersionedHome)
+			assert.NoError(t, m.Validate())
+		})
+	}
+
+	_, err := NewManifestFromVersion("8.12", "data/elastic-agent-4f2d39")
+	assert.ErrorContains(t, err, "parsing package version")
+
+	_, err = NewManifestFromVersion("8.12.0", "/data/elastic-agent-4f2d39")
+	assert.ErrorContains(t, err, "invalid versioned home")
+}
+
+func TestParseManifestRejectsUnknownTopLevelKeys(t *testing.T) {
+	manifest := `
+version: co.elastic.agent/v1
+kind: PackageManifest
+packge:
+  version: 8.12.0
+`
+	_, err := ParseManifest(strings.NewReader(manifest))
+	assert.ErrorContains(t, err, `unknown key "packge"`)
+
+	jsonManifest := `{"version": "co.elastic.agent/v1", "kind": "PackageManifest", "packge": {"version": "8.12.0"}}`
+	_, err = ParseManifestJSON(strings.NewReader(jsonManifest))
+	assert.ErrorContains(t, err, `unknown key "packge"`)
+}
+
+func TestParseManifestIgnoresUnknownPackageKeys(t *testing.T) {
+	// manifests produced by newer versions may carry additional package
+	// fields, they must not break parsing
+	manifest := `
+version: co.elastic.agent/v1
+kind: PackageManifest
+package:
+  version: 8.12.0
+  some-future-field: value
+`
+	m, err := ParseManifest(strings.NewReader(manifest))
+	require.NoError(t, err)
+	assert.Equal(t, "8.12.0", m.Package.Version)
+}
+
+func TestParseManifestEmpty(t *testing.T) {
+	for _, input := range []string{"", " \n\t", "# only a comment\n", "null"} {
+		_, err := ParseManifest(strings.NewReader(input))
+		assert.ErrorIs(t, err, ErrEmptyManifest, "input %q", input)
+	}
+	for _, input := range []string{"", " \n\t", "null"} {
+		_, err := ParseManifestJSON(strings.NewReader(input))
+		assert.ErrorIs(t, err, ErrEmptyManifest, "input %q", input)
+	}
+
+	_, err := ParseManifestAuto(strings.NewReader(" \n\t"))
+	assert.ErrorIs(t, err, ErrEmptyManifest)
+}
+
+func TestParseManifestKindAndVersion(t *testing.T) {
+	testcases := []struct {
+		name     string
+		yaml     string
+		json     string
+		expected error
+	}{
+		{
+			name:     "invalid kind",
+			yaml:     "version: co.elastic.agent/v1\nkind: SomethingElse\n",
+			json:     `{"version": "co.elastic.agent/v1", "kind": "SomethingElse"}`,
+			expected: ErrInvalidKind,
+		},
+		{
+			name:     "missing kind",
+			yaml:     "version: co.elastic.agent/v1\n",
+			json:     `{"version": "co.elastic.agent/v1"}`,
+			expected: ErrInvalidKind,
+		},
+		{
+			name:     "unsupported version",
+			yaml:     "version: co.elastic.agent/v2\nkind: PackageManifest\n",
+			json:     `{"version": "co.elastic.agent/v2", "kind": "PackageManifest"}`,
+			expected: ErrUnsupportedVersion,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseManifest(strings.NewReader(tc.yaml))
+			assert.ErrorIs(t, err, tc.expected)
+			assert.ErrorContains(t, err, "decoding package manifest: ")
+
+			_, err = ParseManifestJSON(strings.NewReader(tc.json))
+			assert.ErrorIs(t, err, tc.expected)
+		})
+	}
+
+	_, err := ParseManifest(strings.NewReader("version: [\n"))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrEmptyManifest)
+	assert.NotErrorIs(t, err, ErrInvalidKind)
+}
+
+func TestPackageManifestCanUpgradeFrom(t *testing.T) {
+	manifest := func(desc PackageDesc) *PackageManifest {
+		m := NewManifest()
+		m.Package = desc
+		return m
+	}
+	testcases := []struct {
+		name    string
+		current *PackageManifest
+		target  *PackageManifest
+		allowed bool
+		reason  string
+	}{
+		{
+			name:    "minor upgrade",
+			current: manifest(PackageDesc{Version: "9.0.1"}),
+			target:  manifest(PackageDesc{Version: "9.1.0"}),
+			allowed: true,
+		},
+		{
+			name:    "major upgrade",
+			current: manifest(PackageDesc{Version: "8.19.0"}),
+			target:  manifest(PackageDesc{Version: "9.0.0"}),
+			allowed: true,
+		},
+		{
+			name:    "downgrade within a major",
+			current: manifest(PackageDesc{Version: "9.1.0"}),
+			target:  manifest(PackageDesc{Version: "9.0.3"}),
+			allowed: true,
+		},
+		{
+			name:    "snapshot to release of the same version",
+			current: manifest(PackageDesc{Version: "9.1.0-SNAPSHOT"}),
+			target:  manifest(PackageDesc{Version: "9.1.0"}),
+			allowed: true,
+		},
+		{
+			name:    "snapshots of the same version with different hashes",
+			current: manifest(PackageDesc{Version: "9.1.0", Snapshot: true, Hash: "abcdef"}),
+			target:  manifest(PackageDesc{Version: "9.1.0", Snapshot: true, Hash: "012345"}),
+			allowed: true,
+		},
+		{
+			name:    "same build",
+			current: manifest(PackageDesc{Version: "9.1.0", Snapshot: true, Hash: "abcdef"}),
+			target:  manifest(PackageDesc{Version: "9.1.0-SNAPSHOT", Hash: "abcdef"}),
+			reason:  "the Elastic Agent is already at version 9.1.0-SNAPSHOT",
+		},
+		{
+			name:    "downgrade to an older major",
+			current: manifest(PackageDesc{Version: "9.0.0"}),
+			target:  manifest(PackageDesc{Version: "8.19.0"}),
+			reason:  "downgrading from version 9.0.0 to the older major version 8.19.0 is not supported",
+		},
+		{
+			name:    "FIPS to non-FIPS",
+			current: manifest(PackageDesc{Version: "9.0.0", Fips: true}),
+			target:  manifest(PackageDesc{Version: "9.1.0"}),
+			reason:  "the FIPS-capable version 9.0.0 cannot be upgraded to the non-FIPS-capable version 9.1.0",
+		},
+		{
+			name:    "non-FIPS to FIPS",
+			current: manifest(PackageDesc{Version: "9.0.0"}),
+			target:  manifest(PackageDesc{Version: "9.1.0", Fips: true}),
+			reason:  "the non-FIPS-capable version 9.0.0 cannot be upgraded to the FIPS-capable version 9.1.0",
+		},
+		{
+			name:   "missing current manifest",
+			target: manifest(PackageDesc{Version: "9.1.0"}),
+			reason: "the current package manifest is missing",
+		},
+		{
+			name:    "invalid target version",
+			current: manifest(PackageDesc{Version: "9.0.0"}),
+			target:  manifest(PackageDesc{Version: "not-a-version"}),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			allowed, reason := tc.target.CanUpgradeFrom(tc.current)
+			assert.Equal(t, tc.allowed, allowed)
+			if tc.allowed {
+				assert.Empty(t, reason)
+				return
+			}
+			if tc.reason == "" {
+				assert.NotEmpty(t, reason)
+				return
+			}
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
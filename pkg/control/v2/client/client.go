@@ -77,6 +77,8 @@ const (
 	CollectorComponentStatusStopping CollectorComponentStatus = cproto.CollectorComponentStatus_StatusStopping
 	// CollectorComponentStatusStopped is when the collector component is stopped.
 	CollectorComponentStatusStopped CollectorComponentStatus = cproto.CollectorComponentStatus_StatusStopped
+	// CollectorComponentStatusDisabled is when the collector pipeline is disabled, see Client.SetOtelPipelineEnabled.
+	CollectorComponentStatusDisabled CollectorComponentStatus = cproto.CollectorComponentStatus_StatusDisabled
 )
 
 const (
@@ -150,6 +152,8 @@ type AgentState struct {
 	FleetMessage   string                 `yaml:"fleet_message"`
 	UpgradeDetails *cproto.UpgradeDetails `json:"upgrade_details,omitempty" yaml:"upgrade_details,omitempty"`
 	Collector      *CollectorComponent    `json:"collector,omitempty" yaml:"collector,omitempty"`
+	// Otel is the status of the collector and its pipelines in a stable format, see OtelStatus.
+	Otel *OtelStatus `json:"otel,omitempty" yaml:"otel,omitempty"`
 }
 
 // DiagnosticFileResult is a diagnostic file result.
@@ -222,6 +226,10 @@ type Client interface {
 	// Configure sends a new configuration to the Elastic Agent.
 	// Only works in the case that Elastic Agent is started in testing mode.
 	Configure(ctx context.Context, config string) error
+	// ReloadConfig reloads the configuration files of a standalone Elastic Agent right away.
+	ReloadConfig(ctx context.Context) error
+	// SetOtelPipelineEnabled starts or stops a pipeline of the otel collector run by the Elastic Agent.
+	SetOtelPipelineEnabled(ctx context.Context, name string, enabled bool) error
 	// AvailableRollbacks returns all the existing elastic-agent installs that can be used to rollback the agent
 	AvailableRollbacks(ctx context.Context) ([]AvailableRollback, error)
 }
@@ -489,6 +497,24 @@ func (c *client) Configure(ctx context.Context, config string) error {
 	return err
 }
 
+// ReloadConfig reloads the configuration files of a standalone Elastic Agent right away, instead of
+// waiting for the next periodic check for changes.
+//
+// An error is returned when the reloaded configuration fails to be applied, in which case the
+// previous configuration keeps running.
+func (c *client) ReloadConfig(ctx context.Context) error {
+	_, err := c.client.ReloadConfig(ctx, &cproto.Empty{})
+	return err
+}
+
+// SetOtelPipelineEnabled starts or stops a pipeline of the otel collector run by the Elastic Agent, without
+// changing its configuration. A disabled pipeline stays disabled across configuration changes that don't
+// modify it and is reported with the CollectorComponentStatusDisabled status.
+func (c *client) SetOtelPipelineEnabled(ctx context.Context, name string, enabled bool) error {
+	_, err := c.client.SetOtelPipelineEnabled(ctx, &cproto.OtelPipelineRequest{Name: name, Enabled: enabled})
+	return err
+}
+
 // AvailableRollbacks returns all the existing elastic-agent installs that can be used to rollback the agent
 func (c *client) AvailableRollbacks(ctx context.Context) ([]AvailableRollback, error) {
 	rollbackResponse, err := c.client.AvailableRollbacks(ctx, &cproto.Empty{})
@@ -584,6 +610,7 @@ func toState(res *cproto.StateResponse) (*AgentState, error) {
 			return nil, err
 		}
 		s.Collector = cs
+		s.Otel = s.OtelStatus()
 	}
 	return s, nil
 }
@@ -500,6 +500,57 @@ func (_c *MockClient_Disconnect_Call) RunAndReturn(run func()) *MockClient_Disco
 	return _c
 }
 
+// ReloadConfig provides a mock function for the type MockClient
+func (_mock *MockClient) ReloadConfig(ctx context.Context) error {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReloadConfig")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockClient_ReloadConfig_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReloadConfig'
+type MockClient_ReloadConfig_Call struct {
+	*mock.Call
+}
+
+// ReloadConfig is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockClient_Expecter) ReloadConfig(ctx interface{}) *MockClient_ReloadConfig_Call {
+	return &MockClient_ReloadConfig_Call{Call: _e.mock.On("ReloadConfig", ctx)}
+}
+
+func (_c *MockClient_ReloadConfig_Call) Run(run func(ctx context.Context)) *MockClient_ReloadConfig_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockClient_ReloadConfig_Call) Return(err error) *MockClient_ReloadConfig_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockClient_ReloadConfig_Call) RunAndReturn(run func(ctx context.Context) error) *MockClient_ReloadConfig_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Restart provides a mock function for the type MockClient
 func (_mock *MockClient) Restart(ctx context.Context) error {
 	ret := _mock.Called(ctx)
@@ -551,6 +602,69 @@ func (_c *MockClient_Restart_Call) RunAndReturn(run func(ctx context.Context) er
 	return _c
 }
 
+// SetOtelPipelineEnabled provides a mock function for the type MockClient
+func (_mock *MockClient) SetOtelPipelineEnabled(ctx context.Context, name string, enabled bool) error {
+	ret := _mock.Called(ctx, name, enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetOtelPipelineEnabled")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, bool) error); ok {
+		r0 = returnFunc(ctx, name, enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockClient_SetOtelPipelineEnabled_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetOtelPipelineEnabled'
+type MockClient_SetOtelPipelineEnabled_Call struct {
+	*mock.Call
+}
+
+// SetOtelPipelineEnabled is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+//   - enabled bool
+func (_e *MockClient_Expecter) SetOtelPipelineEnabled(ctx interface{}, name interface{}, enabled interface{}) *MockClient_SetOtelPipelineEnabled_Call {
+	return &MockClient_SetOtelPipelineEnabled_Call{Call: _e.mock.On("SetOtelPipelineEnabled", ctx, name, enabled)}
+}
+
+func (_c *MockClient_SetOtelPipelineEnabled_Call) Run(run func(ctx context.Context, name string, enabled bool)) *MockClient_SetOtelPipelineEnabled_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 bool
+		if args[2] != nil {
+			arg2 = args[2].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockClient_SetOtelPipelineEnabled_Call) Return(err error) *MockClient_SetOtelPipelineEnabled_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockClient_SetOtelPipelineEnabled_Call) RunAndReturn(run func(ctx context.Context, name string, enabled bool) error) *MockClient_SetOtelPipelineEnabled_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // State provides a mock function for the type MockClient
 func (_mock *MockClient) State(ctx context.Context) (*AgentState, error) {
 	ret := _mock.Called(ctx)
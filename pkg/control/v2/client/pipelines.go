@@ -0,0 +1,175 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package client
+
+import (
+	"sort"
+	"strings"
+)
+
+// pipelineIDPrefix prefixes the ID of the pipelines in the collector component status map.
+const pipelineIDPrefix = "pipeline:"
+
+// PipelineStatus is the status of a pipeline run by the collector managed by the Elastic Agent.
+type PipelineStatus struct {
+	// Name is the name of the pipeline, e.g. "logs" or "logs/custom".
+	Name string `json:"name" yaml:"name"`
+	// Signal is the signal type of the pipeline, e.g. "logs", "metrics" or "traces".
+	Signal string `json:"signal" yaml:"signal"`
+	// ComponentIDs are the IDs of the components of the pipeline, e.g. "receiver:filelog", sorted.
+	ComponentIDs []string `json:"component_ids" yaml:"component_ids"`
+	// Status is the status of the pipeline.
+	Status CollectorComponentStatus `json:"status" yaml:"status"`
+	// Error is the error reported by the pipeline, or by the first of its components that reports one,
+	// see CollectorComponent.FirstError.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Healthy returns true when the pipeline is running without errors.
+func (p PipelineStatus) Healthy() bool {
+	return p.Status == CollectorComponentStatusOK && p.Error == ""
+}
+
+// Disabled returns true when the pipeline is disabled, see Client.SetOtelPipelineEnabled.
+func (p PipelineStatus) Disabled() bool {
+	return p.Status == CollectorComponentStatusDisabled
+}
+
+// OtelPipelines returns the status of the pipelines run by the collector, ordered by name.
+// It is empty when the Elastic Agent doesn't run a collector.
+func (s *AgentState) OtelPipelines() []PipelineStatus {
+	if s.Collector == nil {
+		return nil
+	}
+	var pipelines []PipelineStatus
+	for id, pipeline := range s.Collector.ComponentStatusMap {
+		name, ok := strings.CutPrefix(id, pipelineIDPrefix)
+		if !ok || pipeline == nil {
+			continue
+		}
+		signal, _, _ := strings.Cut(name, "/")
+		status := PipelineStatus{
+			Name:         name,
+			Signal:       signal,
+			ComponentIDs: sortedComponentIDs(pipeline),
+			Status:       pipeline.Status,
+			Error:        pipeline.FirstError(),
+		}
+		pipelines = append(pipelines, status)
+	}
+	sort.Slice(pipelines, func(i, j int) bool {
+		return pipelines[i].Name < pipelines[j].Name
+	})
+	return pipelines
+}
+
+// OtelStatus is the status of the collector managed by the Elastic Agent, reported in the `otel` section
+// of `elastic-agent status --output json`. Unlike the raw collector status, the statuses are names, e.g.
+// "StatusOK", and the pipelines and their components are ordered lists.
+type OtelStatus struct {
+	// Status is the overall status of the collector.
+	Status string `json:"status" yaml:"status"`
+	// Healthy is true when the collector and all its pipelines which aren't disabled are running without errors.
+	Healthy bool `json:"healthy" yaml:"healthy"`
+	// Error is the error reported by the collector itself.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Pipelines are the pipelines run by the collector, ordered by name.
+	Pipelines []OtelPipelineStatus `json:"pipelines" yaml:"pipelines"`
+}
+
+// OtelPipelineStatus is the status of a pipeline in OtelStatus.
+type OtelPipelineStatus struct {
+	// Name is the name of the pipeline, e.g. "logs" or "logs/custom".
+	Name string `json:"name" yaml:"name"`
+	// Signal is the signal type of the pipeline, e.g. "logs", "metrics" or "traces".
+	Signal  string `json:"signal" yaml:"signal"`
+	Status  string `json:"status" yaml:"status"`
+	Healthy bool   `json:"healthy" yaml:"healthy"`
+	// Error is the error reported by the pipeline, or by the first of its components that reports one.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Components are the components of the pipeline, ordered by ID.
+	Components []OtelComponentStatus `json:"components" yaml:"components"`
+}
+
+// OtelComponentStatus is the status of a component of a pipeline in OtelStatus.
+type OtelComponentStatus struct {
+	// ID is the ID of the component in the pipeline, e.g. "receiver:filelog".
+	ID string `json:"id" yaml:"id"`
+	// Kind is the kind of the component, e.g. "receiver".
+	Kind    string `json:"kind" yaml:"kind"`
+	Status  string `json:"status" yaml:"status"`
+	Healthy bool   `json:"healthy" yaml:"healthy"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// OtelStatus returns the status of the collector and of its pipelines, nil when the Elastic Agent doesn't
+// run a collector.
+func (s *AgentState) OtelStatus() *OtelStatus {
+	if s.Collector == nil {
+		return nil
+	}
+	status := &OtelStatus{
+		Status:    s.Collector.Status.String(),
+		Healthy:   s.Collector.Status == CollectorComponentStatusOK && s.Collector.Error == "",
+		Error:     s.Collector.Error,
+		Pipelines: []OtelPipelineStatus{},
+	}
+	for _, pipeline := range s.OtelPipelines() {
+		pipelineStatus := OtelPipelineStatus{
+			Name:       pipeline.Name,
+			Signal:     pipeline.Signal,
+			Status:     pipeline.Status.String(),
+			Healthy:    pipeline.Healthy(),
+			Error:      pipeline.Error,
+			Components: make([]OtelComponentStatus, 0, len(pipeline.ComponentIDs)),
+		}
+		components := s.Collector.ComponentStatusMap[pipelineIDPrefix+pipeline.Name].ComponentStatusMap
+		for _, id := range pipeline.ComponentIDs {
+			component := components[id]
+			if component == nil {
+				continue
+			}
+			kind, _, _ := strings.Cut(id, ":")
+			errMsg := component.FirstError()
+			pipelineStatus.Components = append(pipelineStatus.Components, OtelComponentStatus{
+				ID:      id,
+				Kind:    kind,
+				Status:  component.Status.String(),
+				Healthy: component.Status == CollectorComponentStatusOK && errMsg == "",
+				Error:   errMsg,
+			})
+		}
+		status.Healthy = status.Healthy && (pipelineStatus.Healthy || pipeline.Disabled())
+		status.Pipelines = append(status.Pipelines, pipelineStatus)
+	}
+	return status
+}
+
+// FirstError returns the error of the collector component or, recursively, the first error of its
+// sub-components ordered by ID, prefixed with the path of IDs to the sub-component reporting it.
+func (c *CollectorComponent) FirstError() string {
+	if c.Error != "" {
+		return c.Error
+	}
+	for _, id := range sortedComponentIDs(c) {
+		sub := c.ComponentStatusMap[id]
+		if sub == nil {
+			continue
+		}
+		if err := sub.FirstError(); err != "" {
+			return id + ": " + err
+		}
+	}
+	return ""
+}
+
+func sortedComponentIDs(c *CollectorComponent) []string {
+	ids := make([]string, 0, len(c.ComponentStatusMap))
+	for id := range c.ComponentStatusMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,161 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentStateOtelPipelines(t *testing.T) {
+	assert.Empty(t, (&AgentState{}).OtelPipelines())
+
+	state := &AgentState{
+		Collector: &CollectorComponent{
+			Status: CollectorComponentStatusRecoverableError,
+			ComponentStatusMap: map[string]*CollectorComponent{
+				"pipeline:logs": {
+					Status: CollectorComponentStatusOK,
+					ComponentStatusMap: map[string]*CollectorComponent{
+						"receiver:filelog": {Status: CollectorComponentStatusOK},
+						"exporter:file":    {Status: CollectorComponentStatusOK},
+					},
+				},
+				"pipeline:metrics/custom": {
+					Status: CollectorComponentStatusRecoverableError,
+					ComponentStatusMap: map[string]*CollectorComponent{
+						"receiver:hostmetrics":    {Status: CollectorComponentStatusOK},
+						"exporter:elasticsearch":  {Status: CollectorComponentStatusRecoverableError, Error: "connection refused"},
+						"exporter:elasticsearch2": {Status: CollectorComponentStatusRecoverableError, Error: "ignored"},
+					},
+				},
+				"extensions": {Status: CollectorComponentStatusOK},
+			},
+		},
+	}
+	assert.Equal(t, []PipelineStatus{
+		{
+			Name:         "logs",
+			Signal:       "logs",
+			ComponentIDs: []string{"exporter:file", "receiver:filelog"},
+			Status:       CollectorComponentStatusOK,
+		},
+		{
+			Name:         "metrics/custom",
+			Signal:       "metrics",
+			ComponentIDs: []string{"exporter:elasticsearch", "exporter:elasticsearch2", "receiver:hostmetrics"},
+			Status:       CollectorComponentStatusRecoverableError,
+			Error:        "exporter:elasticsearch: connection refused",
+		},
+	}, state.OtelPipelines())
+	assert.True(t, state.OtelPipelines()[0].Healthy())
+	assert.False(t, state.OtelPipelines()[1].Healthy())
+}
+
+func TestCollectorComponentFirstError(t *testing.T) {
+	assert.Empty(t, (&CollectorComponent{}).FirstError())
+
+	c := &CollectorComponent{
+		ComponentStatusMap: map[string]*CollectorComponent{
+			"a": {Status: CollectorComponentStatusOK},
+			"b": {ComponentStatusMap: map[string]*CollectorComponent{
+				"b1": nil,
+				"b2": {Error: "nested"},
+			}},
+			"c": {Error: "ignored"},
+		},
+	}
+	assert.Equal(t, "b: b2: nested", c.FirstError())
+
+	c.Error = "own"
+	assert.Equal(t, "own", c.FirstError())
+}
+
+func TestAgentStateOtelStatus(t *testing.T) {
+	assert.Nil(t, (&AgentState{}).OtelStatus())
+
+	state := &AgentState{
+		Collector: &CollectorComponent{
+			Status: CollectorComponentStatusOK,
+			ComponentStatusMap: map[string]*CollectorComponent{
+				"pipeline:logs": {
+					Status: CollectorComponentStatusOK,
+					ComponentStatusMap: map[string]*CollectorComponent{
+						"receiver:filelog": {Status: CollectorComponentStatusOK},
+						"exporter:file":    {Status: CollectorComponentStatusOK},
+					},
+				},
+				"extensions": {Status: CollectorComponentStatusOK},
+			},
+		},
+	}
+	expected := &OtelStatus{
+		Status:  "StatusOK",
+		Healthy: true,
+		Pipelines: []OtelPipelineStatus{{
+			Name:    "logs",
+			Signal:  "logs",
+			Status:  "StatusOK",
+			Healthy: true,
+			Components: []OtelComponentStatus{
+				{ID: "exporter:file", Kind: "exporter", Status: "StatusOK", Healthy: true},
+				{ID: "receiver:filelog", Kind: "receiver", Status: "StatusOK", Healthy: true},
+			},
+		}},
+	}
+	assert.Equal(t, expected, state.OtelStatus())
+
+	state.Collector.ComponentStatusMap["pipeline:logs"].ComponentStatusMap["exporter:file"] = &CollectorComponent{
+		Status: CollectorComponentStatusRecoverableError,
+		Error:  "disk full",
+	}
+	status := state.OtelStatus()
+	assert.False(t, status.Healthy, "an unhealthy component makes the collector unhealthy")
+	assert.False(t, status.Pipelines[0].Healthy)
+	assert.Equal(t, "exporter:file: disk full", status.Pipelines[0].Error)
+	assert.Equal(t, OtelComponentStatus{
+		ID:     "exporter:file",
+		Kind:   "exporter",
+		Status: "StatusRecoverableError",
+		Error:  "disk full",
+	}, status.Pipelines[0].Components[0])
+
+	// no pipelines is an empty list
+	state.Collector.ComponentStatusMap = nil
+	assert.Equal(t, &OtelStatus{Status: "StatusOK", Healthy: true, Pipelines: []OtelPipelineStatus{}}, state.OtelStatus())
+}
+
+func TestAgentStateOtelStatusDisabledPipeline(t *testing.T) {
+	state := &AgentState{
+		Collector: &CollectorComponent{
+			Status: CollectorComponentStatusOK,
+			ComponentStatusMap: map[string]*CollectorComponent{
+				"pipeline:logs": {Status: CollectorComponentStatusDisabled},
+				"pipeline:metrics": {
+					Status: CollectorComponentStatusOK,
+					ComponentStatusMap: map[string]*CollectorComponent{
+						"receiver:hostmetrics": {Status: CollectorComponentStatusOK},
+					},
+				},
+			},
+		},
+	}
+	pipelines := state.OtelPipelines()
+	require.Len(t, pipelines, 2)
+	assert.True(t, pipelines[0].Disabled())
+	assert.False(t, pipelines[0].Healthy())
+	assert.False(t, pipelines[1].Disabled())
+
+	status := state.OtelStatus()
+	assert.True(t, status.Healthy, "a disabled pipeline doesn't make the collector unhealthy")
+	assert.Equal(t, OtelPipelineStatus{
+		Name:       "logs",
+		Signal:     "logs",
+		Status:     "StatusDisabled",
+		Components: []OtelComponentStatus{},
+	}, status.Pipelines[0])
+}
@@ -24,16 +24,18 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ElasticAgentControl_Version_FullMethodName              = "/cproto.ElasticAgentControl/Version"
-	ElasticAgentControl_State_FullMethodName                = "/cproto.ElasticAgentControl/State"
-	ElasticAgentControl_StateWatch_FullMethodName           = "/cproto.ElasticAgentControl/StateWatch"
-	ElasticAgentControl_Restart_FullMethodName              = "/cproto.ElasticAgentControl/Restart"
-	ElasticAgentControl_Upgrade_FullMethodName              = "/cproto.ElasticAgentControl/Upgrade"
-	ElasticAgentControl_DiagnosticAgent_FullMethodName      = "/cproto.ElasticAgentControl/DiagnosticAgent"
-	ElasticAgentControl_DiagnosticUnits_FullMethodName      = "/cproto.ElasticAgentControl/DiagnosticUnits"
-	ElasticAgentControl_DiagnosticComponents_FullMethodName = "/cproto.ElasticAgentControl/DiagnosticComponents"
-	ElasticAgentControl_Configure_FullMethodName            = "/cproto.ElasticAgentControl/Configure"
-	ElasticAgentControl_AvailableRollbacks_FullMethodName   = "/cproto.ElasticAgentControl/AvailableRollbacks"
+	ElasticAgentControl_Version_FullMethodName                = "/cproto.ElasticAgentControl/Version"
+	ElasticAgentControl_State_FullMethodName                  = "/cproto.ElasticAgentControl/State"
+	ElasticAgentControl_StateWatch_FullMethodName             = "/cproto.ElasticAgentControl/StateWatch"
+	ElasticAgentControl_Restart_FullMethodName                = "/cproto.ElasticAgentControl/Restart"
+	ElasticAgentControl_Upgrade_FullMethodName                = "/cproto.ElasticAgentControl/Upgrade"
+	ElasticAgentControl_DiagnosticAgent_FullMethodName        = "/cproto.ElasticAgentControl/DiagnosticAgent"
+	ElasticAgentControl_DiagnosticUnits_FullMethodName        = "/cproto.ElasticAgentControl/DiagnosticUnits"
+	ElasticAgentControl_DiagnosticComponents_FullMethodName   = "/cproto.ElasticAgentControl/DiagnosticComponents"
+	ElasticAgentControl_Configure_FullMethodName              = "/cproto.ElasticAgentControl/Configure"
+	ElasticAgentControl_AvailableRollbacks_FullMethodName     = "/cproto.ElasticAgentControl/AvailableRollbacks"
+	ElasticAgentControl_ReloadConfig_FullMethodName           = "/cproto.ElasticAgentControl/ReloadConfig"
+	ElasticAgentControl_SetOtelPipelineEnabled_FullMethodName = "/cproto.ElasticAgentControl/SetOtelPipelineEnabled"
 )
 
 // ElasticAgentControlClient is the client API for ElasticAgentControl service.
@@ -68,6 +70,16 @@ type ElasticAgentControlClient interface {
 	Configure(ctx context.Context, in *ConfigureRequest, opts ...grpc.CallOption) (*Empty, error)
 	// AvailableRollbacks returns any existing agent installs than can be used as target for a manual rollback operation
 	AvailableRollbacks(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*AvailableRollbacksResponse, error)
+	// ReloadConfig reloads the configuration files of a standalone Elastic Agent right away, instead of
+	// waiting for the next periodic check for changes.
+	//
+	// An error is returned when the reloaded configuration fails to be applied, in which case the
+	// previous configuration keeps running.
+	ReloadConfig(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	// SetOtelPipelineEnabled starts or stops a single pipeline of the OTel collector, without changing
+	// the configuration. A disabled pipeline stays disabled across configuration changes that don't
+	// modify it and is reported with the StatusDisabled status.
+	SetOtelPipelineEnabled(ctx context.Context, in *OtelPipelineRequest, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type elasticAgentControlClient struct {
@@ -205,6 +217,26 @@ func (c *elasticAgentControlClient) AvailableRollbacks(ctx context.Context, in *
 	return out, nil
 }
 
+func (c *elasticAgentControlClient) ReloadConfig(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_ReloadConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) SetOtelPipelineEnabled(ctx context.Context, in *OtelPipelineRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_SetOtelPipelineEnabled_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ElasticAgentControlServer is the server API for ElasticAgentControl service.
 // All implementations must embed UnimplementedElasticAgentControlServer
 // for forward compatibility.
@@ -237,6 +269,16 @@ type ElasticAgentControlServer interface {
 	Configure(context.Context, *ConfigureRequest) (*Empty, error)
 	// AvailableRollbacks returns any existing agent installs than can be used as target for a manual rollback operation
 	AvailableRollbacks(context.Context, *Empty) (*AvailableRollbacksResponse, error)
+	// ReloadConfig reloads the configuration files of a standalone Elastic Agent right away, instead of
+	// waiting for the next periodic check for changes.
+	//
+	// An error is returned when the reloaded configuration fails to be applied, in which case the
+	// previous configuration keeps running.
+	ReloadConfig(context.Context, *Empty) (*Empty, error)
+	// SetOtelPipelineEnabled starts or stops a single pipeline of the OTel collector, without changing
+	// the configuration. A disabled pipeline stays disabled across configuration changes that don't
+	// modify it and is reported with the StatusDisabled status.
+	SetOtelPipelineEnabled(context.Context, *OtelPipelineRequest) (*Empty, error)
 	mustEmbedUnimplementedElasticAgentControlServer()
 }
 
@@ -277,6 +319,12 @@ func (UnimplementedElasticAgentControlServer) Configure(context.Context, *Config
 func (UnimplementedElasticAgentControlServer) AvailableRollbacks(context.Context, *Empty) (*AvailableRollbacksResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AvailableRollbacks not implemented")
 }
+func (UnimplementedElasticAgentControlServer) ReloadConfig(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadConfig not implemented")
+}
+func (UnimplementedElasticAgentControlServer) SetOtelPipelineEnabled(context.Context, *OtelPipelineRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetOtelPipelineEnabled not implemented")
+}
 func (UnimplementedElasticAgentControlServer) mustEmbedUnimplementedElasticAgentControlServer() {}
 func (UnimplementedElasticAgentControlServer) testEmbeddedByValue()                             {}
 
@@ -457,6 +505,42 @@ func _ElasticAgentControl_AvailableRollbacks_Handler(srv interface{}, ctx contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ElasticAgentControl_ReloadConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).ReloadConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_ReloadConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).ReloadConfig(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_SetOtelPipelineEnabled_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OtelPipelineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).SetOtelPipelineEnabled(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_SetOtelPipelineEnabled_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).SetOtelPipelineEnabled(ctx, req.(*OtelPipelineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ElasticAgentControl_ServiceDesc is the grpc.ServiceDesc for ElasticAgentControl service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -492,6 +576,14 @@ var ElasticAgentControl_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AvailableRollbacks",
 			Handler:    _ElasticAgentControl_AvailableRollbacks_Handler,
 		},
+		{
+			MethodName: "ReloadConfig",
+			Handler:    _ElasticAgentControl_ReloadConfig_Handler,
+		},
+		{
+			MethodName: "SetOtelPipelineEnabled",
+			Handler:    _ElasticAgentControl_SetOtelPipelineEnabled_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
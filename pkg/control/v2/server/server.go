@@ -46,6 +46,13 @@ type TestModeConfigSetter interface {
 	SetConfig(ctx context.Context, cfg string) error
 }
 
+// ConfigReloader reloads the configuration on demand, it is only available for a standalone
+// Elastic Agent reading its configuration from files.
+type ConfigReloader interface {
+	// ReloadConfig reloads the configuration, returning an error when it fails to be applied.
+	ReloadConfig(ctx context.Context) error
+}
+
 type RollbacksSource interface {
 	Get() (map[string]ttl.TTLMarker, error)
 }
@@ -64,6 +71,7 @@ type Server struct {
 	grpcConfig *configuration.GRPCConfig
 
 	tmSetter       TestModeConfigSetter
+	reloader       ConfigReloader
 	rollbackSource RollbacksSource
 }
 
@@ -85,6 +93,11 @@ func (s *Server) SetTestModeConfigSetter(setter TestModeConfigSetter) {
 	s.tmSetter = setter
 }
 
+// SetConfigReloader sets the configuration reloader used by ReloadConfig.
+func (s *Server) SetConfigReloader(reloader ConfigReloader) {
+	s.reloader = reloader
+}
+
 // Start starts the GRPC endpoint and accepts new connections.
 func (s *Server) Start() error {
 	if s.server != nil {
@@ -145,7 +158,12 @@ func (s *Server) Version(_ context.Context, _ *cproto.Empty) (*cproto.VersionRes
 // State returns the overall state of the agent.
 func (s *Server) State(_ context.Context, _ *cproto.Empty) (*cproto.StateResponse, error) {
 	state := s.coord.State()
-	return stateToProto(&state, s.agentInfo)
+	resp, err := stateToProto(&state, s.agentInfo)
+	if err != nil {
+		return nil, err
+	}
+	markDisabledPipelines(resp.Collector, s.coord.DisabledOtelPipelines())
+	return resp, nil
 }
 
 // StateWatch streams the current state of the Elastic Agent to the client.
@@ -165,6 +183,7 @@ func (s *Server) StateWatch(_ *cproto.Empty, srv cproto.ElasticAgentControl_Stat
 			if err != nil {
 				return err
 			}
+			markDisabledPipelines(resp.Collector, s.coord.DisabledOtelPipelines())
 			err = srv.Send(resp)
 			if err != nil {
 				return err
@@ -369,6 +388,29 @@ func (s *Server) Configure(ctx context.Context, req *cproto.ConfigureRequest) (*
 	return &cproto.Empty{}, nil
 }
 
+// ReloadConfig reloads the configuration files of a standalone Elastic Agent, the previous
+// configuration keeps running when the reloaded one fails to be applied.
+func (s *Server) ReloadConfig(ctx context.Context, _ *cproto.Empty) (*cproto.Empty, error) {
+	if s.reloader == nil {
+		return nil, errors.New("configuration reload is only available for a standalone Elastic Agent reading its configuration from files")
+	}
+	if err := s.reloader.ReloadConfig(ctx); err != nil {
+		return nil, err
+	}
+	return &cproto.Empty{}, nil
+}
+
+// SetOtelPipelineEnabled starts or stops a pipeline of the otel collector configuration.
+func (s *Server) SetOtelPipelineEnabled(ctx context.Context, req *cproto.OtelPipelineRequest) (*cproto.Empty, error) {
+	if req.Name == "" {
+		return nil, errors.New("pipeline name is required")
+	}
+	if err := s.coord.SetOtelPipelineEnabled(ctx, req.Name, req.Enabled); err != nil {
+		return nil, err
+	}
+	return &cproto.Empty{}, nil
+}
+
 func (s *Server) AvailableRollbacks(context.Context, *cproto.Empty) (*cproto.AvailableRollbacksResponse, error) {
 	rollbacks, err := s.rollbackSource.Get()
 	if err != nil {
@@ -491,6 +533,23 @@ func collectorToProto(s *status.AggregateStatus) *cproto.CollectorComponent {
 	return r
 }
 
+// markDisabledPipelines reports the disabled pipelines in the collector status with the StatusDisabled status.
+// The collector doesn't run disabled pipelines so it doesn't report them.
+func markDisabledPipelines(collector *cproto.CollectorComponent, disabled []string) {
+	if collector == nil || len(disabled) == 0 {
+		return
+	}
+	if collector.ComponentStatusMap == nil {
+		collector.ComponentStatusMap = make(map[string]*cproto.CollectorComponent, len(disabled))
+	}
+	for _, name := range disabled {
+		collector.ComponentStatusMap["pipeline:"+name] = &cproto.CollectorComponent{
+			Status:    cproto.CollectorComponentStatus_StatusDisabled,
+			Timestamp: collector.Timestamp,
+		}
+	}
+}
+
 func otelComponentStatusToProto(s componentstatus.Status) cproto.CollectorComponentStatus {
 	switch s {
 	case componentstatus.StatusNone:
@@ -220,3 +220,35 @@ func TestStateMapping(t *testing.T) {
 		})
 	}
 }
+
+func TestMarkDisabledPipelines(t *testing.T) {
+	t.Run("no collector", func(t *testing.T) {
+		markDisabledPipelines(nil, []string{"logs"})
+	})
+
+	t.Run("disabled pipelines are reported", func(t *testing.T) {
+		collector := &cproto.CollectorComponent{
+			Status:    cproto.CollectorComponentStatus_StatusOK,
+			Timestamp: "2025-01-01T00:00:00Z",
+			ComponentStatusMap: map[string]*cproto.CollectorComponent{
+				"pipeline:metrics": {Status: cproto.CollectorComponentStatus_StatusOK},
+			},
+		}
+		markDisabledPipelines(collector, []string{"logs", "traces/custom"})
+
+		require.Len(t, collector.ComponentStatusMap, 3)
+		assert.Equal(t, cproto.CollectorComponentStatus_StatusOK, collector.ComponentStatusMap["pipeline:metrics"].Status)
+		for _, id := range []string{"pipeline:logs", "pipeline:traces/custom"} {
+			require.Contains(t, collector.ComponentStatusMap, id)
+			assert.Equal(t, cproto.CollectorComponentStatus_StatusDisabled, collector.ComponentStatusMap[id].Status)
+			assert.Equal(t, collector.Timestamp, collector.ComponentStatusMap[id].Timestamp)
+		}
+	})
+
+	t.Run("collector without components", func(t *testing.T) {
+		collector := &cproto.CollectorComponent{Status: cproto.CollectorComponentStatus_StatusOK}
+		markDisabledPipelines(collector, []string{"logs"})
+		require.Contains(t, collector.ComponentStatusMap, "pipeline:logs")
+		assert.Equal(t, cproto.CollectorComponentStatus_StatusDisabled, collector.ComponentStatusMap["pipeline:logs"].Status)
+	})
+}
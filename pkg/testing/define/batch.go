@@ -51,6 +51,10 @@ type Batch struct {
 	// Stack defines the stack required for this batch.
 	Stack *Stack `json:"stack,omitempty"`
 
+	// Resources defines the resources of the host the tests of this batch need, the
+	// largest of the resources required by each test.
+	Resources *Resources `json:"resources,omitempty"`
+
 	// Tests define the set of packages and tests that do not require sudo
 	// privileges to be performed.
 	Tests []BatchPackageTests `json:"tests"`
@@ -207,6 +211,9 @@ func appendTest(batches []Batch, tar testActionResult, req Requirements) []Batch
 			// assign the stack to this batch
 			batch.Stack = copyStack(req.Stack)
 		}
+		if req.Resources != nil {
+			batch.Resources = mergeResources(batch.Resources, req.Resources)
+		}
 		if req.Sudo {
 			batch.SudoTests = appendPackageTest(batch.SudoTests, tar.Package, tar.Test, req.Stack != nil)
 		} else {
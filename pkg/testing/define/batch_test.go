@@ -253,6 +253,30 @@ var testLinuxLocalBatch = []Batch{
 	},
 }
 
+func TestAppendTestResources(t *testing.T) {
+	linux := []OS{{Type: Linux, Arch: AMD64}}
+	tar := testActionResult{Package: "github.com/elastic/elastic-agent/testing/integration/ess"}
+
+	tar.Test = "TestNoResources"
+	batches := appendTest(nil, tar, Requirements{Group: "otel", OS: linux})
+	require.Len(t, batches, 1)
+	require.Nil(t, batches[0].Resources)
+
+	tar.Test = "TestSmall"
+	batches = appendTest(batches, tar, Requirements{Group: "otel", OS: linux, Resources: &Resources{MinMemoryMB: 2048}})
+	tar.Test = "TestLarge"
+	batches = appendTest(batches, tar, Requirements{Group: "otel", OS: linux, Resources: &Resources{MinMemoryMB: 4096}})
+	tar.Test = "TestSmallAgain"
+	batches = appendTest(batches, tar, Requirements{Group: "otel", OS: linux, Resources: &Resources{MinMemoryMB: 1024}})
+	tar.Test = "TestOtherGroup"
+	batches = appendTest(batches, tar, Requirements{Group: Default, OS: linux})
+
+	require.Len(t, batches, 2)
+	require.Equal(t, &Resources{MinMemoryMB: 4096}, batches[0].Resources)
+	require.Len(t, batches[0].Tests[0].Tests, 4)
+	require.Nil(t, batches[1].Resources)
+}
+
 func TestGoTestFlags(t *testing.T) {
 	testcases := []struct {
 		name     string
@@ -5,6 +5,7 @@
 package define
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
@@ -17,6 +18,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -25,11 +27,13 @@ import (
 	"github.com/gofrs/uuid/v5"
 
 	"github.com/elastic/elastic-agent-libs/kibana"
+	"github.com/elastic/elastic-agent-libs/testing/estools"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-sysinfo"
 	"github.com/elastic/go-sysinfo/types"
 
 	atesting "github.com/elastic/elastic-agent/pkg/testing"
+	"github.com/elastic/elastic-agent/pkg/testing/tools/esutil"
 	semver "github.com/elastic/elastic-agent/pkg/version"
 	"github.com/elastic/elastic-agent/version"
 
@@ -72,6 +76,12 @@ type Info struct {
 	Namespace string
 }
 
+// DataStream returns the name of the data stream of the given type and dataset in the namespace of
+// the test, e.g. `logs-generic-<namespace>`. An empty dataset matches every dataset.
+func (i *Info) DataStream(dataType, dataset string) esutil.DataStream {
+	return esutil.DataStream{Type: dataType, Dataset: dataset, Namespace: i.Namespace}
+}
+
 func (i *Info) KubeClient() (klient.Client, error) {
 	c, err := klient.NewWithKubeConfigFile(os.Getenv("KUBECONFIG"))
 	if err != nil {
@@ -102,6 +112,14 @@ func NewFixtureFromLocalFIPSBuild(t *testing.T, version string, opts ...atesting
 	return NewFixtureWithBinary(t, version, "elastic-agent", buildsDir(t), true, opts...)
 }
 
+// AttachFixture returns a new Elastic Agent testing fixture for the Elastic Agent already present
+// in dir, e.g. an Elastic Agent installed manually, with the agent logging to the test logger.
+// See atesting.AttachFixture.
+func AttachFixture(t *testing.T, dir string, opts ...atesting.FixtureOpt) (*atesting.Fixture, error) {
+	opts = append(opts, atesting.WithLogOutput())
+	return atesting.AttachFixture(t, dir, opts...)
+}
+
 // NewFixtureWithBinary returns a new Elastic Agent testing fixture with a LocalFetcher and
 // the agent logging to the test logger.
 func NewFixtureWithBinary(t *testing.T, version string, binary string, buildsDir string, fips bool, opts ...atesting.FixtureOpt) (*atesting.Fixture, error) {
@@ -126,6 +144,27 @@ func NewFixtureWithBinary(t *testing.T, version string, binary string, buildsDir
 	return atesting.NewFixture(t, version, opts...)
 }
 
+// missingComponents returns the components required by the test that are not packaged in the
+// artifact used by NewFixtureFromLocalBuild for the current platform.
+func missingComponents(t *testing.T, req Requirements) ([]string, error) {
+	f, err := NewFixtureWithBinary(t, Version(), "elastic-agent", buildsDir(t), req.FIPS)
+	if err != nil {
+		return nil, err
+	}
+	packaged, err := f.PackagedComponents(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, name := range req.RequiredComponents {
+		if !slices.Contains(packaged, name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
 // findProjectRoot finds the root directory of the project, by finding the go.mod file.
 func findProjectRoot() (string, error) {
 	_, caller, _, ok := runtime.Caller(1)
@@ -208,6 +247,21 @@ func runOrSkip(t *testing.T, req Requirements, local bool) *Info {
 		return dryRun(t, req)
 	}
 
+	if len(req.RequiredComponents) > 0 {
+		missing, err := missingComponents(t, req)
+		if err != nil {
+			if local {
+				t.Skipf("test requires components %v but failed to inspect the local build: %s", req.RequiredComponents, err)
+				return nil
+			}
+			panic(err)
+		}
+		if len(missing) > 0 {
+			t.Skipf("components %v required by test are not packaged in the local build", missing)
+			return nil
+		}
+	}
+
 	t.Cleanup(func() {
 		if runtime.GOOS != "windows" {
 			_ = os.RemoveAll("/tmp/elastic-agent") // clean up any leftover data from tests
@@ -231,6 +285,25 @@ func runOrSkip(t *testing.T, req Requirements, local bool) *Info {
 			// non-local test and stack was required
 			panic(err)
 		}
+		if req.MinStackVersion != "" {
+			stackVersion, err := getStackVersion(info.ESClient)
+			if err != nil {
+				if local {
+					t.Skipf("test requires a minimum stack version but failed to determine the stack version: %s", err)
+					return nil
+				}
+				// non-local test and stack was required
+				panic(fmt.Errorf("test requires a minimum stack version but failed to determine the stack version: %w", err))
+			}
+			allowed, err := req.stackVersionAllowed(stackVersion)
+			if err != nil {
+				panic(fmt.Errorf("failed to compare stack version %s with the minimum stack version: %w", stackVersion, err))
+			}
+			if !allowed {
+				t.Skipf("stack version %s is older than the minimum stack version %s required by test", stackVersion, req.MinStackVersion)
+				return nil
+			}
+		}
 		info.KibanaClient, err = getKibanaClient()
 		if err != nil {
 			if local {
@@ -277,6 +350,13 @@ func getNamespace(t *testing.T, local bool) (string, error) {
 	return namespace, nil
 }
 
+const (
+	// esClientMaxRetries is the number of retries of the requests of the Elasticsearch client.
+	esClientMaxRetries = 3
+	// esClientRetryBackoff is the initial backoff between the retries of the Elasticsearch client.
+	esClientRetryBackoff = time.Second
+)
+
 // getESClient creates the elasticsearch client from the information passed from the test runner.
 func getESClient() (*elasticsearch.Client, error) {
 	esHost := os.Getenv("ELASTICSEARCH_HOST")
@@ -285,15 +365,23 @@ func getESClient() (*elasticsearch.Client, error) {
 	if esHost == "" || esUser == "" || esPass == "" {
 		return nil, errors.New("ELASTICSEARCH_* must be defined by the test runner")
 	}
-	c, err := elasticsearch.NewClient(elasticsearch.Config{
+	// transient errors of a cloud deployment must not fail the tests checking the ingested data
+	return esutil.NewClientWithRetry(elasticsearch.Config{
 		Addresses: []string{esHost},
 		Username:  esUser,
 		Password:  esPass,
-	})
+	}, esClientMaxRetries, esClientRetryBackoff)
+}
+
+// getStackVersion returns the version of the elasticsearch cluster the client is connected to.
+func getStackVersion(client *elasticsearch.Client) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ping, err := estools.GetPing(ctx, client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create elasticsearch client: %w", err)
+		return "", fmt.Errorf("failed to get elasticsearch version: %w", err)
 	}
-	return c, nil
+	return ping.Version.Number, nil
 }
 
 // getKibanaClient creates the kibana client from the information passed from the test runner.
@@ -63,6 +63,8 @@ type TestPlatform struct {
 type TestMetadata struct {
 	Local bool `json:"local" yaml:"local"`
 	Sudo  bool `json:"sudo" yaml:"sudo"`
+	// MinMemoryMB is the minimum memory of the host the test needs, see Resources.
+	MinMemoryMB int `json:"min_memory_mb,omitempty" yaml:"min_memory_mb,omitempty"`
 }
 
 type TestOS struct {
@@ -182,10 +184,14 @@ func discoverTest(test Named, reqs Requirements) {
 		for _, o := range osForPlatform {
 			testsByOS := ensureMapping(mappedOSesForPlatform.OperatingSystems, o, NewTestByOS)
 			testGroup := ensureMapping(testsByOS.Groups, reqs.Group, NewTestGroup)
-			testGroup.Tests[test.Name()] = TestMetadata{
+			metadata := TestMetadata{
 				Local: reqs.Local,
 				Sudo:  reqs.Sudo,
 			}
+			if reqs.Resources != nil {
+				metadata.MinMemoryMB = reqs.Resources.MinMemoryMB
+			}
+			testGroup.Tests[test.Name()] = metadata
 		}
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	"github.com/elastic/elastic-agent/pkg/component"
+	semver "github.com/elastic/elastic-agent/pkg/version"
 )
 
 const (
@@ -41,9 +42,11 @@ type OS struct {
 	// This is always required to be defined on the OS structure.
 	// If it is not defined the test runner will error.
 	Type string `json:"type"`
-	// Arch is the architecture type (amd64 or arm64).
+	// Arch is the architecture type, either AMD64 ("amd64") or ARM64 ("arm64").
+	// The values match runtime.GOARCH, which define.Require compares it against
+	// to skip the test on any other architecture.
 	//
-	// In the case that it's not provided the test will run on every
+	// In the case that it's not provided (empty) the test will run on every
 	// architecture that is supported.
 	Arch string `json:"arch"`
 	// Version is a specific version of the OS type to run this test on
@@ -91,6 +94,36 @@ type Stack struct {
 	Version string `json:"version"`
 }
 
+// Resources defines the resources of the host a test needs, for the test runner to schedule the
+// test on a host that has them.
+type Resources struct {
+	// MinMemoryMB is the minimum memory, in megabytes, of the host the test runs on, e.g. for the
+	// tests running a full OpenTelemetry collector next to the Elastic Agent.
+	MinMemoryMB int `json:"min_memory_mb,omitempty"`
+}
+
+// Validate returns an error if not valid.
+func (r Resources) Validate() error {
+	if r.MinMemoryMB < 0 {
+		return fmt.Errorf("invalid min memory %d MB: must not be negative", r.MinMemoryMB)
+	}
+	return nil
+}
+
+// mergeResources returns the resources satisfying both a and b, nil when both are nil.
+func mergeResources(a *Resources, b *Resources) *Resources {
+	if a == nil && b == nil {
+		return nil
+	}
+	var merged Resources
+	for _, r := range []*Resources{a, b} {
+		if r != nil {
+			merged.MinMemoryMB = max(merged.MinMemoryMB, r.MinMemoryMB)
+		}
+	}
+	return &merged
+}
+
 // Requirements defines the testing requirements for the test to run.
 type Requirements struct {
 	// Group must be set on each test to define which group the tests belongs to.
@@ -108,6 +141,18 @@ type Requirements struct {
 	// Stack defines the stack required for the test.
 	Stack *Stack `json:"stack,omitempty"`
 
+	// MinStackVersion defines the minimum version of the stack the test can run against,
+	// e.g. when the test installs an integration that must match the version of the Elastic Agent.
+	//
+	// Only the major, minor and patch of the versions are compared. The test is skipped before
+	// it starts when the running stack is older. Requires Stack to be defined.
+	MinStackVersion string `json:"min_stack_version,omitempty"`
+
+	// RequiredComponents defines the components (e.g. "apm-server") that must be packaged with
+	// the locally built Elastic Agent for the test to run. The test is skipped before it starts
+	// when any of them is missing from the package.
+	RequiredComponents []string `json:"required_components,omitempty"`
+
 	// Local defines if this test can safely be performed on a local development machine.
 	// If not set then the test will not be performed when local only testing is performed.
 	//
@@ -122,6 +167,10 @@ type Requirements struct {
 	// FIPS defines that this test must be run in an environment that is configured for FIPS,
 	// e.g. a Linux VM with OpenSSL configured with the FIPS provider.
 	FIPS bool `json:"fips"`
+
+	// Resources defines the resources of the host the test needs, they are hints for the test
+	// runner and are not checked when the test runs.
+	Resources *Resources `json:"resources,omitempty"`
 }
 
 // Validate returns an error if not valid.
@@ -134,9 +183,47 @@ func (r Requirements) Validate() error {
 			return fmt.Errorf("invalid os %d: %w", i, err)
 		}
 	}
+	for i, name := range r.RequiredComponents {
+		if name == "" {
+			return fmt.Errorf("invalid required component %d: name must be defined", i)
+		}
+	}
+	if r.Resources != nil {
+		if err := r.Resources.Validate(); err != nil {
+			return fmt.Errorf("invalid resources: %w", err)
+		}
+	}
+	if r.MinStackVersion != "" {
+		if r.Stack == nil {
+			return errors.New("min stack version can only be set when stack is defined")
+		}
+		if _, err := semver.ParseVersion(r.MinStackVersion); err != nil {
+			return fmt.Errorf("invalid min stack version %q: %w", r.MinStackVersion, err)
+		}
+	}
 	return nil
 }
 
+// stackVersionAllowed returns true if the stack version satisfies the minimum stack version.
+func (r Requirements) stackVersionAllowed(stackVersion string) (bool, error) {
+	if r.MinStackVersion == "" {
+		// all allowed
+		return true, nil
+	}
+	minVersion, err := semver.ParseVersion(r.MinStackVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid min stack version %q: %w", r.MinStackVersion, err)
+	}
+	version, err := semver.ParseVersion(stackVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid stack version %q: %w", stackVersion, err)
+	}
+	// ignore the prerelease, a snapshot stack satisfies the same release
+	minCore := semver.NewParsedSemVer(minVersion.Major(), minVersion.Minor(), minVersion.Patch(), "", "")
+	core := semver.NewParsedSemVer(version.Major(), version.Minor(), version.Patch(), "", "")
+	return !core.Less(*minCore), nil
+}
+
 // runtimeAllowed returns true if the runtime matches a valid OS.
 func (r Requirements) runtimeAllowed(os string, arch string, version string, distro string, dockerVariant string) bool {
 	if len(r.OS) == 0 {
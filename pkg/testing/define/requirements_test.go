@@ -0,0 +1,70 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package define
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequirementsValidateMinStackVersion(t *testing.T) {
+	req := Requirements{Group: Default, MinStackVersion: "9.1.0"}
+	assert.ErrorContains(t, req.Validate(), "min stack version can only be set when stack is defined")
+
+	req.Stack = &Stack{}
+	assert.NoError(t, req.Validate())
+
+	req.MinStackVersion = "not-a-version"
+	assert.ErrorContains(t, req.Validate(), "invalid min stack version")
+}
+
+func TestRequirementsValidateResources(t *testing.T) {
+	req := Requirements{Group: Default, Resources: &Resources{MinMemoryMB: 4096}}
+	assert.NoError(t, req.Validate())
+
+	req.Resources.MinMemoryMB = -1
+	assert.ErrorContains(t, req.Validate(), "invalid resources")
+}
+
+func TestRequirementsStackVersionAllowed(t *testing.T) {
+	testcases := []struct {
+		name         string
+		minVersion   string
+		stackVersion string
+		allowed      bool
+	}{
+		{name: "no minimum", minVersion: "", stackVersion: "8.0.0", allowed: true},
+		{name: "equal", minVersion: "9.1.0", stackVersion: "9.1.0", allowed: true},
+		{name: "newer", minVersion: "9.1.0", stackVersion: "9.2.0", allowed: true},
+		{name: "older", minVersion: "9.1.0", stackVersion: "9.0.5", allowed: false},
+		{name: "snapshot stack", minVersion: "9.1.0", stackVersion: "9.1.0-SNAPSHOT", allowed: true},
+		{name: "snapshot minimum", minVersion: "9.1.0-SNAPSHOT", stackVersion: "9.1.0", allowed: true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := Requirements{Group: Default, Stack: &Stack{}, MinStackVersion: tc.minVersion}
+			allowed, err := req.stackVersionAllowed(tc.stackVersion)
+			require.NoError(t, err)
+			assert.Equal(t, tc.allowed, allowed)
+		})
+	}
+}
+
+func TestRequirementsRuntimeAllowedArch(t *testing.T) {
+	req := Requirements{Group: Default, OS: []OS{{Type: Linux, Arch: AMD64}}}
+	require.NoError(t, req.Validate())
+	assert.True(t, req.runtimeAllowed(Linux, AMD64, "", "", ""))
+	assert.False(t, req.runtimeAllowed(Linux, ARM64, "", "", ""))
+
+	// empty arch allows any architecture
+	req.OS[0].Arch = ""
+	assert.True(t, req.runtimeAllowed(Linux, AMD64, "", "", ""))
+	assert.True(t, req.runtimeAllowed(Linux, ARM64, "", "", ""))
+
+	req.OS[0].Arch = "386"
+	assert.ErrorContains(t, req.Validate(), "arch must be either amd64 or arm64")
+}
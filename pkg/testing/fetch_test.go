@@ -5,7 +5,12 @@
 package testing
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
 	"errors"
+	"os"
+	"path/filepath"
 	gtesting "testing"
 
 	"github.com/stretchr/testify/require"
@@ -56,3 +61,87 @@ func TestGetPackageSuffix(t *gtesting.T) {
 		})
 	}
 }
+
+var archiveTestFiles = []string{
+	"elastic-agent-9.1.0-linux-x86_64/elastic-agent",
+	"elastic-agent-9.1.0-linux-x86_64/data/elastic-agent-abc123/elastic-agent",
+	"elastic-agent-9.1.0-linux-x86_64/data/elastic-agent-abc123/components/apm-server",
+	"elastic-agent-9.1.0-linux-x86_64/data/elastic-agent-abc123/components/apm-server.spec.yml",
+	"elastic-agent-9.1.0-linux-x86_64/data/elastic-agent-abc123/components/agentbeat.exe",
+	"elastic-agent-9.1.0-linux-x86_64/data/elastic-agent-abc123/components/agentbeat.spec.yml",
+	// files without extension that have no spec file are not components
+	"elastic-agent-9.1.0-linux-x86_64/data/elastic-agent-abc123/components/NOTICE",
+	"elastic-agent-9.1.0-linux-x86_64/data/elastic-agent-abc123/components/README",
+	"elastic-agent-9.1.0-linux-x86_64/data/elastic-agent-abc123/components/module/nested",
+	"elastic-agent-9.1.0-linux-x86_64/components/not-a-component",
+}
+
+func TestArchiveComponents(t *gtesting.T) {
+	expected := []string{"agentbeat", "apm-server"}
+
+	t.Run("tar.gz", func(t *gtesting.T) {
+		archivePath := filepath.Join(t.TempDir(), "elastic-agent.tar.gz")
+		f, err := os.Create(archivePath)
+		require.NoError(t, err)
+		zw := gzip.NewWriter(f)
+		tw := tar.NewWriter(zw)
+		for _, name := range archiveTestFiles {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: 0, Typeflag: tar.TypeReg}))
+		}
+		require.NoError(t, tw.Close())
+		require.NoError(t, zw.Close())
+		require.NoError(t, f.Close())
+
+		components, err := ArchiveComponents(archivePath)
+		require.NoError(t, err)
+		require.Equal(t, expected, components)
+	})
+
+	t.Run("zip", func(t *gtesting.T) {
+		archivePath := filepath.Join(t.TempDir(), "elastic-agent.zip")
+		f, err := os.Create(archivePath)
+		require.NoError(t, err)
+		zw := zip.NewWriter(f)
+		for _, name := range archiveTestFiles {
+			_, err := zw.Create(name)
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		require.NoError(t, f.Close())
+
+		components, err := ArchiveComponents(archivePath)
+		require.NoError(t, err)
+		require.Equal(t, expected, components)
+	})
+
+	t.Run("rebuilt archive", func(t *gtesting.T) {
+		archivePath := filepath.Join(t.TempDir(), "elastic-agent.zip")
+		writeZip := func(names ...string) {
+			f, err := os.Create(archivePath)
+			require.NoError(t, err)
+			zw := zip.NewWriter(f)
+			for _, name := range names {
+				_, err := zw.Create(name)
+				require.NoError(t, err)
+			}
+			require.NoError(t, zw.Close())
+			require.NoError(t, f.Close())
+		}
+
+		writeZip(archiveTestFiles[:4]...)
+		components, err := ArchiveComponents(archivePath)
+		require.NoError(t, err)
+		require.Equal(t, []string{"apm-server"}, components)
+
+		// the cached result is not reused once the archive changed
+		writeZip(archiveTestFiles...)
+		components, err = ArchiveComponents(archivePath)
+		require.NoError(t, err)
+		require.Equal(t, expected, components)
+	})
+
+	t.Run("unknown", func(t *gtesting.T) {
+		_, err := ArchiveComponents("elastic-agent.deb")
+		require.ErrorContains(t, err, "unknown archive type")
+	})
+}
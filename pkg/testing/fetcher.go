@@ -13,7 +13,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -250,3 +253,135 @@ func unzip(archivePath string, extractDir string) error {
 	}
 	return nil
 }
+
+// archiveComponentsCache caches the components packaged in each archive, so an archive is only read
+// once for all the tests requiring components.
+var (
+	archiveComponentsCache   = make(map[archiveKey][]string)
+	archiveComponentsCacheMx sync.Mutex
+)
+
+// archiveKey identifies an archive, the size and modification time ensure a rebuilt archive is read again.
+type archiveKey struct {
+	path    string
+	size    int64
+	modTime int64
+}
+
+// ArchiveComponents returns the names of the component binaries packaged in the Elastic Agent
+// archive (.tar.gz or .zip) at archivePath, without extracting it. Only binaries with a
+// <name>.spec.yml file next to them are reported. The names are sorted and do not include
+// the ".exe" extension of Windows binaries. The result is cached per archive.
+func ArchiveComponents(archivePath string) ([]string, error) {
+	var walk func(string, func(string)) error
+	switch {
+	case strings.HasSuffix(archivePath, ".tar.gz"):
+		walk = walkTarFiles
+	case strings.HasSuffix(archivePath, ".zip"):
+		walk = walkZipFiles
+	default:
+		return nil, fmt.Errorf("unknown archive type for %s", archivePath)
+	}
+	fi, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", archivePath, err)
+	}
+	key := archiveKey{path: archivePath, size: fi.Size(), modTime: fi.ModTime().UnixNano()}
+
+	archiveComponentsCacheMx.Lock()
+	defer archiveComponentsCacheMx.Unlock()
+	if components, ok := archiveComponentsCache[key]; ok {
+		return slices.Clone(components), nil
+	}
+
+	// component binaries have a spec file next to them, that tells them apart from the other
+	// files without extension, e.g. a NOTICE
+	binaries := make(map[string]string)
+	specs := make(map[string]bool)
+	err = walk(archivePath, func(name string) {
+		dir, file, ok := archiveComponentsFile(name)
+		if !ok {
+			return
+		}
+		if spec, ok := strings.CutSuffix(file, ".spec.yml"); ok {
+			specs[path.Join(dir, spec)] = true
+		} else if ext := path.Ext(file); ext == "" || ext == ".exe" {
+			component := strings.TrimSuffix(file, ".exe")
+			binaries[path.Join(dir, component)] = component
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", archivePath, err)
+	}
+	var components []string
+	for binary, component := range binaries {
+		if specs[binary] {
+			components = append(components, component)
+		}
+	}
+	sort.Strings(components)
+	archiveComponentsCache[key] = components
+	return slices.Clone(components), nil
+}
+
+// archiveComponentsFile splits name into its directory and file name when it is a file directly
+// under the data/elastic-agent-*/components directory of the archive.
+func archiveComponentsFile(name string) (string, string, bool) {
+	dir, file := path.Split(name)
+	if file == "" {
+		return "", "", false
+	}
+	versionDir, components := path.Split(strings.TrimSuffix(dir, "/"))
+	if components != "components" {
+		return "", "", false
+	}
+	dataDir, home := path.Split(strings.TrimSuffix(versionDir, "/"))
+	if !strings.HasPrefix(home, "elastic-agent-") || path.Base(dataDir) != "data" {
+		return "", "", false
+	}
+	return dir, file, true
+}
+
+// walkTarFiles calls fn with the name of every regular file in the .tar.gz at archivePath.
+func walkTarFiles(archivePath string, fn func(string)) error {
+	r, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(zr)
+	for {
+		f, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().Mode().IsRegular() {
+			fn(f.Name)
+		}
+	}
+}
+
+// walkZipFiles calls fn with the name of every regular file in the .zip at archivePath.
+func walkZipFiles(archivePath string, fn func(string)) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().Mode().IsRegular() {
+			fn(f.Name)
+		}
+	}
+	return nil
+}
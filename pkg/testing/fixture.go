@@ -5,15 +5,19 @@
 package testing
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -27,13 +31,19 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
+	v1 "github.com/elastic/elastic-agent/pkg/api/v1"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/control"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
 	"github.com/elastic/elastic-agent/pkg/core/process"
+	semver "github.com/elastic/elastic-agent/pkg/version"
 )
 
+// ErrShutdownTimeout is returned by the otel runs when the collector did not exit within
+// OtelRunOptions.ShutdownTimeout after the context was cancelled and had to be killed.
+var ErrShutdownTimeout = errors.New("elastic-agent did not exit within the shutdown timeout")
+
 // Fixture handles the setup and management of the Elastic Agent.
 type Fixture struct {
 	t       *testing.T
@@ -51,7 +61,10 @@ type Fixture struct {
 	binaryName      string
 	runLength       time.Duration
 	additionalArgs  []string
+	env             map[string]string
+	inputFiles      map[string]string
 	fipsArtifact    bool
+	keepWorkDir     bool
 
 	srcPackage string
 	workDir    string
@@ -72,10 +85,24 @@ type Fixture struct {
 	// its value.
 	fileNamePrefix string
 
-	// procMutex protects access to proc and stopping
+	// procMutex protects access to proc, procArgs and stopping
 	procMutex sync.Mutex
 	proc      *process.Info
+	procArgs  []string
 	stopping  bool
+
+	// outputMx protects access to outputSubs, the subscribers to the output of the Elastic Agent,
+	// and outputHistory, the last lines output by the running Elastic Agent
+	outputMx      sync.Mutex
+	outputSubs    map[chan string]struct{}
+	outputHistory []string
+
+	// proxyMx protects access to proxies, the endpoint proxies started with ProxyEndpoint
+	proxyMx sync.Mutex
+	proxies []*endpointProxy
+
+	// inputFilesStaged is set once the files of WithInputFiles are written
+	inputFilesStaged bool
 }
 
 // FixtureOpt is an option for the fixture.
@@ -153,6 +180,53 @@ func WithAdditionalArgs(args []string) FixtureOpt {
 	}
 }
 
+// WithEnv sets environment variables for the processes started by the fixture, e.g. to test
+// collector configurations using `${env:VAR}` expansion. The variables are added to the
+// environment of the test process, overriding the ones with the same name. WithEnv can be
+// used several times, the variables are merged.
+func WithEnv(env map[string]string) FixtureOpt {
+	return func(f *Fixture) {
+		if f.env == nil {
+			f.env = make(map[string]string, len(env))
+		}
+		for k, v := range env {
+			f.env[k] = v
+		}
+	}
+}
+
+// WithInputFiles stages the files, by path, with their content before the Elastic Agent is first
+// run by the fixture, e.g. the files read by a filelog receiver configured with
+// `start_at: beginning`, so the collector reads known content from its start instead of racing
+// the test writing them. A relative path is relative to the fixture work directory. Each file is
+// written to a temporary file renamed into place, so a receiver never reads a partial file. The
+// staged files are removed when the test ends. WithInputFiles can be used several times, the
+// files are merged.
+func WithInputFiles(files map[string]string) FixtureOpt {
+	return func(f *Fixture) {
+		if f.inputFiles == nil {
+			f.inputFiles = make(map[string]string, len(files))
+		}
+		for path, content := range files {
+			f.inputFiles[path] = content
+		}
+	}
+}
+
+// KeepWorkDirOnFailureEnv is the environment variable that, when set to true, enables
+// WithKeepWorkDirOnFailure for every fixture.
+const KeepWorkDirOnFailureEnv = "KEEP_WORKDIR_ON_FAILURE"
+
+// WithKeepWorkDirOnFailure instructs the fixture to copy its work directory into the
+// diagnostics directory when the test fails, so the configs, logs and data of the
+// Elastic Agent survive the cleanup of the temporary directories. Setting the
+// KEEP_WORKDIR_ON_FAILURE environment variable to true has the same effect.
+func WithKeepWorkDirOnFailure() FixtureOpt {
+	return func(f *Fixture) {
+		f.keepWorkDir = true
+	}
+}
+
 func WithFIPSArtifact() FixtureOpt {
 	return func(f *Fixture) {
 		f.fipsArtifact = true
@@ -174,6 +248,7 @@ func NewFixture(t *testing.T, version string, opts ...FixtureOpt) (*Fixture, err
 	if runtime.GOOS == "windows" {
 		pkgFormat = "zip"
 	}
+	keepWorkDir, _ := strconv.ParseBool(os.Getenv(KeepWorkDirOnFailureEnv))
 	f := &Fixture{
 		t:               t,
 		version:         version,
@@ -183,12 +258,52 @@ func NewFixture(t *testing.T, version string, opts ...FixtureOpt) (*Fixture, err
 		architecture:    runtime.GOARCH,
 		packageFormat:   pkgFormat,
 		connectTimout:   15 * time.Second,
+		keepWorkDir:     keepWorkDir,
 		// default to elastic-agent, can be changed by a set FixtureOpt below
 		binaryName: "elastic-agent",
 	}
 	for _, o := range opts {
 		o(f)
 	}
+	if f.keepWorkDir {
+		t.Cleanup(f.keepWorkDirOnFailure)
+	}
+	return f, nil
+}
+
+// AttachFixture creates a fixture for the Elastic Agent already present in dir, e.g. an Elastic
+// Agent installed manually or extracted by hand, instead of fetching and preparing a new one. This
+// shortens the iteration loop when debugging a test against a local build.
+//
+// The returned fixture is already prepared: Exec, IsHealthy and the RunOtel functions run the
+// elastic-agent binary in dir, and the version of the fixture is read from that binary. dir is
+// never removed by the fixture.
+func AttachFixture(t *testing.T, dir string, opts ...FixtureOpt) (*Fixture, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path of %s: %w", dir, err)
+	}
+	f, err := NewFixture(t, "", opts...)
+	if err != nil {
+		return nil, err
+	}
+	f.srcPackage = dir
+	f.extractDir = dir
+	f.workDir = dir
+	if _, err := os.Stat(f.binaryPath()); err != nil {
+		return nil, fmt.Errorf("failed to find the %s binary in %s: %w", f.binaryName, dir, err)
+	}
+
+	version, err := f.ExecVersion(t.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the version of the Elastic Agent in %s: %w", dir, err)
+	}
+	f.version = version.Binary.String()
+	f.hash = version.Binary.Commit
+
+	socketPath := paths.ControlSocketFromPath(f.operatingSystem, dir)
+	f.setSocketPath(socketPath)
+	f.setClient(client.New(client.WithAddress(socketPath)))
 	return f, nil
 }
 
@@ -355,6 +470,17 @@ func (f *Fixture) SrcPackage(ctx context.Context) (string, error) {
 	return f.srcPackage, nil
 }
 
+// PackagedComponents returns the names of the component binaries packaged in the artifact of this fixture,
+// see ArchiveComponents. The artifact is fetched with the fixture's fetcher when it is not cached yet, the
+// fixture doesn't need to be prepared.
+func (f *Fixture) PackagedComponents(ctx context.Context) ([]string, error) {
+	src, err := f.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the artifact: %w", err)
+	}
+	return ArchiveComponents(src)
+}
+
 // PackageFormat returns the package format for the  fixture
 func (f *Fixture) PackageFormat() string {
 	return f.packageFormat
@@ -444,6 +570,7 @@ func (f *Fixture) RunBeat(ctx context.Context) error {
 		f.binaryPath(),
 		process.WithContext(ctx),
 		process.WithArgs(args),
+		process.WithEnv(f.envList()),
 		process.WithCmdOptions(attachOutErr(stdOut, stdErr)))
 	if err != nil {
 		return fmt.Errorf("failed to spawn %s: %w", f.binaryName, err)
@@ -501,60 +628,167 @@ func RunProcess(t *testing.T,
 	logOutput, allowErrs bool,
 	processPath string, args ...string,
 ) error {
+	_, err := RunProcessResult(t, lp, ctx, runLength, logOutput, allowErrs, processPath, args...)
+	return err
+}
+
+// ProcessResult is the outcome of a process run by RunProcessResult.
+type ProcessResult struct {
+	// ExitCode is the exit code of the process, -1 when it was killed or never exited.
+	ExitCode int
+	// Stdout is the output the process wrote to stdout.
+	Stdout string
+	// Stderr is the output the process wrote to stderr.
+	Stderr string
+	// Duration is the time the process ran for.
+	Duration time.Duration
+}
+
+// RunProcessResult runs the given process like RunProcess, and also returns its exit code,
+// captured output and run duration so callers can report why a process exited early.
+func RunProcessResult(t *testing.T,
+	lp Logger,
+	ctx context.Context, runLength time.Duration,
+	logOutput, allowErrs bool,
+	processPath string, args ...string,
+) (ProcessResult, error) {
+	return RunProcessWithOptions(t, lp, ctx, RunProcessOptions{
+		RunLength: runLength,
+		LogOutput: logOutput,
+		AllowErrs: allowErrs,
+	}, processPath, args...)
+}
+
+// RunProcessOptions are the options of RunProcessWithOptions.
+type RunProcessOptions struct {
+	// RunLength is the time after which the process is stopped, it runs until ctx is cancelled when zero.
+	RunLength time.Duration
+	// LogOutput replicates the output of the process to the Logger.
+	LogOutput bool
+	// AllowErrs allows the process to log errors, by default it is killed when it logs one.
+	AllowErrs bool
+	// GracePeriod is the time the process is given to shut down cleanly when ctx is cancelled.
+	// It's first sent SIGTERM (CTRL_BREAK_EVENT on Windows) and only killed when it did not exit
+	// within the grace period. By default, the process is killed right away.
+	GracePeriod time.Duration
+	// OutputWaitDelay is how long the output of the process is still read after it exited, a child
+	// process can keep the output open. The remaining output is dropped after the delay, which
+	// defaults to DefaultOutputWaitDelay.
+	OutputWaitDelay time.Duration
+}
+
+// RunProcessWithOptions runs the given process like RunProcessResult, with the given options.
+func RunProcessWithOptions(t *testing.T,
+	lp Logger,
+	ctx context.Context, opts RunProcessOptions,
+	processPath string, args ...string,
+) (ProcessResult, error) {
 	if _, deadlineSet := ctx.Deadline(); !deadlineSet {
 		t.Fatal("Context passed to RunProcess() has no deadline set.")
 	}
 
-	var err error
+	outputWaitDelay := opts.OutputWaitDelay
+	if outputWaitDelay <= 0 {
+		outputWaitDelay = DefaultOutputWaitDelay
+	}
+
+	procCtx := ctx
+	if opts.GracePeriod > 0 {
+		// the process is stopped below when ctx is cancelled, don't let exec kill it right away
+		procCtx = context.WithoutCancel(ctx)
+	}
+
 	var logProxy Logger
-	if logOutput {
+	if opts.LogOutput {
 		logProxy = lp
 	}
 	stdOut := newLogWatcher(logProxy)
 	stdErr := newLogWatcher(logProxy)
+	output := &processOutput{}
 
+	result := ProcessResult{ExitCode: -1}
+	started := time.Now()
 	proc, err := process.Start(
 		processPath,
-		process.WithContext(ctx),
+		process.WithContext(procCtx),
 		process.WithArgs(args),
-		process.WithCmdOptions(attachOutErr(stdOut, stdErr)))
+		process.WithCmdOptions(output.capture(stdOut, stdErr)))
+	output.started()
 	if err != nil {
-		return fmt.Errorf("failed to spawn %q: %w", processPath, err)
+		output.wait(0)
+		return result, fmt.Errorf("failed to spawn %q: %w", processPath, err)
 	}
 
 	procWaitCh := proc.Wait()
-	killProc := func() {
+	exited := func(ps *os.ProcessState) ProcessResult {
+		if ps != nil {
+			result.ExitCode = ps.ExitCode()
+		}
+		// the process already exited, wait for its output to be copied
+		if !output.wait(outputWaitDelay) {
+			t.Logf("output of %s was still open %s after it exited, the captured output may be truncated", processPath, outputWaitDelay)
+		}
+		result.Stdout = output.stdOut.String()
+		result.Stderr = output.stdErr.String()
+		result.Duration = time.Since(started)
+		return result
+	}
+	killProc := func() ProcessResult {
 		_ = proc.Kill()
-		<-procWaitCh
+		return exited(<-procWaitCh)
+	}
+	terminateProc := func() ProcessResult {
+		signal := "SIGTERM"
+		if runtime.GOOS == "windows" {
+			signal = "CTRL_BREAK_EVENT"
+		}
+		if err := proc.Stop(); err != nil {
+			t.Logf("failed to send %s to %s, killing it: %s", signal, processPath, err)
+			return killProc()
+		}
+		gracePeriod := time.After(opts.GracePeriod)
+		for {
+			select {
+			case ps := <-procWaitCh:
+				t.Logf("%s stopped with %s", processPath, signal)
+				return exited(ps)
+			case <-gracePeriod:
+				t.Logf("%s did not stop within %s after %s, killing it with SIGKILL", processPath, opts.GracePeriod, signal)
+				return killProc()
+			case <-stdOut.Watch():
+				// errors logged while shutting down are ignored
+			case <-stdErr.Watch():
+			}
+		}
 	}
 
 	var doneChan <-chan time.Time
-	if runLength != 0 {
-		doneChan = time.After(runLength)
+	if opts.RunLength != 0 {
+		doneChan = time.After(opts.RunLength)
 	}
 
 	stopping := false
 	for {
 		select {
 		case <-ctx.Done():
-			killProc()
-			return ctx.Err()
+			if opts.GracePeriod > 0 {
+				return terminateProc(), ctx.Err()
+			}
+			return killProc(), ctx.Err()
 		case ps := <-procWaitCh:
 			if stopping {
-				return nil
+				return exited(ps), nil
 			}
-			return fmt.Errorf("elastic-agent exited unexpectedly with exit code: %d", ps.ExitCode())
+			return exited(ps), fmt.Errorf("elastic-agent exited unexpectedly with exit code: %d", ps.ExitCode())
 		case err := <-stdOut.Watch():
-			if !allowErrs {
+			if !opts.AllowErrs {
 				// no errors allowed
-				killProc()
-				return fmt.Errorf("elastic-agent logged an unexpected error: %w", err)
+				return killProc(), fmt.Errorf("elastic-agent logged an unexpected error: %w", err)
 			}
 		case err := <-stdErr.Watch():
-			if !allowErrs {
+			if !opts.AllowErrs {
 				// no errors allowed
-				killProc()
-				return fmt.Errorf("elastic-agent logged an unexpected error: %w", err)
+				return killProc(), fmt.Errorf("elastic-agent logged an unexpected error: %w", err)
 			}
 		case <-doneChan:
 			if !stopping {
@@ -566,30 +800,107 @@ func RunProcess(t *testing.T,
 	}
 }
 
-// RunOtelWithClient runs the provided binary in otel mode.
+// RunOtelWithClient runs the provided binary in otel mode until each of states has been reached,
+// or until the context is cancelled when no states are provided.
 //
-// If `states` are provided, agent runs until each state has been reached. Once reached the
-// Elastic Agent is stopped. If at any time the Elastic Agent logs an error log and the Fixture is not started
-// with `WithAllowErrors()` then `Run` will exit early and return the logged error.
+// Deprecated: use [Fixture.RunOtelWithOptions] with [OtelRunOptions.States], which also exposes the
+// other settings of the run.
+func (f *Fixture) RunOtelWithClient(ctx context.Context, states ...State) error {
+	return f.RunOtelWithOptions(ctx, OtelRunOptions{States: states})
+}
+
+// OtelRunOptions configures how [Fixture.RunOtelWithClientAsync] runs the collector.
+type OtelRunOptions struct {
+	// States are the states the Elastic Agent runs until, see [Fixture.RunOtelWithOptions].
+	States []State
+	// FeatureGates are forwarded to the collector's feature gate registry with `--feature-gates`,
+	// e.g. "exporter.elasticsearch.example" to enable or "-exporter.elasticsearch.example" to disable a gate.
+	FeatureGates []string
+	// ShutdownTimeout is how long the collector is given to exit once the context is cancelled.
+	// When set, cancelling the context stops the collector gracefully (SIGTERM, CTRL_BREAK_EVENT on
+	// Windows) and kills it only if it is still running after ShutdownTimeout, in which case the run
+	// returns ErrShutdownTimeout instead of the context error. When zero, the collector is killed
+	// as soon as the context is cancelled.
+	//
+	// On shutdown, exporters with an in-memory `sending_queue` try to export the queued data before
+	// exiting, so ShutdownTimeout should be longer than the time the exporters need to drain their
+	// queues, bounded by their own `timeout`. Data still queued when the collector is killed is lost,
+	// unless the queue is persisted with `sending_queue::storage`.
+	ShutdownTimeout time.Duration
+	// Args are additional arguments passed to the collector for this run only, after the ones set with
+	// `WithAdditionalArgs()`, e.g. "--set=processors::batch::timeout=2s" to override a value of a base
+	// config file without rebuilding the fixture.
+	Args []string
+}
+
+func (o OtelRunOptions) args() []string {
+	var args []string
+	if len(o.FeatureGates) > 0 {
+		args = append(args, "--feature-gates="+strings.Join(o.FeatureGates, ","))
+	}
+	return append(args, o.Args...)
+}
+
+// executeOptions returns the options running the collector, with args before the arguments of o.
+func (o OtelRunOptions) executeOptions(args ...string) executeOptions {
+	return executeOptions{
+		command:         "otel",
+		args:            append(args, o.args()...),
+		shutdownTimeout: o.ShutdownTimeout,
+		states:          o.States,
+	}
+}
+
+// RunOtelWithOptions runs the provided binary in otel mode configured by opts.
 //
-// If no `states` are provided then the Elastic Agent runs until the context is cancelled.
+// If opts.States are provided, the collector runs until each state has been reached. Once reached
+// it is stopped. If at any time the collector logs an error log and the Fixture is not started
+// with `WithAllowErrors()` then the run exits early and returns the logged error.
 //
-// The Elastic-Agent is started agent in test mode (--testing-mode) this mode
-// expects the initial configuration (full YAML config) via gRPC.
-// This configuration should be passed in the State.Configure field.
+// If no states are provided then the collector runs until the context is cancelled.
 //
-// The `elastic-agent.yml` generated by `Fixture.Configure` is ignored
-// when `Run` is called.
+// The collector configuration is passed with `--config` through `WithAdditionalArgs()` or
+// opts.Args, see also [Fixture.RunOtelWithConfig].
+func (f *Fixture) RunOtelWithOptions(ctx context.Context, opts OtelRunOptions) error {
+	return f.executeWithClient(ctx, opts.executeOptions())
+}
+
+// RunOtelWithClientAsync starts the provided binary in otel mode in the background and
+// returns immediately.
 //
-// if shouldWatchState is set to false, communicating state does not happen.
-func (f *Fixture) RunOtelWithClient(ctx context.Context, states ...State) error {
-	return f.executeWithClient(ctx, "otel", false, false, false, states...)
+// Once the collector exits, the result of the run is sent to errCh: nil on a clean exit,
+// otherwise the error that stopped it. This includes startup failures (bad port, TLS issue),
+// unexpected exits and logged errors when the Fixture is not started with `WithAllowErrors()`,
+// so callers can select on errCh to fail fast instead of waiting for their assertions to time out.
+// Exactly one value is sent, errCh should be buffered if the caller may stop receiving from it.
+func (f *Fixture) RunOtelWithClientAsync(ctx context.Context, opts OtelRunOptions, errCh chan<- error) {
+	go func() {
+		errCh <- f.executeWithClient(ctx, opts.executeOptions())
+	}()
+}
+
+// otelConfigEnvVar is the environment variable holding the configuration passed to RunOtelWithConfig.
+const otelConfigEnvVar = "ELASTIC_AGENT_TEST_OTEL_CONFIG"
+
+// RunOtelWithConfig runs the provided binary in otel mode with cfg as the collector configuration.
+//
+// The configuration is kept in memory: it is set in the environment of the collector and passed with
+// the collector's `env:` config provider, so nothing is written to disk and tests don't have to write
+// and clean up config files themselves. It is merged after any `--config` passed with
+// `WithAdditionalArgs()`. The environment is limited in size (32K characters on Windows), use a config
+// file for large configurations.
+//
+// It otherwise behaves like [Fixture.RunOtelWithOptions].
+func (f *Fixture) RunOtelWithConfig(ctx context.Context, cfg []byte, opts OtelRunOptions) error {
+	execOpts := opts.executeOptions("--config=env:" + otelConfigEnvVar)
+	execOpts.env = []string{otelConfigEnvVar + "=" + string(cfg)}
+	return f.executeWithClient(ctx, execOpts)
 }
 
 // Stop gracefully stops the Elastic Agent process that has been started
-// by [RunOtelWithCliet] or [Run].
+// by one of the RunOtel functions or [Run].
 // If the Elastic Agent has been installed, or the process
-// has not been started by [RunOtelWithClient] or [Run],
+// has not been started by one of the RunOtel functions or [Run],
 // Stop fails the test by calling t.Error
 func (f *Fixture) Stop() {
 	f.procMutex.Lock()
@@ -610,7 +921,46 @@ func (f *Fixture) Stop() {
 	}
 }
 
-func (f *Fixture) executeWithClient(ctx context.Context, command string, disableEncryptedStore bool, shouldWatchState bool, enableTestingMode bool, states ...State) error {
+// markStopping marks the Elastic Agent as stopping and returns whether it already was.
+func (f *Fixture) markStopping() bool {
+	f.procMutex.Lock()
+	defer f.procMutex.Unlock()
+	stopping := f.stopping
+	f.stopping = true
+	return stopping
+}
+
+func (f *Fixture) isStopping() bool {
+	f.procMutex.Lock()
+	defer f.procMutex.Unlock()
+	return f.stopping
+}
+
+// executeOptions configures how executeWithClient runs the Elastic Agent.
+type executeOptions struct {
+	// command is the elastic-agent command to run, e.g. "run" or "otel".
+	command string
+	// disableEncryptedStore adds `--disable-encrypted-store`, the otel command doesn't accept it.
+	disableEncryptedStore bool
+	// watchState watches the state of the Elastic Agent through its control socket, which the otel
+	// command doesn't serve.
+	watchState bool
+	// testingMode adds `--testing-mode`, the initial configuration is then expected through the control
+	// protocol, the otel command doesn't accept it.
+	testingMode bool
+	// args are added after the arguments set with `WithAdditionalArgs()`.
+	args []string
+	// env are environment variables in the KEY=VALUE form added to the ones set with `WithEnv()`.
+	env []string
+	// shutdownTimeout is how long the process is given to exit once the context is cancelled, see
+	// OtelRunOptions.ShutdownTimeout.
+	shutdownTimeout time.Duration
+	// states are the states the Elastic Agent runs until.
+	states []State
+}
+
+func (f *Fixture) executeWithClient(ctx context.Context, opts executeOptions) error {
+	command, states, shutdownTimeout := opts.command, opts.states, opts.shutdownTimeout
 	if _, deadlineSet := ctx.Deadline(); !deadlineSet {
 		f.t.Error("Context passed to Fixture.Run() has no deadline set.")
 	}
@@ -627,6 +977,9 @@ func (f *Fixture) executeWithClient(ctx context.Context, command string, disable
 	if err != nil {
 		return err
 	}
+	if err := f.stageInputFiles(); err != nil {
+		return err
+	}
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -649,10 +1002,8 @@ func (f *Fixture) executeWithClient(ctx context.Context, command string, disable
 		return fmt.Errorf("failed to get control protcol address: %w", err)
 	}
 
-	var logProxy Logger
-	if f.logOutput {
-		logProxy = f.t
-	}
+	f.resetOutputHistory()
+	logProxy := f.outputLogger()
 	stdOut := newLogWatcher(logProxy)
 	stdErr := newLogWatcher(logProxy)
 
@@ -660,28 +1011,37 @@ func (f *Fixture) executeWithClient(ctx context.Context, command string, disable
 	if command != "otel" {
 		// otel command doesn't share these arguments with elastic-agent
 		args = append(args, "-e")
-		if disableEncryptedStore {
+		if opts.disableEncryptedStore {
 			args = append(args, "--disable-encrypted-store")
 		}
-		if enableTestingMode {
+		if opts.testingMode {
 			args = append(args, "--testing-mode")
 		}
 	}
 
 	args = append(args, f.additionalArgs...)
+	args = append(args, opts.args...)
+
+	procCtx := ctx
+	if shutdownTimeout > 0 {
+		// the process is stopped gracefully when ctx is done, it must not be killed by the context
+		procCtx = context.WithoutCancel(ctx)
+	}
 
 	f.procMutex.Lock()
 	f.proc, err = process.Start(
 		f.binaryPath(),
-		process.WithContext(ctx),
+		process.WithContext(procCtx),
 		process.WithArgs(args),
+		process.WithEnv(append(f.envList(), opts.env...)),
 		process.WithCmdOptions(attachOutErr(stdOut, stdErr)))
+	f.procArgs = args
 	f.procMutex.Unlock()
 	if err != nil {
 		return fmt.Errorf("failed to spawn %s: %w", f.binaryName, err)
 	}
 
-	if shouldWatchState {
+	if opts.watchState {
 		agentClient = client.New(client.WithAddress(cAddr))
 		f.setClient(agentClient)
 		defer f.setClient(nil)
@@ -699,6 +1059,29 @@ func (f *Fixture) executeWithClient(ctx context.Context, command string, disable
 		<-procWaitCh
 	}
 
+	// stopProc stops the process, errors logged while shutting down are ignored
+	stopProc := func() {
+		if !f.markStopping() {
+			_ = f.proc.Stop()
+		}
+	}
+	shutdownProc := func() error {
+		stopProc()
+		timeout := time.After(shutdownTimeout)
+		for {
+			select {
+			case <-procWaitCh:
+				return ctx.Err()
+			case <-timeout:
+				killProc()
+				return fmt.Errorf("%w of %s, it was killed", ErrShutdownTimeout, shutdownTimeout)
+			case <-stdOut.Watch():
+				// the log watchers block until their alerts are received
+			case <-stdErr.Watch():
+			}
+		}
+	}
+
 	f.procMutex.Lock()
 	f.stopping = false
 	f.procMutex.Unlock()
@@ -706,10 +1089,13 @@ func (f *Fixture) executeWithClient(ctx context.Context, command string, disable
 	for {
 		select {
 		case <-ctx.Done():
+			if shutdownTimeout > 0 {
+				return shutdownProc()
+			}
 			killProc()
 			return ctx.Err()
 		case ps := <-procWaitCh:
-			if f.stopping {
+			if f.isStopping() {
 				return nil
 			}
 			return fmt.Errorf("elastic-agent exited unexpectedly with exit code: %d", ps.ExitCode())
@@ -726,7 +1112,7 @@ func (f *Fixture) executeWithClient(ctx context.Context, command string, disable
 				return fmt.Errorf("elastic-agent logged an unexpected error: %w", err)
 			}
 		case err := <-stateErrCh:
-			if !f.stopping {
+			if !f.isStopping() {
 				// Give the log watchers a second to write out the agent logs.
 				// Client connnection failures can happen quickly enough to prevent logging.
 				time.Sleep(time.Second)
@@ -735,11 +1121,8 @@ func (f *Fixture) executeWithClient(ctx context.Context, command string, disable
 				return fmt.Errorf("elastic-agent client received unexpected error: %w", err)
 			}
 		case <-doneChan:
-			if !f.stopping {
-				// trigger the stop
-				f.stopping = true
-				_ = f.proc.Stop()
-			}
+			// trigger the stop
+			stopProc()
 		case state := <-stateCh:
 			if smInstance != nil {
 				cfg, cont, err := smInstance.next(ctx, state)
@@ -748,11 +1131,8 @@ func (f *Fixture) executeWithClient(ctx context.Context, command string, disable
 					return fmt.Errorf("state management failed with unexpected error: %w", err)
 				}
 				if !cont {
-					if !f.stopping {
-						// trigger the stop
-						f.stopping = true
-						_ = f.proc.Stop()
-					}
+					// trigger the stop
+					stopProc()
 				} else if cfg != "" {
 					err := performConfigure(ctx, agentClient, cfg, 3*time.Second)
 					if err != nil {
@@ -780,7 +1160,13 @@ func (f *Fixture) executeWithClient(ctx context.Context, command string, disable
 // The `elastic-agent.yml` generated by `Fixture.Configure` is ignored
 // when `Run` is called.
 func (f *Fixture) Run(ctx context.Context, states ...State) error {
-	return f.executeWithClient(ctx, "run", true, true, true, states...)
+	return f.executeWithClient(ctx, executeOptions{
+		command:               "run",
+		disableEncryptedStore: true,
+		watchState:            true,
+		testingMode:           true,
+		states:                states,
+	})
 }
 
 // Exec provides a way of performing subcommand on the prepared Elastic Agent binary.
@@ -812,6 +1198,9 @@ func (f *Fixture) PrepareAgentCommand(ctx context.Context, args []string, opts .
 
 	// #nosec G204 -- Not so many ways to support variadic arguments to the elastic-agent command :(
 	cmd := exec.CommandContext(ctx, f.binaryPath(), args...)
+	if len(f.env) > 0 {
+		cmd.Env = append(os.Environ(), f.envList()...)
+	}
 	for _, o := range opts {
 		if err := o(cmd); err != nil {
 			return nil, fmt.Errorf("error adding opts to Exec: %w", err)
@@ -821,6 +1210,16 @@ func (f *Fixture) PrepareAgentCommand(ctx context.Context, args []string, opts .
 	return cmd, nil
 }
 
+// envList returns the environment variables set with WithEnv in the KEY=VALUE form, sorted by key.
+func (f *Fixture) envList() []string {
+	env := make([]string, 0, len(f.env))
+	for k, v := range f.env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
 type ExecErr struct {
 	err    error
 	Output []byte
@@ -1038,10 +1437,80 @@ func (f *Fixture) IsHealthy(ctx context.Context, opts ...statusOpt) error {
 	}
 
 	if status.State != int(cproto.State_HEALTHY) {
-		return fmt.Errorf("agent isn't healthy, current state: %s, full status: %+v",
-			client.State(status.State), status) //nolint:gosec // value will never be over 32-bit
+		return fmt.Errorf("agent isn't healthy, current state: %s, unhealthy: %v, full status: %+v",
+			client.State(status.State), newAgentStatus(status).Unhealthy(), status) //nolint:gosec // value will never be over 32-bit
+	}
+
+	return nil
+}
+
+// controlSocketPollInterval is the interval at which WaitForControlSocket connects to the control socket.
+const controlSocketPollInterval = 250 * time.Millisecond
+
+// WaitForControlSocket waits at most timeout for the control socket of the Elastic Agent to accept
+// connections, so that an Elastic Agent that isn't up yet can be told apart from an unhealthy one
+// before checking its health with IsHealthy. The socket is the one of the installed or attached
+// Elastic Agent, otherwise the one of the Elastic Agent run from the work directory of the fixture.
+// It returns the last connection error when the socket doesn't accept connections in time.
+func (f *Fixture) WaitForControlSocket(ctx context.Context, timeout time.Duration) error {
+	addr, err := f.controlSocketAddress()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(controlSocketPollInterval)
+	defer ticker.Stop()
+	for {
+		conn, err := client.Dialer(ctx, addr)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("control socket %s is not accepting connections: %w", addr, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// controlSocketAddress returns the address of the control socket of the Elastic Agent.
+func (f *Fixture) controlSocketAddress() (string, error) {
+	f.cMx.RLock()
+	socketPath := f.socketPath
+	f.cMx.RUnlock()
+	if socketPath != "" {
+		return socketPath, nil
 	}
+	if f.workDir == "" {
+		return "", errors.New("no control socket, the fixture is not prepared")
+	}
+	addr, err := control.AddressFromPath(f.operatingSystem, f.workDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the control socket address: %w", err)
+	}
+	return addr, nil
+}
 
+// ReloadConfig makes the running standalone Elastic Agent reload its configuration files right away,
+// e.g. after the test changed them with Configure, instead of waiting for its next check for changes.
+// It returns an error when the reloaded configuration fails to be applied, in which case the previous
+// configuration keeps running.
+func (f *Fixture) ReloadConfig(ctx context.Context) error {
+	addr, err := f.controlSocketAddress()
+	if err != nil {
+		return err
+	}
+	c := client.New(client.WithAddress(addr))
+	if err := c.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to the control socket %s: %w", addr, err)
+	}
+	defer c.Disconnect()
+	if err := c.ReloadConfig(ctx); err != nil {
+		return fmt.Errorf("failed to reload the configuration: %w", err)
+	}
 	return nil
 }
 
@@ -1330,6 +1799,31 @@ func (f *Fixture) DumpProcesses(suffix string) {
 	}
 }
 
+// keepWorkDirOnFailure copies the work directory into the diagnostics directory when the test failed.
+func (f *Fixture) keepWorkDirOnFailure() {
+	if !f.t.Failed() || f.workDir == "" {
+		return
+	}
+	dir, err := f.DiagnosticsDir()
+	if err != nil {
+		f.t.Logf("failed to keep work directory %s: %s", f.workDir, err)
+		return
+	}
+	dest := filepath.Join(dir, f.FileNamePrefix()+"-workdir")
+	err = copy.Copy(f.workDir, dest, copy.Options{
+		Skip: func(srcinfo os.FileInfo, src, dest string) (bool, error) {
+			// skip the control sockets and other special files
+			mode := srcinfo.Mode()
+			return !mode.IsRegular() && !mode.IsDir() && mode&os.ModeSymlink == 0, nil
+		},
+	})
+	if err != nil {
+		f.t.Logf("failed to keep work directory %s: %s", f.workDir, err)
+		return
+	}
+	f.t.Logf("work directory %s kept at %s for investigation", f.workDir, dest)
+}
+
 // MoveToDiagnosticsDir moves file to 'build/diagnostics' which contents are
 // available on CI if the test fails or on the agent's 'build/diagnostics'
 // if the test is run locally.
@@ -1466,19 +1960,123 @@ func findAgentDataVersionDir(dir, version string) (string, error) {
 }
 
 // FindComponentsDir identifies the directory that holds the components.
+//
+// When dir holds the package manifest, the components directory is resolved from its versioned
+// home and path mappings. Otherwise, or when the resolved directory doesn't exist, the data
+// directory is searched for the versioned home of the given version (any version when empty).
+// The returned error lists every path that was tried.
 func FindComponentsDir(dir, version string) (string, error) {
+	var tried []string
+	componentsDir, err := componentsDirFromManifest(dir, version)
+	if err == nil {
+		if isDir(componentsDir) {
+			return componentsDir, nil
+		}
+		tried = append(tried, componentsDir)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		tried = append(tried, fmt.Sprintf("%s (%s)", filepath.Join(dir, v1.ManifestFileName), err))
+	}
+
 	versionDir, err := findAgentDataVersionDir(dir, version)
 	if err != nil {
-		return "", err
+		tried = append(tried, fmt.Sprintf("%s (%s)", filepath.Join(dir, "data", "elastic-agent-*", "components"), err))
+		return "", fmt.Errorf("failed to find components directory, tried: %s", strings.Join(tried, ", "))
 	}
-	componentsDir := filepath.Join(versionDir, "components")
-	fi, err := os.Stat(componentsDir)
-	if (err != nil && !os.IsExist(err)) || !fi.IsDir() {
-		return "", fmt.Errorf("failed to find components directory at %s: %w", componentsDir, err)
+	componentsDir = filepath.Join(versionDir, "components")
+	if !isDir(componentsDir) {
+		tried = append(tried, componentsDir)
+		return "", fmt.Errorf("failed to find components directory, tried: %s", strings.Join(tried, ", "))
 	}
 	return componentsDir, nil
 }
 
+// FindComponentBinary returns the path of the binary of the named component, e.g. "apm-server",
+// of the Elastic Agent in workDir. The ".exe" extension is added on Windows.
+//
+// When the package manifest in workDir declares a home for the component, see
+// v1.PackageManifest.ComponentHome, the binary is looked up there first. Otherwise it is looked up
+// in the components directory, where both components/<name> and components/<name>/<name> layouts
+// are supported.
+func FindComponentBinary(workDir, name string) (string, error) {
+	binary := name
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	var candidates []string
+	// an invalid manifest is reported by FindComponentsDir when the components directory isn't found
+	if manifest, err := readManifest(workDir); err == nil {
+		if home, ok := manifest.ComponentHome(name); ok {
+			candidates = append(candidates, filepath.Join(workDir, filepath.FromSlash(home), binary))
+		}
+	}
+	componentsDir, err := FindComponentsDir(workDir, "")
+	if err != nil && len(candidates) == 0 {
+		return "", err
+	}
+	if err == nil {
+		candidates = append(candidates,
+			filepath.Join(componentsDir, binary),
+			filepath.Join(componentsDir, name, binary),
+		)
+	}
+	for _, candidate := range candidates {
+		if fi, err := os.Stat(candidate); err == nil && fi.Mode().IsRegular() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("failed to find component %s binary, tried: %s", name, strings.Join(candidates, ", "))
+}
+
+// componentsDirFromManifest resolves the components directory from the package manifest in dir.
+// It returns an error wrapping os.ErrNotExist when dir holds no manifest.
+func componentsDirFromManifest(dir, version string) (string, error) {
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return "", err
+	}
+	if manifest.Package.VersionedHome == "" {
+		return "", errors.New("manifest has no versioned home")
+	}
+	if version != "" {
+		expected, err := semver.ParseVersion(version)
+		if err != nil {
+			return "", fmt.Errorf("invalid version %q: %w", version, err)
+		}
+		actual, err := manifest.Package.SemVer()
+		if err != nil {
+			return "", err
+		}
+		if actual.CoreVersion() != expected.CoreVersion() {
+			return "", fmt.Errorf("manifest is for version %s", manifest.Package.Version)
+		}
+	}
+	components, _ := manifest.ResolvePath(path.Join(manifest.Package.VersionedHome, "components"))
+	return filepath.Join(dir, filepath.FromSlash(components)), nil
+}
+
+// readManifest parses the package manifest in dir, with the path mapping overrides of the
+// environment applied. It returns an error wrapping os.ErrNotExist when dir holds no manifest.
+func readManifest(dir string) (*v1.PackageManifest, error) {
+	manifestFile, err := os.Open(filepath.Join(dir, v1.ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer manifestFile.Close()
+	manifest, err := v1.ParseManifest(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := manifest.WithPathMappingOverrides(os.Environ()); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func isDir(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.IsDir()
+}
+
 // FindRunDir identifies the directory that holds the run folder.
 func FindRunDir(fixture *Fixture) (string, error) {
 	agentWorkDir := fixture.AgentDataDir()
@@ -1514,6 +2112,98 @@ func writeSpecFile(dest string, spec *component.Spec) error {
 	return nil
 }
 
+// DefaultOutputWaitDelay is the default RunProcessOptions.OutputWaitDelay.
+const DefaultOutputWaitDelay = time.Second
+
+// processOutput captures the output of a process run by RunProcessWithOptions. The output is read
+// from pipes by goroutines owned by processOutput, so waiting for the output to be copied doesn't
+// depend on exec.Cmd once the process was reaped by process.Info.Wait.
+type processOutput struct {
+	stdOut, stdErr outputBuffer
+
+	wg      sync.WaitGroup
+	readers []*os.File
+	writers []*os.File
+}
+
+// capture attaches the logWatchers to std out and std error of the spawned process, and captures
+// both into the output buffers.
+func (o *processOutput) capture(stdOut *logWatcher, stdErr *logWatcher) process.CmdOption {
+	return func(cmd *exec.Cmd) error {
+		var err error
+		cmd.Stdout, err = o.pipe(io.MultiWriter(&o.stdOut, stdOut))
+		if err != nil {
+			return err
+		}
+		cmd.Stderr, err = o.pipe(io.MultiWriter(&o.stdErr, stdErr))
+		return err
+	}
+}
+
+// pipe returns the write end of a pipe whose content is copied to w.
+func (o *processOutput) pipe(w io.Writer) (*os.File, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output pipe: %w", err)
+	}
+	o.readers = append(o.readers, pr)
+	o.writers = append(o.writers, pw)
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		_, _ = io.Copy(w, pr)
+	}()
+	return pw, nil
+}
+
+// started closes the write ends of the pipes once the process started, or failed to start, so
+// the copies end when the process closes its output.
+func (o *processOutput) started() {
+	for _, w := range o.writers {
+		_ = w.Close()
+	}
+}
+
+// wait waits for the output to be copied. When the copies didn't end after delay, e.g. because a
+// child process keeps the output open, the pipes are closed and false is returned.
+func (o *processOutput) wait(delay time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+	copied := true
+	select {
+	case <-done:
+	case <-time.After(delay):
+		copied = false
+	}
+	for _, r := range o.readers {
+		_ = r.Close()
+	}
+	<-done
+	return copied
+}
+
+// outputBuffer is a bytes.Buffer safe for concurrent use, the output of a process is copied
+// into it while the process runs.
+type outputBuffer struct {
+	mx  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *outputBuffer) String() string {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.buf.String()
+}
+
 // attachOutErr attaches the logWatcher to std out and std error of the spawned process.
 func attachOutErr(stdOut *logWatcher, stdErr *logWatcher) process.CmdOption {
 	return func(cmd *exec.Cmd) error {
@@ -1650,6 +2340,9 @@ type AgentStatusOutput struct {
 	FleetState     int                         `json:"FleetState"`
 	FleetMessage   string                      `json:"FleetMessage"`
 	UpgradeDetails *details.Details            `json:"upgrade_details"`
+	// Otel is the status of the collector pipelines, nil when the Elastic Agent runs no collector or
+	// doesn't report it.
+	Otel *client.OtelStatus `json:"otel"`
 }
 
 type AgentStatusOutputVersionInfo struct {
@@ -0,0 +1,141 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MatchMode is how WaitForFileContains matches the expected substrings.
+type MatchMode int
+
+const (
+	// MatchAll waits until every substring was found.
+	MatchAll MatchMode = iota
+	// MatchAny waits until at least one of the substrings was found.
+	MatchAny
+)
+
+// fileContainsInterval is how often WaitForFileContains reads the file.
+const fileContainsInterval = 500 * time.Millisecond
+
+// WaitForFileContains waits until the file at path contains the substrings, all of them or any
+// of them depending on mode. A relative path is relative to the fixture work directory.
+//
+// The file is read again until timeout or until ctx is done, a substring found once counts as
+// found even if the file is later truncated or rotated, e.g. by a file exporter. The returned
+// error lists the substrings that were never found.
+func (f *Fixture) WaitForFileContains(ctx context.Context, path string, substrings []string, mode MatchMode, timeout time.Duration) error {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(f.workDir, path)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	found := make(map[string]bool, len(substrings))
+	matched := func() bool {
+		for _, s := range substrings {
+			if found[s] && mode == MatchAny {
+				return true
+			}
+			if !found[s] && mode == MatchAll {
+				return false
+			}
+		}
+		return mode == MatchAll
+	}
+
+	ticker := time.NewTicker(fileContainsInterval)
+	defer ticker.Stop()
+	var readErr error
+	for {
+		var content []byte
+		content, readErr = os.ReadFile(path)
+		if readErr == nil {
+			for _, s := range substrings {
+				if !found[s] && strings.Contains(string(content), s) {
+					found[s] = true
+				}
+			}
+			if matched() {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			var missing []string
+			for _, s := range substrings {
+				if !found[s] {
+					missing = append(missing, s)
+				}
+			}
+			if readErr != nil {
+				return fmt.Errorf("file %s does not contain %q: %w (last read error: %w)", path, missing, ctx.Err(), readErr)
+			}
+			return fmt.Errorf("file %s does not contain %q: %w", path, missing, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// stageInputFiles writes the files of WithInputFiles, once, before the first run of the fixture.
+// Later runs, e.g. restarts of the collector, find the files as the previous run left them.
+func (f *Fixture) stageInputFiles() error {
+	if f.inputFilesStaged {
+		return nil
+	}
+	paths := make([]string, 0, len(f.inputFiles))
+	for path := range f.inputFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		content := f.inputFiles[path]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(f.workDir, path)
+		}
+		if err := writeFileAtomic(path, []byte(content)); err != nil {
+			return fmt.Errorf("failed to stage input file %s: %w", path, err)
+		}
+		f.t.Cleanup(func() {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				f.t.Logf("failed to remove input file %s: %v", path, err)
+			}
+		})
+	}
+	f.inputFilesStaged = true
+	return nil
+}
+
+// writeFileAtomic writes content to path through a temporary file in the same directory renamed
+// into place, creating the directory when needed.
+func writeFileAtomic(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -192,6 +192,68 @@ func (f *Fixture) Install(ctx context.Context, installOpts *InstallOpts, opts ..
 	return f.installFunc(ctx, installOpts, true, opts...)
 }
 
+// ServiceInstallOpts specifies how InstallAsService installs the Elastic Agent.
+type ServiceInstallOpts struct {
+	InstallOpts
+
+	// OtelConfig is the configuration of the collector run by the installed Elastic Agent. It is
+	// written as the elastic-agent.yml of the installed Elastic Agent, which detects the collector
+	// configuration in it, so it can also hold Elastic Agent settings (hybrid mode).
+	OtelConfig []byte
+	// PollInterval is how often the state of the installed Elastic Agent is checked while waiting
+	// for the collector to run, one second when zero.
+	PollInterval time.Duration
+}
+
+// InstallAsService installs the prepared Elastic Agent as a system service running the collector
+// configured with opts.OtelConfig, without enrolling it, and waits until the service reports the
+// pipelines of the collector through the control protocol. It covers the otel mode of an installed
+// Elastic Agent, RunOtelWithOptions only runs the collector as a child process of the test.
+//
+// Like Install, a t.Cleanup function uninstalls the service when the test ends, call Uninstall to
+// tear it down earlier. It returns the combined output of the install command.
+func (f *Fixture) InstallAsService(ctx context.Context, opts *ServiceInstallOpts) ([]byte, error) {
+	if opts == nil || len(opts.OtelConfig) == 0 {
+		return nil, errors.New("the collector configuration is required to install the Elastic Agent as a service running it")
+	}
+	if err := f.Configure(ctx, opts.OtelConfig); err != nil {
+		return nil, fmt.Errorf("failed to write the collector configuration: %w", err)
+	}
+	installOpts := opts.InstallOpts
+	installOpts.Force = true
+	installOpts.NonInteractive = true
+	out, err := f.InstallWithoutEnroll(ctx, &installOpts)
+	if err != nil {
+		return out, err
+	}
+	if err := f.Client().Connect(ctx); err != nil {
+		return out, fmt.Errorf("failed to connect to the installed Elastic Agent: %w", err)
+	}
+
+	interval := opts.PollInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		pipelines, err := f.ListOtelPipelines(ctx)
+		if err == nil && len(pipelines) > 0 {
+			return out, nil
+		}
+		lastErr = err
+		if err == nil {
+			lastErr = errors.New("no collector pipeline reported")
+		}
+		select {
+		case <-ctx.Done():
+			return out, fmt.Errorf("the installed Elastic Agent did not run the collector: %w", errors.Join(ctx.Err(), lastErr))
+		case <-ticker.C:
+		}
+	}
+}
+
 func (f *Fixture) InstallWithoutEnroll(ctx context.Context, installOpts *InstallOpts, opts ...process.CmdOption) ([]byte, error) {
 	return f.installFunc(ctx, installOpts, false, opts...)
 }
@@ -785,6 +847,10 @@ func (f *Fixture) uninstallNoPkgManager(ctx context.Context, uninstallOpts *Unin
 	return out, nil
 }
 
+// ErrDiagnosticsFallback is returned by CollectDiagnostics when the diagnostics could not be collected
+// and the working directory was archived instead.
+var ErrDiagnosticsFallback = errors.New("diagnostics could not be collected, the working directory was archived instead")
+
 func (f *Fixture) collectDiagnostics() {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer cancel()
@@ -795,40 +861,77 @@ func (f *Fixture) collectDiagnostics() {
 		return
 	}
 
-	err = os.MkdirAll(diagPath, 0755)
+	err = f.CollectDiagnostics(ctx, diagPath)
+	switch {
+	case errors.Is(err, ErrDiagnosticsFallback):
+		f.t.Logf("%s", err)
+	case err != nil:
+		f.t.Logf("failed to collect diagnostics: %s", err)
+	}
+}
+
+// CollectDiagnostics runs `elastic-agent diagnostics` and saves the archive, containing the agent
+// logs, the computed and the collector configuration and the status, as a zip file in destDir.
+// If the diagnostics cannot be collected, e.g. because the Elastic Agent is not running, the
+// whole working directory is archived instead so the logs are still available, and an error
+// wrapping ErrDiagnosticsFallback is returned when the archive was created.
+//
+// Use CollectDiagnosticsOnFailure to collect diagnostics only when the test fails.
+func (f *Fixture) CollectDiagnostics(ctx context.Context, destDir string) error {
+	err := os.MkdirAll(destDir, 0755)
 	if err != nil {
-		f.t.Logf("failed to collect diagnostics; failed to create %s: %s", diagPath, err)
-		return
+		return fmt.Errorf("failed to create %s: %w", destDir, err)
 	}
 
 	prefix := f.FileNamePrefix()
-	outputPath := filepath.Join(diagPath, prefix+"-diagnostics.zip")
+	outputPath := filepath.Join(destDir, prefix+"-diagnostics.zip")
 
 	output, err := f.Exec(ctx, []string{"diagnostics", "-f", outputPath})
-	if err != nil {
-		f.t.Logf("failed to collect diagnostics to %s (%s): %s", outputPath, err, output)
-
-		// possible that the test was so fast that the Elastic Agent was just installed, the control protocol is
-		// not fully running yet. wait 15 seconds to try again, ensuring that best effort is performed in fetching
-		// diagnostics
-		if strings.Contains(string(output), "connection error") {
-			f.t.Logf("retrying in 15 seconds due to connection error; possible Elastic Agent was not fully started")
-			time.Sleep(15 * time.Second)
-			output, err = f.Exec(ctx, []string{"diagnostics", "-f", outputPath})
-			if err != nil {
-				f.t.Logf("failed to collect diagnostics a second time at %s (%s): %s", outputPath, err, output)
-			}
+	if err == nil {
+		f.t.Logf("diagnostics collected to %s", outputPath)
+		return nil
+	}
+	f.t.Logf("failed to collect diagnostics to %s (%s): %s", outputPath, err, output)
+
+	// possible that the test was so fast that the Elastic Agent was just installed, the control protocol is
+	// not fully running yet. wait 15 seconds to try again, ensuring that best effort is performed in fetching
+	// diagnostics
+	if strings.Contains(string(output), "connection error") {
+		f.t.Logf("retrying in 15 seconds due to connection error; possible Elastic Agent was not fully started")
+		select {
+		case <-ctx.Done():
+		case <-time.After(15 * time.Second):
 		}
-		if err != nil {
-			// If collecting diagnostics fails, zip up the entire installation directory with the hope that it will contain logs.
-			f.t.Logf("creating zip archive of the installation directory: %s", f.workDir)
-			zipPath := filepath.Join(diagPath, fmt.Sprintf("%s-install-directory.zip", prefix))
-			err = f.archiveInstallDirectory(f.workDir, zipPath)
-			if err != nil {
-				f.t.Logf("failed to zip install directory to %s: %s", zipPath, err)
-			}
+		output, err = f.Exec(ctx, []string{"diagnostics", "-f", outputPath})
+		if err == nil {
+			f.t.Logf("diagnostics collected to %s", outputPath)
+			return nil
 		}
+		f.t.Logf("failed to collect diagnostics a second time at %s (%s): %s", outputPath, err, output)
 	}
+
+	// If collecting diagnostics fails, zip up the entire installation directory with the hope that it will contain logs.
+	f.t.Logf("creating zip archive of the installation directory: %s", f.workDir)
+	zipPath := filepath.Join(destDir, fmt.Sprintf("%s-install-directory.zip", prefix))
+	if archiveErr := f.archiveInstallDirectory(f.workDir, zipPath); archiveErr != nil {
+		return errors.Join(
+			fmt.Errorf("failed to collect diagnostics to %s: %w", outputPath, err),
+			fmt.Errorf("failed to zip install directory to %s: %w", zipPath, archiveErr))
+	}
+	return fmt.Errorf("%w to %s: %w", ErrDiagnosticsFallback, zipPath, err)
+}
+
+// CollectDiagnosticsOnFailure registers a cleanup function collecting diagnostics in
+// DiagnosticsDir, which is kept as CI artifact, when the test has failed. Failing to collect
+// the diagnostics is logged but does not fail the test.
+func (f *Fixture) CollectDiagnosticsOnFailure() {
+	f.t.Cleanup(func() {
+		if !f.t.Failed() {
+			return
+		}
+		f.t.Logf("collecting diagnostics; test failed")
+		f.collectDiagnostics()
+	})
 }
 
 func (f *Fixture) archiveInstallDirectory(installPath string, outputPath string) error {
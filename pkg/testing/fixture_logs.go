@@ -0,0 +1,206 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+const (
+	// followLogsInterval is how often FollowLogs checks the log files for new lines.
+	followLogsInterval = 250 * time.Millisecond
+	// logFlushDelay is how long AssertNoErrors waits for the lines logged at the end of its window
+	// to be read from the output of the Elastic Agent.
+	logFlushDelay = 250 * time.Millisecond
+)
+
+// AssertNoErrors runs during and then fails the test with the error level entries of the
+// structured logs output by the Elastic Agent while during ran, e.g. the errors of collector
+// components that don't prevent the Elastic Agent from reporting a healthy status but indicate a
+// problem. The warning level entries are logged to the test output without failing it.
+//
+// Only the output of an Elastic Agent run by the fixture is captured, installed Elastic Agents are
+// not supported. Unlike the runs of the fixture, which stop at the first logged error, AssertNoErrors
+// reports every error, so it is meant to be used with WithAllowErrors.
+func (f *Fixture) AssertNoErrors(ctx context.Context, during func()) {
+	f.t.Helper()
+	f.assertNoErrors(ctx, f.t, during)
+}
+
+// assertNoErrors implements AssertNoErrors, reporting to t.
+func (f *Fixture) assertNoErrors(ctx context.Context, t testing.TB, during func()) {
+	t.Helper()
+	if f.installed {
+		t.Error("AssertNoErrors requires an Elastic Agent run by the fixture, the output of an installed Elastic Agent is not captured")
+		return
+	}
+
+	lines, unsubscribe := f.subscribeOutput()
+	var output []string
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case line := <-lines:
+				output = append(output, line)
+			case <-stop:
+				for {
+					select {
+					case line := <-lines:
+						output = append(output, line)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	during()
+	select {
+	case <-ctx.Done():
+	case <-time.After(logFlushDelay):
+	}
+	unsubscribe()
+	close(stop)
+	<-stopped
+
+	for _, warning := range logEntries(output, logp.WarnLevel) {
+		t.Logf("the Elastic Agent logged a warning: %s", warning)
+	}
+	if errs := logEntries(output, logp.ErrorLevel); len(errs) > 0 {
+		t.Errorf("the Elastic Agent logged %d error(s):\n%s", len(errs), strings.Join(errs, "\n"))
+	}
+}
+
+// logEntries returns the lines of output that are structured log entries at level.
+func logEntries(output []string, level logp.Level) []string {
+	var entries []string
+	for _, line := range output {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var evt map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &evt); err != nil {
+			continue
+		}
+		if getLevel(evt, "log.level") == level {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
+
+// FollowLogs tails the log files written by the Elastic Agent, e.g. when it runs as a service,
+// and streams their lines, as written (ndjson), until ctx is done. The returned channel is
+// closed once ctx is done.
+//
+// Log files are followed from their beginning, including the files that don't exist yet when
+// FollowLogs is called and the files created by the log rotation. Lines are only sent once
+// complete, i.e. terminated by a newline.
+func (f *Fixture) FollowLogs(ctx context.Context) (<-chan string, error) {
+	if err := f.EnsurePrepared(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare before following logs: %w", err)
+	}
+
+	pattern := filepath.Join(f.topPath(), "data", "elastic-agent-*", "logs", "elastic-agent-*.ndjson")
+	return followFiles(ctx, f.t, pattern, followLogsInterval), nil
+}
+
+// topPath returns the directory the Elastic Agent keeps its data in.
+func (f *Fixture) topPath() string {
+	if f.installed && (f.packageFormat == "deb" || f.packageFormat == "rpm") {
+		topPath := "/var/lib/elastic-agent"
+		if f.installOpts != nil && f.installOpts.BasePath != "" {
+			topPath = f.installOpts.BasePath + topPath
+		}
+		return topPath
+	}
+	return filepath.Dir(f.binaryPath())
+}
+
+// followFiles streams the complete lines of the files matching pattern, checking them every
+// interval until ctx is done. Files of the Elastic Agent watcher are ignored.
+func followFiles(ctx context.Context, log Logger, pattern string, interval time.Duration) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+
+		offsets := make(map[string]int64)
+		partial := make(map[string][]byte)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			files, err := filepath.Glob(pattern)
+			if err != nil {
+				log.Logf("failed to find log files matching %s: %s", pattern, err)
+				return
+			}
+			sort.Strings(files)
+			for _, file := range files {
+				if strings.Contains(filepath.Base(file), "watcher") {
+					continue
+				}
+				read, err := readFrom(file, offsets[file])
+				if err != nil {
+					log.Logf("failed to read log file %s: %s", file, err)
+					continue
+				}
+				offsets[file] += int64(len(read))
+
+				data := append(partial[file], read...)
+				for {
+					idx := bytes.IndexByte(data, '\n')
+					if idx < 0 {
+						break
+					}
+					select {
+					case lines <- string(bytes.TrimRight(data[:idx], "\r")):
+					case <-ctx.Done():
+						return
+					}
+					data = data[idx+1:]
+				}
+				partial[file] = data
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return lines
+}
+
+// readFrom returns the content of file after offset.
+func readFrom(file string, offset int64) ([]byte, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	if _, err := fd.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(fd)
+}
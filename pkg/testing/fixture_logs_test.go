@@ -0,0 +1,129 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+func TestFollowFiles(t *testing.T) {
+	dir := t.TempDir()
+	pattern := filepath.Join(dir, "elastic-agent-*.ndjson")
+	first := filepath.Join(dir, "elastic-agent-20250101.ndjson")
+	require.NoError(t, os.WriteFile(first, []byte("{\"message\":\"one\"}\n{\"message\":\"tw"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "elastic-agent-watcher-20250101.ndjson"), []byte("{\"message\":\"watcher\"}\n"), 0o600))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	lines := followFiles(ctx, t, pattern, 5*time.Millisecond)
+
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for a log line")
+			return ""
+		}
+	}
+	assert.Equal(t, `{"message":"one"}`, next())
+
+	// complete the partial line and rotate to a new file
+	fd, err := os.OpenFile(first, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = fd.WriteString("o\"}\n")
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+	assert.Equal(t, `{"message":"two"}`, next())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "elastic-agent-20250101-1.ndjson"), []byte("{\"message\":\"three\"}\n"), 0o600))
+	assert.Equal(t, `{"message":"three"}`, next())
+
+	cancel()
+	for range lines {
+		// drain until the channel is closed
+	}
+}
+
+func TestLogEntries(t *testing.T) {
+	output := []string{
+		`{"log.level":"info","message":"started"}`,
+		`{"log.level":"error","message":"failed to export"}`,
+		`not json`,
+		`{"log.level":"warn","message":"retrying"}`,
+		`{"log.level":"error","message":"connection refused"}`,
+		`{"message":"no level"}`,
+	}
+	assert.Equal(t, []string{
+		`{"log.level":"error","message":"failed to export"}`,
+		`{"log.level":"error","message":"connection refused"}`,
+	}, logEntries(output, logp.ErrorLevel))
+	assert.Equal(t, []string{`{"log.level":"warn","message":"retrying"}`}, logEntries(output, logp.WarnLevel))
+	assert.Empty(t, logEntries([]string{"plain text error"}, logp.ErrorLevel))
+}
+
+func TestFixtureAssertNoErrors(t *testing.T) {
+	t.Run("errors outside the window are ignored", func(t *testing.T) {
+		f := &Fixture{t: t}
+		out := f.outputLogger()
+		out.Log(`{"log.level":"error","message":"logged before the window"}`)
+
+		ran := false
+		tb := &fakeTB{}
+		f.assertNoErrors(t.Context(), tb, func() {
+			ran = true
+			out.Log(`{"log.level":"info","message":"healthy"}`)
+			out.Log(`{"log.level":"warn","message":"logged, not failing"}`)
+		})
+		out.Log(`{"log.level":"error","message":"logged after the window"}`)
+		assert.True(t, ran)
+		assert.Empty(t, tb.errors)
+		assert.Len(t, tb.logs, 1, "the warning is logged")
+	})
+
+	t.Run("errors inside the window fail", func(t *testing.T) {
+		f := &Fixture{t: t}
+		out := f.outputLogger()
+
+		tb := &fakeTB{}
+		f.assertNoErrors(t.Context(), tb, func() {
+			out.Log(`{"log.level":"info","message":"healthy"}`)
+			out.Log(`{"log.level":"error","message":"failed to export"}`)
+		})
+		require.Len(t, tb.errors, 1)
+		assert.Contains(t, tb.errors[0], "logged 1 error(s)")
+		assert.Contains(t, tb.errors[0], "failed to export")
+	})
+}
+
+// fakeTB captures the errors and logs reported by assertNoErrors.
+type fakeTB struct {
+	testing.TB
+	errors []string
+	logs   []string
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Error(args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprint(args...))
+}
+
+func (tb *fakeTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *fakeTB) Logf(format string, args ...any) {
+	tb.logs = append(tb.logs, fmt.Sprintf(format, args...))
+}
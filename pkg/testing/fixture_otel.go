@@ -0,0 +1,476 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// otelReloadLogMessage is logged by the collector when a changed config file is valid and
+	// the collector reloads its configuration, see watchfileprovider.ReloadLogMessage of the edot module.
+	otelReloadLogMessage = "Config file changed, reloading collector configuration"
+	// otelRejectedLogMessage is logged by the collector when a changed config file is invalid,
+	// the collector keeps running with the previous configuration, see
+	// watchfileprovider.RejectedLogMessage of the edot module.
+	otelRejectedLogMessage = "Config file changed but the new configuration is invalid, keeping the running configuration"
+
+	// outputSubscriberBuffer is the number of output lines buffered for each subscriber, lines are
+	// dropped when a subscriber falls behind.
+	outputSubscriberBuffer = 1000
+
+	// outputHistorySize is the number of the last output lines kept for the subscribers interested
+	// in what was output before they subscribed.
+	outputHistorySize = 1000
+
+	// filelogReceiverType is the type of the filelog receiver, it logs when it starts reading a file.
+	filelogReceiverType = "filelog"
+	// fileWatchStartedMessage is logged by the filelog receiver when it starts reading a file.
+	fileWatchStartedMessage = "Started watching file"
+	// collectorReadyMessage is logged by the collector once all its components are started.
+	collectorReadyMessage = "Everything is ready. Begin running and processing data."
+
+	// otelMergedDiagnosticFile is the agent diagnostics file holding the configuration of the
+	// collector managed by the Elastic Agent.
+	otelMergedDiagnosticFile = "otel-merged.yaml"
+	// noOtelConfigDiagnostic is the content of otelMergedDiagnosticFile when the Elastic Agent runs
+	// no collector.
+	noOtelConfigDiagnostic = "no active OTel configuration"
+
+	// otelLivenessPath and otelReadinessPath are the paths of the probes served on the
+	// `--health-addr` of the collector.
+	otelLivenessPath  = "/livez"
+	otelReadinessPath = "/readyz"
+)
+
+// UpdateOtelConfig replaces the configuration of the collector started with one of the RunOtel
+// functions while it runs, and waits for the collector to reload it.
+//
+// Only the standalone collector started with a `--config-refresh-interval` watches its configuration
+// files, UpdateOtelConfig fails without writing anything when the collector runs without it, or with
+// `--supervised`, which is reconfigured by the Elastic Agent instead, or when the fixture doesn't run
+// the collector.
+//
+// The configuration file replaced is the first one passed with `--config` through
+// `WithAdditionalArgs()` or the run options, or the one written by `ConfigureOtel`. It is replaced
+// atomically, the collector never reads a partially written configuration. UpdateOtelConfig returns
+// right away when the file already holds cfg, as the collector only reloads changed content. An
+// error is returned when the collector rejects the new configuration, or when ctx is done before the
+// collector reloads it.
+func (f *Fixture) UpdateOtelConfig(ctx context.Context, cfg []byte) error {
+	f.procMutex.Lock()
+	args := f.procArgs
+	f.procMutex.Unlock()
+	if len(args) == 0 || args[0] != "otel" {
+		return errors.New("the collector is not running, start it with one of the RunOtel functions")
+	}
+	if slices.Contains(args, "--supervised") {
+		return errors.New("the supervised collector doesn't watch its configuration files, only the standalone collector can be updated")
+	}
+	if !otelConfigReloadEnabled(args) {
+		return errors.New("the collector only watches its configuration files when started with a --config-refresh-interval")
+	}
+	cfgFilePath, err := f.otelConfigFile(args)
+	if err != nil {
+		return err
+	}
+
+	if current, err := os.ReadFile(cfgFilePath); err == nil && bytes.Equal(current, cfg) {
+		// the collector only reloads on content changes, there is no reload to wait for
+		return nil
+	}
+
+	// subscribe before writing the file to not miss the reload
+	lines, unsubscribe := f.subscribeOutput()
+	defer unsubscribe()
+
+	if err := writeFileAtomic(cfgFilePath, cfg); err != nil {
+		return fmt.Errorf("failed to write otel configuration %s: %w", cfgFilePath, err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("collector did not reload the configuration %s: %w", cfgFilePath, ctx.Err())
+		case line := <-lines:
+			switch {
+			case strings.Contains(line, otelReloadLogMessage):
+				return nil
+			case strings.Contains(line, otelRejectedLogMessage):
+				return fmt.Errorf("collector rejected the configuration %s: %s", cfgFilePath, line)
+			}
+		}
+	}
+}
+
+// WaitForReceiverStarted waits until the receiver receiverID, e.g. `filelog` or `filelog/logs`,
+// of the collector started with one of the RunOtel functions is running.
+//
+// A filelog receiver is only considered started once it logs that it started watching a file, so
+// with `start_at: beginning` the file is being read from the beginning when WaitForReceiverStarted
+// returns. Any other receiver is considered started once the collector reports that all its
+// components are started. The output logged since the process started is taken into account, so
+// WaitForReceiverStarted can be called at any time after starting the collector. An error is
+// returned when ctx is done before the receiver starts.
+func (f *Fixture) WaitForReceiverStarted(ctx context.Context, receiverID string) error {
+	if receiverID == "" {
+		return errors.New("receiver ID is required")
+	}
+	marker := collectorReadyMessage
+	componentID := regexp.MustCompile(`"otelcol\.component\.id":\s*"` + regexp.QuoteMeta(receiverID) + `"`)
+	receiverType, _, _ := strings.Cut(receiverID, "/")
+	if receiverType == filelogReceiverType {
+		marker = fileWatchStartedMessage
+	}
+	started := func(line string) bool {
+		if !strings.Contains(line, marker) {
+			return false
+		}
+		// the collector ready message isn't logged by a specific component
+		return marker == collectorReadyMessage || componentID.MatchString(line)
+	}
+
+	lines, history, unsubscribe := f.subscribeOutputWithHistory()
+	defer unsubscribe()
+	if slices.ContainsFunc(history, started) {
+		return nil
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("receiver %s did not start: %w", receiverID, ctx.Err())
+		case line := <-lines:
+			if started(line) {
+				return nil
+			}
+		}
+	}
+}
+
+// OtelEffectiveConfig returns the YAML encoded configuration of the collector currently run by the
+// Elastic Agent started by the fixture, once the configuration files are merged, the variables
+// expanded and the configuration of the components translated, so tests can check what the
+// collector actually runs instead of what was written to disk.
+//
+// It is read from the agent diagnostics through the control protocol client of the fixture, so it
+// only works while the Elastic Agent is started with one of the Run functions in a mode that serves
+// the control protocol. The standalone collector started by the RunOtel functions doesn't.
+func (f *Fixture) OtelEffectiveConfig(ctx context.Context) ([]byte, error) {
+	c := f.Client()
+	if c == nil {
+		return nil, errors.New("no control protocol client, the Elastic Agent is not running")
+	}
+	files, err := c.DiagnosticAgent(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the Elastic Agent diagnostics: %w", err)
+	}
+	for _, file := range files {
+		if file.Filename != otelMergedDiagnosticFile {
+			continue
+		}
+		if string(file.Content) == noOtelConfigDiagnostic {
+			return nil, errors.New("the Elastic Agent runs no collector")
+		}
+		return file.Content, nil
+	}
+	return nil, fmt.Errorf("the Elastic Agent diagnostics have no %s", otelMergedDiagnosticFile)
+}
+
+// OtelValidationResult is the output of `elastic-agent otel validate --output json`.
+type OtelValidationResult struct {
+	Valid     bool                  `json:"valid"`
+	Errors    []OtelValidationError `json:"errors"`
+	Preflight []OtelPreflightCheck  `json:"preflight,omitempty"`
+}
+
+// OtelValidationError is an error reported by `elastic-agent otel validate --output json`.
+type OtelValidationError struct {
+	// Path is the path of the invalid configuration key, e.g. "service::pipelines::logs".
+	Path    string `json:"path,omitempty"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// OtelPreflightCheck is a check reported by `elastic-agent otel validate --preflight --output json`.
+type OtelPreflightCheck struct {
+	Component string `json:"component"`
+	Check     string `json:"check"`
+	Target    string `json:"target"`
+	// Error is the reason the check failed, empty when it passed.
+	Error    string `json:"error,omitempty"`
+	Failover string `json:"failover,omitempty"`
+}
+
+// OtelComponentsOutput is the output of `elastic-agent otel components --output json`.
+type OtelComponentsOutput struct {
+	BuildInfo struct {
+		Command     string `json:"Command"`
+		Description string `json:"Description"`
+		Version     string `json:"Version"`
+	} `json:"buildinfo"`
+	Receivers  []OtelComponent `json:"receivers"`
+	Processors []OtelComponent `json:"processors"`
+	Exporters  []OtelComponent `json:"exporters"`
+	Connectors []OtelComponent `json:"connectors"`
+	Extensions []OtelComponent `json:"extensions"`
+}
+
+// OtelComponent is a component listed by `elastic-agent otel components --output json`.
+type OtelComponent struct {
+	Name string `json:"name"`
+	// Stability is the stability level of the component by signal, e.g. "logs": "Beta".
+	Stability map[string]string `json:"stability"`
+}
+
+// OtelComponentSchema is a component described by `elastic-agent otel schema --output json`.
+type OtelComponentSchema struct {
+	Kind     string         `json:"kind"`
+	Type     string         `json:"type"`
+	Fields   any            `json:"fields"`
+	Defaults map[string]any `json:"defaults"`
+}
+
+// ExecOtel runs the `elastic-agent otel` subcommand sub with args and returns its combined
+// output, see Exec.
+func (f *Fixture) ExecOtel(ctx context.Context, sub string, args ...string) ([]byte, error) {
+	f.t.Helper()
+	return f.Exec(ctx, append([]string{"otel", sub}, args...))
+}
+
+// OtelValidate runs `elastic-agent otel validate` with args and `--output json`. An invalid
+// configuration is reported by the returned result, not by the error, which is only returned when
+// the command doesn't output a validation result.
+func (f *Fixture) OtelValidate(ctx context.Context, args ...string) (OtelValidationResult, error) {
+	f.t.Helper()
+	var result OtelValidationResult
+	err := f.execOtelJSON(ctx, &result, "validate", args...)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// the command fails on an invalid configuration, after writing the result
+		return result, nil
+	}
+	return result, err
+}
+
+// OtelComponents runs `elastic-agent otel components --output json` and returns the components
+// of the collector.
+func (f *Fixture) OtelComponents(ctx context.Context) (OtelComponentsOutput, error) {
+	f.t.Helper()
+	var components OtelComponentsOutput
+	err := f.execOtelJSON(ctx, &components, "components")
+	return components, err
+}
+
+// OtelSchema runs `elastic-agent otel schema` for the component id, e.g. "filelog" or
+// "otlp/elastic", with args and `--output json`, and returns the description of the components
+// named id.
+func (f *Fixture) OtelSchema(ctx context.Context, id string, args ...string) ([]OtelComponentSchema, error) {
+	f.t.Helper()
+	var schemas []OtelComponentSchema
+	err := f.execOtelJSON(ctx, &schemas, "schema", append([]string{id}, args...)...)
+	return schemas, err
+}
+
+// OtelTranslate runs `elastic-agent otel translate` with args and `--output json`, and returns the
+// collector configuration translated from the Elastic Agent policy.
+func (f *Fixture) OtelTranslate(ctx context.Context, args ...string) (map[string]any, error) {
+	f.t.Helper()
+	var cfg map[string]any
+	err := f.execOtelJSON(ctx, &cfg, "translate", args...)
+	return cfg, err
+}
+
+// execOtelJSON runs the `elastic-agent otel` subcommand sub with args and `--output json`, and
+// decodes its standard output into v. When the output is decoded, the error of the command is
+// returned as is, so a failure after writing the output can be told apart with errors.As.
+func (f *Fixture) execOtelJSON(ctx context.Context, v any, sub string, args ...string) error {
+	f.t.Helper()
+	cmd, err := f.PrepareAgentCommand(ctx, append([]string{"otel", sub, "--output", "json"}, args...))
+	if err != nil {
+		return fmt.Errorf("error creating cmd: %w", err)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	f.t.Logf(">> running binary with: %v", cmd.Args)
+
+	stdout, runErr := cmd.Output()
+	if err := json.Unmarshal(stdout, v); err != nil {
+		if runErr != nil {
+			return fmt.Errorf("otel %s failed: %v, output: %s", sub, runErr, bytes.TrimSpace(append(stdout, stderr.Bytes()...)))
+		}
+		return fmt.Errorf("failed to decode the output of otel %s: %w, output: %s", sub, err, stdout)
+	}
+	return runErr
+}
+
+// IsOtelReady returns an error unless the readiness probe served on healthAddr, the `--health-addr`
+// the collector is started with, reports that the collector loaded its configuration and started its
+// pipelines. Unlike IsHealthy, it doesn't need the control socket of the Elastic Agent, so it also
+// works with the standalone collector.
+func (f *Fixture) IsOtelReady(ctx context.Context, healthAddr string) error {
+	return probeOtelHealth(ctx, healthAddr, otelReadinessPath)
+}
+
+// IsOtelLive returns an error unless the liveness probe served on healthAddr, the `--health-addr`
+// the collector is started with, reports that the collector is starting or running.
+func (f *Fixture) IsOtelLive(ctx context.Context, healthAddr string) error {
+	return probeOtelHealth(ctx, healthAddr, otelLivenessPath)
+}
+
+// probeOtelHealth requests the probe at path of the collector health address healthAddr, an address
+// without host, e.g. `:13133`, is probed on localhost.
+func probeOtelHealth(ctx context.Context, healthAddr string, path string) error {
+	host, port, err := net.SplitHostPort(healthAddr)
+	if err != nil {
+		return fmt.Errorf("invalid health address %q: %w", healthAddr, err)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+net.JoinHostPort(host, port)+path, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("error creating the %s probe request: %w", path, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("collector %s probe failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+	state, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("collector %s probe failed with status %d, collector state: %s", path, resp.StatusCode, bytes.TrimSpace(state))
+	}
+	return nil
+}
+
+// otelConfigFile returns the local configuration file the collector is started with args.
+func (f *Fixture) otelConfigFile(args []string) (string, error) {
+	for i, arg := range args {
+		var location string
+		switch {
+		case arg == "--config" && i+1 < len(args):
+			location = args[i+1]
+		case strings.HasPrefix(arg, "--config="):
+			location = strings.TrimPrefix(arg, "--config=")
+		default:
+			continue
+		}
+		if filename, ok := strings.CutPrefix(location, "file:"); ok {
+			return filename, nil
+		}
+		if !strings.Contains(location, ":") || filepath.VolumeName(location) != "" {
+			// plain path, including Windows paths with a drive letter
+			return location, nil
+		}
+	}
+	if f.workDir == "" {
+		return "", fmt.Errorf("no otel configuration file, the fixture is not prepared")
+	}
+	return filepath.Join(f.workDir, "otel.yml"), nil
+}
+
+// otelConfigReloadEnabled returns true when the otel command args set a non-zero
+// `--config-refresh-interval`, which enables reloading the configuration files.
+func otelConfigReloadEnabled(args []string) bool {
+	for i, arg := range args {
+		var value string
+		switch {
+		case arg == "--config-refresh-interval" && i+1 < len(args):
+			value = args[i+1]
+		case strings.HasPrefix(arg, "--config-refresh-interval="):
+			value = strings.TrimPrefix(arg, "--config-refresh-interval=")
+		default:
+			continue
+		}
+		interval, err := time.ParseDuration(value)
+		return err == nil && interval > 0
+	}
+	return false
+}
+
+// subscribeOutput returns a channel receiving every line output by the Elastic Agent started by the
+// fixture, and the function to call to stop receiving them.
+func (f *Fixture) subscribeOutput() (<-chan string, func()) {
+	lines, _, unsubscribe := f.subscribeOutputWithHistory()
+	return lines, unsubscribe
+}
+
+// subscribeOutputWithHistory is subscribeOutput also returning the last lines output by the
+// Elastic Agent before subscribing, none of them is missed or received twice.
+func (f *Fixture) subscribeOutputWithHistory() (<-chan string, []string, func()) {
+	f.outputMx.Lock()
+	defer f.outputMx.Unlock()
+	history := slices.Clone(f.outputHistory)
+	ch := make(chan string, outputSubscriberBuffer)
+	if f.outputSubs == nil {
+		f.outputSubs = make(map[chan string]struct{})
+	}
+	f.outputSubs[ch] = struct{}{}
+	return ch, history, func() {
+		f.outputMx.Lock()
+		delete(f.outputSubs, ch)
+		f.outputMx.Unlock()
+	}
+}
+
+// resetOutputHistory forgets the lines output by a previous run of the Elastic Agent.
+func (f *Fixture) resetOutputHistory() {
+	f.outputMx.Lock()
+	f.outputHistory = nil
+	f.outputMx.Unlock()
+}
+
+// outputLogger returns the Logger the output of the Elastic Agent is replicated to, it logs to
+// the test logger when the fixture logs the output and notifies the output subscribers.
+func (f *Fixture) outputLogger() Logger {
+	return &fixtureOutput{f: f}
+}
+
+type fixtureOutput struct {
+	f *Fixture
+}
+
+func (o *fixtureOutput) Log(args ...any) {
+	if o.f.logOutput {
+		o.f.t.Log(args...)
+	}
+	o.publish(fmt.Sprint(args...))
+}
+
+func (o *fixtureOutput) Logf(format string, args ...any) {
+	if o.f.logOutput {
+		o.f.t.Logf(format, args...)
+	}
+	o.publish(fmt.Sprintf(format, args...))
+}
+
+func (o *fixtureOutput) publish(line string) {
+	o.f.outputMx.Lock()
+	defer o.f.outputMx.Unlock()
+	if len(o.f.outputHistory) >= outputHistorySize {
+		o.f.outputHistory = slices.Delete(o.f.outputHistory, 0, len(o.f.outputHistory)-outputHistorySize+1)
+	}
+	o.f.outputHistory = append(o.f.outputHistory, line)
+	for ch := range o.f.outputSubs {
+		select {
+		case ch <- line:
+		default:
+			// subscriber is falling behind, drop the line
+		}
+	}
+}
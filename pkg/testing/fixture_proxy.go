@@ -0,0 +1,175 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testing
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// proxyDialTimeout is the timeout of the proxies started by ProxyEndpoint to connect to their target.
+const proxyDialTimeout = 10 * time.Second
+
+// ProxyEndpoint starts a TCP proxy controlled by the fixture forwarding the connections to target,
+// the host:port of an endpoint the Elastic Agent sends data to, e.g. the otlp/elastic or
+// Elasticsearch endpoint. It returns the local address of the proxy, to configure in place of
+// target, so that BlockEndpoint can simulate the endpoint going down in the middle of a test.
+//
+// The proxy forwards the bytes as is, TLS connections are not terminated, so a client verifying
+// the certificate of target must be configured with its server name. The proxy is closed when the
+// test ends.
+func (f *Fixture) ProxyEndpoint(target string) (string, error) {
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", target, err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for the proxy of %s: %w", target, err)
+	}
+	p := &endpointProxy{
+		target:   target,
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	f.proxyMx.Lock()
+	f.proxies = append(f.proxies, p)
+	f.proxyMx.Unlock()
+	f.t.Cleanup(p.close)
+
+	p.wg.Add(1)
+	go p.serve()
+	return listener.Addr().String(), nil
+}
+
+// BlockEndpoint simulates the endpoint addr going down, addr being either the target given to
+// ProxyEndpoint or the address of its proxy. The proxy closes the connections it forwards and
+// closes any new connection right away until UnblockEndpoint is called. The test fails when
+// no proxy was started for addr.
+func (f *Fixture) BlockEndpoint(addr string) {
+	f.t.Helper()
+	f.endpointProxy(addr).setBlocked(true)
+}
+
+// UnblockEndpoint simulates the endpoint addr blocked with BlockEndpoint coming back, its proxy
+// forwards the new connections again.
+func (f *Fixture) UnblockEndpoint(addr string) {
+	f.t.Helper()
+	f.endpointProxy(addr).setBlocked(false)
+}
+
+// endpointProxy returns the proxy of addr started with ProxyEndpoint, failing the test when there
+// is none.
+func (f *Fixture) endpointProxy(addr string) *endpointProxy {
+	f.t.Helper()
+	f.proxyMx.Lock()
+	defer f.proxyMx.Unlock()
+	for _, p := range f.proxies {
+		if p.target == addr || p.listener.Addr().String() == addr {
+			return p
+		}
+	}
+	f.t.Fatalf("no proxy for endpoint %s, start one with ProxyEndpoint", addr)
+	return nil
+}
+
+// endpointProxy is a TCP proxy started by ProxyEndpoint.
+type endpointProxy struct {
+	target   string
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	// mx protects blocked and conns, the connections currently forwarded
+	mx      sync.Mutex
+	blocked bool
+	conns   map[net.Conn]struct{}
+}
+
+func (p *endpointProxy) serve() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			// listener closed
+			return
+		}
+		p.wg.Add(1)
+		go p.forward(conn)
+	}
+}
+
+func (p *endpointProxy) forward(conn net.Conn) {
+	defer p.wg.Done()
+	if !p.track(conn) {
+		_ = conn.Close()
+		return
+	}
+	defer p.untrackAndClose(conn)
+
+	upstream, err := net.DialTimeout("tcp", p.target, proxyDialTimeout)
+	if err != nil {
+		return
+	}
+	if !p.track(upstream) {
+		_ = upstream.Close()
+		return
+	}
+	defer p.untrackAndClose(upstream)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	// closing both connections once either side is done unblocks the other copy
+	<-done
+	_ = conn.Close()
+	_ = upstream.Close()
+	<-done
+}
+
+// track records conn as forwarded, unless the proxy is blocked.
+func (p *endpointProxy) track(conn net.Conn) bool {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	if p.blocked {
+		return false
+	}
+	p.conns[conn] = struct{}{}
+	return true
+}
+
+func (p *endpointProxy) untrackAndClose(conn net.Conn) {
+	p.mx.Lock()
+	delete(p.conns, conn)
+	p.mx.Unlock()
+	_ = conn.Close()
+}
+
+// setBlocked blocks or unblocks the proxy, blocking it closes the forwarded connections.
+func (p *endpointProxy) setBlocked(blocked bool) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.blocked = blocked
+	if !blocked {
+		return
+	}
+	for conn := range p.conns {
+		_ = conn.Close()
+	}
+}
+
+// close stops the proxy and waits for the connections it forwards to be closed.
+func (p *endpointProxy) close() {
+	_ = p.listener.Close()
+	p.setBlocked(true)
+	p.wg.Wait()
+}
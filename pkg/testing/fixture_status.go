@@ -0,0 +1,146 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+// AgentStatus is the health of the Elastic Agent broken down per component and per collector pipeline.
+type AgentStatus struct {
+	// State is the overall state of the Elastic Agent.
+	State cproto.State
+	// Message is the overall status message of the Elastic Agent.
+	Message string
+	// Components is the health of every component run by the Elastic Agent.
+	Components []ComponentHealth
+	// Pipelines is the status of every pipeline run by the collector, ordered by name, empty when no
+	// collector is running.
+	Pipelines []client.PipelineStatus
+	// Output is the raw status output the health is computed from.
+	Output AgentStatusOutput
+}
+
+// ComponentHealth is the health of a single component.
+type ComponentHealth struct {
+	ID    string
+	Name  string
+	State cproto.State
+	// LastError is the message of the first unit not reporting healthy, or the component message
+	// when the component itself is not healthy. Empty when the component and all its units are healthy.
+	LastError string
+}
+
+// Healthy returns true when the component and all its units are healthy.
+func (c ComponentHealth) Healthy() bool {
+	return c.State == cproto.State_HEALTHY && c.LastError == ""
+}
+
+// Unhealthy returns a human-readable description of every component and pipeline that is not healthy.
+func (s *AgentStatus) Unhealthy() []string {
+	var unhealthy []string
+	for _, comp := range s.Components {
+		if !comp.Healthy() {
+			unhealthy = append(unhealthy, describeHealth("component "+comp.ID, comp.State.String(), comp.LastError))
+		}
+	}
+	for _, pipeline := range s.Pipelines {
+		if !pipeline.Healthy() {
+			unhealthy = append(unhealthy, describeHealth("pipeline:"+pipeline.Name, pipeline.Status.String(), pipeline.Error))
+		}
+	}
+	return unhealthy
+}
+
+func describeHealth(id, state, lastErr string) string {
+	if lastErr == "" {
+		return fmt.Sprintf("%s (%s)", id, state)
+	}
+	return fmt.Sprintf("%s (%s): %s", id, state, lastErr)
+}
+
+// Status returns the health of the Elastic Agent per component and per collector pipeline.
+//
+// Unlike IsHealthy, which only reports that the Elastic Agent is not healthy, Status lets
+// tests report which component or pipeline is degraded and why.
+func (f *Fixture) Status(ctx context.Context, opts ...statusOpt) (*AgentStatus, error) {
+	out, err := f.ExecStatus(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newAgentStatus(out), nil
+}
+
+func newAgentStatus(out AgentStatusOutput) *AgentStatus {
+	status := &AgentStatus{
+		State:   ProtoStateFromInt(out.State),
+		Message: out.Message,
+		Output:  out,
+	}
+
+	for _, comp := range out.Components {
+		health := ComponentHealth{
+			ID:    comp.ID,
+			Name:  comp.Name,
+			State: ProtoStateFromInt(comp.State),
+		}
+		for _, unit := range comp.Units {
+			if ProtoStateFromInt(unit.State) != cproto.State_HEALTHY {
+				health.LastError = unit.Message
+				break
+			}
+		}
+		if health.LastError == "" && health.State != cproto.State_HEALTHY {
+			health.LastError = comp.Message
+		}
+		status.Components = append(status.Components, health)
+	}
+
+	if out.Collector != nil {
+		state := client.AgentState{Collector: out.Collector.collectorComponent()}
+		status.Pipelines = state.OtelPipelines()
+	}
+
+	return status
+}
+
+// collectorComponent converts the collector status output to the control protocol client type.
+func (c *AgentStatusCollectorOutput) collectorComponent() *client.CollectorComponent {
+	component := &client.CollectorComponent{
+		Status: client.CollectorComponentStatus(c.Status), //nolint:gosec // value will never be over 32-bit
+		Error:  c.Error,
+	}
+	if len(c.ComponentStatusMap) > 0 {
+		component.ComponentStatusMap = make(map[string]*client.CollectorComponent, len(c.ComponentStatusMap))
+		for id, sub := range c.ComponentStatusMap {
+			if sub != nil {
+				component.ComponentStatusMap[id] = sub.collectorComponent()
+			}
+		}
+	}
+	return component
+}
+
+// ListOtelPipelines returns the status of the pipelines run by the collector managed by the
+// Elastic Agent started by the fixture, ordered by name.
+//
+// It uses the control protocol client of the fixture, so it only works while the Elastic Agent is
+// started with one of the Run functions in a mode that serves the control protocol.
+func (f *Fixture) ListOtelPipelines(ctx context.Context) ([]client.PipelineStatus, error) {
+	c := f.Client()
+	if c == nil {
+		return nil, errors.New("no control protocol client, the Elastic Agent is not running")
+	}
+	state, err := c.State(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the Elastic Agent state: %w", err)
+	}
+	return state.OtelPipelines(), nil
+}
@@ -0,0 +1,813 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+func TestOtelRunOptionsArgs(t *testing.T) {
+	assert.Empty(t, OtelRunOptions{}.args())
+	assert.Equal(t,
+		[]string{"--feature-gates=exporter.elasticsearch.example,-receiver.filelog.example"},
+		OtelRunOptions{FeatureGates: []string{"exporter.elasticsearch.example", "-receiver.filelog.example"}}.args())
+	assert.Equal(t,
+		[]string{"--feature-gates=exporter.elasticsearch.example", "--set=processors::batch::timeout=2s"},
+		OtelRunOptions{
+			FeatureGates: []string{"exporter.elasticsearch.example"},
+			Args:         []string{"--set=processors::batch::timeout=2s"},
+		}.args())
+}
+
+func TestOtelRunOptionsExecuteOptions(t *testing.T) {
+	states := []State{{Configure: "receivers:"}}
+	opts := OtelRunOptions{
+		States:          states,
+		FeatureGates:    []string{"exporter.elasticsearch.example"},
+		ShutdownTimeout: time.Second,
+		Args:            []string{"--set=processors::batch::timeout=2s"},
+	}
+	assert.Equal(t, executeOptions{
+		command:         "otel",
+		args:            []string{"--config=otel.yml", "--feature-gates=exporter.elasticsearch.example", "--set=processors::batch::timeout=2s"},
+		shutdownTimeout: time.Second,
+		states:          states,
+	}, opts.executeOptions("--config=otel.yml"))
+	// the collector doesn't serve the control socket nor accept the elastic-agent run flags
+	assert.Equal(t, executeOptions{command: "otel"}, OtelRunOptions{}.executeOptions())
+}
+
+func TestWithEnv(t *testing.T) {
+	f := &Fixture{}
+	WithEnv(map[string]string{"OUTPUT_PATH": "/tmp/out", "LOG_LEVEL": "info"})(f)
+	WithEnv(map[string]string{"LOG_LEVEL": "debug"})(f)
+
+	assert.Equal(t, []string{"LOG_LEVEL=debug", "OUTPUT_PATH=/tmp/out"}, f.envList())
+	assert.Empty(t, (&Fixture{}).envList())
+}
+
+// newFakeAgentFixture attaches a fixture to a fake elastic-agent binary, a shell script reporting
+// version 9.1.0-SNAPSHOT and commit abc123 for `version` and running script otherwise. The script
+// can find the working directory of the fixture with `$(dirname "$0")`. The test is skipped on
+// Windows.
+func newFakeAgentFixture(t *testing.T, script string) *Fixture {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake elastic-agent binary is a shell script")
+	}
+	dir := t.TempDir()
+	script = `#!/bin/sh
+if [ "$1" = version ]; then printf 'binary:\n  version: 9.1.0\n  commit: abc123\n  snapshot: true\n'; exit 0; fi
+` + script
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "elastic-agent"), []byte(script), 0o755))
+	f, err := AttachFixture(t, dir)
+	require.NoError(t, err)
+	return f
+}
+
+func TestAttachFixture(t *testing.T) {
+	_, err := AttachFixture(t, t.TempDir())
+	require.ErrorContains(t, err, "failed to find the elastic-agent binary")
+
+	f := newFakeAgentFixture(t, "")
+	assert.Equal(t, "9.1.0-SNAPSHOT", f.Version())
+	assert.Equal(t, "abc123", f.Hash())
+	assert.FileExists(t, filepath.Join(f.WorkDir(), "elastic-agent"))
+	assert.NoError(t, f.EnsurePrepared(t.Context()))
+	assert.ErrorContains(t, f.Prepare(t.Context()), "already been prepared")
+}
+
+func TestFindComponentsDir(t *testing.T) {
+	t.Run("from manifest", func(t *testing.T) {
+		dir := t.TempDir()
+		manifest := `version: co.elastic.agent/v1
+kind: PackageManifest
+package:
+  version: 9.1.0
+  versioned-home: data/elastic-agent-abc123
+  path-mappings:
+    - data/elastic-agent-abc123: data/elastic-agent-9.1.0-abc123
+`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0o644))
+		expected := filepath.Join(dir, "data", "elastic-agent-9.1.0-abc123", "components")
+		require.NoError(t, os.MkdirAll(expected, 0o755))
+
+		componentsDir, err := FindComponentsDir(dir, "9.1.0-SNAPSHOT")
+		require.NoError(t, err)
+		assert.Equal(t, expected, componentsDir)
+	})
+
+	t.Run("from data directory", func(t *testing.T) {
+		dir := t.TempDir()
+		expected := filepath.Join(dir, "data", "elastic-agent-abc123", "components")
+		require.NoError(t, os.MkdirAll(expected, 0o755))
+
+		componentsDir, err := FindComponentsDir(dir, "")
+		require.NoError(t, err)
+		assert.Equal(t, expected, componentsDir)
+	})
+
+	t.Run("lists tried paths", func(t *testing.T) {
+		dir := t.TempDir()
+		manifest := `version: co.elastic.agent/v1
+kind: PackageManifest
+package:
+  version: 9.1.0
+  versioned-home: data/elastic-agent-abc123
+`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0o644))
+
+		_, err := FindComponentsDir(dir, "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), filepath.Join(dir, "data", "elastic-agent-abc123", "components"))
+		assert.Contains(t, err.Error(), filepath.Join(dir, "data", "elastic-agent-*", "components"))
+	})
+}
+
+func TestFindComponentBinary(t *testing.T) {
+	binary := "apm-server"
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	dir := t.TempDir()
+	componentsDir := filepath.Join(dir, "data", "elastic-agent-abc123", "components")
+	require.NoError(t, os.MkdirAll(componentsDir, 0o755))
+	_, err := FindComponentBinary(dir, "apm-server")
+	require.ErrorContains(t, err, "failed to find component apm-server binary")
+
+	// component subdirectory layout
+	require.NoError(t, os.MkdirAll(filepath.Join(componentsDir, "apm-server"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(componentsDir, "apm-server", binary), nil, 0o755))
+	path, err := FindComponentBinary(dir, "apm-server")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(componentsDir, "apm-server", binary), path)
+
+	// component home declared by the manifest
+	manifest := `version: co.elastic.agent/v1
+kind: PackageManifest
+package:
+  version: 9.1.0
+  versioned-home: data/elastic-agent-abc123
+  component-homes:
+    apm-server: data/apm-server-abc123
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0o644))
+	path, err = FindComponentBinary(dir, "apm-server")
+	require.NoError(t, err, "falls back to the components directory")
+	assert.Equal(t, filepath.Join(componentsDir, "apm-server", binary), path)
+
+	homeDir := filepath.Join(dir, "data", "apm-server-abc123")
+	require.NoError(t, os.MkdirAll(homeDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(homeDir, binary), nil, 0o755))
+	path, err = FindComponentBinary(dir, "apm-server")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(homeDir, binary), path)
+}
+
+func TestRunProcessResult(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Minute)
+	defer cancel()
+	result, err := RunProcessResult(t, t, ctx, 0, false, true, "/bin/sh", "-c", "echo out; echo err >&2; exit 3")
+	require.ErrorContains(t, err, "exited unexpectedly with exit code: 3")
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "out\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	assert.Positive(t, result.Duration)
+}
+
+func TestRunProcessWithOptionsGracePeriod(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Minute)
+	// the process traps SIGTERM and exits cleanly
+	script := "trap 'echo terminated; exit 0' TERM; echo started; while true; do sleep 0.1; done"
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		cancel()
+	}()
+	result, err := RunProcessWithOptions(t, t, ctx, RunProcessOptions{AllowErrs: true, GracePeriod: 10 * time.Second}, "/bin/sh", "-c", script)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "started\nterminated\n", result.Stdout)
+}
+
+func TestRunProcessWithOptionsOutputWaitDelay(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Minute)
+	defer cancel()
+	// the background child keeps the output open long after the process exited
+	script := "sleep 30 & echo out"
+	started := time.Now()
+	result, err := RunProcessWithOptions(t, t, ctx, RunProcessOptions{AllowErrs: true, OutputWaitDelay: 200 * time.Millisecond}, "/bin/sh", "-c", script)
+	require.ErrorContains(t, err, "exited unexpectedly with exit code: 0")
+	assert.Equal(t, "out\n", result.Stdout)
+	assert.Less(t, time.Since(started), 10*time.Second, "output should not be waited for past the delay")
+}
+
+func TestRunOtelWithOptionsShutdownTimeout(t *testing.T) {
+	// the fake collector logs enough errors while shutting down to fill the output pipe, it only
+	// exits when its output keeps being read
+	const shutdownErrors = `i=0; while [ $i -lt 2000 ]; do ` +
+		`echo '{"log.level":"error","message":"failed to flush the exporter queue while shutting down"}'; ` +
+		`i=$((i+1)); done`
+	newFixture := func(t *testing.T, onTerm string) *Fixture {
+		return newFakeAgentFixture(t, fmt.Sprintf(`on_term() {
+	%s
+}
+trap on_term TERM
+while true; do sleep 0.1; done
+`, onTerm))
+	}
+	runAndCancel := func(t *testing.T, f *Fixture, shutdownTimeout time.Duration) error {
+		ctx, cancel := context.WithTimeout(t.Context(), time.Minute)
+		defer cancel()
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			cancel()
+		}()
+		return f.RunOtelWithOptions(ctx, OtelRunOptions{ShutdownTimeout: shutdownTimeout})
+	}
+
+	t.Run("graceful exit", func(t *testing.T) {
+		f := newFixture(t, shutdownErrors+"; exit 0")
+		err := runAndCancel(t, f, 30*time.Second)
+		require.ErrorIs(t, err, context.Canceled)
+		require.NotErrorIs(t, err, ErrShutdownTimeout)
+	})
+
+	t.Run("killed after timeout", func(t *testing.T) {
+		// SIGTERM is ignored
+		f := newFixture(t, ":")
+		err := runAndCancel(t, f, time.Second)
+		require.ErrorIs(t, err, ErrShutdownTimeout)
+	})
+}
+
+func TestRunOtelWithConfig(t *testing.T) {
+	// the fake collector records its arguments and the configuration of its environment
+	f := newFakeAgentFixture(t, fmt.Sprintf(`dir=$(dirname "$0")
+printf '%%s' "$%s" > "$dir/config.seen.tmp" && mv "$dir/config.seen.tmp" "$dir/config.seen"
+echo "$@" > "$dir/args.txt"
+trap 'exit 0' TERM
+while true; do sleep 0.1; done
+`, otelConfigEnvVar))
+	dir := f.WorkDir()
+	seenArgs := filepath.Join(dir, "args.txt")
+	seenConfig := filepath.Join(dir, "config.seen")
+
+	const cfg = "receivers:\n  nop:\nexporters:\n  debug:\n    password: secret\n"
+	ctx, cancel := context.WithTimeout(t.Context(), time.Minute)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- f.RunOtelWithConfig(ctx, []byte(cfg), OtelRunOptions{ShutdownTimeout: 10 * time.Second})
+	}()
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		content, err := os.ReadFile(seenConfig)
+		require.NoError(c, err)
+		assert.Equal(c, cfg, string(content))
+	}, 30*time.Second, 100*time.Millisecond)
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		args, err := os.ReadFile(seenArgs)
+		require.NoError(c, err)
+		assert.Contains(c, string(args), "--config=env:"+otelConfigEnvVar)
+		assert.NotContains(c, string(args), "secret", "the configuration must not be on the command line")
+	}, 30*time.Second, 100*time.Millisecond)
+
+	// the configuration is never written to the working directory
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path == seenConfig {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		assert.NotContains(t, string(content), "secret", "the configuration was written to %s", path)
+		return nil
+	})
+	require.NoError(t, err)
+
+	cancel()
+	require.ErrorIs(t, <-errCh, context.Canceled)
+}
+
+func TestCollectDiagnosticsFallback(t *testing.T) {
+	f := newFakeAgentFixture(t, `echo "Error: failed to communicate with Elastic Agent daemon"; exit 1
+`)
+
+	destDir := t.TempDir()
+	err := f.CollectDiagnostics(t.Context(), destDir)
+	require.ErrorIs(t, err, ErrDiagnosticsFallback)
+	assert.FileExists(t, filepath.Join(destDir, f.FileNamePrefix()+"-install-directory.zip"))
+}
+
+func TestNewAgentStatus(t *testing.T) {
+	const statusJSON = `{
+  "info": {"id": "agent-id", "version": "9.1.0"},
+  "state": 3,
+  "message": "1 or more components/units in a degraded state",
+  "components": [
+    {"id": "filestream-default", "name": "filestream", "state": 2, "message": "Healthy",
+     "units": [{"unit_id": "filestream-default", "unit_type": 0, "state": 2, "message": "Healthy"}]},
+    {"id": "system/metrics-default", "name": "system/metrics", "state": 2, "message": "Healthy",
+     "units": [{"unit_id": "system/metrics-default-unit", "unit_type": 0, "state": 3, "message": "failed to read /proc"}]}
+  ],
+  "collector": {
+    "status": 3,
+    "components": {
+      "pipeline:logs": {"status": 2, "components": {"receiver:filelog": {"status": 2}}},
+      "pipeline:metrics": {"status": 3, "components": {
+        "exporter:elasticsearch": {"status": 3, "error": "connection refused"},
+        "receiver:hostmetrics": {"status": 2}
+      }}
+    }
+  }
+}`
+	var out AgentStatusOutput
+	require.NoError(t, json.Unmarshal([]byte(statusJSON), &out))
+
+	status := newAgentStatus(out)
+	assert.Equal(t, cproto.State_DEGRADED, status.State)
+	require.Len(t, status.Components, 2)
+	assert.True(t, status.Components[0].Healthy())
+	assert.Equal(t, "failed to read /proc", status.Components[1].LastError)
+	require.Len(t, status.Pipelines, 2)
+	assert.Equal(t, "logs", status.Pipelines[0].Name)
+	assert.True(t, status.Pipelines[0].Healthy())
+	assert.Equal(t, "exporter:elasticsearch: connection refused", status.Pipelines[1].Error)
+	assert.Equal(t, []string{
+		"component system/metrics-default (HEALTHY): failed to read /proc",
+		"pipeline:metrics (StatusRecoverableError): exporter:elasticsearch: connection refused",
+	}, status.Unhealthy())
+}
+
+func TestFixtureOtelConfigFile(t *testing.T) {
+	f := &Fixture{workDir: "work"}
+	path, err := f.otelConfigFile([]string{"otel"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("work", "otel.yml"), path)
+
+	for _, args := range [][]string{
+		{"otel", "--config", "custom.yml"},
+		{"otel", "--config=custom.yml"},
+		{"otel", "--config", "file:custom.yml"},
+		{"otel", "--config", "env:OTEL_CONFIG", "--config", "custom.yml"},
+	} {
+		path, err = f.otelConfigFile(args)
+		require.NoError(t, err)
+		assert.Equal(t, "custom.yml", path, "args: %v", args)
+	}
+
+	_, err = (&Fixture{}).otelConfigFile([]string{"otel"})
+	assert.Error(t, err)
+}
+
+func TestFixtureUpdateOtelConfigGuards(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "otel.yml")
+	for name, args := range map[string][]string{
+		"not running": nil,
+		"agent":       {"run", "-e"},
+		"supervised":  {"otel", "--supervised", "--config", cfgPath, "--config-refresh-interval", "10s"},
+		"no reload":   {"otel", "--config", cfgPath},
+		"zero reload": {"otel", "--config", cfgPath, "--config-refresh-interval=0s"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := &Fixture{workDir: dir, procArgs: args}
+			require.Error(t, f.UpdateOtelConfig(t.Context(), []byte("receivers: {}\n")))
+			assert.NoFileExists(t, cfgPath)
+		})
+	}
+}
+
+func TestFixtureUpdateOtelConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "otel.yml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("receivers: {}\n"), 0o600))
+	f := &Fixture{t: t, workDir: dir, procArgs: []string{"otel", "--config", cfgPath, "--config-refresh-interval", "10s"}}
+
+	t.Run("unchanged", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, f.UpdateOtelConfig(ctx, []byte("receivers: {}\n")), "unchanged content must not wait for a reload")
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		const cfg = "receivers:\n  nop:\n"
+		ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+		defer cancel()
+		// the fake collector logs the reload once it reads the new content
+		go func() {
+			for ctx.Err() == nil {
+				if content, _ := os.ReadFile(cfgPath); string(content) == cfg {
+					f.outputLogger().Log(otelReloadLogMessage)
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}()
+		require.NoError(t, f.UpdateOtelConfig(ctx, []byte(cfg)))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "the temporary file must be renamed over the configuration")
+		assert.Equal(t, "otel.yml", entries[0].Name())
+	})
+}
+
+func TestFixtureOutputSubscribers(t *testing.T) {
+	f := &Fixture{t: t}
+	lines, unsubscribe := f.subscribeOutput()
+	out := f.outputLogger()
+	out.Log("first")
+	out.Logf("second %d", 2)
+	assert.Equal(t, "first", <-lines)
+	assert.Equal(t, "second 2", <-lines)
+
+	unsubscribe()
+	out.Log("third")
+	assert.Empty(t, lines)
+}
+
+func TestFixtureOutputHistory(t *testing.T) {
+	f := &Fixture{t: t}
+	out := f.outputLogger()
+	for i := range outputHistorySize + 1 {
+		out.Logf("line %d", i)
+	}
+	_, history, unsubscribe := f.subscribeOutputWithHistory()
+	unsubscribe()
+	require.Len(t, history, outputHistorySize)
+	assert.Equal(t, "line 1", history[0])
+	assert.Equal(t, fmt.Sprintf("line %d", outputHistorySize), history[len(history)-1])
+
+	f.resetOutputHistory()
+	_, history, unsubscribe = f.subscribeOutputWithHistory()
+	unsubscribe()
+	assert.Empty(t, history)
+}
+
+func TestFixtureWaitForReceiverStarted(t *testing.T) {
+	const watchLine = `2025-01-01T00:00:00.000Z	info	fileconsumer/file.go:261	Started watching file	{"resource": {}, "otelcol.component.id": "%s", "otelcol.component.kind": "receiver", "path": "/tmp/input.log"}`
+
+	t.Run("filelog logged before", func(t *testing.T) {
+		f := &Fixture{t: t}
+		f.outputLogger().Logf(watchLine, "filelog")
+		require.NoError(t, f.WaitForReceiverStarted(t.Context(), "filelog"))
+	})
+
+	t.Run("filelog logged after", func(t *testing.T) {
+		f := &Fixture{t: t}
+		out := f.outputLogger()
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			out.Log(collectorReadyMessage)
+			out.Logf(watchLine, "filelog/other")
+			out.Logf(watchLine, "filelog/logs")
+		}()
+		require.NoError(t, f.WaitForReceiverStarted(t.Context(), "filelog/logs"))
+	})
+
+	t.Run("other receiver", func(t *testing.T) {
+		f := &Fixture{t: t}
+		f.outputLogger().Log(`2025-01-01T00:00:00.000Z	info	service@v0.148.0/service.go:275	` + collectorReadyMessage)
+		require.NoError(t, f.WaitForReceiverStarted(t.Context(), "otlp"))
+	})
+
+	t.Run("not started", func(t *testing.T) {
+		f := &Fixture{t: t}
+		f.outputLogger().Log(collectorReadyMessage)
+		f.outputLogger().Logf(watchLine, "filelog/other")
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, f.WaitForReceiverStarted(ctx, "filelog"), context.DeadlineExceeded)
+	})
+}
+
+func TestFixtureOtelEffectiveConfig(t *testing.T) {
+	f := &Fixture{t: t}
+	_, err := f.OtelEffectiveConfig(t.Context())
+	require.ErrorContains(t, err, "the Elastic Agent is not running")
+
+	cfg := []byte("receivers:\n  filelog:\n    include: [/tmp/input.log]\n")
+	c := client.NewMockClient(t)
+	c.EXPECT().DiagnosticAgent(mock.Anything, mock.Anything).Return([]client.DiagnosticFileResult{
+		{Filename: "otel.yaml", Content: []byte("receivers: {}\n")},
+		{Filename: otelMergedDiagnosticFile, Content: cfg},
+	}, nil).Once()
+	c.EXPECT().DiagnosticAgent(mock.Anything, mock.Anything).Return([]client.DiagnosticFileResult{
+		{Filename: otelMergedDiagnosticFile, Content: []byte(noOtelConfigDiagnostic)},
+	}, nil).Once()
+	f.setClient(c)
+
+	actual, err := f.OtelEffectiveConfig(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, cfg, actual)
+
+	_, err = f.OtelEffectiveConfig(t.Context())
+	require.ErrorContains(t, err, "runs no collector")
+}
+
+func TestFixtureExecOtel(t *testing.T) {
+	f := newFakeAgentFixture(t, `case "$2 $3 $4 $5 $6" in
+"validate --output json --config valid.yml") echo '{"valid": true, "errors": []}' ;;
+"validate --output json --config invalid.yml")
+	echo '{"valid": false, "errors": [{"path": "service::pipelines::logs", "kind": "invalid", "message": "boom"}]}'
+	echo "Error: invalid configuration" >&2; exit 1 ;;
+"validate --output json --config missing.yml") echo "Error: missing.yml not found" >&2; exit 1 ;;
+"components --output json  ")
+	echo '{"buildinfo": {"Command": "otelcol", "Version": "9.1.0"}, "receivers": [{"name": "filelog", "stability": {"logs": "Beta"}}]}' ;;
+"schema --output json filelog ") echo '[{"kind": "receiver", "type": "filelog", "defaults": {"start_at": "end"}}]' ;;
+"translate --output json --config policy.yml") echo 'receivers: {}' ;;
+*) echo "$@" ;;
+esac
+`)
+	ctx := t.Context()
+
+	out, err := f.ExecOtel(ctx, "validate", "--config", "other.yml")
+	require.NoError(t, err)
+	assert.Equal(t, "otel validate --config other.yml\n", string(out))
+
+	result, err := f.OtelValidate(ctx, "--config", "valid.yml")
+	require.NoError(t, err)
+	assert.Equal(t, OtelValidationResult{Valid: true, Errors: []OtelValidationError{}}, result)
+
+	result, err = f.OtelValidate(ctx, "--config", "invalid.yml")
+	require.NoError(t, err, "an invalid configuration is reported by the result")
+	assert.False(t, result.Valid)
+	assert.Equal(t, []OtelValidationError{{Path: "service::pipelines::logs", Kind: "invalid", Message: "boom"}}, result.Errors)
+
+	_, err = f.OtelValidate(ctx, "--config", "missing.yml")
+	require.ErrorContains(t, err, "missing.yml not found")
+
+	components, err := f.OtelComponents(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "9.1.0", components.BuildInfo.Version)
+	assert.Equal(t, []OtelComponent{{Name: "filelog", Stability: map[string]string{"logs": "Beta"}}}, components.Receivers)
+
+	schemas, err := f.OtelSchema(ctx, "filelog")
+	require.NoError(t, err)
+	require.Len(t, schemas, 1)
+	assert.Equal(t, "receiver", schemas[0].Kind)
+	assert.Equal(t, map[string]any{"start_at": "end"}, schemas[0].Defaults)
+
+	_, err = f.OtelTranslate(ctx, "--config", "policy.yml")
+	require.ErrorContains(t, err, "failed to decode the output of otel translate")
+}
+
+func TestWithInputFiles(t *testing.T) {
+	dir := t.TempDir()
+	absPath := filepath.Join(dir, "logs", "input.log")
+
+	var files []string
+	t.Run("staged", func(t *testing.T) {
+		f := &Fixture{t: t, workDir: dir}
+		WithInputFiles(map[string]string{absPath: "Line 0\n"})(f)
+		WithInputFiles(map[string]string{"relative.log": "Line 1\n"})(f)
+
+		require.NoError(t, f.stageInputFiles())
+		content, err := os.ReadFile(absPath)
+		require.NoError(t, err)
+		assert.Equal(t, "Line 0\n", string(content))
+		content, err = os.ReadFile(filepath.Join(dir, "relative.log"))
+		require.NoError(t, err)
+		assert.Equal(t, "Line 1\n", string(content))
+
+		// the files are only staged before the first run
+		require.NoError(t, os.WriteFile(absPath, []byte("appended\n"), 0o600))
+		require.NoError(t, f.stageInputFiles())
+		content, err = os.ReadFile(absPath)
+		require.NoError(t, err)
+		assert.Equal(t, "appended\n", string(content))
+
+		entries, err := os.ReadDir(filepath.Dir(absPath))
+		require.NoError(t, err)
+		for _, entry := range entries {
+			files = append(files, entry.Name())
+		}
+	})
+	assert.Equal(t, []string{"input.log"}, files, "no temporary file must be left")
+	assert.NoFileExists(t, absPath, "staged files must be removed when the test ends")
+	assert.NoFileExists(t, filepath.Join(dir, "relative.log"), "staged files must be removed when the test ends")
+}
+
+func TestFixtureWaitForFileContains(t *testing.T) {
+	dir := t.TempDir()
+	f := &Fixture{workDir: dir}
+	path := filepath.Join(dir, "output.json")
+
+	go func() {
+		time.Sleep(2 * fileContainsInterval)
+		_ = os.WriteFile(path, []byte("first line\n"), 0o600)
+		time.Sleep(2 * fileContainsInterval)
+		// truncated, like a rotated file
+		_ = os.WriteFile(path, []byte("second line\n"), 0o600)
+	}()
+	require.NoError(t, f.WaitForFileContains(t.Context(), "output.json", []string{"first", "second"}, MatchAll, 10*time.Second))
+	require.NoError(t, f.WaitForFileContains(t.Context(), path, []string{"missing", "second"}, MatchAny, 10*time.Second))
+
+	err := f.WaitForFileContains(t.Context(), path, []string{"second", "missing"}, MatchAll, 2*fileContainsInterval)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, `["missing"]`)
+
+	err = f.WaitForFileContains(t.Context(), "nofile", []string{"any"}, MatchAny, fileContainsInterval)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFixtureIsOtelReady(t *testing.T) {
+	var state atomic.Value
+	state.Store("Starting")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := state.Load().(string)
+		if r.URL.Path == otelReadinessPath && current != "Running" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = fmt.Fprintln(w, current)
+	}))
+	defer server.Close()
+	// the probes are sent to localhost when the health address has no host
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	healthAddr := ":" + port
+
+	f := &Fixture{t: t}
+	require.NoError(t, f.IsOtelLive(t.Context(), healthAddr))
+	err = f.IsOtelReady(t.Context(), healthAddr)
+	require.ErrorContains(t, err, "collector /readyz probe failed with status 503, collector state: Starting")
+
+	state.Store("Running")
+	require.NoError(t, f.IsOtelReady(t.Context(), healthAddr))
+
+	require.ErrorContains(t, f.IsOtelReady(t.Context(), "13133"), `invalid health address "13133"`)
+}
+
+func TestFixtureBlockEndpoint(t *testing.T) {
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer upstream.Close()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	target := upstream.Addr().String()
+
+	f := &Fixture{t: t}
+	proxyAddr, err := f.ProxyEndpoint(target)
+	require.NoError(t, err)
+	_, err = f.ProxyEndpoint("no-port")
+	require.ErrorContains(t, err, `invalid endpoint "no-port"`)
+
+	echo := func(conn net.Conn) error {
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			return err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return err
+		}
+		if string(buf) != "ping" {
+			return fmt.Errorf("unexpected echo %q", buf)
+		}
+		return nil
+	}
+
+	conn, err := net.Dial("tcp", proxyAddr)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, echo(conn))
+
+	// blocking closes the forwarded connections and the new ones
+	f.BlockEndpoint(target)
+	require.Error(t, echo(conn))
+	blocked, err := net.Dial("tcp", proxyAddr)
+	require.NoError(t, err)
+	defer blocked.Close()
+	require.Error(t, echo(blocked))
+
+	f.UnblockEndpoint(proxyAddr)
+	recovered, err := net.Dial("tcp", proxyAddr)
+	require.NoError(t, err)
+	defer recovered.Close()
+	require.NoError(t, echo(recovered))
+}
+
+func TestFixtureWaitForControlSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the control socket is a named pipe on Windows")
+	}
+	f := &Fixture{t: t}
+	require.ErrorContains(t, f.WaitForControlSocket(t.Context(), time.Second), "the fixture is not prepared")
+
+	// short path, unix socket paths are limited in length
+	dir, err := os.MkdirTemp("", "sock")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "control.sock")
+	f.setSocketPath("unix://" + socketPath)
+
+	err = f.WaitForControlSocket(t.Context(), 500*time.Millisecond)
+	require.ErrorContains(t, err, "is not accepting connections")
+
+	listening := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		listener, err := net.Listen("unix", socketPath)
+		assert.NoError(t, err)
+		listening <- listener
+	}()
+	require.NoError(t, f.WaitForControlSocket(t.Context(), 10*time.Second))
+	if listener := <-listening; listener != nil {
+		_ = listener.Close()
+	}
+}
+
+// reloadConfigServer is a control protocol server answering ReloadConfig with err.
+type reloadConfigServer struct {
+	cproto.UnimplementedElasticAgentControlServer
+	calls atomic.Int32
+	err   error
+}
+
+func (s *reloadConfigServer) ReloadConfig(context.Context, *cproto.Empty) (*cproto.Empty, error) {
+	s.calls.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &cproto.Empty{}, nil
+}
+
+func TestFixtureReloadConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the control socket is a named pipe on Windows")
+	}
+	f := &Fixture{t: t}
+	require.ErrorContains(t, f.ReloadConfig(t.Context()), "the fixture is not prepared")
+
+	// short path, unix socket paths are limited in length
+	dir, err := os.MkdirTemp("", "sock")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "control.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	srv := &reloadConfigServer{}
+	grpcServer := grpc.NewServer()
+	cproto.RegisterElasticAgentControlServer(grpcServer, srv)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+	f.setSocketPath("unix://" + socketPath)
+
+	require.NoError(t, f.ReloadConfig(t.Context()))
+	assert.Equal(t, int32(1), srv.calls.Load())
+
+	srv.err = errors.New("invalid configuration")
+	require.ErrorContains(t, f.ReloadConfig(t.Context()), "invalid configuration")
+	assert.Equal(t, int32(2), srv.calls.Load())
+}
@@ -5,8 +5,12 @@
 package testing
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -15,11 +19,30 @@ import (
 var _ Logger = &LogWatcher{}
 
 // LogWatcher wraps actual logger and watches for occurrences of strings
+// or of regular expressions added with AddPattern.
+//
+// LogWatcher is safe for concurrent use: lines can be logged from one goroutine (e.g. RunProcess)
+// while others register keys and wait for them. A key registered while lines are being logged
+// only matches the lines logged after its registration.
 type LogWatcher struct {
+	// watchesLock protects the watch state below, wrapped must be safe for concurrent use.
+	watchesLock sync.RWMutex
+
 	activeWatches map[string]bool
+	patterns      map[string]*regexp.Regexp
+	matchedKeys   map[string]string
+	matchSources  map[string]string
+	trackedKeys   map[string]struct{}
+	forbiddenKeys map[string]struct{}
+	forbiddenErr  error
+	forbiddenSeen chan struct{}
+	callbacks     []matchCallback
 	wrapped       Logger
+}
 
-	watchesLock sync.Mutex
+type matchCallback struct {
+	key string
+	fn  func(line string)
 }
 
 // NewLogWatcher returns watches initialised with watches and underlying logger
@@ -32,6 +55,39 @@ func NewLogWatcher(wrappedLogger Logger, watches ...string) *LogWatcher {
 	return &LogWatcher{
 		wrapped:       wrappedLogger,
 		activeWatches: activeWatches,
+		patterns:      make(map[string]*regexp.Regexp),
+		matchedKeys:   make(map[string]string),
+		matchSources:  make(map[string]string),
+		trackedKeys:   make(map[string]struct{}),
+		forbiddenKeys: make(map[string]struct{}),
+		forbiddenSeen: make(chan struct{}),
+	}
+}
+
+// NewLogWatcherRegex returns a LogWatcher watching for lines matching patterns, see AddPattern.
+func NewLogWatcherRegex(wrappedLogger Logger, patterns ...*regexp.Regexp) *LogWatcher {
+	l := NewLogWatcher(wrappedLogger)
+	for _, p := range patterns {
+		l.AddPattern(p)
+	}
+	return l
+}
+
+// AddPattern watches for lines matching the regular expression p. The pattern is keyed by
+// p.String(), which is the key to pass to KeyOccured, WaitForKeys and the other key based methods.
+//
+// Every watched pattern is evaluated against every logged line until it matches, and after it
+// matched only when it is tracked with TrackLastMatch or has OnMatch callbacks. Unlike substring
+// keys this is not cheap: prefer substring keys for fixed strings and keep the number of patterns
+// low on verbose log streams.
+func (l *LogWatcher) AddPattern(p *regexp.Regexp) {
+	l.watchesLock.Lock()
+	defer l.watchesLock.Unlock()
+
+	key := p.String()
+	l.patterns[key] = p
+	if _, matched := l.matchedKeys[key]; !matched {
+		l.activeWatches[key] = false
 	}
 }
 
@@ -39,14 +95,56 @@ func NewLogWatcher(wrappedLogger Logger, watches ...string) *LogWatcher {
 func (l *LogWatcher) Log(args ...any) {
 	l.wrapped.Log(args...)
 	line := fmt.Sprintln(args...)
-	l.checkLine(line)
+	l.checkLine("", line)
 }
 
 // Logf logs the formatted arguments.
 func (l *LogWatcher) Logf(format string, args ...any) {
 	l.wrapped.Logf(format, args...)
 	line := fmt.Sprintf(format, args...)
-	l.checkLine(line)
+	l.checkLine("", line)
+}
+
+// AddSource watches the lines read from r in a new goroutine. Lines are logged to the wrapped logger
+// prefixed with tag, and MatchSource reports tag for the keys they match. This lets a single LogWatcher
+// track several streams, e.g. the agent and the apm-server logs.
+//
+// The goroutine stops when r returns an error or io.EOF, or when ctx is done, the returned channel is
+// closed once it exited. A blocked read can't be interrupted, so when ctx is done r is closed if it is
+// an io.Closer, otherwise the caller remains responsible for closing r. When the wrapped logger is a
+// testing.TB and r an io.Closer, r is also closed and the goroutine waited for when the test
+// completes, so that nothing is logged afterwards.
+func (l *LogWatcher) AddSource(ctx context.Context, r io.Reader, tag string) <-chan struct{} {
+	done := make(chan struct{})
+	closer, canClose := r.(io.Closer)
+	stop := context.AfterFunc(ctx, func() {
+		if canClose {
+			_ = closer.Close()
+		}
+	})
+	if tb, ok := l.wrapped.(interface{ Cleanup(func()) }); ok && canClose {
+		tb.Cleanup(func() {
+			_ = closer.Close()
+			<-done
+		})
+	}
+	go func() {
+		defer close(done)
+		defer stop()
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if ctx.Err() != nil {
+				return
+			}
+			line := scanner.Text()
+			l.wrapped.Logf("[%s] %s", tag, line)
+			l.checkLine(tag, line)
+		}
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			l.wrapped.Logf("[%s] failed to read log source: %s", tag, err)
+		}
+	}()
+	return done
 }
 
 // KeyOccured return true in case key was hit before
@@ -74,25 +172,230 @@ func (l *LogWatcher) WaitForKeys(ctx context.Context, timeout, interval time.Dur
 	}
 }
 
-func (l *LogWatcher) checkLine(line string) {
+// WaitForAllKeys waits for every key to occur in a log stream.
+//
+// Unlike WaitForKeys, keys that were not passed to NewLogWatcher are watched from now on
+// instead of being considered as occurred, so only lines logged after the call can match them.
+// On timeout the returned error lists the keys that did not occur.
+func (l *LogWatcher) WaitForAllKeys(ctx context.Context, timeout, interval time.Duration, keys ...string) error {
+	l.watchKeys(keys...)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if l.keysOccured(keys...) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("keys did not occur %q: %w", l.missingKeys(keys...), ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+// OnMatch calls fn with every line that matches key, a substring or a pattern added with AddPattern.
+//
+// fn is called from the goroutine that logs the line (the goroutine scanning the process output
+// when used with RunProcess), once per matching line, after the line has been recorded, so
+// KeyOccured and LastMatch already reflect it. Lines logged from a single goroutine are delivered
+// in order, callbacks matching the same line are called in registration order.
+//
+// No lock is held while fn runs, so it can use the LogWatcher without deadlocking, but a slow fn
+// delays the processing of the following lines: hand long work off to another goroutine.
+func (l *LogWatcher) OnMatch(key string, fn func(line string)) {
+	l.watchesLock.Lock()
+	defer l.watchesLock.Unlock()
+
+	l.callbacks = append(l.callbacks, matchCallback{key: key, fn: fn})
+}
+
+// TrackLastMatch keeps LastMatch and MatchSource up to date with the last line matching keys, instead
+// of the line that first matched them. Keys that are not watched yet are watched from now on.
+//
+// Tracked keys are evaluated against every logged line for the life of the LogWatcher, only track the
+// keys whose latest occurrence matters.
+func (l *LogWatcher) TrackLastMatch(keys ...string) {
+	l.watchesLock.Lock()
+	defer l.watchesLock.Unlock()
+
+	for _, k := range keys {
+		l.trackedKeys[k] = struct{}{}
+		if _, matched := l.matchedKeys[k]; !matched {
+			l.activeWatches[k] = false
+		}
+	}
+}
+
+// LastMatch returns the complete line, as logged, that first contained key, or that last contained it
+// when key is tracked with TrackLastMatch.
+// It returns false when key is not watched or did not occur yet.
+func (l *LogWatcher) LastMatch(key string) (string, bool) {
+	l.watchesLock.RLock()
+	defer l.watchesLock.RUnlock()
+
+	line, found := l.matchedKeys[key]
+	return line, found
+}
+
+// MatchSource returns the tag of the source, see AddSource, of the line returned by LastMatch for key.
+// The tag is empty for lines logged with Log and Logf. It returns false when key did not occur yet.
+func (l *LogWatcher) MatchSource(key string) (string, bool) {
+	l.watchesLock.RLock()
+	defer l.watchesLock.RUnlock()
+
+	source, found := l.matchSources[key]
+	return source, found
+}
+
+// AssertAbsent marks keys as forbidden: once a line containing one of them is logged,
+// WatchFor returns an error right away and AbsentErr reports the line.
+// Only lines logged after the call are checked.
+func (l *LogWatcher) AssertAbsent(keys ...string) {
+	l.watchesLock.Lock()
+	defer l.watchesLock.Unlock()
+
+	for _, k := range keys {
+		l.forbiddenKeys[k] = struct{}{}
+	}
+}
+
+// AbsentErr returns an error describing the first line that contained a key passed to AssertAbsent,
+// or nil when no forbidden key occurred.
+func (l *LogWatcher) AbsentErr() error {
+	l.watchesLock.RLock()
+	defer l.watchesLock.RUnlock()
+
+	return l.forbiddenErr
+}
+
+// WatchFor waits for every key to occur in a log stream like WaitForAllKeys, but returns
+// immediately with an error as soon as a key passed to AssertAbsent occurs.
+func (l *LogWatcher) WatchFor(ctx context.Context, timeout, interval time.Duration, keys ...string) error {
+	l.watchKeys(keys...)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := l.AbsentErr(); err != nil {
+			return err
+		}
+		if l.keysOccured(keys...) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("keys did not occur %q: %w", l.missingKeys(keys...), ctx.Err())
+		case <-l.forbiddenSeen:
+		case <-t.C:
+		}
+	}
+}
+
+// watchKeys starts watching the keys that are neither watched nor matched yet.
+func (l *LogWatcher) watchKeys(keys ...string) {
+	l.watchesLock.Lock()
+	defer l.watchesLock.Unlock()
+
+	for _, k := range keys {
+		if _, matched := l.matchedKeys[k]; matched {
+			continue
+		}
+		l.activeWatches[k] = false
+	}
+}
+
+// missingKeys returns the sorted keys that did not occur yet.
+func (l *LogWatcher) missingKeys(keys ...string) []string {
+	l.watchesLock.RLock()
+	defer l.watchesLock.RUnlock()
+
+	var missing []string
+	for _, k := range keys {
+		if _, found := l.activeWatches[k]; found {
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func (l *LogWatcher) checkLine(source string, line string) {
+	line = strings.TrimRight(line, "\r\n")
+	// callbacks run without holding the lock so they can use the LogWatcher
+	for _, fn := range l.matchLine(source, line) {
+		fn(line)
+	}
+}
+
+// matchLine updates the watch state with line and returns the OnMatch callbacks to invoke for it.
+func (l *LogWatcher) matchLine(source string, line string) []func(string) {
 	l.watchesLock.Lock()
 	defer l.watchesLock.Unlock()
 
 	var removeKeys []string
 	for k := range l.activeWatches {
-		if strings.Contains(line, k) {
+		if l.matchKey(k, line) {
 			removeKeys = append(removeKeys, k)
 		}
 	}
 
+	// keep track of the last line matching the tracked keys that occurred before this line
+	for k := range l.trackedKeys {
+		if _, active := l.activeWatches[k]; active {
+			continue
+		}
+		if _, matched := l.matchedKeys[k]; matched && l.matchKey(k, line) {
+			l.matchedKeys[k] = line
+			l.matchSources[k] = source
+		}
+	}
+
 	for _, k := range removeKeys {
 		delete(l.activeWatches, k)
+		l.matchedKeys[k] = line
+		l.matchSources[k] = source
+	}
+
+	if l.forbiddenErr == nil {
+		for k := range l.forbiddenKeys {
+			if strings.Contains(line, k) {
+				l.forbiddenErr = fmt.Errorf("forbidden key %q occurred in line: %s", k, line)
+				close(l.forbiddenSeen)
+				break
+			}
+		}
+	}
+
+	var callbacks []func(string)
+	for _, cb := range l.callbacks {
+		if l.matchKey(cb.key, line) {
+			callbacks = append(callbacks, cb.fn)
+		}
 	}
+	return callbacks
+}
 
+// matchKey returns true when line contains the key, or matches it when the key is a pattern.
+// Must be called with watchesLock held.
+func (l *LogWatcher) matchKey(key string, line string) bool {
+	if p, isPattern := l.patterns[key]; isPattern {
+		return p.MatchString(line)
+	}
+	return strings.Contains(line, key)
 }
+
 func (l *LogWatcher) keysOccured(keys ...string) bool {
-	l.watchesLock.Lock()
-	defer l.watchesLock.Unlock()
+	l.watchesLock.RLock()
+	defer l.watchesLock.RUnlock()
 
 	for _, k := range keys {
 		if _, found := l.activeWatches[k]; found {
@@ -0,0 +1,253 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogWatcherWaitForAllKeys(t *testing.T) {
+	t.Run("all keys occur", func(t *testing.T) {
+		w := NewLogWatcher(t, "first")
+		w.Log("the first line")
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			w.Logf("the %s line", "second")
+		}()
+
+		require.NoError(t, w.WaitForAllKeys(context.Background(), time.Second, 5*time.Millisecond, "first", "second"))
+	})
+
+	t.Run("unregistered keys are not considered as occurred", func(t *testing.T) {
+		w := NewLogWatcher(t, "first")
+		w.Log("the first line")
+
+		err := w.WaitForAllKeys(context.Background(), 50*time.Millisecond, 5*time.Millisecond, "first", "third", "second")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, `["second" "third"]`)
+	})
+}
+
+func TestLogWatcherAssertAbsent(t *testing.T) {
+	t.Run("forbidden key fails fast", func(t *testing.T) {
+		w := NewLogWatcher(t)
+		w.AssertAbsent("panic", "failed to connect")
+		w.Log("starting")
+		require.NoError(t, w.AbsentErr())
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			w.Log("exporter failed to connect to localhost:9200")
+		}()
+
+		start := time.Now()
+		err := w.WatchFor(context.Background(), 10*time.Second, time.Second, "ready")
+		require.ErrorContains(t, err, `forbidden key "failed to connect" occurred in line: exporter failed to connect to localhost:9200`)
+		assert.Less(t, time.Since(start), 5*time.Second, "WatchFor should return as soon as the forbidden key occurs")
+		assert.Equal(t, err, w.AbsentErr())
+	})
+
+	t.Run("keys occur without forbidden keys", func(t *testing.T) {
+		w := NewLogWatcher(t, "ready")
+		w.AssertAbsent("panic")
+		w.Log("component is ready")
+		require.NoError(t, w.WatchFor(context.Background(), time.Second, 5*time.Millisecond, "ready"))
+	})
+}
+
+func TestLogWatcherLastMatch(t *testing.T) {
+	w := NewLogWatcher(t, "apm-server is ready", "never")
+
+	_, found := w.LastMatch("apm-server is ready")
+	assert.False(t, found)
+
+	w.Log(`{"log.level":"info","@timestamp":"2025-01-01T00:00:00.000Z","message":"apm-server is ready","port":8200}`)
+	line, found := w.LastMatch("apm-server is ready")
+	require.True(t, found)
+	assert.Equal(t, `{"log.level":"info","@timestamp":"2025-01-01T00:00:00.000Z","message":"apm-server is ready","port":8200}`, line)
+
+	// the first match is kept until the key is tracked
+	w.Logf("%s: apm-server is ready", "2025-01-01T00:00:01.000Z")
+	line, found = w.LastMatch("apm-server is ready")
+	require.True(t, found)
+	assert.Equal(t, `{"log.level":"info","@timestamp":"2025-01-01T00:00:00.000Z","message":"apm-server is ready","port":8200}`, line)
+
+	w.TrackLastMatch("apm-server is ready")
+	w.Logf("%s: apm-server is ready", "2025-01-01T00:00:02.000Z")
+	line, found = w.LastMatch("apm-server is ready")
+	require.True(t, found)
+	assert.Equal(t, "2025-01-01T00:00:02.000Z: apm-server is ready", line)
+
+	// tracking a key that is not watched yet watches it
+	w.TrackLastMatch("apm-server stopped")
+	assert.False(t, w.KeyOccured("apm-server stopped"))
+	w.Log("apm-server stopped")
+	w.Log("apm-server stopped again")
+	line, found = w.LastMatch("apm-server stopped")
+	require.True(t, found)
+	assert.Equal(t, "apm-server stopped again", line)
+
+	_, found = w.LastMatch("never")
+	assert.False(t, found)
+	_, found = w.LastMatch("not watched")
+	assert.False(t, found)
+}
+
+func TestLogWatcherPatterns(t *testing.T) {
+	requestID := regexp.MustCompile(`request [0-9a-f]{8} completed`)
+	port := regexp.MustCompile(`listening on port \d+`)
+	w := NewLogWatcherRegex(t, requestID)
+	w.AddPattern(port)
+
+	w.Log("request abcdefgh completed")
+	assert.False(t, w.KeyOccured(requestID.String()))
+
+	w.Log("request 0123abcd completed in 3ms")
+	assert.True(t, w.KeyOccured(requestID.String()))
+	line, found := w.LastMatch(requestID.String())
+	require.True(t, found)
+	assert.Equal(t, "request 0123abcd completed in 3ms", line)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		w.Log("server listening on port 8200")
+	}()
+	require.NoError(t, w.WaitForKeys(context.Background(), time.Second, 5*time.Millisecond, port.String()))
+
+	// pattern keys are matched as regular expressions, not as substrings
+	w.AddPattern(regexp.MustCompile(`a.c`))
+	w.Log("abc")
+	assert.True(t, w.KeyOccured(`a.c`))
+}
+
+// TestLogWatcherConcurrentUse is meant to be run with -race.
+func TestLogWatcherConcurrentUse(t *testing.T) {
+	const lines = 200
+	w := NewLogWatcher(t, "line 0")
+	w.AssertAbsent("panic")
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < lines; i++ {
+			w.Logf("line %d", i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < lines; i++ {
+			w.KeyOccured(fmt.Sprintf("line %d", i))
+			w.LastMatch("line 0")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < lines; i++ {
+			w.AddPattern(regexp.MustCompile(fmt.Sprintf(`^line %d$`, i)))
+			w.AssertAbsent(fmt.Sprintf("panic %d", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, w.WaitForKeys(context.Background(), 5*time.Second, time.Millisecond, "line 0"))
+	}()
+	wg.Wait()
+
+	assert.NoError(t, w.AbsentErr())
+	assert.True(t, w.KeyOccured("line 0"))
+}
+
+func TestLogWatcherOnMatch(t *testing.T) {
+	w := NewLogWatcher(t, "ready")
+	var matched []string
+	w.OnMatch("ready", func(line string) {
+		// the watch state is updated before callbacks run, and the watcher can be used from them
+		assert.True(t, w.KeyOccured("ready"))
+		matched = append(matched, line)
+	})
+
+	w.Log("apm-server ready")
+	w.Log("unrelated")
+	w.Log("still ready")
+	assert.Equal(t, []string{"apm-server ready", "still ready"}, matched)
+
+	t.Run("slow callback does not block the watcher", func(t *testing.T) {
+		w := NewLogWatcher(t, "other")
+		release := make(chan struct{})
+		w.OnMatch("slow", func(string) {
+			<-release
+		})
+		logged := make(chan struct{})
+		go func() {
+			defer close(logged)
+			w.Log("slow line")
+		}()
+
+		w.Log("other line")
+		assert.True(t, w.KeyOccured("other"))
+		close(release)
+		<-logged
+	})
+}
+
+func TestLogWatcherAddSource(t *testing.T) {
+	w := NewLogWatcher(t, "agent started", "apm-server ready", "collector started")
+
+	w.AddSource(t.Context(), strings.NewReader("booting\nagent started\n"), "agent")
+	apmReader, apmWriter := io.Pipe()
+	defer apmReader.Close()
+	w.AddSource(t.Context(), apmReader, "apm-server")
+	w.Log("collector started")
+
+	go func() {
+		_, _ = apmWriter.Write([]byte("apm-server ready on port 8200\n"))
+		_ = apmWriter.Close()
+	}()
+
+	require.NoError(t, w.WaitForKeys(context.Background(), time.Second, 5*time.Millisecond,
+		"agent started", "apm-server ready", "collector started"))
+
+	source, found := w.MatchSource("agent started")
+	require.True(t, found)
+	assert.Equal(t, "agent", source)
+	source, found = w.MatchSource("apm-server ready")
+	require.True(t, found)
+	assert.Equal(t, "apm-server", source)
+	line, _ := w.LastMatch("apm-server ready")
+	assert.Equal(t, "apm-server ready on port 8200", line)
+	source, found = w.MatchSource("collector started")
+	require.True(t, found)
+	assert.Empty(t, source)
+}
+
+func TestLogWatcherAddSourceStopsWithContext(t *testing.T) {
+	w := NewLogWatcher(t, "late line")
+	ctx, cancel := context.WithCancel(t.Context())
+	r, writer := io.Pipe()
+	done := w.AddSource(ctx, r, "agent")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the source goroutine did not exit when the context was cancelled")
+	}
+	// the reader is closed when the context is done, so the writer fails instead of blocking
+	_, err := writer.Write([]byte("late line\n"))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	assert.False(t, w.KeyOccured("late line"))
+}
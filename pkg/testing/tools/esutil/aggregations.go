@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
+)
+
+// Aggregation is the result of a single aggregation, either a metric aggregation such as sum, avg or
+// max, which sets Value, or a bucket aggregation such as terms or date_histogram, which sets Buckets.
+type Aggregation struct {
+	// Value is the value of a single-value metric aggregation, nil when the aggregation has no value,
+	// e.g. the max of a field without documents.
+	Value *float64 `json:"value"`
+	// Buckets are the buckets of a bucket aggregation.
+	Buckets []AggregationBucket `json:"buckets"`
+}
+
+// AggregationBucket is a single bucket of a bucket aggregation.
+type AggregationBucket struct {
+	Key         any    `json:"key"`
+	KeyAsString string `json:"key_as_string"`
+	DocCount    int    `json:"doc_count"`
+	// Aggregations are the sub-aggregations computed for the documents of the bucket.
+	Aggregations map[string]Aggregation `json:"-"`
+}
+
+// UnmarshalJSON decodes the bucket, every object field that is not part of the bucket itself is a sub-aggregation.
+func (b *AggregationBucket) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	type bucket AggregationBucket
+	if err := json.Unmarshal(data, (*bucket)(b)); err != nil {
+		return err
+	}
+	for name, raw := range fields {
+		switch name {
+		case "key", "key_as_string", "doc_count":
+			continue
+		}
+		if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			continue
+		}
+		var agg Aggregation
+		if err := json.Unmarshal(raw, &agg); err != nil {
+			return fmt.Errorf("error unmarshaling sub-aggregation %s: %w", name, err)
+		}
+		if b.Aggregations == nil {
+			b.Aggregations = make(map[string]Aggregation)
+		}
+		b.Aggregations[name] = agg
+	}
+	return nil
+}
+
+// RunAggregation runs the aggregations aggs over all the documents of index and returns their
+// results by name. aggs is the raw aggs clause of the search request, e.g.
+//
+//	map[string]interface{}{
+//		"total": map[string]interface{}{"sum": map[string]interface{}{"field": "system.cpu.total.pct"}},
+//	}
+func RunAggregation(ctx context.Context, client elastictransport.Interface, index string, aggs map[string]interface{}) (map[string]Aggregation, error) {
+	var res struct {
+		Aggregations map[string]Aggregation `json:"aggregations"`
+	}
+	err := search(ctx, client, index, map[string]interface{}{
+		"size": 0,
+		"aggs": aggs,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res.Aggregations, nil
+}
@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAggregation(t *testing.T) {
+	transport := newFakeTransport(okResponse(`{
+			"hits": {"total": {"value": 3, "relation": "eq"}, "hits": []},
+			"aggregations": {
+				"total": {"value": 42.5},
+				"missing": {"value": null},
+				"per_host": {
+					"buckets": [
+						{"key": "host-a", "doc_count": 2, "total": {"value": 40}},
+						{"key": "host-b", "doc_count": 1, "total": {"value": 2.5}}
+					]
+				}
+			}
+		}`))
+	aggs := map[string]any{
+		"total": map[string]any{"sum": map[string]any{"field": "metric"}},
+	}
+
+	res, err := RunAggregation(t.Context(), transport, "metrics-*", aggs)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{"size": float64(0), "aggs": aggs}, transport.bodies[0])
+
+	require.NotNil(t, res["total"].Value)
+	assert.InDelta(t, 42.5, *res["total"].Value, 0.001)
+	assert.Nil(t, res["missing"].Value)
+
+	buckets := res["per_host"].Buckets
+	require.Len(t, buckets, 2)
+	assert.Equal(t, "host-a", buckets[0].Key)
+	assert.Equal(t, 2, buckets[0].DocCount)
+	require.Contains(t, buckets[0].Aggregations, "total")
+	assert.InDelta(t, 40, *buckets[0].Aggregations["total"].Value, 0.001)
+	assert.Equal(t, "host-b", buckets[1].Key)
+}
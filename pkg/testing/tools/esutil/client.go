@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// maxRetryBackoff caps the exponential backoff between the retries of NewClientWithRetry.
+const maxRetryBackoff = 30 * time.Second
+
+// retryOnStatus are the transient errors a cloud Elasticsearch returns while a node restarts or is
+// overloaded, which the client created by NewClientWithRetry retries.
+var retryOnStatus = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// NewClientWithRetry creates an Elasticsearch client from cfg that retries a request up to
+// maxRetries times on connection errors, timeouts and transient 429, 502, 503 and 504 responses,
+// so a short unavailability of the cluster doesn't fail a test. The backoff between retries starts
+// at backoff and doubles after each retry, up to 30 seconds. The retry settings of cfg are
+// replaced, the time a request can take including its retries should be bounded by its context.
+func NewClientWithRetry(cfg elasticsearch.Config, maxRetries int, backoff time.Duration) (*elasticsearch.Client, error) {
+	if maxRetries < 0 {
+		return nil, fmt.Errorf("invalid max retries %d, it must not be negative", maxRetries)
+	}
+	cfg.MaxRetries = maxRetries
+	cfg.DisableRetry = maxRetries == 0
+	cfg.RetryOnStatus = retryOnStatus
+	cfg.EnableRetryOnTimeout = true
+	cfg.RetryBackoff = func(attempt int) time.Duration {
+		return retryBackoff(backoff, attempt)
+	}
+	c, err := elasticsearch.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create elasticsearch client: %w", err)
+	}
+	return c, nil
+}
+
+// retryBackoff returns the backoff before the retry attempt, starting at 1.
+func retryBackoff(backoff time.Duration, attempt int) time.Duration {
+	d := backoff
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
+
+// NormalizeESHost normalizes the address of an Elasticsearch cluster, e.g. the ELASTICSEARCH_HOST
+// given by the test runner, into a `<scheme>://<host>:<port>[/<path>]` URL. The https scheme is
+// used when raw has none, the default port of the scheme is added when raw has none, and trailing
+// slashes are removed. IPv6 literals must be enclosed in brackets, e.g. `[::1]:9200`.
+func NormalizeESHost(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("elasticsearch host cannot be empty")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid elasticsearch host %q: %w", raw, err)
+	}
+	var defaultPort string
+	switch u.Scheme {
+	case "https":
+		defaultPort = "443"
+	case "http":
+		defaultPort = "80"
+	default:
+		return "", fmt.Errorf("invalid elasticsearch host %q: unsupported scheme %q, must be http or https", raw, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid elasticsearch host %q: no host", raw)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
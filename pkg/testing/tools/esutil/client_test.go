@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+func TestNewClientWithRetry(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"count":3}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClientWithRetry(elasticsearch.Config{Addresses: []string{srv.URL}}, 2, time.Millisecond)
+	require.NoError(t, err)
+	count, err := CountDocuments(t.Context(), c, "logs-*", map[string]any{"match_all": map[string]any{}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.EqualValues(t, 3, requests.Load())
+
+	requests.Store(0)
+	c, err = NewClientWithRetry(elasticsearch.Config{Addresses: []string{srv.URL}}, 0, time.Millisecond)
+	require.NoError(t, err)
+	_, err = CountDocuments(t.Context(), c, "logs-*", map[string]any{"match_all": map[string]any{}})
+	require.ErrorContains(t, err, "502")
+	assert.EqualValues(t, 1, requests.Load())
+
+	_, err = NewClientWithRetry(elasticsearch.Config{}, -1, time.Second)
+	require.Error(t, err)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, retryBackoff(time.Second, 1))
+	assert.Equal(t, 4*time.Second, retryBackoff(time.Second, 3))
+	assert.Equal(t, maxRetryBackoff, retryBackoff(time.Second, 10))
+}
+
+func TestNormalizeESHost(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"https://es.elastic-cloud.com":        "https://es.elastic-cloud.com:443",
+		"https://es.elastic-cloud.com:9243":   "https://es.elastic-cloud.com:9243",
+		"http://localhost:9200":               "http://localhost:9200",
+		"http://localhost":                    "http://localhost:80",
+		"es.elastic-cloud.com":                "https://es.elastic-cloud.com:443",
+		"localhost:9200":                      "https://localhost:9200",
+		"https://es.elastic-cloud.com/":       "https://es.elastic-cloud.com:443",
+		"https://proxy.example.com:8443/es//": "https://proxy.example.com:8443/es",
+		"http://[::1]:9200":                   "http://[::1]:9200",
+		"https://[2001:db8::1]":               "https://[2001:db8::1]:443",
+		"  https://es.elastic-cloud.com\n":    "https://es.elastic-cloud.com:443",
+	} {
+		t.Run(raw, func(t *testing.T) {
+			host, err := NormalizeESHost(raw)
+			require.NoError(t, err)
+			assert.Equal(t, expected, host)
+		})
+	}
+
+	for _, raw := range []string{"", "ftp://es.elastic-cloud.com", "https://", "https://es elastic"} {
+		t.Run("invalid "+raw, func(t *testing.T) {
+			_, err := NormalizeESHost(raw)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dataStreamInvalidChars are the characters not allowed in the parts of a data stream name.
+const dataStreamInvalidChars = `-\/*?"<>| ,#:`
+
+// DataStream is the name of a data stream following the `<type>-<dataset>-<namespace>` naming
+// scheme, e.g. `logs-apm.app-default`. Empty parts match anything, and the parts of a DataStream
+// built without NewDataStream can hold wildcards, so it can also be used as the index pattern of a
+// search, e.g. of the data streams of every dataset or every namespace of a type.
+type DataStream struct {
+	// Type is the type of data, e.g. logs, metrics or traces.
+	Type string
+	// Dataset is the source of the data, e.g. apm.app or generic.
+	Dataset string
+	// Namespace isolates the data of a test, see define.Info.Namespace.
+	Namespace string
+}
+
+// NewDataStream returns the name of the data stream of the given type, dataset and namespace. It
+// fails when a part has a character the naming scheme doesn't allow, like a `-` in the dataset.
+func NewDataStream(dataType, dataset, namespace string) (DataStream, error) {
+	d := DataStream{Type: dataType, Dataset: dataset, Namespace: namespace}
+	for _, part := range []struct{ name, value string }{
+		{"type", dataType},
+		{"dataset", dataset},
+		{"namespace", namespace},
+	} {
+		if strings.ContainsAny(part.value, dataStreamInvalidChars) || part.value != strings.ToLower(part.value) {
+			return DataStream{}, fmt.Errorf("invalid data stream %s %q: it must be lowercase and must not contain any of %q", part.name, part.value, dataStreamInvalidChars)
+		}
+	}
+	return d, nil
+}
+
+// String returns the name of the data stream, empty parts are replaced by the `*` wildcard.
+func (d DataStream) String() string {
+	return wildcardIfEmpty(d.Type) + "-" + wildcardIfEmpty(d.Dataset) + "-" + wildcardIfEmpty(d.Namespace)
+}
+
+// BackingIndices returns the pattern matching the backing indices of the data stream.
+func (d DataStream) BackingIndices() string {
+	return ".ds-" + d.String() + "-*"
+}
+
+func wildcardIfEmpty(s string) string {
+	if s == "" {
+		return "*"
+	}
+	return s
+}
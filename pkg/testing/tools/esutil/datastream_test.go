@@ -0,0 +1,30 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataStream(t *testing.T) {
+	d, err := NewDataStream("logs", "apm.app", "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "logs-apm.app-abc123", d.String())
+	assert.Equal(t, ".ds-logs-apm.app-abc123-*", d.BackingIndices())
+
+	d, err = NewDataStream("traces", "", "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "traces-*-abc123", d.String())
+
+	assert.Equal(t, "metrics-*-*", DataStream{Type: "metrics"}.String())
+
+	_, err = NewDataStream("logs", "apm-app", "abc123")
+	assert.ErrorContains(t, err, `invalid data stream dataset "apm-app"`)
+	_, err = NewDataStream("logs", "generic", "Default")
+	assert.ErrorContains(t, err, `invalid data stream namespace "Default"`)
+}
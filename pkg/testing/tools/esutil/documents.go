@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/testing/estools"
+)
+
+// Contains is an AssertDocFields expectation matching the field values containing the substring.
+type Contains string
+
+// ContainsEach is an AssertDocFields expectation matched when each substring is contained in the
+// field value of at least one hit, e.g. the messages of the ingested log lines.
+type ContainsEach []string
+
+// AssertDocFields asserts the fields of the hits of docs, by the dotted field name, e.g.
+// service.name. An expectation is a Contains substring, a ContainsEach list of substrings, or
+// otherwise the exact value of the field. Contains and exact expectations must be matched by
+// every hit, a hit without the field doesn't match. It returns whether all expectations are
+// matched and, like the testify assertions, can be used in assert.EventuallyWithT.
+func AssertDocFields(t assert.TestingT, docs estools.Documents, expectations map[string]interface{}) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if len(docs.Hits.Hits) == 0 {
+		return assert.Fail(t, "no documents to assert the fields of")
+	}
+
+	fields := make([]string, 0, len(expectations))
+	for field := range expectations {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	ok := true
+	for _, field := range fields {
+		switch expected := expectations[field].(type) {
+		case ContainsEach:
+			for _, substr := range expected {
+				if !docsContain(docs, field, substr) {
+					ok = assert.Fail(t, fmt.Sprintf("no document has field %s containing %q", field, substr))
+				}
+			}
+		default:
+			for i, hit := range docs.Hits.Hits {
+				value, found := docField(hit.Source, field)
+				if !found {
+					ok = assert.Fail(t, fmt.Sprintf("document %d (%s) has no field %s", i, hit.Index, field))
+					continue
+				}
+				if substr, isContains := expected.(Contains); isContains {
+					if !strings.Contains(fmt.Sprint(value), string(substr)) {
+						ok = assert.Fail(t, fmt.Sprintf("document %d (%s) field %s: %q does not contain %q", i, hit.Index, field, fmt.Sprint(value), substr))
+					}
+					continue
+				}
+				if !assert.ObjectsAreEqualValues(expected, value) {
+					ok = assert.Fail(t, fmt.Sprintf("document %d (%s) field %s: expected %#v, got %#v", i, hit.Index, field, expected, value))
+				}
+			}
+		}
+	}
+	return ok
+}
+
+// docsContain returns whether the field of a hit of docs contains substr.
+func docsContain(docs estools.Documents, field string, substr string) bool {
+	for _, hit := range docs.Hits.Hits {
+		if value, found := docField(hit.Source, field); found && strings.Contains(fmt.Sprint(value), substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// docField returns the value of the dotted field of source, which can be stored either as a single
+// dotted key or as nested objects.
+func docField(source map[string]interface{}, field string) (interface{}, bool) {
+	if value, found := source[field]; found {
+		return value, true
+	}
+	value, err := mapstr.M(source).GetValue(field)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
@@ -0,0 +1,105 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent-libs/testing/estools"
+)
+
+// recordingT records the assertion failures instead of failing the test.
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertDocFields(t *testing.T) {
+	docs := estools.Documents{Hits: estools.Hits{Hits: []estools.ESDoc{
+		{Index: "logs-apm.app.test-default", Source: map[string]interface{}{
+			"message": "This is a test error message",
+			"service": map[string]interface{}{"name": "elastic-otel-test"},
+			"labels":  map[string]interface{}{"host_test-id": "abc"},
+			"count":   float64(1),
+		}},
+		{Index: "logs-apm.app.test-default", Source: map[string]interface{}{
+			"message":      "This is a test debug message 2",
+			"service.name": "elastic-otel-test",
+			"labels":       map[string]interface{}{"host_test-id": "abc"},
+			"count":        float64(1),
+		}},
+	}}}
+
+	testCases := []struct {
+		name         string
+		expectations map[string]interface{}
+		failures     int
+	}{
+		{
+			name: "matching",
+			expectations: map[string]interface{}{
+				"service.name":        "elastic-otel-test",
+				"labels.host_test-id": "abc",
+				"count":               1,
+				"message":             Contains("This is a test"),
+			},
+		},
+		{
+			name: "each substring seen in a hit",
+			expectations: map[string]interface{}{
+				"message": ContainsEach{"error message", "debug message 2"},
+			},
+		},
+		{
+			name: "exact value mismatch",
+			expectations: map[string]interface{}{
+				"service.name": "other",
+			},
+			failures: 2,
+		},
+		{
+			name: "substring not in every hit",
+			expectations: map[string]interface{}{
+				"message": Contains("error"),
+			},
+			failures: 1,
+		},
+		{
+			name: "substring not seen",
+			expectations: map[string]interface{}{
+				"message": ContainsEach{"error message", "debug message 3"},
+			},
+			failures: 1,
+		},
+		{
+			name: "missing field",
+			expectations: map[string]interface{}{
+				"host.name": Contains(""),
+			},
+			failures: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := &recordingT{}
+			ok := AssertDocFields(rt, docs, tc.expectations)
+			assert.Equal(t, tc.failures == 0, ok)
+			assert.Len(t, rt.errors, tc.failures, "failures: %v", rt.errors)
+		})
+	}
+
+	t.Run("no documents", func(t *testing.T) {
+		rt := &recordingT{}
+		assert.False(t, AssertDocFields(rt, estools.Documents{}, map[string]interface{}{"message": Contains("")}))
+		assert.Len(t, rt.errors, 1)
+	})
+}
@@ -0,0 +1,501 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package esutil complements github.com/elastic/elastic-agent-libs/testing/estools with the
+// Elasticsearch helpers used by the Elastic Agent integration tests that estools doesn't provide.
+package esutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/testing/estools"
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+const (
+	// defaultSearchSize is the number of hits returned by a search, same as the elastic-agent-libs estools.
+	defaultSearchSize = 300
+	// pageSize is the number of hits fetched per page by GetAllLogsForIndex.
+	pageSize = 1000
+	// pitKeepAlive is how long the point in time used by GetAllLogsForIndex is kept between pages.
+	pitKeepAlive = "1m"
+)
+
+// SearchOpt is an option of the search helpers.
+type SearchOpt func(*searchOpts)
+
+type searchOpts struct {
+	size int
+}
+
+// WithSize sets the maximum number of hits returned by a search, defaults to 300 as the elastic-agent-libs estools.
+func WithSize(size int) SearchOpt {
+	return func(o *searchOpts) {
+		o.size = size
+	}
+}
+
+func newSearchOpts(opts []SearchOpt) searchOpts {
+	o := searchOpts{size: defaultSearchSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// GetLogsForIndexWithQuery returns the documents of index matching query. Unlike
+// estools.GetLogsForIndexWithContext, which only accepts a single match and returns at most 300
+// hits, query is the raw query clause of the search request, e.g. a bool query combining must,
+// should and must_not clauses or a range on @timestamp, and the number of hits can be set with
+// WithSize.
+func GetLogsForIndexWithQuery(ctx context.Context, client elastictransport.Interface, index string, query map[string]interface{}, opts ...SearchOpt) (estools.Documents, error) {
+	o := newSearchOpts(opts)
+	var docs estools.Documents
+	err := search(ctx, client, index, map[string]interface{}{
+		"query": query,
+		"size":  o.size,
+	}, &docs)
+	if err != nil {
+		return estools.Documents{}, err
+	}
+	return docs, nil
+}
+
+// GetAllLogsForIndex returns the documents of index matching query, see GetLogsForIndexWithQuery.
+// Unlike a single search, which is limited to the first hits, it pages through all the matching
+// documents with search_after over a point in time, so the number of returned hits can be relied on.
+//
+// When maxDocs is greater than zero at most maxDocs documents are returned and hasMore reports
+// whether more documents match query.
+func GetAllLogsForIndex(ctx context.Context, client elastictransport.Interface, index string, query map[string]interface{}, maxDocs int) (docs estools.Documents, hasMore bool, err error) {
+	pitID, err := openPointInTime(ctx, client, index)
+	if err != nil {
+		return estools.Documents{}, false, err
+	}
+	defer func() {
+		// use a new context, the point in time must be closed even when ctx is done
+		if closeErr := closePointInTime(context.WithoutCancel(ctx), client, pitID); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+	}()
+
+	var searchAfter []any
+	for {
+		size := pageSize
+		if maxDocs > 0 {
+			// fetch one more document than needed to know if there are more
+			size = min(size, maxDocs-len(docs.Hits.Hits)+1)
+		}
+		body := map[string]interface{}{
+			"query": query,
+			"size":  size,
+			"pit": map[string]interface{}{
+				"id":         pitID,
+				"keep_alive": pitKeepAlive,
+			},
+			"sort": []any{map[string]any{"_shard_doc": "asc"}},
+		}
+		if searchAfter != nil {
+			body["search_after"] = searchAfter
+		}
+
+		var page searchPage
+		if err := search(ctx, client, "", body, &page); err != nil {
+			return estools.Documents{}, false, err
+		}
+		if page.PitID != "" {
+			pitID = page.PitID
+		}
+		docs.Took += page.Took
+		docs.TimedOut = docs.TimedOut || page.TimedOut
+		docs.Hits.Total = page.Hits.Total
+		for _, hit := range page.Hits.Hits {
+			if maxDocs > 0 && len(docs.Hits.Hits) == maxDocs {
+				return docs, true, nil
+			}
+			docs.Hits.Hits = append(docs.Hits.Hits, hit.ESDoc)
+			searchAfter = hit.Sort
+		}
+		if len(page.Hits.Hits) < size {
+			return docs, false, nil
+		}
+	}
+}
+
+// searchPage is a page of search results including the sort values needed for search_after.
+type searchPage struct {
+	PitID    string `json:"pit_id"`
+	Took     int    `json:"took"`
+	TimedOut bool   `json:"timed_out"`
+	Hits     struct {
+		Total estools.TotalDocCount `json:"total"`
+		Hits  []struct {
+			estools.ESDoc
+			Sort []any `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func openPointInTime(ctx context.Context, client elastictransport.Interface, index string) (string, error) {
+	es := esapi.New(client)
+	res, err := es.OpenPointInTime([]string{index}, pitKeepAlive,
+		es.OpenPointInTime.WithExpandWildcards("all"),
+		es.OpenPointInTime.WithContext(ctx),
+	)
+	if err != nil {
+		return "", fmt.Errorf("error opening point in time for %s: %w", index, err)
+	}
+
+	var pit struct {
+		ID string `json:"id"`
+	}
+	if err := handleResponse(res, &pit); err != nil {
+		return "", fmt.Errorf("error opening point in time for %s: %w", index, err)
+	}
+	return pit.ID, nil
+}
+
+func closePointInTime(ctx context.Context, client elastictransport.Interface, pitID string) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]string{"id": pitID}); err != nil {
+		return fmt.Errorf("error creating close point in time request: %w", err)
+	}
+
+	es := esapi.New(client)
+	res, err := es.ClosePointInTime(
+		es.ClosePointInTime.WithBody(&buf),
+		es.ClosePointInTime.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("error closing point in time: %w", err)
+	}
+	var closed map[string]any
+	if err := handleResponse(res, &closed); err != nil {
+		return fmt.Errorf("error closing point in time: %w", err)
+	}
+	return nil
+}
+
+// WaitForDocCount polls index every interval until at least minCount documents match query and returns
+// the last count. query is the raw query clause, see GetLogsForIndexWithQuery, nil matches all documents.
+//
+// Failing requests are retried until timeout, the error returned on timeout includes the last
+// count and the last request error, if any.
+func WaitForDocCount(ctx context.Context, client elastictransport.Interface, index string, query map[string]interface{}, minCount int, timeout, interval time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var count int
+	var lastErr error
+	for {
+		count, lastErr = CountDocuments(ctx, client, index, query)
+		if lastErr == nil && count >= minCount {
+			return count, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			err := fmt.Errorf("expected at least %d documents in %s, got %d: %w", minCount, index, count, ctx.Err())
+			if lastErr != nil {
+				err = errors.Join(err, lastErr)
+			}
+			return count, err
+		case <-ticker.C:
+		}
+	}
+}
+
+// CountDocuments returns the number of documents of index matching query, nil matches all documents.
+func CountDocuments(ctx context.Context, client elastictransport.Interface, index string, query map[string]interface{}) (int, error) {
+	es := esapi.New(client)
+	opts := []func(*esapi.CountRequest){
+		es.Count.WithIndex(index),
+		es.Count.WithExpandWildcards("all"),
+		es.Count.WithContext(ctx),
+	}
+	if query != nil {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"query": query}); err != nil {
+			return 0, fmt.Errorf("error creating ES query: %w", err)
+		}
+		opts = append(opts, es.Count.WithBody(&buf))
+	}
+	res, err := es.Count(opts...)
+	if err != nil {
+		return 0, fmt.Errorf("error counting documents of %s: %w", index, err)
+	}
+
+	var count struct {
+		Count int `json:"count"`
+	}
+	if err := handleResponse(res, &count); err != nil {
+		return 0, fmt.Errorf("error counting documents of %s: %w", index, err)
+	}
+	return count.Count, nil
+}
+
+// WriterAPIKeyRequest returns the request of an API key that can only write documents to the
+// indices and data streams matching indices, instead of inheriting all the privileges of the user
+// creating it. Like the API keys of the Fleet outputs, it can also monitor the cluster, which the
+// Beats and the elasticsearch exporter need to check the Elasticsearch version. The request can be
+// passed to estools.CreateAPIKey, metadata is attached to the API key as is and can be nil.
+func WriterAPIKeyRequest(name string, expiration string, metadata mapstr.M, indices ...string) estools.APIKeyRequest {
+	return estools.APIKeyRequest{
+		Name:       name,
+		Expiration: expiration,
+		RoleDescriptors: mapstr.M{
+			name + "-writer": mapstr.M{
+				"cluster": []string{"monitor"},
+				"indices": []mapstr.M{
+					{
+						"names":      indices,
+						"privileges": []string{"auto_configure", "create_doc"},
+					},
+				},
+			},
+		},
+		Metadata: metadata,
+	}
+}
+
+// ErrMissingPrivileges is returned by HasPrivileges when the API key lacks some of the requested
+// privileges.
+var ErrMissingPrivileges = errors.New("missing privileges")
+
+// Privileges are the cluster and index privileges checked by HasPrivileges.
+type Privileges struct {
+	Cluster []string          `json:"cluster,omitempty"`
+	Index   []IndexPrivileges `json:"index,omitempty"`
+}
+
+// IndexPrivileges are privileges on the indices and data streams matching Names.
+type IndexPrivileges struct {
+	Names      []string `json:"names"`
+	Privileges []string `json:"privileges"`
+}
+
+// HasPrivileges checks that apiKey has privileges, by calling the _security/user/_has_privileges
+// API authenticated as the API key instead of the user of client. It returns an error wrapping
+// ErrMissingPrivileges and listing every missing privilege, e.g. `logs-apm*:create_doc`, when the
+// API key lacks some of them.
+func HasPrivileges(ctx context.Context, client elastictransport.Interface, apiKey estools.APIKeyResponse, privileges Privileges) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(privileges); err != nil {
+		return fmt.Errorf("error creating privileges request: %w", err)
+	}
+
+	es := esapi.New(client)
+	res, err := es.Security.HasPrivileges(&buf,
+		es.Security.HasPrivileges.WithHeader(map[string]string{"Authorization": "ApiKey " + apiKey.Encoded}),
+		es.Security.HasPrivileges.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("error checking privileges of API key %s: %w", apiKey.Name, err)
+	}
+	var result struct {
+		HasAllRequested bool                       `json:"has_all_requested"`
+		Cluster         map[string]bool            `json:"cluster"`
+		Index           map[string]map[string]bool `json:"index"`
+	}
+	if err := handleResponse(res, &result); err != nil {
+		return fmt.Errorf("error checking privileges of API key %s: %w", apiKey.Name, err)
+	}
+	if result.HasAllRequested {
+		return nil
+	}
+
+	var missing []string
+	for privilege, granted := range result.Cluster {
+		if !granted {
+			missing = append(missing, "cluster:"+privilege)
+		}
+	}
+	for index, indexPrivileges := range result.Index {
+		for privilege, granted := range indexPrivileges {
+			if !granted {
+				missing = append(missing, index+":"+privilege)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("API key %s: %w: %s", apiKey.Name, ErrMissingPrivileges, strings.Join(missing, ", "))
+}
+
+// EnsureIngestPipeline makes sure the ingest pipeline id exists. When it doesn't, it is created with
+// the pipeline definition, e.g. `{"processors": [...]}`, or an error is returned when pipeline is
+// nil, which lets a test check that a pipeline installed by an integration package is present. An
+// existing pipeline is left untouched.
+func EnsureIngestPipeline(ctx context.Context, client elastictransport.Interface, id string, pipeline map[string]any) error {
+	es := esapi.New(client)
+	res, err := es.Ingest.GetPipeline(
+		es.Ingest.GetPipeline.WithPipelineID(id),
+		es.Ingest.GetPipeline.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("error getting ingest pipeline %s: %w", id, err)
+	}
+	if res.StatusCode != http.StatusNotFound {
+		var pipelines map[string]any
+		if err := handleResponse(res, &pipelines); err != nil {
+			return fmt.Errorf("error getting ingest pipeline %s: %w", id, err)
+		}
+		return nil
+	}
+	res.Body.Close()
+	if pipeline == nil {
+		return fmt.Errorf("ingest pipeline %s does not exist", id)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(pipeline); err != nil {
+		return fmt.Errorf("error creating ingest pipeline %s request: %w", id, err)
+	}
+	res, err = es.Ingest.PutPipeline(id, &buf,
+		es.Ingest.PutPipeline.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating ingest pipeline %s: %w", id, err)
+	}
+	var created map[string]any
+	if err := handleResponse(res, &created); err != nil {
+		return fmt.Errorf("error creating ingest pipeline %s: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteDataStream deletes the data streams matching pattern together with their backing indices.
+// It returns no error when no data stream matches pattern, so it can be used in t.Cleanup
+// regardless of whether the test ingested any data.
+func DeleteDataStream(ctx context.Context, client elastictransport.Interface, pattern string) error {
+	es := esapi.New(client)
+	res, err := es.Indices.DeleteDataStream([]string{pattern},
+		es.Indices.DeleteDataStream.WithExpandWildcards("all"),
+		es.Indices.DeleteDataStream.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting data stream %s: %w", pattern, err)
+	}
+	if err := handleDeleteResponse(res); err != nil {
+		return fmt.Errorf("error deleting data stream %s: %w", pattern, err)
+	}
+	return nil
+}
+
+// DeleteIndex deletes the index name. It returns no error when the index does not exist.
+func DeleteIndex(ctx context.Context, client elastictransport.Interface, name string) error {
+	es := esapi.New(client)
+	res, err := es.Indices.Delete([]string{name},
+		es.Indices.Delete.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting index %s: %w", name, err)
+	}
+	if err := handleDeleteResponse(res); err != nil {
+		return fmt.Errorf("error deleting index %s: %w", name, err)
+	}
+	return nil
+}
+
+// ErrIndexNotFound is returned by RefreshIndex when no index matches, e.g. when nothing was
+// ingested yet. It is a signal to retry rather than a failure.
+var ErrIndexNotFound = errors.New("index not found")
+
+// RefreshIndex refreshes the indices and data streams matching index, so the documents indexed
+// so far are searchable right away instead of after the next periodic refresh, letting tests
+// assert on them promptly. It returns an error wrapping ErrIndexNotFound when no index matches,
+// so callers polling for documents can retry.
+func RefreshIndex(ctx context.Context, client elastictransport.Interface, index string) error {
+	es := esapi.New(client)
+	res, err := es.Indices.Refresh(
+		es.Indices.Refresh.WithIndex(index),
+		es.Indices.Refresh.WithExpandWildcards("all"),
+		es.Indices.Refresh.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("error refreshing %s: %w", index, err)
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return fmt.Errorf("error refreshing %s: %w", index, ErrIndexNotFound)
+	}
+	var refreshed struct {
+		Shards struct {
+			Total int `json:"total"`
+		} `json:"_shards"`
+	}
+	if err := handleResponse(res, &refreshed); err != nil {
+		return fmt.Errorf("error refreshing %s: %w", index, err)
+	}
+	// a pattern matching no index is refreshed successfully without any shard
+	if refreshed.Shards.Total == 0 {
+		return fmt.Errorf("error refreshing %s: %w", index, ErrIndexNotFound)
+	}
+	return nil
+}
+
+// handleDeleteResponse handles the response of a delete request, a 404 means there is nothing to delete.
+func handleDeleteResponse(res *esapi.Response) error {
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil
+	}
+	var deleted map[string]any
+	return handleResponse(res, &deleted)
+}
+
+// search performs a search with body and decodes the response into v. Searches over a point in
+// time must not set index.
+func search(ctx context.Context, client elastictransport.Interface, index string, body map[string]interface{}, v any) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return fmt.Errorf("error creating ES query: %w", err)
+	}
+
+	es := esapi.New(client)
+	opts := []func(*esapi.SearchRequest){
+		es.Search.WithBody(&buf),
+		es.Search.WithTrackTotalHits(true),
+		es.Search.WithContext(ctx),
+	}
+	if index != "" {
+		opts = append(opts, es.Search.WithIndex(index), es.Search.WithExpandWildcards("all"))
+	}
+	res, err := es.Search(opts...)
+	if err != nil {
+		return fmt.Errorf("error performing ES search: %w", err)
+	}
+
+	return handleResponse(res, v)
+}
+
+// handleResponse decodes the body of a successful response into v, it closes the response body.
+func handleResponse(res *esapi.Response, v any) error {
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("non-200 return code: %v, response: '%s'", res.StatusCode, res.String())
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/testing/estools"
+)
+
+func TestGetLogsForIndexWithQuery(t *testing.T) {
+	transport := newFakeTransport(okResponse(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"logs-apm-default","_source":{"message":"hello"}}]}}`))
+	query := map[string]any{
+		"bool": map[string]any{
+			"must": []any{
+				map[string]any{"match": map[string]any{"labels.host_test-id": "test"}},
+			},
+			"must_not": []any{
+				map[string]any{"match": map[string]any{"log.level": "debug"}},
+			},
+		},
+	}
+
+	docs, err := GetLogsForIndexWithQuery(t.Context(), transport, "logs-apm*", query)
+	require.NoError(t, err)
+	require.Len(t, docs.Hits.Hits, 1)
+	assert.Equal(t, "hello", docs.Hits.Hits[0].Source["message"])
+
+	require.Len(t, transport.requests, 1)
+	assert.Equal(t, "/logs-apm*/_search", transport.requests[0].URL.Path)
+	expected, err := json.Marshal(map[string]any{"query": query, "size": defaultSearchSize})
+	require.NoError(t, err)
+	actual, err := json.Marshal(transport.bodies[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestGetLogsForIndexWithQuerySize(t *testing.T) {
+	transport := newFakeTransport()
+	query := map[string]any{"match": map[string]any{"labels.host_test-id": "test"}}
+
+	_, err := GetLogsForIndexWithQuery(t.Context(), transport, "logs-apm*", query, WithSize(4))
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, transport.bodies[0]["size"])
+}
+
+func TestGetLogsForIndexWithQueryError(t *testing.T) {
+	transport := newFakeTransport(fakeResponse{status: http.StatusNotFound, body: `{"error":{"type":"index_not_found_exception"}}`})
+
+	_, err := GetLogsForIndexWithQuery(t.Context(), transport, "logs-missing", map[string]any{"match_all": map[string]any{}})
+	require.ErrorContains(t, err, "non-200 return code: 404")
+}
+
+func TestGetAllLogsForIndex(t *testing.T) {
+	hits := func(ids ...int) string {
+		var docs []string
+		for _, id := range ids {
+			docs = append(docs, fmt.Sprintf(`{"_index":"logs","_source":{"id":%d},"sort":[%d]}`, id, id))
+		}
+		return fmt.Sprintf(`{"pit_id":"pit","hits":{"total":{"value":3,"relation":"eq"},"hits":[%s]}}`, strings.Join(docs, ","))
+	}
+
+	t.Run("all documents", func(t *testing.T) {
+		transport := newFakeTransport(
+			okResponse(`{"id":"pit"}`),
+			okResponse(hits(1, 2, 3)),
+			okResponse(`{"succeeded":true}`),
+		)
+
+		docs, hasMore, err := GetAllLogsForIndex(t.Context(), transport, "logs-*", map[string]any{"match_all": map[string]any{}}, 0)
+		require.NoError(t, err)
+		assert.False(t, hasMore)
+		assert.Len(t, docs.Hits.Hits, 3)
+
+		require.Len(t, transport.requests, 3)
+		assert.Equal(t, "/logs-*/_pit", transport.requests[0].URL.Path)
+		assert.Equal(t, "/_search", transport.requests[1].URL.Path)
+		assert.Equal(t, map[string]any{"id": "pit", "keep_alive": pitKeepAlive}, transport.bodies[1]["pit"])
+		assert.Equal(t, http.MethodDelete, transport.requests[2].Method)
+		assert.Equal(t, "/_pit", transport.requests[2].URL.Path)
+	})
+
+	t.Run("max documents", func(t *testing.T) {
+		transport := newFakeTransport(
+			okResponse(`{"id":"pit"}`),
+			okResponse(hits(1, 2, 3)),
+			okResponse(`{"succeeded":true}`),
+		)
+
+		docs, hasMore, err := GetAllLogsForIndex(t.Context(), transport, "logs-*", map[string]any{"match_all": map[string]any{}}, 2)
+		require.NoError(t, err)
+		assert.True(t, hasMore)
+		require.Len(t, docs.Hits.Hits, 2)
+		assert.EqualValues(t, 2, docs.Hits.Hits[1].Source["id"])
+		assert.EqualValues(t, 3, transport.bodies[1]["size"])
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		firstPage := make([]int, pageSize)
+		for i := range firstPage {
+			firstPage[i] = i + 1
+		}
+		transport := newFakeTransport(
+			okResponse(`{"id":"pit"}`),
+			okResponse(hits(firstPage...)),
+			okResponse(hits(pageSize+1)),
+			okResponse(`{"succeeded":true}`),
+		)
+
+		docs, hasMore, err := GetAllLogsForIndex(t.Context(), transport, "logs-*", map[string]any{"match_all": map[string]any{}}, 0)
+		require.NoError(t, err)
+		assert.False(t, hasMore)
+		assert.Len(t, docs.Hits.Hits, pageSize+1)
+		require.Len(t, transport.requests, 4)
+		assert.Nil(t, transport.bodies[1]["search_after"])
+		assert.Equal(t, []any{float64(pageSize)}, transport.bodies[2]["search_after"])
+	})
+}
+
+func TestDeleteDataStream(t *testing.T) {
+	transport := newFakeTransport(
+		okResponse(`{"acknowledged":true}`),
+		fakeResponse{status: http.StatusNotFound, body: `{"error":{"type":"index_not_found_exception"}}`},
+		fakeResponse{status: http.StatusForbidden, body: `{"error":{"type":"security_exception"}}`},
+	)
+
+	require.NoError(t, DeleteDataStream(t.Context(), transport, "logs-apm*"))
+	assert.Equal(t, http.MethodDelete, transport.requests[0].Method)
+	assert.Equal(t, "/_data_stream/logs-apm*", transport.requests[0].URL.Path)
+
+	require.NoError(t, DeleteDataStream(t.Context(), transport, "logs-apm*"), "already deleted data stream must not fail")
+	require.ErrorContains(t, DeleteDataStream(t.Context(), transport, "logs-apm*"), "non-200 return code: 403")
+}
+
+func TestDeleteIndex(t *testing.T) {
+	transport := newFakeTransport(
+		okResponse(`{"acknowledged":true}`),
+		fakeResponse{status: http.StatusNotFound, body: `{"error":{"type":"index_not_found_exception"}}`},
+	)
+
+	require.NoError(t, DeleteIndex(t.Context(), transport, "test-index"))
+	assert.Equal(t, http.MethodDelete, transport.requests[0].Method)
+	assert.Equal(t, "/test-index", transport.requests[0].URL.Path)
+
+	require.NoError(t, DeleteIndex(t.Context(), transport, "test-index"), "already deleted index must not fail")
+}
+
+func TestRefreshIndex(t *testing.T) {
+	transport := newFakeTransport(
+		okResponse(`{"_shards":{"total":2,"successful":1,"failed":0}}`),
+		fakeResponse{status: http.StatusNotFound, body: `{"error":{"type":"index_not_found_exception"}}`},
+		okResponse(`{"_shards":{"total":0,"successful":0,"failed":0}}`),
+		fakeResponse{status: http.StatusForbidden, body: `{"error":{"type":"security_exception"}}`},
+	)
+
+	require.NoError(t, RefreshIndex(t.Context(), transport, "logs-apm*"))
+	assert.Equal(t, http.MethodPost, transport.requests[0].Method)
+	assert.Equal(t, "/logs-apm*/_refresh", transport.requests[0].URL.Path)
+	assert.Equal(t, "all", transport.requests[0].URL.Query().Get("expand_wildcards"))
+
+	require.ErrorIs(t, RefreshIndex(t.Context(), transport, "test-index"), ErrIndexNotFound)
+	require.ErrorIs(t, RefreshIndex(t.Context(), transport, "logs-apm*"), ErrIndexNotFound, "a pattern matching no index must be retriable")
+
+	err := RefreshIndex(t.Context(), transport, "logs-apm*")
+	require.ErrorContains(t, err, "non-200 return code: 403")
+	assert.NotErrorIs(t, err, ErrIndexNotFound)
+}
+
+func TestWriterAPIKeyRequest(t *testing.T) {
+	req := WriterAPIKeyRequest("apm-test", "1d", mapstr.M{"test": "apm"}, "logs-apm*", "traces-apm*")
+
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "apm-test",
+		"expiration": "1d",
+		"role_descriptors": {
+			"apm-test-writer": {
+				"cluster": ["monitor"],
+				"indices": [{"names": ["logs-apm*", "traces-apm*"], "privileges": ["auto_configure", "create_doc"]}]
+			}
+		},
+		"metadata": {"test": "apm"}
+	}`, string(data))
+}
+
+func TestHasPrivileges(t *testing.T) {
+	apiKey := estools.APIKeyResponse{Name: "apm-test", Encoded: "ZW5jb2RlZA=="}
+	privileges := Privileges{
+		Cluster: []string{"monitor"},
+		Index:   []IndexPrivileges{{Names: []string{"logs-apm*"}, Privileges: []string{"auto_configure", "create_doc"}}},
+	}
+	transport := newFakeTransport(
+		okResponse(`{"has_all_requested":true,"cluster":{"monitor":true},"index":{"logs-apm*":{"auto_configure":true,"create_doc":true}}}`),
+		okResponse(`{"has_all_requested":false,"cluster":{"monitor":false},"index":{"logs-apm*":{"auto_configure":true,"create_doc":false}}}`),
+		fakeResponse{status: http.StatusUnauthorized, body: `{"error":{"type":"security_exception"}}`},
+	)
+
+	require.NoError(t, HasPrivileges(t.Context(), transport, apiKey, privileges))
+	assert.Equal(t, "/_security/user/_has_privileges", transport.requests[0].URL.Path)
+	assert.Equal(t, "ApiKey ZW5jb2RlZA==", transport.requests[0].Header.Get("Authorization"))
+	assert.Equal(t, map[string]any{
+		"cluster": []any{"monitor"},
+		"index":   []any{map[string]any{"names": []any{"logs-apm*"}, "privileges": []any{"auto_configure", "create_doc"}}},
+	}, transport.bodies[0])
+
+	err := HasPrivileges(t.Context(), transport, apiKey, privileges)
+	require.ErrorIs(t, err, ErrMissingPrivileges)
+	assert.ErrorContains(t, err, "cluster:monitor, logs-apm*:create_doc")
+
+	err = HasPrivileges(t.Context(), transport, apiKey, privileges)
+	require.ErrorContains(t, err, "non-200 return code: 401")
+	assert.NotErrorIs(t, err, ErrMissingPrivileges)
+}
+
+func TestEnsureIngestPipeline(t *testing.T) {
+	pipeline := map[string]any{"processors": []any{map[string]any{"set": map[string]any{"field": "test", "value": "ok"}}}}
+
+	t.Run("exists", func(t *testing.T) {
+		transport := newFakeTransport(okResponse(`{"test-pipeline":{"processors":[]}}`))
+		require.NoError(t, EnsureIngestPipeline(t.Context(), transport, "test-pipeline", pipeline))
+		require.Len(t, transport.requests, 1)
+		assert.Equal(t, http.MethodGet, transport.requests[0].Method)
+		assert.Equal(t, "/_ingest/pipeline/test-pipeline", transport.requests[0].URL.Path)
+	})
+
+	t.Run("created", func(t *testing.T) {
+		transport := newFakeTransport(
+			fakeResponse{status: http.StatusNotFound, body: `{}`},
+			okResponse(`{"acknowledged":true}`),
+		)
+		require.NoError(t, EnsureIngestPipeline(t.Context(), transport, "test-pipeline", pipeline))
+		require.Len(t, transport.requests, 2)
+		assert.Equal(t, http.MethodPut, transport.requests[1].Method)
+		assert.Equal(t, "/_ingest/pipeline/test-pipeline", transport.requests[1].URL.Path)
+		assert.Equal(t, pipeline, transport.bodies[1])
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		transport := newFakeTransport(fakeResponse{status: http.StatusNotFound, body: `{}`})
+		require.ErrorContains(t, EnsureIngestPipeline(t.Context(), transport, "test-pipeline", nil), "ingest pipeline test-pipeline does not exist")
+		assert.Len(t, transport.requests, 1)
+	})
+}
+
+func TestWaitForDocCount(t *testing.T) {
+	query := map[string]any{"match": map[string]any{"labels.host_test-id": "test"}}
+
+	t.Run("count reached", func(t *testing.T) {
+		transport := newFakeTransport(
+			okResponse(`{"count":0}`),
+			fakeResponse{status: http.StatusServiceUnavailable, body: `{"error":"unavailable"}`},
+			okResponse(`{"count":4}`),
+		)
+
+		count, err := WaitForDocCount(t.Context(), transport, "logs-apm*", query, 4, time.Second, time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, 4, count)
+		require.Len(t, transport.requests, 3)
+		assert.Equal(t, "/logs-apm*/_count", transport.requests[0].URL.Path)
+		assert.Equal(t, map[string]any{"query": query}, transport.bodies[0])
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		transport := newFakeTransport()
+		for range 100 {
+			transport.responses = append(transport.responses, okResponse(`{"count":2}`))
+		}
+
+		count, err := WaitForDocCount(t.Context(), transport, "logs-apm*", query, 4, 50*time.Millisecond, 10*time.Millisecond)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "expected at least 4 documents in logs-apm*, got 2")
+		assert.Equal(t, 2, count)
+	})
+}
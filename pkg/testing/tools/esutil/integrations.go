@@ -0,0 +1,45 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/kibana"
+)
+
+// InstallPackage installs the integration package name at version through the Fleet API of Kibana,
+// together with its index templates and ingest pipelines, so a test can ingest data without
+// depending on the package version installed in the stack. An already installed package is
+// upgraded or downgraded to version. The latest version available to Kibana is installed when
+// version is empty.
+func InstallPackage(ctx context.Context, client *kibana.Client, name string, version string) error {
+	if name == "" {
+		return errors.New("package name is required")
+	}
+	apiPath := "/api/fleet/epm/packages/" + url.PathEscape(name)
+	pkg := name
+	if version != "" {
+		apiPath += "/" + url.PathEscape(version)
+		pkg += " " + version
+	}
+	// force is required to install another version than the latest one
+	resp, err := client.Connection.SendWithContext(ctx, http.MethodPost, apiPath, nil, nil, strings.NewReader(`{"force":true}`))
+	if err != nil {
+		return fmt.Errorf("error installing package %s: %w", pkg, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error installing package %s: non-200 return code: %v, response: '%s'", pkg, resp.StatusCode, string(body))
+	}
+	return nil
+}
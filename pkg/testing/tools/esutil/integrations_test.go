@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/kibana"
+)
+
+func TestInstallPackage(t *testing.T) {
+	var paths, bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		bodies = append(bodies, string(body))
+		if r.URL.Path == "/api/fleet/epm/packages/unknown" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"package not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	}))
+	defer srv.Close()
+	client := &kibana.Client{Connection: kibana.Connection{URL: srv.URL, HTTP: srv.Client()}}
+
+	require.NoError(t, InstallPackage(t.Context(), client, "apm", "9.1.0"))
+	require.NoError(t, InstallPackage(t.Context(), client, "apm", ""))
+	err := InstallPackage(t.Context(), client, "unknown", "")
+	require.ErrorContains(t, err, "error installing package unknown: non-200 return code: 404")
+
+	assert.Equal(t, []string{
+		"POST /api/fleet/epm/packages/apm/9.1.0",
+		"POST /api/fleet/epm/packages/apm",
+		"POST /api/fleet/epm/packages/unknown",
+	}, paths)
+	assert.JSONEq(t, `{"force":true}`, bodies[0])
+
+	require.ErrorContains(t, InstallPackage(t.Context(), client, "", "9.1.0"), "package name is required")
+}
@@ -0,0 +1,102 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
+)
+
+// ingestionLatencyPollInterval is how often MeasureIngestionLatency queries for the marker, the measured
+// latency is accurate to it.
+const ingestionLatencyPollInterval = 100 * time.Millisecond
+
+// MeasureIngestionLatency returns the time elapsed from writing a unique marker with writeFn, e.g. appending
+// a log line carrying it to the file read by the collector, until queryFn finds it, e.g. with MarkerQueryFunc.
+//
+// queryFn is polled until it returns true or ctx is done. Failing queries are retried, the error returned
+// when ctx is done includes the last query error, if any. A writeFn error is returned right away.
+func MeasureIngestionLatency(
+	ctx context.Context,
+	writeFn func(ctx context.Context, marker string) error,
+	queryFn func(ctx context.Context, marker string) (bool, error),
+) (time.Duration, error) {
+	marker, err := newIngestionMarker()
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	if err := writeFn(ctx, marker); err != nil {
+		return 0, fmt.Errorf("error writing marker %s: %w", marker, err)
+	}
+
+	ticker := time.NewTicker(ingestionLatencyPollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		found, err := queryFn(ctx, marker)
+		if err == nil && found {
+			return time.Since(start), nil
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			err := fmt.Errorf("marker %s not found after %s: %w", marker, time.Since(start), ctx.Err())
+			if lastErr != nil {
+				err = errors.Join(err, lastErr)
+			}
+			return 0, err
+		case <-ticker.C:
+		}
+	}
+}
+
+// MarkerQuery returns the query clause matching the documents which message contains marker.
+func MarkerQuery(marker string) map[string]interface{} {
+	return map[string]interface{}{
+		"match_phrase": map[string]interface{}{
+			"message": marker,
+		},
+	}
+}
+
+// MarkerQueryFunc returns a query function for MeasureIngestionLatency which refreshes index, so the
+// documents indexed so far are searchable, and returns true once a document of index matches
+// MarkerQuery. An index which doesn't exist yet is reported as the marker not being found.
+func MarkerQueryFunc(client elastictransport.Interface, index string) func(ctx context.Context, marker string) (bool, error) {
+	return func(ctx context.Context, marker string) (bool, error) {
+		err := RefreshIndex(ctx, client, index)
+		if errors.Is(err, ErrIndexNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		count, err := CountDocuments(ctx, client, index, MarkerQuery(marker))
+		if err != nil {
+			return false, err
+		}
+		return count > 0, nil
+	}
+}
+
+// newIngestionMarker returns a unique marker, made of a single word so it is a single token once
+// analyzed.
+func newIngestionMarker() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating ingestion marker: %w", err)
+	}
+	return "ingestmarker" + hex.EncodeToString(b), nil
+}
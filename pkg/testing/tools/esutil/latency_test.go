@@ -0,0 +1,100 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasureIngestionLatency(t *testing.T) {
+	t.Run("marker found", func(t *testing.T) {
+		var written string
+		queries := 0
+		latency, err := MeasureIngestionLatency(t.Context(),
+			func(_ context.Context, marker string) error {
+				written = marker
+				return nil
+			},
+			func(_ context.Context, marker string) (bool, error) {
+				assert.Equal(t, written, marker)
+				queries++
+				if queries == 1 {
+					return false, errors.New("index not ready")
+				}
+				return queries == 3, nil
+			})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(written, "ingestmarker"), "unexpected marker %s", written)
+		assert.Equal(t, 3, queries)
+		assert.GreaterOrEqual(t, latency, 2*ingestionLatencyPollInterval)
+	})
+
+	t.Run("unique markers", func(t *testing.T) {
+		markers := map[string]bool{}
+		for range 2 {
+			_, err := MeasureIngestionLatency(t.Context(),
+				func(_ context.Context, marker string) error {
+					markers[marker] = true
+					return nil
+				},
+				func(context.Context, string) (bool, error) { return true, nil })
+			require.NoError(t, err)
+		}
+		assert.Len(t, markers, 2)
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		_, err := MeasureIngestionLatency(t.Context(),
+			func(context.Context, string) error { return errors.New("disk full") },
+			func(context.Context, string) (bool, error) {
+				t.Error("the marker must not be queried when it wasn't written")
+				return false, nil
+			})
+		require.ErrorContains(t, err, "disk full")
+	})
+
+	t.Run("marker not found", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), 3*ingestionLatencyPollInterval)
+		defer cancel()
+		_, err := MeasureIngestionLatency(ctx,
+			func(context.Context, string) error { return nil },
+			func(context.Context, string) (bool, error) { return false, errors.New("connection refused") })
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "connection refused")
+	})
+}
+
+func TestMarkerQueryFunc(t *testing.T) {
+	transport := newFakeTransport(
+		fakeResponse{status: http.StatusNotFound, body: `{"error":{"type":"index_not_found_exception"}}`},
+		okResponse(`{"_shards":{"total":2,"successful":2,"failed":0}}`),
+		okResponse(`{"count":0}`),
+		okResponse(`{"_shards":{"total":2,"successful":2,"failed":0}}`),
+		okResponse(`{"count":1}`),
+	)
+	queryFn := MarkerQueryFunc(transport, "logs-apm*")
+
+	found, err := queryFn(t.Context(), "ingestmarker42")
+	require.NoError(t, err)
+	assert.False(t, found, "the index doesn't exist yet")
+
+	found, err = queryFn(t.Context(), "ingestmarker42")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, "/logs-apm*/_refresh", transport.requests[1].URL.Path)
+	assert.Equal(t, "/logs-apm*/_count", transport.requests[2].URL.Path)
+	assert.Equal(t, MarkerQuery("ingestmarker42"), transport.bodies[2]["query"])
+
+	found, err = queryFn(t.Context(), "ingestmarker42")
+	require.NoError(t, err)
+	assert.True(t, found)
+}
@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/testing/estools"
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
+)
+
+// TracesAPM is the data stream pattern APM Server writes the transactions and spans of the traces
+// it receives to, e.g. from the otlp/elastic exporter.
+var TracesAPM = DataStream{Type: "traces", Dataset: "apm*"}
+
+// TraceQuery returns the query clause matching the documents of the trace traceID, the hex encoded
+// trace ID of the spans, as stored in the trace.id field.
+func TraceQuery(traceID string) map[string]interface{} {
+	return map[string]interface{}{
+		"term": map[string]interface{}{
+			"trace.id": traceID,
+		},
+	}
+}
+
+// GetTraceDocuments returns the documents of index, e.g. TracesAPM, of the trace traceID. APM Server
+// indexes the root span of a trace as a transaction and its other spans as spans, they can be told
+// apart with the processor.event field.
+func GetTraceDocuments(ctx context.Context, client elastictransport.Interface, index string, traceID string, opts ...SearchOpt) (estools.Documents, error) {
+	return GetLogsForIndexWithQuery(ctx, client, index, TraceQuery(traceID), opts...)
+}
+
+// WaitForTrace polls index every interval until at least minDocs documents of the trace traceID are
+// indexed, see WaitForDocCount.
+func WaitForTrace(ctx context.Context, client elastictransport.Interface, index string, traceID string, minDocs int, timeout, interval time.Duration) (int, error) {
+	return WaitForDocCount(ctx, client, index, TraceQuery(traceID), minDocs, timeout, interval)
+}
@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTraceDocuments(t *testing.T) {
+	transport := newFakeTransport(okResponse(`{"hits":{"total":{"value":2,"relation":"eq"},"hits":[` +
+		`{"_index":".ds-traces-apm-default","_source":{"trace":{"id":"abc"},"processor":{"event":"transaction"}}},` +
+		`{"_index":".ds-traces-apm-default","_source":{"trace":{"id":"abc"},"processor":{"event":"span"}}}]}}`))
+
+	docs, err := GetTraceDocuments(t.Context(), transport, TracesAPM.String(), "abc")
+	require.NoError(t, err)
+	require.Len(t, docs.Hits.Hits, 2)
+	assert.True(t, AssertDocFields(t, docs, map[string]interface{}{
+		"trace.id":        "abc",
+		"processor.event": ContainsEach{"transaction", "span"},
+	}))
+
+	require.Len(t, transport.requests, 1)
+	assert.Equal(t, "/traces-apm*-*/_search", transport.requests[0].URL.Path)
+	assert.Equal(t, map[string]any{"term": map[string]any{"trace.id": "abc"}}, transport.bodies[0]["query"])
+}
+
+func TestWaitForTrace(t *testing.T) {
+	transport := newFakeTransport(okResponse(`{"count":1}`), okResponse(`{"count":2}`))
+
+	count, err := WaitForTrace(t.Context(), transport, TracesAPM.String(), "abc", 2, time.Second, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, map[string]any{"term": map[string]any{"trace.id": "abc"}}, transport.bodies[1]["query"])
+}
@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package esutil
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// fakeTransport records the requests it receives and answers them with the next response, or with
+// an empty 200 response once all the responses are used.
+type fakeTransport struct {
+	requests  []*http.Request
+	bodies    []map[string]any
+	responses []fakeResponse
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+func newFakeTransport(responses ...fakeResponse) *fakeTransport {
+	return &fakeTransport{responses: responses}
+}
+
+// okResponse returns a 200 response with body.
+func okResponse(body string) fakeResponse {
+	return fakeResponse{status: http.StatusOK, body: body}
+}
+
+func (f *fakeTransport) Perform(req *http.Request) (*http.Response, error) {
+	f.requests = append(f.requests, req)
+	var body map[string]any
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &body); err != nil {
+				return nil, err
+			}
+		}
+	}
+	f.bodies = append(f.bodies, body)
+
+	resp := okResponse(`{}`)
+	if len(f.responses) > 0 {
+		resp, f.responses = f.responses[0], f.responses[1:]
+	}
+	return &http.Response{
+		StatusCode: resp.status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(resp.body)),
+	}, nil
+}
@@ -6,16 +6,45 @@ package testcontext
 
 import (
 	"context"
+	"os"
+	"strconv"
 	"testing"
 	"time"
 )
 
+const (
+	// DeadlineFactorEnv is the environment variable scaling the time remaining
+	// until the deadlines requested to WithDeadline, e.g. "0.5" halves them.
+	DeadlineFactorEnv = "TEST_DEADLINE_FACTOR"
+	// DeadlineMaxEnv is the environment variable capping the time remaining
+	// until the deadlines requested to WithDeadline, e.g. "5m".
+	DeadlineMaxEnv = "TEST_DEADLINE_MAX"
+)
+
+// New returns a context with a deadline calculated from the provided timeout
+// duration, like WithTimeout, that is cancelled by t.Cleanup when the test
+// ends, so callers don't need to defer the cancellation.
+func New(t *testing.T, timeout time.Duration) context.Context {
+	t.Helper()
+	ctx, cancel := WithTimeout(t, context.Background(), timeout)
+	t.Cleanup(cancel)
+	return ctx
+}
+
 // WithDeadline returns a context with a deadline. The deadline is the earliest
 // of either the provided 'deadline' or t.Deadline().
+//
+// The budget of the CI job can shrink the deadline through the environment:
+// the time remaining until 'deadline' is first multiplied by
+// TEST_DEADLINE_FACTOR, then capped to TEST_DEADLINE_MAX. t.Deadline() still
+// wins when it is earlier than the resulting deadline. Invalid values fail the
+// test.
 func WithDeadline(
 	t *testing.T,
 	parent context.Context,
 	deadline time.Time) (context.Context, context.CancelFunc) {
+	t.Helper()
+	deadline = budgetDeadline(t, time.Now(), deadline)
 	if d, ok := t.Deadline(); ok {
 		if d.Before(deadline) {
 			deadline = d
@@ -33,5 +62,28 @@ func WithTimeout(
 	parentCtx context.Context,
 	timeout time.Duration,
 ) (context.Context, context.CancelFunc) {
+	t.Helper()
 	return WithDeadline(t, parentCtx, time.Now().Add(timeout))
 }
+
+// budgetDeadline applies TEST_DEADLINE_FACTOR and TEST_DEADLINE_MAX to the
+// deadline requested at now.
+func budgetDeadline(t *testing.T, now time.Time, deadline time.Time) time.Time {
+	t.Helper()
+	remaining := deadline.Sub(now)
+	if v := os.Getenv(DeadlineFactorEnv); v != "" {
+		factor, err := strconv.ParseFloat(v, 64)
+		if err != nil || factor <= 0 {
+			t.Fatalf("invalid %s %q: must be a positive number", DeadlineFactorEnv, v)
+		}
+		remaining = time.Duration(float64(remaining) * factor)
+	}
+	if v := os.Getenv(DeadlineMaxEnv); v != "" {
+		maxRemaining, err := time.ParseDuration(v)
+		if err != nil || maxRemaining <= 0 {
+			t.Fatalf("invalid %s %q: must be a positive duration", DeadlineMaxEnv, v)
+		}
+		remaining = min(remaining, maxRemaining)
+	}
+	return now.Add(remaining)
+}
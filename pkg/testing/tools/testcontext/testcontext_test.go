@@ -0,0 +1,57 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testcontext
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetDeadline(t *testing.T) {
+	now := time.Now()
+	deadline := now.Add(10 * time.Minute)
+
+	testcases := []struct {
+		name     string
+		factor   string
+		max      string
+		expected time.Duration
+	}{
+		{name: "no budget", expected: 10 * time.Minute},
+		{name: "factor", factor: "0.5", expected: 5 * time.Minute},
+		{name: "max", max: "2m", expected: 2 * time.Minute},
+		{name: "max above requested", max: "1h", expected: 10 * time.Minute},
+		{name: "factor then max", factor: "2", max: "15m", expected: 15 * time.Minute},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(DeadlineFactorEnv, tc.factor)
+			t.Setenv(DeadlineMaxEnv, tc.max)
+			assert.Equal(t, now.Add(tc.expected), budgetDeadline(t, now, deadline))
+		})
+	}
+}
+
+func TestWithDeadlineBudget(t *testing.T) {
+	t.Setenv(DeadlineMaxEnv, "1m")
+	ctx, cancel := WithTimeout(t, t.Context(), time.Hour)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 10*time.Second)
+}
+
+func TestNew(t *testing.T) {
+	var ctx context.Context
+	t.Run("cancelled on cleanup", func(t *testing.T) {
+		ctx = New(t, time.Hour)
+		assert.NoError(t, ctx.Err())
+	})
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
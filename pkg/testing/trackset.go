@@ -0,0 +1,98 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TrackSet tracks which items of a set, e.g. the log lines a test expects to be ingested, have been
+// seen. It is safe for concurrent use.
+type TrackSet struct {
+	mx   sync.Mutex
+	seen map[string]bool
+}
+
+// NewTrackSet returns a TrackSet tracking items, none of them seen yet.
+func NewTrackSet(items ...string) *TrackSet {
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		seen[item] = false
+	}
+	return &TrackSet{seen: seen}
+}
+
+// MarkSeen marks item as seen. It returns false when item is not tracked by the set.
+func (s *TrackSet) MarkSeen(item string) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if _, ok := s.seen[item]; !ok {
+		return false
+	}
+	s.seen[item] = true
+	return true
+}
+
+// MarkSeenIn marks every item that is a substring of text as seen, e.g. the expected lines found in
+// the message of a document, and returns how many items were found.
+func (s *TrackSet) MarkSeenIn(text string) int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	found := 0
+	for item := range s.seen {
+		if strings.Contains(text, item) {
+			s.seen[item] = true
+			found++
+		}
+	}
+	return found
+}
+
+// AllSeen returns true when every item was seen, including when the set is empty.
+func (s *TrackSet) AllSeen() bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for _, seen := range s.seen {
+		if !seen {
+			return false
+		}
+	}
+	return true
+}
+
+// AnySeen returns true when at least one item was seen.
+func (s *TrackSet) AnySeen() bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for _, seen := range s.seen {
+		if seen {
+			return true
+		}
+	}
+	return false
+}
+
+// Remaining returns the sorted items that were not seen yet.
+func (s *TrackSet) Remaining() []string {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	var remaining []string
+	for item, seen := range s.seen {
+		if !seen {
+			remaining = append(remaining, item)
+		}
+	}
+	sort.Strings(remaining)
+	return remaining
+}
+
+// String describes the items that were not seen yet, so a TrackSet can be passed as argument of
+// a failure message formatted when the assertion fails.
+func (s *TrackSet) String() string {
+	return fmt.Sprintf("remaining %q", s.Remaining())
+}
@@ -0,0 +1,36 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package testing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackSet(t *testing.T) {
+	s := NewTrackSet("first line", "second line", "third line")
+	assert.False(t, s.AllSeen())
+	assert.False(t, s.AnySeen())
+	assert.Equal(t, []string{"first line", "second line", "third line"}, s.Remaining())
+
+	assert.True(t, s.MarkSeen("second line"))
+	assert.False(t, s.MarkSeen("unknown line"), "untracked items are not added")
+	assert.True(t, s.AnySeen())
+	assert.False(t, s.AllSeen())
+	assert.Equal(t, []string{"first line", "third line"}, s.Remaining())
+	assert.Equal(t, `remaining ["first line" "third line"]`, s.String())
+
+	assert.Equal(t, 2, s.MarkSeenIn("2024-01-01 ERROR the first line and the third line"))
+	assert.True(t, s.AllSeen())
+	assert.Empty(t, s.Remaining())
+}
+
+func TestTrackSetEmpty(t *testing.T) {
+	s := NewTrackSet()
+	assert.True(t, s.AllSeen())
+	assert.False(t, s.AnySeen())
+	assert.Empty(t, s.Remaining())
+}
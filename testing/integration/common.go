@@ -8,8 +8,6 @@ package integration
 
 import (
 	"errors"
-	"fmt"
-	"net/url"
 	"os"
 	"testing"
 	"time"
@@ -18,21 +16,17 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/elastic-agent-libs/testing/estools"
+	"github.com/elastic/elastic-agent/pkg/testing/tools/esutil"
 )
 
+// GetESHost returns the address of the Elasticsearch cluster given by the test runner in
+// ELASTICSEARCH_HOST, normalized by esutil.NormalizeESHost.
 func GetESHost() (string, error) {
-	fixedESHost := os.Getenv("ELASTICSEARCH_HOST")
-	if len(fixedESHost) == 0 {
+	esHost := os.Getenv("ELASTICSEARCH_HOST")
+	if len(esHost) == 0 {
 		return "", errors.New("ELASTICSEARCH_HOST cannot be empty")
 	}
-	parsedES, err := url.Parse(fixedESHost)
-	if err != nil {
-		return "", err
-	}
-	if parsedES.Port() == "" {
-		fixedESHost = fmt.Sprintf("%s:443", fixedESHost)
-	}
-	return fixedESHost, nil
+	return esutil.NormalizeESHost(esHost)
 }
 
 // FindESDocs runs `findFn` until at least one document is returned and there is no error
@@ -23,6 +23,7 @@ import (
 
 	"github.com/elastic/elastic-agent-libs/testing/estools"
 	"github.com/elastic/elastic-agent-libs/testing/fs"
+	aTesting "github.com/elastic/elastic-agent/pkg/testing"
 	"github.com/elastic/elastic-agent/pkg/testing/define"
 	"github.com/elastic/elastic-agent/pkg/testing/tools/testcontext"
 	"github.com/elastic/elastic-agent/testing/integration"
@@ -37,9 +38,10 @@ import (
 //  6. Ensures all data is ingested and no duplication happens
 func TestFilebeatReceiverLogAsFilestream(t *testing.T) {
 	info := define.Require(t, define.Requirements{
-		Stack: &define.Stack{},
-		Group: integration.Default,
-		Local: true,
+		Stack:     &define.Stack{},
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Windows},
 			{Type: define.Linux},
@@ -117,7 +119,7 @@ func TestFilebeatReceiverLogAsFilestream(t *testing.T) {
 		defer wg.Done()
 		ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(3*time.Minute))
 		defer cancel()
-		require.NoError(t, fixture.RunOtelWithClient(ctx))
+		require.NoError(t, fixture.RunOtelWithOptions(ctx, aTesting.OtelRunOptions{}))
 	}()
 
 	agentLogFile := fs.LogFile{}
@@ -156,7 +158,7 @@ func TestFilebeatReceiverLogAsFilestream(t *testing.T) {
 		defer wg.Done()
 		ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(5*time.Minute))
 		defer cancel()
-		require.NoError(t, fixture.RunOtelWithClient(ctx))
+		require.NoError(t, fixture.RunOtelWithOptions(ctx, aTesting.OtelRunOptions{}))
 	}()
 
 	// Ensure the Filestream input starts
@@ -202,7 +204,7 @@ func TestFilebeatReceiverLogAsFilestream(t *testing.T) {
 		ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(3*
 			time.Minute))
 		defer cancel()
-		require.NoError(t, fixture.RunOtelWithClient(ctx))
+		require.NoError(t, fixture.RunOtelWithOptions(ctx, aTesting.OtelRunOptions{}))
 	}()
 
 	// Start Elastic Agent again to ensure it is correctly tracking the state
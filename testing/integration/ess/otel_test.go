@@ -9,14 +9,19 @@ package ess
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io/fs"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -39,11 +44,17 @@ import (
 	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
 	aTesting "github.com/elastic/elastic-agent/pkg/testing"
 	"github.com/elastic/elastic-agent/pkg/testing/define"
+	"github.com/elastic/elastic-agent/pkg/testing/tools/esutil"
 	"github.com/elastic/elastic-agent/pkg/testing/tools/testcontext"
+	"github.com/elastic/elastic-agent/pkg/version"
 	"github.com/elastic/elastic-agent/testing/integration"
 	"github.com/elastic/go-elasticsearch/v8"
 )
 
+// otelShutdownTimeout is how long the collectors stopped by cancelling their context are given to
+// export their queued data and persist the file offsets before they are killed.
+const otelShutdownTimeout = 20 * time.Second
+
 const apmProcessingContent = `2023-06-19 05:20:50 ERROR This is a test error message
 2023-06-20 12:50:00 DEBUG This is a test debug message 2
 2023-06-20 12:51:00 DEBUG This is a test debug message 3
@@ -60,6 +71,10 @@ const apmOtelConfig = `receivers:
           layout: '%%Y-%%m-%%d %%H:%%M:%%S'
         severity:
           parse_from: attributes.sev
+  otlp:
+    protocols:
+      http:
+        endpoint: 127.0.0.1:4318
 
 processors:
   resource:
@@ -92,12 +107,18 @@ service:
       processors: [resource]
       exporters:
         - debug
+        - otlp/elastic
+    traces:
+      receivers: [otlp]
+      processors: [resource]
+      exporters:
         - otlp/elastic`
 
 func TestOtelStartShutdown(t *testing.T) {
 	define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Linux},
 			{Type: define.Darwin},
@@ -122,8 +143,7 @@ service:
 	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version())
 	require.NoError(t, err)
 
-	ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(10*time.Minute))
-	defer cancel()
+	ctx := testcontext.New(t, 10*time.Minute)
 	err = fixture.Prepare(ctx)
 	require.NoError(t, err)
 
@@ -157,8 +177,9 @@ service:
 
 func TestOtelFileProcessing(t *testing.T) {
 	define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Windows},
 			{Type: define.Linux},
@@ -169,27 +190,13 @@ func TestOtelFileProcessing(t *testing.T) {
 	// replace default elastic-agent.yml with otel config
 	// otel mode should be detected automatically
 	tmpDir := t.TempDir()
-	// create input file
+	// the input file is staged by the fixture before the collector starts
 	numEvents := 50
-	inputFile, err := os.CreateTemp(tmpDir, "input.txt")
-	require.NoError(t, err, "failed to create temp file to hold data to ingest")
-	inputFilePath := inputFile.Name()
+	inputFilePath := filepath.Join(tmpDir, "input.txt")
+	var inputContent strings.Builder
 	for i := 0; i < numEvents; i++ {
-		_, err = inputFile.Write([]byte(fmt.Sprintf("Line %d\n", i)))
-		require.NoErrorf(t, err, "failed to write line %d to temp file", i)
+		fmt.Fprintf(&inputContent, "Line %d\n", i)
 	}
-	err = inputFile.Close()
-	require.NoError(t, err, "failed to close data temp file")
-	t.Cleanup(func() {
-		if t.Failed() {
-			contents, err := os.ReadFile(inputFilePath)
-			if err != nil {
-				t.Logf("no data file to import at %s", inputFilePath)
-				return
-			}
-			t.Logf("contents of import file:\n%s\n", string(contents))
-		}
-	})
 	// create output filename
 	outputFilePath := filepath.Join(tmpDir, "output.txt")
 	t.Cleanup(func() {
@@ -248,7 +255,10 @@ service:
 	})
 	// now we can actually run the test
 
-	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version(), aTesting.WithAdditionalArgs([]string{"--config", otelConfigPath}))
+	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version(),
+		// the config files are only watched for changes with a refresh interval
+		aTesting.WithAdditionalArgs([]string{"--config", otelConfigPath, "--config-refresh-interval", "10s"}),
+		aTesting.WithInputFiles(map[string]string{inputFilePath: inputContent.String()}))
 	require.NoError(t, err)
 
 	ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(10*time.Minute))
@@ -263,11 +273,16 @@ service:
 	fixtureWg.Add(1)
 	go func() {
 		defer fixtureWg.Done()
-		err = fixture.RunOtelWithClient(ctx)
+		err = fixture.RunOtelWithOptions(ctx, aTesting.OtelRunOptions{ShutdownTimeout: otelShutdownTimeout})
 	}()
 
 	validateCommandIsWorking(t, ctx, fixture, tmpDir)
 
+	// the input file is read from the beginning once the receiver watches it
+	startedCtx, startedCancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer startedCancel()
+	require.NoError(t, fixture.WaitForReceiverStarted(startedCtx, "filelog"))
+
 	var content []byte
 	require.Eventually(t,
 		func() bool {
@@ -282,6 +297,27 @@ service:
 		},
 		3*time.Minute, 500*time.Millisecond,
 		fmt.Sprintf("there should be exported logs by now"))
+
+	// reconfigure the running collector to export to another file
+	reloadedOutputFilePath := filepath.Join(tmpDir, "output-reloaded.txt")
+	otelConfigBuffer.Reset()
+	require.NoError(t,
+		template.Must(template.New("otelConfig").Parse(otelConfigTemplate)).Execute(&otelConfigBuffer,
+			otelConfigOptions{
+				InputPath:  inputFilePath,
+				OutputPath: reloadedOutputFilePath,
+			}))
+	require.NoError(t, fixture.UpdateOtelConfig(ctx, otelConfigBuffer.Bytes()))
+	require.Eventually(t,
+		func() bool {
+			content, err := os.ReadFile(reloadedOutputFilePath)
+			if err != nil || len(content) == 0 {
+				return false
+			}
+			return bytes.Count(content, []byte(filepath.Base(inputFilePath))) == numEvents
+		},
+		3*time.Minute, 500*time.Millisecond,
+		"there should be exported logs after the configuration reload")
 	cancel()
 	fixtureWg.Wait()
 	require.True(t, err == nil || err == context.Canceled || err == context.DeadlineExceeded, "Retrieved unexpected error: %s", err.Error())
@@ -289,8 +325,9 @@ service:
 
 func TestOtelHybridFileProcessing(t *testing.T) {
 	define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			// input path missing on windows
 			{Type: define.Linux},
@@ -414,13 +451,193 @@ service:
 		otelCollectorStatus := status.Collector
 		require.NotNil(collect, otelCollectorStatus)
 		assert.Equal(collect, int(cproto.CollectorComponentStatus_StatusOK), otelCollectorStatus.Status)
-		return
+
+		// the otel section reports the health of the logs pipeline and of its components
+		require.NotNil(collect, status.Otel)
+		assert.True(collect, status.Otel.Healthy, "collector should be healthy: %+v", status.Otel)
+		logsIdx := slices.IndexFunc(status.Otel.Pipelines, func(p client.OtelPipelineStatus) bool { return p.Name == "logs" })
+		require.NotEqual(collect, -1, logsIdx, "logs pipeline should be reported, got %+v", status.Otel.Pipelines)
+		logsPipeline := status.Otel.Pipelines[logsIdx]
+		assert.True(collect, logsPipeline.Healthy, "logs pipeline should be healthy, error: %s", logsPipeline.Error)
+		assert.Equal(collect, []client.OtelComponentStatus{
+			{ID: "exporter:file", Kind: "exporter", Status: "StatusOK", Healthy: true},
+			{ID: "receiver:filelog", Kind: "receiver", Status: "StatusOK", Healthy: true},
+		}, logsPipeline.Components)
 	}, 1*time.Minute, 1*time.Second)
 
+	pipelines, pipelinesErr := fixture.ListOtelPipelines(ctx)
+	require.NoError(t, pipelinesErr)
+	logsIdx := slices.IndexFunc(pipelines, func(p client.PipelineStatus) bool { return p.Name == "logs" })
+	require.NotEqual(t, -1, logsIdx, "logs pipeline should exist, got %v", pipelines)
+	logsPipeline := pipelines[logsIdx]
+	assert.Equal(t, "logs", logsPipeline.Signal)
+	assert.Equal(t, []string{"exporter:file", "receiver:filelog"}, logsPipeline.ComponentIDs)
+	assert.True(t, logsPipeline.Healthy(), "logs pipeline should be running, error: %s", logsPipeline.Error)
+
 	cancel()
 	fixtureWg.Wait()
 }
 
+func TestOtelSelfMetrics(t *testing.T) {
+	define.Require(t, define.Requirements{
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
+		OS: []define.OS{
+			{Type: define.Linux},
+			{Type: define.Darwin},
+		},
+	})
+
+	tmpDir := t.TempDir()
+	outputFilePath := filepath.Join(tmpDir, "output.txt")
+	t.Cleanup(func() {
+		if t.Failed() {
+			contents, err := os.ReadFile(outputFilePath)
+			if err != nil {
+				t.Logf("no output data at %s", outputFilePath)
+				return
+			}
+			t.Logf("contents of output file:\n%s\n", string(contents))
+		}
+	})
+	// the agent scrapes the collector's own metrics into the user metrics pipeline
+	otelConfigTemplate := `agent.collector.self_metrics:
+  enabled: true
+  interval: 1s
+
+receivers:
+  nop:
+
+exporters:
+  file:
+    path: {{.OutputPath}}
+service:
+  pipelines:
+    metrics:
+      receivers:
+        - nop
+      exporters:
+        - file
+`
+	var otelConfigBuffer bytes.Buffer
+	require.NoError(t,
+		template.Must(template.New("otelConfig").Parse(otelConfigTemplate)).Execute(&otelConfigBuffer,
+			struct{ OutputPath string }{OutputPath: outputFilePath}))
+
+	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version())
+	require.NoError(t, err)
+
+	ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(10*time.Minute))
+	defer cancel()
+	err = fixture.Prepare(ctx, fakeComponent)
+	require.NoError(t, err)
+
+	var fixtureWg sync.WaitGroup
+	fixtureWg.Add(1)
+	go func() {
+		defer fixtureWg.Done()
+		err = fixture.Run(ctx, aTesting.State{
+			Configure: otelConfigBuffer.String(),
+			Reached: func(state *client.AgentState) bool {
+				// keep running (context cancel will stop it)
+				return false
+			},
+		})
+	}()
+
+	// the self metrics receiver accepts and the file exporter sends the scraped metrics, their
+	// throughput is then part of the exported metrics
+	require.Eventually(t,
+		func() bool {
+			content, err := os.ReadFile(outputFilePath)
+			if err != nil {
+				return false
+			}
+			return bytes.Contains(content, []byte("otelcol_exporter_sent_metric_points")) &&
+				bytes.Contains(content, []byte("otelcol_receiver_accepted_metric_points"))
+		},
+		3*time.Minute, 500*time.Millisecond,
+		"the collector self metrics should be exported by now")
+
+	cancel()
+	fixtureWg.Wait()
+}
+
+func TestOtelInstalledAsService(t *testing.T) {
+	define.Require(t, define.Requirements{
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     false,
+		Sudo:      true,
+		OS: []define.OS{
+			{Type: define.Linux},
+		},
+	})
+
+	tmpDir := t.TempDir()
+	numEvents := 50
+	inputFilePath := filepath.Join(tmpDir, "input.txt")
+	var input strings.Builder
+	for i := 0; i < numEvents; i++ {
+		fmt.Fprintf(&input, "Line %d\n", i)
+	}
+	require.NoError(t, os.WriteFile(inputFilePath, []byte(input.String()), 0o644))
+	outputFilePath := filepath.Join(tmpDir, "output.txt")
+
+	otelConfig := fmt.Sprintf(`receivers:
+  filelog:
+    include:
+      - %s
+    start_at: beginning
+exporters:
+  file:
+    path: %s
+service:
+  pipelines:
+    logs:
+      receivers:
+        - filelog
+      exporters:
+        - file
+`, inputFilePath, outputFilePath)
+
+	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version())
+	require.NoError(t, err)
+
+	ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(10*time.Minute))
+	defer cancel()
+	require.NoError(t, fixture.Prepare(ctx))
+
+	// the collector configuration is detected in elastic-agent.yml by the service
+	out, err := fixture.InstallAsService(ctx, &aTesting.ServiceInstallOpts{
+		InstallOpts: aTesting.InstallOpts{Privileged: true},
+		OtelConfig:  []byte(otelConfig),
+	})
+	require.NoError(t, err, "failed to install the Elastic Agent as a service running the collector: %s", out)
+
+	require.NoError(t, fixture.WaitForFileContains(ctx, outputFilePath, []string{fmt.Sprintf("Line %d", numEvents-1)}, aTesting.MatchAll, 3*time.Minute))
+
+	// uninstalling the service stops the collector
+	out, err = fixture.Uninstall(ctx, &aTesting.UninstallOpts{Force: true})
+	require.NoError(t, err, "failed to uninstall the Elastic Agent: %s", out)
+}
+
+// waitForAgentHealthy waits for the agent started by fixture to become healthy within healthyTimeout.
+// The agent is up once its control socket accepts connections, so only its health is polled afterwards.
+func waitForAgentHealthy(t *testing.T, ctx context.Context, fixture *aTesting.Fixture, healthyTimeout time.Duration) {
+	t.Helper()
+	require.NoError(t, fixture.WaitForControlSocket(ctx, 30*time.Second))
+	require.Eventually(t, func() bool {
+		err := fixture.IsHealthy(ctx)
+		if err != nil {
+			t.Logf("waiting for agent healthy: %s", err.Error())
+			return false
+		}
+		return true
+	}, healthyTimeout, 1*time.Second)
+}
+
 func validateCommandIsWorking(t *testing.T, ctx context.Context, fixture *aTesting.Fixture, tempDir string) {
 	fileProcessingConfig := []byte(`receivers:
   filelog:
@@ -437,18 +654,26 @@ service:
       exporters:
         - file
 `)
+	t.Cleanup(func() {
+		// the file exporter path and, when the collector runs it under the Elastic Agent, the files it is rotated
+		// to, named after it with a timestamp
+		rotated, _ := filepath.Glob("/tmp/testfileprocessing-*.json")
+		for _, path := range append(rotated, "/tmp/testfileprocessing.json") {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				t.Logf("failed to remove file exporter output %s: %v", path, err)
+			}
+		}
+	})
 	cfgFilePath := filepath.Join(tempDir, "otel-valid.yml")
 	require.NoError(t, os.WriteFile(cfgFilePath, []byte(fileProcessingConfig), 0o600))
 
 	// check `elastic-agent otel validate` command works for otel config
-	cmd, err := fixture.PrepareAgentCommand(ctx, []string{"otel", "validate", "--config", cfgFilePath})
-	require.NoError(t, err)
-
-	err = cmd.Run()
+	result, err := fixture.OtelValidate(ctx, "--config", cfgFilePath)
 	require.NoError(t, err)
+	require.True(t, result.Valid, "unexpected validation errors: %v", result.Errors)
 
 	// check feature gate works
-	out, err := fixture.Exec(ctx, []string{"otel", "validate", "--config", cfgFilePath, "--feature-gates", "foo.bar"})
+	out, err := fixture.ExecOtel(ctx, "validate", "--config", cfgFilePath, "--feature-gates", "foo.bar")
 	require.Error(t, err)
 	require.Contains(t, string(out), `no such feature gate "foo.bar"`)
 
@@ -472,10 +697,18 @@ service:
 `)
 	require.NoError(t, os.WriteFile(cfgFilePath, []byte(fileInvalidOtelConfig), 0o600))
 
-	out, err = fixture.Exec(ctx, []string{"otel", "validate", "--config", cfgFilePath})
+	out, err = fixture.ExecOtel(ctx, "validate", "--config", cfgFilePath)
 	require.Error(t, err)
 	require.False(t, len(out) == 0)
 	require.Contains(t, string(out), `service::pipelines::logs: references processor "nonexistingprocessor" which is not configured`)
+
+	// check the machine-readable output reports the invalid key
+	result, err = fixture.OtelValidate(ctx, "--config", cfgFilePath)
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.Len(t, result.Errors, 1)
+	require.Equal(t, "service::pipelines::logs", result.Errors[0].Path)
+	require.Equal(t, "invalid", result.Errors[0].Kind)
 }
 
 var logsIngestionConfigTemplate = `
@@ -539,8 +772,9 @@ service:
 
 func TestOtelLogsIngestion(t *testing.T) {
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Windows},
 			{Type: define.Linux},
@@ -564,7 +798,8 @@ func TestOtelLogsIngestion(t *testing.T) {
 
 	esClient := info.ESClient
 	require.NotNil(t, esClient)
-	esApiKey := createESApiKey(t, esClient)
+	// the logs are written to the testId index
+	esApiKey := createESApiKey(t, esClient, testId)
 
 	logsIngestionConfig := logsIngestionConfigTemplate
 	logsIngestionConfig = strings.ReplaceAll(logsIngestionConfig, "{{.ESApiKey}}", esApiKey.Encoded)
@@ -573,10 +808,7 @@ func TestOtelLogsIngestion(t *testing.T) {
 	logsIngestionConfig = strings.ReplaceAll(logsIngestionConfig, "{{.TestId}}", testId)
 	logsIngestionConfig = strings.ReplaceAll(logsIngestionConfig, "{{.OTelLogFile}}", otelLogFilePath)
 
-	cfgFilePath := filepath.Join(tempDir, "otel.yml")
-	require.NoError(t, os.WriteFile(cfgFilePath, []byte(logsIngestionConfig), 0o600))
-
-	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version(), aTesting.WithAdditionalArgs([]string{"--config", cfgFilePath}))
+	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version())
 	require.NoError(t, err)
 
 	ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(10*time.Minute))
@@ -595,7 +827,7 @@ func TestOtelLogsIngestion(t *testing.T) {
 	fixtureWg.Add(1)
 	go func() {
 		defer fixtureWg.Done()
-		err = fixture.RunOtelWithClient(ctx)
+		err = fixture.RunOtelWithConfig(ctx, []byte(logsIngestionConfig), aTesting.OtelRunOptions{ShutdownTimeout: otelShutdownTimeout})
 	}()
 
 	// Write logs to input file.
@@ -639,38 +871,43 @@ func TestOtelLogsIngestion(t *testing.T) {
 
 func TestOtelAPMIngestion(t *testing.T) {
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Stack: &define.Stack{},
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Stack:     &define.Stack{},
+		// the APM integration matching apm-server is installed by the test, so the stack can be
+		// older than the agent
+		RequiredComponents: []string{"apm-server"},
+		Local:              true,
 		OS: []define.OS{
 			// apm server not supported on darwin
 			{Type: define.Linux},
 		},
 	})
 
-	const apmVersionMismatch = "The APM integration must be upgraded"
 	const apmReadyLog = "all precondition checks are now satisfied"
 	logWatcher := aTesting.NewLogWatcher(t,
-		apmVersionMismatch, // apm version mismatch
-		apmReadyLog,        // apm ready
+		apmReadyLog, // apm ready
 	)
 
 	// prepare agent
 	testId := info.Namespace
 	tempDir := t.TempDir()
 	cfgFilePath := filepath.Join(tempDir, "otel.yml")
-	fileName := "content.log"
-	apmConfig := fmt.Sprintf(apmOtelConfig, filepath.Join(tempDir, fileName), testId)
+	inputFilePath := filepath.Join(tempDir, "content.log")
+	apmConfig := fmt.Sprintf(apmOtelConfig, inputFilePath, testId)
 	require.NoError(t, os.WriteFile(cfgFilePath, []byte(apmConfig), 0o600))
-	require.NoError(t, os.WriteFile(filepath.Join(tempDir, fileName), []byte{}, 0o600))
 
-	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version(), aTesting.WithAdditionalArgs([]string{"--config", cfgFilePath}))
+	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version(),
+		aTesting.WithAdditionalArgs([]string{"--config", cfgFilePath}),
+		aTesting.WithInputFiles(map[string]string{inputFilePath: apmProcessingContent}),
+		aTesting.WithKeepWorkDirOnFailure())
 	require.NoError(t, err)
 
 	ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(10*time.Minute))
 	defer cancel()
 	err = fixture.Prepare(ctx, fakeComponent)
 	require.NoError(t, err)
+	fixture.CollectDiagnosticsOnFailure()
 
 	// prepare input
 	agentWorkDir := fixture.WorkDir()
@@ -678,7 +915,7 @@ func TestOtelAPMIngestion(t *testing.T) {
 	err = fixture.EnsurePrepared(ctx)
 	require.NoError(t, err)
 
-	componentsDir, err := aTesting.FindComponentsDir(agentWorkDir, "")
+	apmPath, err := aTesting.FindComponentBinary(agentWorkDir, "apm-server")
 	require.NoError(t, err)
 
 	// start apm default config just configure ES output
@@ -688,6 +925,16 @@ func TestOtelAPMIngestion(t *testing.T) {
 
 	esClient := info.ESClient
 	esApiKey := createESApiKey(t, esClient)
+	// apm-server writes to the logs-apm.* data streams of the default namespace
+	apmLogs := esutil.DataStream{Type: "logs", Dataset: "apm*"}
+	require.NoError(t, esutil.HasPrivileges(ctx, esClient, esApiKey, esutil.Privileges{
+		Index: []esutil.IndexPrivileges{{Names: []string{apmLogs.String(), esutil.TracesAPM.String()}, Privileges: []string{"auto_configure", "create_doc"}}},
+	}), "apm-server needs to write to %s and %s", apmLogs, esutil.TracesAPM)
+	// apm-server refuses to ingest when the installed APM integration is older than itself, install
+	// the version of the integration released with it
+	agentVersion, err := version.ParseVersion(define.Version())
+	require.NoError(t, err)
+	require.NoError(t, esutil.InstallPackage(ctx, info.KibanaClient, "apm", agentVersion.CoreVersion()), "failed to install the APM integration")
 
 	apmArgs := []string{
 		"run",
@@ -698,109 +945,194 @@ func TestOtelAPMIngestion(t *testing.T) {
 		"-E", "apm-server.ssl.enabled=false",
 	}
 
-	apmPath := filepath.Join(componentsDir, "apm-server")
 	var apmFixtureWg sync.WaitGroup
 	apmFixtureWg.Add(1)
 	apmContext, apmCancel := context.WithCancel(ctx)
 	defer apmCancel()
+	apmExited := make(chan struct{})
 	go func() {
-		aTesting.RunProcess(t,
+		defer apmFixtureWg.Done()
+		defer close(apmExited)
+		result, err := aTesting.RunProcessWithOptions(t,
 			logWatcher,
-			apmContext, 0,
-			true, true,
+			apmContext, aTesting.RunProcessOptions{
+				LogOutput: true,
+				AllowErrs: true,
+				// let apm-server flush its events to elasticsearch
+				GracePeriod: 30 * time.Second,
+			},
 			apmPath, apmArgs...)
-		apmFixtureWg.Done()
+		if err != nil && apmContext.Err() == nil {
+			t.Errorf("apm-server exited after %s with exit code %d: %v\nstderr: %s", result.Duration, result.ExitCode, err, result.Stderr)
+		}
 	}()
 
-	// start agent
-	var fixtureWg sync.WaitGroup
-	fixtureWg.Add(1)
+	// wait for apm to start, stop waiting when apm-server exits before it is ready
+	apmReadyCtx, apmReadyCancel := context.WithCancel(ctx)
 	go func() {
-		fixture.RunOtelWithClient(ctx)
-		fixtureWg.Done()
+		select {
+		case <-apmExited:
+			apmReadyCancel()
+		case <-apmReadyCtx.Done():
+		}
 	}()
-
-	// wait for apm to start
-	err = logWatcher.WaitForKeys(context.Background(),
+	err = logWatcher.WaitForAllKeys(apmReadyCtx,
 		10*time.Minute,
 		500*time.Millisecond,
 		apmReadyLog,
 	)
+	apmReadyCancel()
 	require.NoError(t, err, "APM not initialized")
 
-	require.NoError(t, os.WriteFile(filepath.Join(tempDir, fileName), []byte(apmProcessingContent), 0o600))
+	// start agent, the input file is staged before the collector starts so the filelog receiver
+	// reads it from the beginning
+	fixtureErrCh := make(chan error, 1)
+	fixture.RunOtelWithClientAsync(ctx, aTesting.OtelRunOptions{}, fixtureErrCh)
 
 	// check index
-	var hits int
 	match := map[string]interface{}{
 		"labels.host_test-id": testId,
 	}
 
-	// apm mismatch or proper docs in ES
-
-	watchLines := linesTrackMap([]string{
-		"This is a test error message",
-		"This is a test debug message 2",
-		"This is a test debug message 3",
-		"This is a test debug message 4",
-	})
-
-	// failed to get APM version mismatch in time
 	// processing should be running
-	var apmVersionMismatchEncountered bool
-	require.Eventually(t,
-		func() bool {
-			if logWatcher.KeyOccured(apmVersionMismatch) {
-				// mark skipped to make it explicit it was not successfully evaluated
-				apmVersionMismatchEncountered = true
-				return true
+	var fixtureExited bool
+	var fixtureErr error
+	require.EventuallyWithT(t,
+		func(c *assert.CollectT) {
+			select {
+			case fixtureErr = <-fixtureErrCh:
+				// collector exited, stop waiting and report why
+				fixtureExited = true
+				return
+			default:
 			}
 
 			findCtx, findCancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer findCancel()
-			docs, err := estools.GetLogsForIndexWithContext(findCtx, esClient, "logs-apm*", match)
-			if err != nil {
-				return false
-			}
-
-			hits = len(docs.Hits.Hits)
-			if hits <= 0 {
-				return false
+			// make the logs indexed so far searchable, the data stream is created with the first log
+			err := esutil.RefreshIndex(findCtx, esClient, apmLogs.String())
+			if errors.Is(err, esutil.ErrIndexNotFound) {
+				c.Errorf("no apm logs indexed yet: %v", err)
+				return
 			}
+			require.NoError(c, err)
+			docs, err := estools.GetLogsForIndexWithContext(findCtx, esClient, apmLogs.String(), match)
+			require.NoError(c, err)
 
-			for _, hit := range docs.Hits.Hits {
-				s, found := hit.Source["message"]
-				if !found {
-					continue
-				}
-
-				for k := range watchLines {
-					if strings.Contains(fmt.Sprint(s), k) {
-						watchLines[k] = true
-					}
-				}
+			esutil.AssertDocFields(c, docs, map[string]interface{}{
+				// set by the resource processor
+				"service.name": "elastic-otel-test",
+				"message": esutil.ContainsEach{
+					"This is a test error message",
+					"This is a test debug message 2",
+					"This is a test debug message 3",
+					"This is a test debug message 4",
+				},
+			})
+		},
+		2*time.Minute, 500*time.Millisecond,
+		"there should be apm logs by now")
+	require.False(t, fixtureExited, "collector exited before apm logs were ingested: %v", fixtureErr)
+
+	// measure how long a log line takes from being appended to the input file until it is searchable,
+	// the input doesn't end with a new line and the last line was already flushed by the filelog receiver
+	latencyCtx, latencyCancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer latencyCancel()
+	latency, err := esutil.MeasureIngestionLatency(latencyCtx,
+		func(_ context.Context, marker string) error {
+			f, err := os.OpenFile(inputFilePath, os.O_APPEND|os.O_WRONLY, 0o600)
+			if err != nil {
+				return err
 			}
-			return mapAllTrue(watchLines)
+			_, err = fmt.Fprintf(f, "%s INFO Ingestion latency marker %s\n", time.Now().UTC().Format(time.DateTime), marker)
+			return errors.Join(err, f.Close())
 		},
-		5*time.Minute, 500*time.Millisecond,
-		fmt.Sprintf("there should be apm logs by now: %#v", watchLines))
-
-	if apmVersionMismatchEncountered {
-		t.Skip("agent version needs to be equal to stack version")
-	}
+		esutil.MarkerQueryFunc(esClient, apmLogs.String()))
+	require.NoError(t, err, "the marker log line should be ingested")
+	t.Logf("ingestion latency of the logs pipeline: %s", latency)
+
+	// the traces pipeline exports the spans received over OTLP to apm-server, which indexes the
+	// root span as a transaction and the child span as a span
+	traceID := sendOTLPTrace(t, "http://127.0.0.1:4318/v1/traces", "test-transaction", "test-span")
+	_, err = esutil.WaitForTrace(ctx, esClient, esutil.TracesAPM.String(), traceID, 2, 5*time.Minute, time.Second)
+	require.NoError(t, err, "there should be apm traces by now")
+	traceDocs, err := esutil.GetTraceDocuments(ctx, esClient, esutil.TracesAPM.String(), traceID)
+	require.NoError(t, err)
+	esutil.AssertDocFields(t, traceDocs, map[string]interface{}{
+		// set by the resource processor
+		"service.name":     "elastic-otel-test",
+		"trace.id":         traceID,
+		"processor.event":  esutil.ContainsEach{"transaction", "span"},
+		"transaction.name": esutil.ContainsEach{"test-transaction"},
+		"span.name":        esutil.ContainsEach{"test-span"},
+	})
 
 	// cleanup apm
 	cancel()
 	apmCancel()
-	fixtureWg.Wait()
+	<-fixtureErrCh
 	apmFixtureWg.Wait()
 }
 
-func createESApiKey(t *testing.T, esClient *elasticsearch.Client) estools.APIKeyResponse {
+// sendOTLPTrace sends a trace made of a server span named rootName and of its child span named
+// childName, in the OTLP/HTTP JSON encoding, to the OTLP receiver endpoint and returns its trace ID.
+func sendOTLPTrace(t *testing.T, endpoint string, rootName string, childName string) string {
+	t.Helper()
+	randomID := func(n int) string {
+		id := make([]byte, n)
+		_, err := rand.Read(id)
+		require.NoError(t, err)
+		return hex.EncodeToString(id)
+	}
+	traceID, rootID, childID := randomID(16), randomID(8), randomID(8)
+	start := time.Now()
+	span := func(id, parentID, name string, kind int, startTime, endTime time.Time) map[string]any {
+		return map[string]any{
+			"traceId":           traceID,
+			"spanId":            id,
+			"parentSpanId":      parentID,
+			"name":              name,
+			"kind":              kind,
+			"startTimeUnixNano": fmt.Sprint(startTime.UnixNano()),
+			"endTimeUnixNano":   fmt.Sprint(endTime.UnixNano()),
+		}
+	}
+	body, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "elastic-agent-integration-test"},
+				"spans": []any{
+					// SPAN_KIND_SERVER
+					span(rootID, "", rootName, 2, start, start.Add(100*time.Millisecond)),
+					// SPAN_KIND_INTERNAL
+					span(childID, rootID, childName, 1, start.Add(10*time.Millisecond), start.Add(50*time.Millisecond)),
+				},
+			}},
+		}},
+	})
+	require.NoError(t, err)
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, endpoint, bytes.NewReader(body))
+		require.NoError(c, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(c, err)
+		defer resp.Body.Close()
+		assert.Equal(c, http.StatusOK, resp.StatusCode)
+	}, time.Minute, time.Second, "the OTLP receiver should accept the trace")
+	return traceID
+}
+
+// testDataIndices are the index patterns of the data streams the tests write to.
+var testDataIndices = []string{"logs-*", "metrics-*", "traces-*"}
+
+// createESApiKey creates an API key that can only write to testDataIndices and to indices.
+func createESApiKey(t *testing.T, esClient *elasticsearch.Client, indices ...string) estools.APIKeyResponse {
 	esApiKey, err := estools.CreateAPIKey(
 		t.Context(),
 		esClient,
-		estools.APIKeyRequest{Name: "test-api-key", Expiration: "1d"},
+		esutil.WriterAPIKeyRequest("test-api-key", "1d", nil, append(slices.Clone(testDataIndices), indices...)...),
 	)
 
 	require.NoError(t, err, "error creating API key")
@@ -822,38 +1154,11 @@ func getDecodedApiKey(keyResponse estools.APIKeyResponse) (string, error) {
 	return string(decoded), nil
 }
 
-func linesTrackMap(lines []string) map[string]bool {
-	mm := make(map[string]bool)
-	for _, l := range lines {
-		mm[l] = false
-	}
-	return mm
-}
-
-func mapAllTrue(mm map[string]bool) bool {
-	for _, v := range mm {
-		if !v {
-			return false
-		}
-	}
-
-	return true
-}
-
-func mapAtLeastOneTrue(mm map[string]bool) bool {
-	for _, v := range mm {
-		if v {
-			return true
-		}
-	}
-
-	return false
-}
-
 func TestOtelFilestreamInput(t *testing.T) {
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Windows},
 			{Type: define.Linux},
@@ -1017,8 +1322,9 @@ agent.internal.runtime.filebeat.filestream: otel
 
 func TestOTelHTTPMetricsInput(t *testing.T) {
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Windows},
 			{Type: define.Linux},
@@ -1102,14 +1408,7 @@ agent.monitoring:
 		}
 	})
 
-	require.Eventually(t, func() bool {
-		err = fixture.IsHealthy(ctx)
-		if err != nil {
-			t.Logf("waiting for agent healthy: %s", err.Error())
-			return false
-		}
-		return true
-	}, 30*time.Second, 1*time.Second)
+	waitForAgentHealthy(t, ctx, fixture, 30*time.Second)
 
 	// Make sure find the logs
 	actualHits := &struct{ Hits int }{}
@@ -1145,8 +1444,9 @@ func TestHybridAgentE2E(t *testing.T) {
 	// filebeat and fbreceiver. It then compares the final documents in
 	// Elasticsearch to ensure they have no meaningful differences.
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Windows},
 			{Type: define.Linux},
@@ -1321,14 +1621,7 @@ service:
 		}
 	})
 
-	require.Eventually(t, func() bool {
-		err = fixture.IsHealthy(ctx)
-		if err != nil {
-			t.Logf("waiting for agent healthy: %s", err.Error())
-			return false
-		}
-		return true
-	}, 1*time.Minute, 1*time.Second)
+	waitForAgentHealthy(t, ctx, fixture, 1*time.Minute)
 
 	var docs estools.Documents
 	actualHits := &struct {
@@ -1379,8 +1672,9 @@ service:
 
 func TestHybridAgentGlobalProcessors(t *testing.T) {
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Windows},
 			{Type: define.Linux},
@@ -1490,8 +1784,7 @@ processors:
 	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version())
 	require.NoError(t, err)
 
-	ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(5*time.Minute))
-	defer cancel()
+	ctx := testcontext.New(t, 5*time.Minute)
 
 	err = fixture.Prepare(ctx)
 	require.NoError(t, err)
@@ -1516,14 +1809,7 @@ processors:
 		}
 	})
 
-	require.Eventually(t, func() bool {
-		err = fixture.IsHealthy(ctx)
-		if err != nil {
-			t.Logf("waiting for agent healthy: %s", err.Error())
-			return false
-		}
-		return true
-	}, 1*time.Minute, 1*time.Second)
+	waitForAgentHealthy(t, ctx, fixture, 1*time.Minute)
 
 	var docs estools.Documents
 	actualHits := &struct {
@@ -1608,8 +1894,9 @@ func TestFBOtelRestartE2E(t *testing.T) {
 	// At the end it asserts that the unique number of logs in ES is equal to the number of
 	// lines in the input file.
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Windows},
 			{Type: define.Linux},
@@ -1741,7 +2028,7 @@ service:
 	stoppedCh := make(chan int, 1)
 	fCtx, cancel := context.WithDeadline(ctx, time.Now().Add(1*time.Minute))
 	go func() {
-		err = fixture.RunOtelWithClient(fCtx)
+		err = fixture.RunOtelWithOptions(fCtx, aTesting.OtelRunOptions{ShutdownTimeout: otelShutdownTimeout})
 		cancel()
 		assert.Conditionf(t, func() bool {
 			return err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
@@ -1783,7 +2070,7 @@ service:
 	fCtx, cancel = context.WithDeadline(ctx, time.Now().Add(5*time.Minute))
 	go func() {
 		defer fixtureWg.Done()
-		err = fixture.RunOtelWithClient(fCtx)
+		err = fixture.RunOtelWithOptions(fCtx, aTesting.OtelRunOptions{ShutdownTimeout: otelShutdownTimeout})
 	}()
 
 	require.EventuallyWithT(
@@ -1822,8 +2109,9 @@ service:
 
 func TestOtelBeatsAuthExtension(t *testing.T) {
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			// {Type: define.Windows}, we don't support otel on Windows yet
 			{Type: define.Linux},
@@ -1842,7 +2130,7 @@ func TestOtelBeatsAuthExtension(t *testing.T) {
 	esEndpoint, err := integration.GetESHost()
 	require.NoError(t, err, "error getting elasticsearch endpoint")
 	esApiKey := createESApiKey(t, info.ESClient)
-	index := "logs-integration-" + info.Namespace
+	index := info.DataStream("logs", "integration").String()
 
 	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version())
 	require.NoError(t, err)
@@ -1943,14 +2231,7 @@ service:
 		}
 	})
 
-	require.Eventually(t, func() bool {
-		err = fixture.IsHealthy(ctx)
-		if err != nil {
-			t.Logf("waiting for agent healthy: %s", err.Error())
-			return false
-		}
-		return true
-	}, 30*time.Second, 1*time.Second)
+	waitForAgentHealthy(t, ctx, fixture, 30*time.Second)
 
 	// Make sure find the logs
 	actualHits := &struct{ Hits int }{}
@@ -1975,8 +2256,9 @@ service:
 
 func TestOtelBeatsAuthExtensionInvalidCertificates(t *testing.T) {
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			// {Type: define.Windows}, we don't support otel on Windows yet
 			{Type: define.Linux},
@@ -1994,7 +2276,7 @@ func TestOtelBeatsAuthExtensionInvalidCertificates(t *testing.T) {
 	esEndpoint, err := integration.GetESHost()
 	require.NoError(t, err, "error getting elasticsearch endpoint")
 	esApiKey := createESApiKey(t, info.ESClient)
-	index := "logs-integration-" + info.Namespace
+	index := info.DataStream("logs", "integration").String()
 
 	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version())
 	require.NoError(t, err)
@@ -2106,10 +2388,11 @@ service:
 
 func TestOutputStatusReporting(t *testing.T) {
 	define.Require(t, define.Requirements{
-		Sudo:  true,
-		Group: integration.Default,
-		Local: false,
-		Stack: nil,
+		Sudo:      true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     false,
+		Stack:     nil,
 		OS: []define.OS{
 			{Type: define.Windows},
 			{Type: define.Linux},
@@ -2153,8 +2436,7 @@ outputs:
 		otelConfigOptions{
 			StatusReportingEnabled: true,
 		})
-	ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(5*time.Minute))
-	defer cancel()
+	ctx := testcontext.New(t, 5*time.Minute)
 
 	installOpts := aTesting.InstallOpts{
 		NonInteractive: true,
@@ -2223,9 +2505,10 @@ outputs:
 // This tests that live reloading the log level works correctly
 func TestLogReloading(t *testing.T) {
 	define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
-		Stack: &define.Stack{},
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
+		Stack:     &define.Stack{},
 	})
 
 	// Flow of the test
@@ -2286,14 +2569,7 @@ agent.reload:
 
 	require.NoError(t, cmd.Start())
 
-	require.Eventually(t, func() bool {
-		err = fixture.IsHealthy(ctx)
-		if err != nil {
-			t.Logf("waiting for agent healthy: %s", err.Error())
-			return false
-		}
-		return true
-	}, 30*time.Second, 1*time.Second)
+	waitForAgentHealthy(t, ctx, fixture, 30*time.Second)
 
 	// Make sure the Elastic-Agent process is not running before
 	// exiting the test
@@ -2364,8 +2640,9 @@ service:
 
 func TestMonitoringReceiver(t *testing.T) {
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Linux},
 			{Type: define.Darwin},
@@ -2469,14 +2746,7 @@ service:
 		}
 	})
 
-	require.Eventually(t, func() bool {
-		err = fixture.IsHealthy(ctx)
-		if err != nil {
-			t.Logf("waiting for agent healthy: %s", err.Error())
-			return false
-		}
-		return true
-	}, 1*time.Minute, 1*time.Second)
+	waitForAgentHealthy(t, ctx, fixture, 1*time.Minute)
 
 	// Wait for monitoring events to be indexed in Elasticsearch
 	var docs estools.Documents
@@ -2546,8 +2816,9 @@ service:
 
 func TestOtelElasticsearchStateStore_Agentless(t *testing.T) {
 	info := define.Require(t, define.Requirements{
-		Group: integration.Default,
-		Local: true,
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
 		OS: []define.OS{
 			{Type: define.Linux},
 			{Type: define.Darwin},
@@ -2813,6 +3084,67 @@ func (m *httpJSONCursorMockServer) Close() {
 	_ = m.server.Shutdown(ctx)
 }
 
+func TestOtelEnvExpansion(t *testing.T) {
+	define.Require(t, define.Requirements{
+		Group:     integration.Otel,
+		Resources: integration.OtelResources,
+		Local:     true,
+		OS: []define.OS{
+			{Type: define.Windows},
+			{Type: define.Linux},
+			{Type: define.Darwin},
+		},
+	})
+
+	tmpDir := t.TempDir()
+	inputFilePath := filepath.Join(tmpDir, "input.txt")
+	require.NoError(t, os.WriteFile(inputFilePath, []byte("expanded line\n"), 0o600))
+	outputFilePath := filepath.Join(tmpDir, "output.txt")
+
+	// the exporter path is only known through the environment of the collector
+	otelConfig := fmt.Sprintf(`receivers:
+  filelog:
+    include:
+      - %s
+    start_at: beginning
+exporters:
+  file:
+    path: ${env:OTEL_TEST_OUTPUT_PATH}
+service:
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    logs:
+      receivers:
+        - filelog
+      exporters:
+        - file
+`, inputFilePath)
+	otelConfigPath := filepath.Join(tmpDir, "otel.yml")
+	require.NoError(t, os.WriteFile(otelConfigPath, []byte(otelConfig), 0o600))
+
+	fixture, err := define.NewFixtureFromLocalBuild(t, define.Version(),
+		aTesting.WithAdditionalArgs([]string{"--config", otelConfigPath}),
+		aTesting.WithEnv(map[string]string{"OTEL_TEST_OUTPUT_PATH": outputFilePath}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := testcontext.WithDeadline(t, context.Background(), time.Now().Add(5*time.Minute))
+	defer cancel()
+	require.NoError(t, fixture.Prepare(ctx, fakeComponent))
+
+	fixtureErrCh := make(chan error, 1)
+	fixture.RunOtelWithClientAsync(ctx, aTesting.OtelRunOptions{}, fixtureErrCh)
+	t.Cleanup(func() {
+		cancel()
+		<-fixtureErrCh
+	})
+
+	require.NoError(t, fixture.WaitForFileContains(ctx, outputFilePath, []string{"expanded line"}, aTesting.MatchAll, 2*time.Minute),
+		"file exporter path was not expanded from the environment")
+}
+
 type ZapWriter struct {
 	logger *zap.Logger
 	level  zapcore.Level
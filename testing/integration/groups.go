@@ -58,4 +58,11 @@ const (
 
 	// ECHDeployment group of tests. Used for tests that orchestrate ECH deployments.
 	ECHDeployment = "ech-deployment"
+
+	// Otel group of tests. Used for tests running the Elastic Agent in otel mode, they run in
+	// isolation as the collector needs more resources than the Elastic Agent alone.
+	Otel = "otel"
 )
+
+// OtelResources are the resources needed by the tests of the Otel group running a collector.
+var OtelResources = &define.Resources{MinMemoryMB: 4096}